@@ -20,8 +20,19 @@ func newCategorizationAdapter(svc *categorization.Service) importservice.Categor
 }
 
 // CategorizeBatch implements importservice.CategorizationService
-func (a *categorizationAdapter) CategorizeBatch(ctx context.Context, userID uuid.UUID, descriptions []string) ([]*importservice.CategorizationResult, error) {
-	results, err := a.svc.CategorizeBatch(ctx, userID, descriptions)
+func (a *categorizationAdapter) CategorizeBatch(ctx context.Context, userID uuid.UUID, txs []importservice.CategorizationInput) ([]*importservice.CategorizationResult, error) {
+	inputs := make([]categorization.Transaction, len(txs))
+	for i, tx := range txs {
+		inputs[i] = categorization.Transaction{
+			Description:     tx.Description,
+			AmountMinor:     tx.AmountCents,
+			AccountID:       tx.AccountID,
+			InstitutionName: tx.InstitutionName,
+			PostedAt:        tx.PostedAt,
+		}
+	}
+
+	results, err := a.svc.CategorizeBatch(ctx, userID, inputs)
 	if err != nil {
 		return nil, err
 	}
@@ -33,6 +44,8 @@ func (a *categorizationAdapter) CategorizeBatch(ctx context.Context, userID uuid
 			CleanMerchantName: r.CleanMerchantName,
 			CategoryID:        r.CategoryID,
 			IsRecurring:       r.IsRecurring,
+			KnownMerchant:     r.RuleID != nil || r.MerchantID != nil,
+			Confidence:        r.Confidence,
 		}
 	}
 
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"time"
 
 	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/user"
@@ -15,15 +18,18 @@ import (
 	balancehandler "github.com/FACorreiaa/smart-finance-tracker/internal/domain/balance/handler"
 	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/categorization"
 	financehandler "github.com/FACorreiaa/smart-finance-tracker/internal/domain/finance/handler"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/fx"
 	importhandler "github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/handler"
 	importrepo "github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
 	importservice "github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/service"
 	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/insights"
 	insightshandler "github.com/FACorreiaa/smart-finance-tracker/internal/domain/insights/handler"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/transactions"
 
 	"github.com/FACorreiaa/smart-finance-tracker/pkg/config"
 	"github.com/FACorreiaa/smart-finance-tracker/pkg/db"
 	"github.com/FACorreiaa/smart-finance-tracker/pkg/push"
+	"github.com/FACorreiaa/smart-finance-tracker/pkg/storage"
 )
 
 // Dependencies holds all application dependencies
@@ -32,6 +38,9 @@ type Dependencies struct {
 	DB     *db.DB
 	Logger *slog.Logger
 
+	// File storage for uploaded statements
+	FileStorage storage.Storage
+
 	// Repositories
 	AuthRepo           repository.AuthRepository
 	UserRepo           user.UserRepo
@@ -39,16 +48,21 @@ type Dependencies struct {
 	CategorizationRepo *categorization.Repository
 	InsightsRepo       *insights.Repository
 	BalanceRepo        *balance.Repository
+	FXRepo             *fx.Repository
+	TransactionsRepo   *transactions.Repository
 
 	// Services
 	TokenManager          service.TokenManager
 	AuthService           *service.AuthService
 	UserSvc               user.UserService
 	ImportService         *importservice.ImportService
+	ImportJobRunner       *importservice.JobRunner
 	CategorizationService *categorization.Service
 	InsightsService       *insights.Service
 	PushService           *push.Service
 	BalanceService        *balance.Service
+	FXService             *fx.Service
+	TransactionsService   *transactions.Service
 
 	// Handlers
 	AuthHandler     *handler.AuthHandler
@@ -71,6 +85,11 @@ func InitDependencies(cfg *config.Config, logger *slog.Logger) (*Dependencies, e
 		return nil, fmt.Errorf("failed to init database: %w", err)
 	}
 
+	// Initialize file storage
+	if err := deps.initStorage(); err != nil {
+		return nil, fmt.Errorf("failed to init storage: %w", err)
+	}
+
 	// Initialize repositories
 	if err := deps.initRepositories(); err != nil {
 		return nil, fmt.Errorf("failed to init repositories: %w", err)
@@ -86,6 +105,9 @@ func InitDependencies(cfg *config.Config, logger *slog.Logger) (*Dependencies, e
 		return nil, fmt.Errorf("failed to init handlers: %w", err)
 	}
 
+	// Start background workers
+	deps.ImportJobRunner.Start(context.Background())
+
 	logger.Info("all dependencies initialized successfully")
 
 	return deps, nil
@@ -122,6 +144,8 @@ func (d *Dependencies) initRepositories() error {
 	d.CategorizationRepo = categorization.NewRepository(d.DB.Pool)
 	d.InsightsRepo = insights.NewRepository(d.DB.Pool)
 	d.BalanceRepo = balance.NewRepository(d.DB.Pool)
+	d.FXRepo = fx.NewRepository(d.DB.Pool)
+	d.TransactionsRepo = transactions.NewRepository(d.DB.Pool)
 
 	d.Logger.Info("repositories initialized")
 	return nil
@@ -151,10 +175,17 @@ func (d *Dependencies) initServices() error {
 
 	// Categorization service for transaction enrichment
 	d.CategorizationService = categorization.NewService(d.CategorizationRepo)
+	if d.Config.Categorization.AutoLearn {
+		d.CategorizationService.WithAutoLearn()
+	}
 
 	// Import service with categorization wired in
 	d.ImportService = importservice.NewImportService(d.ImportRepo, d.Logger)
 	d.ImportService.WithCategorizationService(newCategorizationAdapter(d.CategorizationService))
+	d.ImportService.WithStorage(d.FileStorage)
+
+	// Background runner for queued import jobs, reading files back from storage
+	d.ImportJobRunner = importservice.NewJobRunner(d.ImportService, d.ImportService, importservice.DefaultJobRunnerConfig(), d.Logger)
 
 	// Push notification service
 	d.PushService = push.NewService(d.Logger)
@@ -165,10 +196,67 @@ func (d *Dependencies) initServices() error {
 	// Balance service for computing user balances
 	d.BalanceService = balance.NewService(d.BalanceRepo)
 
+	// Exchange rates for reporting multi-currency data in a base currency
+	d.FXService = fx.NewService(d.FXRepo, d.Logger)
+	d.loadFXRates()
+
+	// Transaction edits (manual entries, splits, transfers between accounts)
+	d.TransactionsService = transactions.NewService(d.TransactionsRepo, d.Logger)
+	d.TransactionsService.WithCategorizer(d.CategorizationService)
+	d.TransactionsService.WithRuleCreator(d.CategorizationService)
+	d.TransactionsService.WithCorrectionRecorder(d.CategorizationService)
+	d.ImportService.WithTransferDetector(d.TransactionsService)
+
 	d.Logger.Info("services initialized")
 	return nil
 }
 
+// loadFXRates loads ECB rate files from disk. Missing files are not fatal:
+// amounts without a rate are left out of converted totals.
+func (d *Dependencies) loadFXRates() {
+	path := d.Config.FX.RatesPath
+	if path == "" {
+		return
+	}
+	if _, err := d.FXService.LoadPath(context.Background(), path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			d.Logger.Info("no fx rates to load", "path", path)
+			return
+		}
+		d.Logger.Warn("failed to load fx rates", "path", path, "error", err)
+	}
+}
+
+// initStorage selects the backend that keeps uploaded files
+func (d *Dependencies) initStorage() error {
+	cfg := d.Config.Storage
+
+	switch cfg.Backend {
+	case "s3":
+		store, err := storage.NewS3(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			UsePathStyle:    cfg.S3UsePathStyle,
+		}, nil)
+		if err != nil {
+			return err
+		}
+		d.FileStorage = store
+	default:
+		store, err := storage.NewLocal(cfg.LocalPath)
+		if err != nil {
+			return err
+		}
+		d.FileStorage = store
+	}
+
+	d.Logger.Info("file storage initialized", "backend", cfg.Backend)
+	return nil
+}
+
 // initHandlers initializes all handler dependencies
 func (d *Dependencies) initHandlers() error {
 	d.AuthHandler = handler.NewAuthHandler(d.AuthService)
@@ -183,6 +271,10 @@ func (d *Dependencies) initHandlers() error {
 
 // Cleanup closes all resources
 func (d *Dependencies) Cleanup() {
+	// Stop workers first so interrupted jobs are requeued while the pool is open
+	if d.ImportJobRunner != nil {
+		d.ImportJobRunner.Stop()
+	}
 	if d.DB != nil {
 		d.DB.Close()
 	}
@@ -18,14 +18,16 @@ type AccountBalanceData struct {
 	Change24hCents   int64
 	LastActivity     time.Time
 	CurrencyCode     string
+	UnconvertedCount int // Transactions left out of the balance for lack of an exchange rate
 }
 
 // DailyBalanceData holds a single day's balance
 type DailyBalanceData struct {
-	Date         time.Time
-	BalanceCents int64
-	ChangeCents  int64
-	CurrencyCode string
+	Date             time.Time
+	BalanceCents     int64
+	ChangeCents      int64
+	CurrencyCode     string
+	UnconvertedCount int // The day's transactions left out for lack of an exchange rate
 }
 
 // BalanceSummary holds aggregate stats
@@ -48,7 +50,25 @@ func NewRepository(db *pgxpool.Pool) *Repository {
 	return &Repository{db: db}
 }
 
-// GetAccountBalances computes balance for each account by summing transactions
+// convertedAmount is a transaction amount in the user's base currency at the
+// rate of its posting day (NULL when no rate is known, so sums skip it; queries
+// count those rows with unconvertedCount). Queries using it must join users u.
+const convertedAmount = `fx_convert(t.amount_minor, t.currency_code, u.base_currency, t.posted_at::date)`
+
+// unconvertedCount counts the rows convertedAmount has no rate for
+const unconvertedCount = `COUNT(*) FILTER (WHERE ` + convertedAmount + ` IS NULL)`
+
+// excludeTransfers leaves out transfers between the user's own accounts from
+// totals across accounts: both sides cancel out, but only once both are posted
+// and only without exchange rate drift. Per-account balances keep them.
+const excludeTransfers = `NOT EXISTS (
+	SELECT 1 FROM transaction_transfers tr
+	WHERE tr.status <> 'dismissed'
+	  AND t.id IN (tr.outflow_transaction_id, tr.inflow_transaction_id)
+)`
+
+// GetAccountBalances computes balance for each account by summing transactions,
+// converted to the user's base currency
 func (r *Repository) GetAccountBalances(ctx context.Context, userID uuid.UUID) ([]AccountBalanceData, error) {
 	query := `
 		WITH account_balances AS (
@@ -56,21 +76,25 @@ func (r *Repository) GetAccountBalances(ctx context.Context, userID uuid.UUID) (
 				t.account_id,
 				a.name AS account_name,
 				a.type AS account_type,
-				COALESCE(SUM(t.amount_cents), 0) AS total_balance,
-				MAX(t.posted_at) AS last_activity
+				COALESCE(SUM(` + convertedAmount + `), 0) AS total_balance,
+				` + unconvertedCount + ` AS unconverted,
+				MAX(t.posted_at) AS last_activity,
+				u.base_currency
 			FROM transactions t
+			JOIN users u ON u.id = t.user_id
 			LEFT JOIN accounts a ON a.id = t.account_id
-			WHERE t.user_id = $1
-			GROUP BY t.account_id, a.name, a.type
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL
+			GROUP BY t.account_id, a.name, a.type, u.base_currency
 		),
 		daily_change AS (
 			SELECT 
-				account_id,
-				COALESCE(SUM(amount_cents), 0) AS change_24h
-			FROM transactions
-			WHERE user_id = $1 
-			  AND posted_at >= NOW() - INTERVAL '24 hours'
-			GROUP BY account_id
+				t.account_id,
+				COALESCE(SUM(` + convertedAmount + `), 0) AS change_24h
+			FROM transactions t
+			JOIN users u ON u.id = t.user_id
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL
+			  AND t.posted_at >= NOW() - INTERVAL '24 hours'
+			GROUP BY t.account_id
 		)
 		SELECT 
 			ab.account_id,
@@ -78,7 +102,9 @@ func (r *Repository) GetAccountBalances(ctx context.Context, userID uuid.UUID) (
 			COALESCE(ab.account_type, 0),
 			ab.total_balance,
 			COALESCE(dc.change_24h, 0),
-			ab.last_activity
+			ab.last_activity,
+			ab.base_currency,
+			ab.unconverted
 		FROM account_balances ab
 		LEFT JOIN daily_change dc ON dc.account_id = ab.account_id
 		ORDER BY ab.total_balance DESC
@@ -101,12 +127,13 @@ func (r *Repository) GetAccountBalances(ctx context.Context, userID uuid.UUID) (
 			&b.CashBalanceCents,
 			&b.Change24hCents,
 			&b.LastActivity,
+			&b.CurrencyCode,
+			&b.UnconvertedCount,
 		)
 		if err != nil {
 			return nil, err
 		}
 		b.AccountID, _ = uuid.Parse(accountIDStr)
-		b.CurrencyCode = "EUR" // Default, could be fetched from account
 
 		// Separate cash vs investment based on account type
 		// AccountType: 5 = INVESTMENT
@@ -124,9 +151,11 @@ func (r *Repository) GetAccountBalances(ctx context.Context, userID uuid.UUID) (
 // GetTotalBalance computes the total balance across all accounts
 func (r *Repository) GetTotalBalance(ctx context.Context, userID uuid.UUID) (int64, error) {
 	query := `
-		SELECT COALESCE(SUM(amount_cents), 0)
-		FROM transactions
-		WHERE user_id = $1
+		SELECT COALESCE(SUM(` + convertedAmount + `), 0)
+		FROM transactions t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL
+		  AND ` + excludeTransfers + `
 	`
 	var total int64
 	err := r.db.QueryRow(ctx, query, userID).Scan(&total)
@@ -137,11 +166,12 @@ func (r *Repository) GetTotalBalance(ctx context.Context, userID uuid.UUID) (int
 func (r *Repository) GetUpcomingBills(ctx context.Context, userID uuid.UUID) (int64, error) {
 	// Sum recurring subscriptions expected in next 30 days
 	query := `
-		SELECT COALESCE(SUM(ABS(amount_cents)), 0)
-		FROM recurring_subscriptions
-		WHERE user_id = $1 
-		  AND is_active = true
-		  AND next_expected_at <= NOW() + INTERVAL '30 days'
+		SELECT COALESCE(SUM(ABS(fx_convert(rs.amount_minor, rs.currency_code, u.base_currency, CURRENT_DATE))), 0)
+		FROM recurring_subscriptions rs
+		JOIN users u ON u.id = rs.user_id
+		WHERE rs.user_id = $1 
+		  AND rs.status = 'active'
+		  AND rs.next_expected_at <= NOW() + INTERVAL '30 days'
 	`
 	var total int64
 	err := r.db.QueryRow(ctx, query, userID).Scan(&total)
@@ -162,24 +192,29 @@ func (r *Repository) GetBalanceHistory(ctx context.Context, userID uuid.UUID, da
 		),
 		daily_totals AS (
 			SELECT 
-				DATE(posted_at) AS date,
-				SUM(amount_cents) AS daily_sum
-			FROM transactions
-			WHERE user_id = $1
-			  AND posted_at >= CURRENT_DATE - $2
-			GROUP BY DATE(posted_at)
+				DATE(t.posted_at) AS date,
+				SUM(` + convertedAmount + `) AS daily_sum,
+				` + unconvertedCount + ` AS unconverted
+			FROM transactions t
+			JOIN users u ON u.id = t.user_id
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL
+			  AND t.posted_at >= CURRENT_DATE - $2
+			  AND ` + excludeTransfers + `
+			GROUP BY DATE(t.posted_at)
 		),
 		running_balance AS (
 			SELECT 
 				d.date,
 				COALESCE(dt.daily_sum, 0) AS daily_change,
-				SUM(COALESCE(dt.daily_sum, 0)) OVER (ORDER BY d.date) AS balance
+				SUM(COALESCE(dt.daily_sum, 0)) OVER (ORDER BY d.date) AS balance,
+				COALESCE(dt.unconverted, 0) AS unconverted
 			FROM dates d
 			LEFT JOIN daily_totals dt ON dt.date = d.date
 		)
-		SELECT date, balance, daily_change
-		FROM running_balance
-		ORDER BY date
+		SELECT rb.date, rb.balance, rb.daily_change, u.base_currency, rb.unconverted
+		FROM running_balance rb
+		JOIN users u ON u.id = $1
+		ORDER BY rb.date
 	`
 
 	rows, err := r.db.Query(ctx, query, userID, days)
@@ -191,11 +226,10 @@ func (r *Repository) GetBalanceHistory(ctx context.Context, userID uuid.UUID, da
 	var history []DailyBalanceData
 	for rows.Next() {
 		var d DailyBalanceData
-		err := rows.Scan(&d.Date, &d.BalanceCents, &d.ChangeCents)
+		err := rows.Scan(&d.Date, &d.BalanceCents, &d.ChangeCents, &d.CurrencyCode, &d.UnconvertedCount)
 		if err != nil {
 			return nil, err
 		}
-		d.CurrencyCode = "EUR"
 		history = append(history, d)
 	}
 
@@ -207,12 +241,14 @@ func (r *Repository) GetBalanceStats(ctx context.Context, userID uuid.UUID, days
 	query := `
 		WITH daily_balances AS (
 			SELECT 
-				DATE(posted_at) AS date,
-				SUM(SUM(amount_cents)) OVER (ORDER BY DATE(posted_at)) AS running_balance
-			FROM transactions
-			WHERE user_id = $1
-			  AND posted_at >= CURRENT_DATE - $2
-			GROUP BY DATE(posted_at)
+				DATE(t.posted_at) AS date,
+				SUM(COALESCE(SUM(` + convertedAmount + `), 0)) OVER (ORDER BY DATE(t.posted_at)) AS running_balance
+			FROM transactions t
+			JOIN users u ON u.id = t.user_id
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL
+			  AND t.posted_at >= CURRENT_DATE - $2
+			  AND ` + excludeTransfers + `
+			GROUP BY DATE(t.posted_at)
 		)
 		SELECT 
 			COALESCE(MAX(running_balance), 0),
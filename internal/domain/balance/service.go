@@ -24,6 +24,7 @@ type BalanceResult struct {
 	UpcomingBillsCents   int64
 	IsEstimated          bool
 	CurrencyCode         string
+	UnconvertedCount     int // Transactions left out of the totals for lack of an exchange rate
 	Accounts             []AccountBalanceData
 }
 
@@ -34,6 +35,8 @@ type HistoryResult struct {
 	LowestCents  int64
 	AverageCents int64
 	CurrencyCode string
+	// UnconvertedCount is the transactions left out of the history for lack of an exchange rate
+	UnconvertedCount int
 }
 
 // GetBalance computes the user's current balance
@@ -58,9 +61,11 @@ func (s *Service) GetBalance(ctx context.Context, userID uuid.UUID, accountID *u
 
 	// Calculate totals
 	var totalCash, totalInvestment int64
+	unconverted := 0
 	for _, a := range accounts {
 		totalCash += a.CashBalanceCents
 		totalInvestment += a.InvestmentCents
+		unconverted += a.UnconvertedCount
 	}
 	totalNetWorth := totalCash + totalInvestment
 
@@ -79,7 +84,8 @@ func (s *Service) GetBalance(ctx context.Context, userID uuid.UUID, accountID *u
 		TotalInvestmentCents: totalInvestment,
 		UpcomingBillsCents:   upcomingBills,
 		IsEstimated:          true, // Always true until we have real bank APIs
-		CurrencyCode:         "EUR",
+		CurrencyCode:         accountsCurrency(accounts),
+		UnconvertedCount:     unconverted,
 		Accounts:             accounts,
 	}, nil
 }
@@ -99,11 +105,32 @@ func (s *Service) GetBalanceHistory(ctx context.Context, userID uuid.UUID, days
 		highest, lowest, average = 0, 0, 0
 	}
 
+	unconverted := 0
+	for _, d := range history {
+		unconverted += d.UnconvertedCount
+	}
+
 	return &HistoryResult{
-		History:      history,
-		HighestCents: highest,
-		LowestCents:  lowest,
-		AverageCents: average,
-		CurrencyCode: "EUR",
+		History:          history,
+		HighestCents:     highest,
+		LowestCents:      lowest,
+		AverageCents:     average,
+		CurrencyCode:     historyCurrency(history),
+		UnconvertedCount: unconverted,
 	}, nil
 }
+
+// Amounts come back in the user's base currency; EUR is the default when there is nothing to report.
+func accountsCurrency(accounts []AccountBalanceData) string {
+	if len(accounts) > 0 && accounts[0].CurrencyCode != "" {
+		return accounts[0].CurrencyCode
+	}
+	return "EUR"
+}
+
+func historyCurrency(history []DailyBalanceData) string {
+	if len(history) > 0 && history[0].CurrencyCode != "" {
+		return history[0].CurrencyCode
+	}
+	return "EUR"
+}
@@ -0,0 +1,252 @@
+package categorization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// LearnThreshold is how many times a merchant has to be recategorized the
+// same way before a rule is suggested for it
+const LearnThreshold = 3
+
+// merchantKeyWords is how many words of a description name its merchant. Bank
+// descriptions put the merchant first; store numbers and towns follow and vary.
+const merchantKeyWords = 2
+
+// ErrSuggestionNotFound is returned when no suggestion is pending for a merchant and category.
+var ErrSuggestionNotFound = errors.New("rule suggestion not found")
+
+// RuleSuggestion proposes a rule for a merchant the user keeps recategorizing
+type RuleSuggestion struct {
+	MerchantKey       string
+	Pattern           string // Rule pattern matching the merchant
+	CleanName         string
+	CategoryID        uuid.UUID
+	Corrections       int
+	SampleDescription string // Latest corrected description
+}
+
+// minOverridingCorrections is how many times a merchant has to be corrected to
+// the same category before that prior beats a known merchant's category
+const minOverridingCorrections = 2
+
+// correctionPrior is the category the user most often gave a merchant by hand
+type correctionPrior struct {
+	categoryID  uuid.UUID
+	corrections int // Corrections to categoryID
+	total       int // Corrections of the merchant to any category
+}
+
+// overridesMerchants reports whether the prior is strong enough to beat a known
+// merchant: repeated, and the majority of the merchant's corrections. A single
+// correction may be a one-off.
+func (p correctionPrior) overridesMerchants() bool {
+	return p.corrections >= minOverridingCorrections && p.corrections*2 > p.total
+}
+
+// MerchantKey normalizes a description to the merchant it names, so that
+// "COMPRA PINGO DOCE LISBOA 1234" and "PINGO DOCE PORTO" share a key. Words
+// with digits are references, not names, and are dropped. The key is empty when
+// nothing is left.
+func MerchantKey(description string) string {
+	words := strings.FieldsFunc(strings.ToUpper(cleanDescription(description)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	key := make([]string, 0, merchantKeyWords)
+	for _, word := range words {
+		if strings.ContainsFunc(word, unicode.IsDigit) {
+			continue
+		}
+		key = append(key, word)
+		if len(key) == merchantKeyWords {
+			break
+		}
+	}
+	return strings.Join(key, " ")
+}
+
+// learnedPattern is the rule pattern for a merchant key: its words in order,
+// with anything in between.
+func learnedPattern(key string) string {
+	words := strings.Fields(key)
+	for i, word := range words {
+		words[i] = QuotePattern(word)
+	}
+	return strings.Join(words, "*")
+}
+
+// WithAutoLearn makes RecordCorrection create the suggested rule as soon as a
+// merchant reaches LearnThreshold, instead of waiting for the user to accept it.
+func (s *Service) WithAutoLearn() *Service {
+	s.autoLearn = true
+	return s
+}
+
+// RecordCorrection records that the user set a transaction's category by hand.
+// Corrections act as a prior for descriptions no rule covers, and repeated ones
+// become rule suggestions, or rules with WithAutoLearn. Descriptions without a
+// merchant key are ignored.
+func (s *Service) RecordCorrection(ctx context.Context, userID uuid.UUID, correction Correction) error {
+	correction.UserID = userID
+	correction.MerchantKey = MerchantKey(correction.Description)
+	if correction.MerchantKey == "" || correction.CategoryID == uuid.Nil {
+		return nil
+	}
+
+	if err := s.repo.CreateCorrection(ctx, &correction); err != nil {
+		return fmt.Errorf("failed to record correction: %w", err)
+	}
+	s.invalidatePriors(userID)
+	s.invalidateModel(userID)
+
+	if !s.autoLearn {
+		return nil
+	}
+	suggestions, err := s.ListRuleSuggestions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, suggestion := range suggestions {
+		if suggestion.MerchantKey == correction.MerchantKey && suggestion.CategoryID == correction.CategoryID {
+			_, _, err := s.AcceptRuleSuggestion(ctx, userID, suggestion.MerchantKey, suggestion.CategoryID, false)
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRuleSuggestions returns a rule suggestion for every merchant the user
+// recategorized the same way at least LearnThreshold times, most corrected
+// first. Merchants an enabled rule already sends to that category are left out.
+func (s *Service) ListRuleSuggestions(ctx context.Context, userID uuid.UUID) ([]RuleSuggestion, error) {
+	counts, err := s.repo.CountCorrections(ctx, userID, true, LearnThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count corrections: %w", err)
+	}
+	if len(counts) == 0 {
+		return nil, nil
+	}
+	rules, err := s.rules(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []RuleSuggestion
+	for _, c := range counts {
+		result := categorize(Transaction{Description: c.LastDescription}, rules, nil, nil, nil)
+		if result.RuleID != nil && result.CategoryID != nil && *result.CategoryID == c.CategoryID {
+			continue
+		}
+		suggestions = append(suggestions, RuleSuggestion{
+			MerchantKey:       c.MerchantKey,
+			Pattern:           learnedPattern(c.MerchantKey),
+			CleanName:         toTitleCase(c.MerchantKey),
+			CategoryID:        c.CategoryID,
+			Corrections:       c.Count,
+			SampleDescription: c.LastDescription,
+		})
+	}
+	return suggestions, nil
+}
+
+// AcceptRuleSuggestion creates the suggested rule, optionally applies it to
+// existing transactions, and resolves the merchant's corrections.
+func (s *Service) AcceptRuleSuggestion(ctx context.Context, userID uuid.UUID, merchantKey string, categoryID uuid.UUID, applyToExisting bool) (*CategoryRule, *RuleRun, error) {
+	suggestions, err := s.ListRuleSuggestions(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var suggestion *RuleSuggestion
+	for i := range suggestions {
+		if suggestions[i].MerchantKey == merchantKey && suggestions[i].CategoryID == categoryID {
+			suggestion = &suggestions[i]
+			break
+		}
+	}
+	if suggestion == nil {
+		return nil, nil, ErrSuggestionNotFound
+	}
+
+	rule, run, err := s.CreateRule(ctx, userID, RuleInput{
+		Pattern:    suggestion.Pattern,
+		CleanName:  suggestion.CleanName,
+		CategoryID: &suggestion.CategoryID,
+	}, applyToExisting)
+	if rule == nil {
+		return nil, nil, err
+	}
+
+	// The rule exists even when applying it failed, and it hides the suggestion
+	// from now on, so the corrections are resolved either way.
+	if _, resolveErr := s.repo.ResolveCorrections(ctx, userID, merchantKey); resolveErr != nil && err == nil {
+		err = fmt.Errorf("failed to resolve corrections: %w", resolveErr)
+	}
+	return rule, run, err
+}
+
+// DismissRuleSuggestion resolves a merchant's corrections without creating a
+// rule. They still count as a prior; new corrections can suggest it again.
+func (s *Service) DismissRuleSuggestion(ctx context.Context, userID uuid.UUID, merchantKey string) error {
+	resolved, err := s.repo.ResolveCorrections(ctx, userID, merchantKey)
+	if err != nil {
+		return fmt.Errorf("failed to resolve corrections: %w", err)
+	}
+	if resolved == 0 {
+		return ErrSuggestionNotFound
+	}
+	return nil
+}
+
+// priors fetches the user's correction priors by merchant key with caching
+func (s *Service) priors(ctx context.Context, userID uuid.UUID) (map[string]correctionPrior, error) {
+	s.cacheMu.RLock()
+	if priors, ok := s.priorCache[userID]; ok {
+		s.cacheMu.RUnlock()
+		return priors, nil
+	}
+	s.cacheMu.RUnlock()
+
+	counts, err := s.repo.CountCorrections(ctx, userID, false, 1)
+	if err != nil {
+		return nil, err
+	}
+	priors := buildPriors(counts)
+
+	s.cacheMu.Lock()
+	s.priorCache[userID] = priors
+	s.cacheMu.Unlock()
+
+	return priors, nil
+}
+
+// buildPriors picks each merchant's most corrected category, the latest on a
+// tie
+func buildPriors(counts []CorrectionCount) map[string]correctionPrior {
+	priors := make(map[string]correctionPrior)
+	chosen := make(map[string]CorrectionCount)
+	for _, c := range counts {
+		prior := priors[c.MerchantKey]
+		prior.total += c.Count
+		best, seen := chosen[c.MerchantKey]
+		if !seen || c.Count > best.Count || (c.Count == best.Count && c.LastCorrectedAt.After(best.LastCorrectedAt)) {
+			chosen[c.MerchantKey] = c
+			prior.categoryID = c.CategoryID
+			prior.corrections = c.Count
+		}
+		priors[c.MerchantKey] = prior
+	}
+	return priors
+}
+
+func (s *Service) invalidatePriors(userID uuid.UUID) {
+	s.cacheMu.Lock()
+	delete(s.priorCache, userID)
+	s.cacheMu.Unlock()
+}
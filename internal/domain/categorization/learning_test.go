@@ -0,0 +1,125 @@
+package categorization
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMerchantKey(t *testing.T) {
+	tests := []struct {
+		description string
+		expected    string
+	}{
+		{"COMPRA PINGO DOCE LISBOA 1234", "PINGO DOCE"},
+		{"Pingo Doce Porto", "PINGO DOCE"},
+		{"SPOTIFY P1A2B3 STOCKHOLM", "SPOTIFY STOCKHOLM"},
+		{"NETFLIX.COM", "NETFLIX COM"},
+		{"UBER *TRIP*1234", "UBER TRIP"},
+		{"TRF 000123", "TRF"},
+		{"12/03 4567", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if got := MerchantKey(tt.description); got != tt.expected {
+				t.Errorf("MerchantKey(%q) = %q, expected %q", tt.description, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLearnedPattern(t *testing.T) {
+	pattern := learnedPattern("PINGO DOCE")
+	if pattern != "PINGO*DOCE" {
+		t.Fatalf("expected PINGO*DOCE, got %q", pattern)
+	}
+
+	matcher, err := CompileRule(pattern, RuleConditions{})
+	if err != nil {
+		t.Fatalf("CompileRule(%q) failed: %v", pattern, err)
+	}
+	for _, description := range []string{"COMPRA PINGO DOCE LISBOA 1234", "pingo doce porto"} {
+		if !matcher.Match(Transaction{Description: description}) {
+			t.Errorf("expected %q to match %q", pattern, description)
+		}
+	}
+	if matcher.Match(Transaction{Description: "DOCE PINGO"}) {
+		t.Errorf("expected %q to require the words in order", pattern)
+	}
+}
+
+func TestCategorize_UsesCorrectionPriors(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	groceries, restaurants := uuid.New(), uuid.New()
+	priors := buildPriors([]CorrectionCount{
+		{MerchantKey: "PINGO DOCE", CategoryID: restaurants, Count: 1, LastCorrectedAt: day.AddDate(0, 0, 1)},
+		{MerchantKey: "PINGO DOCE", CategoryID: groceries, Count: 2, LastCorrectedAt: day},
+		{MerchantKey: "UBER EATS", CategoryID: groceries, Count: 1, LastCorrectedAt: day},
+		{MerchantKey: "UBER EATS", CategoryID: restaurants, Count: 1, LastCorrectedAt: day.AddDate(0, 0, 1)},
+	})
+
+	result := categorize(Transaction{Description: "COMPRA PINGO DOCE PORTO"}, nil, priors, nil, nil)
+	if result.Explanation.Source != MatchSourceCorrection || result.CategoryID == nil || *result.CategoryID != groceries {
+		t.Fatalf("expected the most corrected category, got %+v", result)
+	}
+	if result.RuleID != nil || result.MerchantID != nil {
+		t.Errorf("expected no rule or merchant on a prior match, got %+v", result)
+	}
+
+	if result := categorize(Transaction{Description: "UBER EATS LISBOA"}, nil, priors, nil, nil); result.CategoryID == nil || *result.CategoryID != restaurants {
+		t.Errorf("expected the latest correction to win a tie, got %+v", result.Explanation)
+	}
+
+	rule := testRule(t, "PINGO DOCE", 0, day, RuleConditions{})
+	if result := categorize(Transaction{Description: "PINGO DOCE PORTO"}, []compiledRule{rule}, priors, nil, nil); result.Explanation.Source != MatchSourceRule {
+		t.Errorf("expected a rule to beat the prior, got %+v", result.Explanation)
+	}
+}
+
+func TestCategorize_PriorNeedsRepeatedCorrectionsToBeatMerchant(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	groceries, restaurants := uuid.New(), uuid.New()
+
+	matcher, err := CompileRule("%PINGO DOCE%", RuleConditions{})
+	if err != nil {
+		t.Fatalf("CompileRule failed: %v", err)
+	}
+	merchants := []compiledMerchant{{merchant: Merchant{ID: uuid.New(), RawPattern: "%PINGO DOCE%", CleanName: "Pingo Doce", DefaultCategoryID: &groceries}, matcher: matcher}}
+	tx := Transaction{Description: "COMPRA PINGO DOCE PORTO"}
+
+	tests := []struct {
+		name     string
+		counts   []CorrectionCount
+		source   string
+		expected uuid.UUID
+	}{
+		{"single correction", []CorrectionCount{
+			{MerchantKey: "PINGO DOCE", CategoryID: restaurants, Count: 1, LastCorrectedAt: day},
+		}, MatchSourceMerchant, groceries},
+		{"repeated but not the majority", []CorrectionCount{
+			{MerchantKey: "PINGO DOCE", CategoryID: restaurants, Count: 2, LastCorrectedAt: day.AddDate(0, 0, 1)},
+			{MerchantKey: "PINGO DOCE", CategoryID: groceries, Count: 2, LastCorrectedAt: day},
+		}, MatchSourceMerchant, groceries},
+		{"repeated majority", []CorrectionCount{
+			{MerchantKey: "PINGO DOCE", CategoryID: restaurants, Count: 2, LastCorrectedAt: day},
+			{MerchantKey: "PINGO DOCE", CategoryID: groceries, Count: 1, LastCorrectedAt: day},
+		}, MatchSourceCorrection, restaurants},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := categorize(tx, nil, buildPriors(tt.counts), merchants, nil)
+			if result.Explanation.Source != tt.source || result.CategoryID == nil || *result.CategoryID != tt.expected {
+				t.Errorf("expected %s from %s, got %+v", tt.expected, tt.source, result.Explanation)
+			}
+		})
+	}
+
+	// Without a known merchant even a single correction applies
+	priors := buildPriors([]CorrectionCount{{MerchantKey: "PINGO DOCE", CategoryID: restaurants, Count: 1, LastCorrectedAt: day}})
+	if result := categorize(tx, nil, priors, nil, nil); result.Explanation.Source != MatchSourceCorrection {
+		t.Errorf("expected a weak prior to apply without a merchant, got %+v", result.Explanation)
+	}
+}
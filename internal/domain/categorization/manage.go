@@ -0,0 +1,165 @@
+package categorization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrRuleNotFound is returned when a rule does not exist or belongs to another user.
+	ErrRuleNotFound = errors.New("category rule not found")
+	// ErrRuleExists is returned when an edit would duplicate another rule's pattern and conditions.
+	ErrRuleExists = errors.New("a rule with this pattern and conditions already exists")
+	// ErrRuleDisabled is returned when applying a disabled rule.
+	ErrRuleDisabled = errors.New("category rule is disabled")
+	// ErrRuleRunNotFound is returned when a rule run does not exist or belongs to another user.
+	ErrRuleRunNotFound = errors.New("category rule run not found")
+	// ErrRuleRunRolledBack is returned when rolling back a run twice.
+	ErrRuleRunRolledBack = errors.New("category rule run is already rolled back")
+)
+
+// GetRule returns one of the user's rules
+func (s *Service) GetRule(ctx context.Context, userID uuid.UUID, ruleID uuid.UUID) (*CategoryRule, error) {
+	rule, err := s.repo.GetRuleByID(ctx, ruleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule: %w", err)
+	}
+	if rule == nil || rule.UserID != userID {
+		return nil, ErrRuleNotFound
+	}
+	return rule, nil
+}
+
+// UpdateRule replaces a rule's pattern, outcome, priority and conditions,
+// keeping whether it is enabled, and optionally applies it to existing
+// transactions. The run is nil when nothing was applied or changed.
+func (s *Service) UpdateRule(ctx context.Context, userID uuid.UUID, ruleID uuid.UUID, input RuleInput, applyToExisting bool) (*CategoryRule, *RuleRun, error) {
+	existing, err := s.GetRule(ctx, userID, ruleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	rule, err := buildRule(userID, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	rule.ID = existing.ID
+	rule.Enabled = existing.Enabled
+	rule.CreatedAt = existing.CreatedAt
+
+	duplicate, err := s.repo.FindRule(ctx, userID, rule.MatchPattern, rule.Conditions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check for duplicate rule: %w", err)
+	}
+	if duplicate != nil && duplicate.ID != rule.ID {
+		return nil, nil, ErrRuleExists
+	}
+
+	defer s.invalidateRules(userID)
+	updated, err := s.repo.UpdateRule(ctx, rule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update rule: %w", err)
+	}
+	if !updated {
+		return nil, nil, ErrRuleNotFound
+	}
+
+	if !applyToExisting || !rule.Enabled {
+		return rule, nil, nil
+	}
+	run, err := s.backfill(ctx, rule)
+	if err != nil {
+		return rule, nil, fmt.Errorf("failed to apply rule: %w", err)
+	}
+	return rule, run, nil
+}
+
+// SetRuleEnabled enables or disables a rule. A disabled rule stays listed but
+// categorizes nothing until it is enabled again.
+func (s *Service) SetRuleEnabled(ctx context.Context, userID uuid.UUID, ruleID uuid.UUID, enabled bool) (*CategoryRule, error) {
+	defer s.invalidateRules(userID)
+	found, err := s.repo.SetRuleEnabled(ctx, userID, ruleID, enabled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update rule: %w", err)
+	}
+	if !found {
+		return nil, ErrRuleNotFound
+	}
+	return s.GetRule(ctx, userID, ruleID)
+}
+
+// DeleteRule deletes a rule. Transactions it categorized keep their category;
+// its runs stay listed and can still be rolled back.
+func (s *Service) DeleteRule(ctx context.Context, userID uuid.UUID, ruleID uuid.UUID) error {
+	defer s.invalidateRules(userID)
+	deleted, err := s.repo.DeleteRule(ctx, userID, ruleID)
+	if err != nil {
+		return fmt.Errorf("failed to delete rule: %w", err)
+	}
+	if !deleted {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// ApplyRule re-runs a rule on the user's history: every existing transaction
+// the rule wins gets its merchant name and category. The run is nil when no
+// transaction changed.
+func (s *Service) ApplyRule(ctx context.Context, userID uuid.UUID, ruleID uuid.UUID) (*RuleRun, error) {
+	rule, err := s.GetRule(ctx, userID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if !rule.Enabled {
+		return nil, ErrRuleDisabled
+	}
+
+	run, err := s.backfill(ctx, rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply rule: %w", err)
+	}
+	return run, nil
+}
+
+// ListRuleRuns returns the user's rule runs, newest first, optionally for one
+// rule, and the total count.
+func (s *Service) ListRuleRuns(ctx context.Context, userID uuid.UUID, ruleID *uuid.UUID, limit, offset int) ([]*RuleRun, int64, error) {
+	runs, total, err := s.repo.ListRuleRuns(ctx, userID, ruleID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list rule runs: %w", err)
+	}
+	return runs, total, nil
+}
+
+// RollbackRuleRun restores the merchant names and categories a run replaced.
+// Transactions edited since the run keep their edits. It returns the run and
+// how many transactions were restored.
+func (s *Service) RollbackRuleRun(ctx context.Context, userID uuid.UUID, runID uuid.UUID) (*RuleRun, int, error) {
+	run, err := s.repo.GetRuleRun(ctx, runID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get rule run: %w", err)
+	}
+	if run == nil || run.UserID != userID {
+		return nil, 0, ErrRuleRunNotFound
+	}
+	if run.RolledBackAt != nil {
+		return nil, 0, ErrRuleRunRolledBack
+	}
+
+	restored, found, err := s.repo.RollbackRuleRun(ctx, userID, runID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to roll back rule run: %w", err)
+	}
+	if !found {
+		// Rolled back concurrently
+		return nil, 0, ErrRuleRunRolledBack
+	}
+
+	run, err = s.repo.GetRuleRun(ctx, runID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get rule run: %w", err)
+	}
+	return run, restored, nil
+}
@@ -0,0 +1,199 @@
+package categorization
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// minModelConfidence is the lowest probability at which a model guess is
+// used; below it the transaction stays uncategorized
+const minModelConfidence = 0.4
+
+// TrainingExample is a categorized transaction the model learns from
+type TrainingExample struct {
+	Transaction
+	CategoryID uuid.UUID
+	Weight     float64 // Zero counts as 1; seed examples weigh less than the user's own
+}
+
+// naiveBayes is a multinomial naive Bayes classifier over the words of a
+// description, the size of the amount and the weekday. It is trained per user
+// and runs entirely in process.
+type naiveBayes struct {
+	classes map[uuid.UUID]*classStats
+	vocab   map[string]bool
+	docs    float64
+}
+
+// trainedModel is a cached model; model is nil when there was nothing to learn
+type trainedModel struct {
+	model     *naiveBayes
+	trainedAt time.Time
+}
+
+type classStats struct {
+	docs     float64
+	features float64
+	counts   map[string]float64
+}
+
+// trainModel fits a classifier to the examples. It is nil when no example has
+// a usable word, since predictions need at least one known word.
+func trainModel(examples []TrainingExample) *naiveBayes {
+	m := &naiveBayes{
+		classes: make(map[uuid.UUID]*classStats),
+		vocab:   make(map[string]bool),
+	}
+	for _, ex := range examples {
+		words, other := modelFeatures(ex.Transaction)
+		if len(words) == 0 || ex.CategoryID == uuid.Nil {
+			continue
+		}
+		weight := ex.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+
+		c := m.classes[ex.CategoryID]
+		if c == nil {
+			c = &classStats{counts: make(map[string]float64)}
+			m.classes[ex.CategoryID] = c
+		}
+		c.docs += weight
+		m.docs += weight
+		for _, f := range append(words, other...) {
+			c.counts[f] += weight
+			c.features += weight
+			m.vocab[f] = true
+		}
+	}
+	if m.docs == 0 {
+		return nil
+	}
+	return m
+}
+
+// predict returns the most likely category and its probability. It reports
+// false when none of the description's words were seen in training, or when
+// fewer than two categories were, since a lone category always wins with
+// probability 1.
+func (m *naiveBayes) predict(tx Transaction) (uuid.UUID, float64, bool) {
+	if m == nil || len(m.classes) < 2 {
+		return uuid.Nil, 0, false
+	}
+	words, other := modelFeatures(tx)
+	var features []string
+	known := false
+	for _, w := range words {
+		if m.vocab[w] {
+			features = append(features, w)
+			known = true
+		}
+	}
+	if !known {
+		return uuid.Nil, 0, false
+	}
+	for _, f := range other {
+		if m.vocab[f] {
+			features = append(features, f)
+		}
+	}
+
+	vocab := float64(len(m.vocab))
+	scores := make(map[uuid.UUID]float64, len(m.classes))
+	var best uuid.UUID
+	bestScore := math.Inf(-1)
+	for id, c := range m.classes {
+		score := math.Log(c.docs / m.docs)
+		for _, f := range features {
+			score += math.Log((c.counts[f] + 1) / (c.features + vocab))
+		}
+		scores[id] = score
+		if score > bestScore || (score == bestScore && id.String() < best.String()) {
+			best, bestScore = id, score
+		}
+	}
+
+	// Softmax of the log scores gives the winner's probability
+	var sum float64
+	for _, score := range scores {
+		sum += math.Exp(score - bestScore)
+	}
+	return best, 1 / sum, true
+}
+
+// modelFeatures splits a transaction into description words and the other
+// features: amount bucket and weekday, when known. Words with digits are
+// references and are left out.
+func modelFeatures(tx Transaction) (words []string, other []string) {
+	fields := strings.FieldsFunc(strings.ToUpper(cleanDescription(tx.Description)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, field := range fields {
+		if len([]rune(field)) < 2 || strings.ContainsFunc(field, unicode.IsDigit) {
+			continue
+		}
+		words = append(words, field)
+	}
+
+	if tx.AmountMinor != 0 {
+		other = append(other, amountBucket(tx.AmountMinor))
+	}
+	if !tx.PostedAt.IsZero() {
+		other = append(other, "#day:"+tx.PostedAt.Weekday().String())
+	}
+	return words, other
+}
+
+// amountBucket names the sign and rough size of an amount, in major units
+func amountBucket(amountMinor int64) string {
+	sign, abs := "+", amountMinor
+	if amountMinor < 0 {
+		sign, abs = "-", -amountMinor
+	}
+	for _, limit := range []int64{500, 2000, 5000, 10000, 50000, 100000} {
+		if abs < limit {
+			return fmt.Sprintf("#amount:%s<%d", sign, limit/100)
+		}
+	}
+	return "#amount:" + sign + ">=1000"
+}
+
+// model fetches the user's model with caching, training it on their
+// categorized history plus the seed examples of their categories
+func (s *Service) model(ctx context.Context, userID uuid.UUID) (*naiveBayes, error) {
+	s.cacheMu.RLock()
+	if cached, ok := s.modelCache[userID]; ok && time.Since(cached.trainedAt) < modelMaxAge {
+		s.cacheMu.RUnlock()
+		return cached.model, nil
+	}
+	s.cacheMu.RUnlock()
+
+	examples, err := s.repo.ListTrainingExamples(ctx, userID, modelTrainingSize)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.repo.GetCategoryIDsByName(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	model := trainModel(append(examples, seedExamples(categories)...))
+
+	s.cacheMu.Lock()
+	s.modelCache[userID] = trainedModel{model: model, trainedAt: time.Now()}
+	s.cacheMu.Unlock()
+
+	return model, nil
+}
+
+func (s *Service) invalidateModel(userID uuid.UUID) {
+	s.cacheMu.Lock()
+	delete(s.modelCache, userID)
+	s.cacheMu.Unlock()
+}
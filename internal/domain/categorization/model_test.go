@@ -0,0 +1,97 @@
+package categorization
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestModel_Predict(t *testing.T) {
+	groceries, restaurants, salary := uuid.New(), uuid.New(), uuid.New()
+	saturday := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	friday := saturday.AddDate(0, 0, -1)
+
+	var examples []TrainingExample
+	for i := 0; i < 5; i++ {
+		examples = append(examples,
+			TrainingExample{Transaction: Transaction{Description: "COMPRA MERCADO DA VILA 12", AmountMinor: -4200, PostedAt: saturday}, CategoryID: groceries},
+			TrainingExample{Transaction: Transaction{Description: "TASCA DO ZE LISBOA", AmountMinor: -1800, PostedAt: friday}, CategoryID: restaurants},
+		)
+	}
+	examples = append(examples, TrainingExample{Transaction: Transaction{Description: "VENCIMENTO ACME LDA", AmountMinor: 250000}, CategoryID: salary})
+	model := trainModel(examples)
+
+	tests := []struct {
+		name     string
+		tx       Transaction
+		expected uuid.UUID
+	}{
+		{"same merchant", Transaction{Description: "MERCADO DA VILA", AmountMinor: -3900, PostedAt: saturday}, groceries},
+		{"shared word, typical amount and day", Transaction{Description: "TASCA DA VILA", AmountMinor: -1500, PostedAt: friday}, restaurants},
+		{"income", Transaction{Description: "VENCIMENTO MARCO", AmountMinor: 260000}, salary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			categoryID, confidence, ok := model.predict(tt.tx)
+			if !ok || categoryID != tt.expected {
+				t.Fatalf("expected %s, got %s (ok=%v)", tt.expected, categoryID, ok)
+			}
+			if confidence <= 0 || confidence > 1 {
+				t.Errorf("confidence %v out of range", confidence)
+			}
+		})
+	}
+
+	if _, _, ok := model.predict(Transaction{Description: "SOMETHING NEW", AmountMinor: -4200, PostedAt: saturday}); ok {
+		t.Error("expected no prediction without a known word")
+	}
+	if _, _, ok := (*naiveBayes)(nil).predict(Transaction{Description: "MERCADO"}); ok {
+		t.Error("expected no prediction from a nil model")
+	}
+	if trainModel(nil) != nil {
+		t.Error("expected no model without examples")
+	}
+
+	single := trainModel(examples[:1])
+	if _, _, ok := single.predict(Transaction{Description: "MERCADO DA VILA"}); ok {
+		t.Error("expected no prediction from a model trained on a single category")
+	}
+}
+
+func TestCategorize_FallsBackToModel(t *testing.T) {
+	groceries, transport := uuid.New(), uuid.New()
+	model := trainModel(seedExamples(map[string]uuid.UUID{"supermercado": groceries, "transportes": transport}))
+	if model == nil {
+		t.Fatal("expected seed examples to train a model")
+	}
+
+	result := categorize(Transaction{Description: "COMPRA LIDL LISBOA 1234"}, nil, nil, nil, model)
+	if result.Explanation.Source != MatchSourceModel || result.CategoryID == nil || *result.CategoryID != groceries {
+		t.Fatalf("expected a model guess, got %+v", result.Explanation)
+	}
+	if result.Confidence < minModelConfidence || result.Confidence > 1 {
+		t.Errorf("unexpected confidence %v", result.Confidence)
+	}
+
+	if result := categorize(Transaction{Description: "NETFLIX.COM"}, nil, nil, nil, model); result.Explanation.Source != MatchSourceNone || result.Confidence != 0 {
+		t.Errorf("expected no match for a category the user lacks, got %+v", result.Explanation)
+	}
+}
+
+func TestAmountBucket(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		expected string
+	}{
+		{-250, "#amount:-<5"},
+		{-4200, "#amount:-<50"},
+		{250000, "#amount:+>=1000"},
+		{7500, "#amount:+<100"},
+	}
+	for _, tt := range tests {
+		if got := amountBucket(tt.amount); got != tt.expected {
+			t.Errorf("amountBucket(%d) = %q, expected %q", tt.amount, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package categorization
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Sources of a categorization
+const (
+	MatchSourceRule       = "rule"
+	MatchSourceCorrection = "correction"
+	MatchSourceMerchant   = "merchant"
+	MatchSourceModel      = "model"
+	MatchSourceNone       = "none"
+)
+
+// MatchExplanation says which rule or merchant categorized a transaction and
+// why it won over the other rules that matched too.
+type MatchExplanation struct {
+	Source           string // "rule", "correction", "merchant", "model" or "none"
+	Pattern          string
+	Priority         int
+	Specificity      int
+	Reason           string
+	OutrankedRuleIDs []uuid.UUID // Rules that matched as well but lost, best first
+}
+
+// RuleConflict reports a rule that matches some of the user's transactions
+// only to lose them to a higher-ranked rule.
+type RuleConflict struct {
+	Winner          CategoryRule
+	Shadowed        CategoryRule
+	Overlap         int  // Sampled transactions both rules match; Winner categorizes them
+	ShadowedMatches int  // Sampled transactions Shadowed matches at all
+	FullyShadowed   bool // Shadowed never wins on the sampled transactions
+	SameOutcome     bool // Both rules set the same name and category, so the overlap is harmless
+}
+
+// rankRules orders rules so that the first match wins: higher priority first,
+// then the more specific rule, then the newer one.
+func rankRules(rules []compiledRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].outranks(rules[j])
+	})
+}
+
+func (r compiledRule) specificity() int {
+	if r.matcher == nil {
+		return 0
+	}
+	return r.matcher.Specificity()
+}
+
+func (r compiledRule) outranks(other compiledRule) bool {
+	if r.rule.Priority != other.rule.Priority {
+		return r.rule.Priority > other.rule.Priority
+	}
+	if a, b := r.specificity(), other.specificity(); a != b {
+		return a > b
+	}
+	if !r.rule.CreatedAt.Equal(other.rule.CreatedAt) {
+		return r.rule.CreatedAt.After(other.rule.CreatedAt)
+	}
+	return r.rule.ID.String() < other.rule.ID.String()
+}
+
+// categorize applies ranked rules, then correction priors by merchant key,
+// then merchants, then the model, to one transaction. A prior only beats a
+// known merchant when it is strong; weaker priors apply after merchants.
+func categorize(tx Transaction, rules []compiledRule, priors map[string]correctionPrior, merchants []compiledMerchant, model *naiveBayes) *CategorizationResult {
+	result := &CategorizationResult{
+		CleanMerchantName: cleanDescription(tx.Description),
+	}
+
+	var matched []compiledRule
+	for _, r := range rules {
+		if r.matcher != nil && r.matcher.Match(tx) {
+			matched = append(matched, r)
+		}
+	}
+	if len(matched) > 0 {
+		winner := matched[0]
+		if winner.rule.CleanName != nil && *winner.rule.CleanName != "" {
+			result.CleanMerchantName = *winner.rule.CleanName
+		}
+		result.CategoryID = winner.rule.AssignedCategoryID
+		result.IsRecurring = winner.rule.IsRecurring
+		result.RuleID = &winner.rule.ID
+		if result.CategoryID != nil {
+			result.Confidence = 1
+		}
+		result.Explanation = MatchExplanation{
+			Source:      MatchSourceRule,
+			Pattern:     winner.rule.MatchPattern,
+			Priority:    winner.rule.Priority,
+			Specificity: winner.specificity(),
+			Reason:      explainWin(winner, matched[1:]),
+		}
+		for _, r := range matched[1:] {
+			result.Explanation.OutrankedRuleIDs = append(result.Explanation.OutrankedRuleIDs, r.rule.ID)
+		}
+		return result
+	}
+
+	key := MerchantKey(tx.Description)
+	prior, hasPrior := priors[key]
+	if hasPrior && prior.overridesMerchants() {
+		return applyPrior(result, key, prior)
+	}
+
+	for _, m := range merchants {
+		if m.matcher == nil || !m.matcher.Match(tx) {
+			continue
+		}
+		result.CleanMerchantName = m.merchant.CleanName
+		result.CategoryID = m.merchant.DefaultCategoryID
+		result.MerchantID = &m.merchant.ID
+		if result.CategoryID != nil {
+			result.Confidence = 1
+		}
+		result.Explanation = MatchExplanation{
+			Source:      MatchSourceMerchant,
+			Pattern:     m.merchant.RawPattern,
+			Specificity: m.matcher.Specificity(),
+			Reason:      fmt.Sprintf("no rule matched; known merchant pattern %q", m.merchant.RawPattern),
+		}
+		return result
+	}
+
+	if hasPrior {
+		return applyPrior(result, key, prior)
+	}
+
+	if categoryID, confidence, ok := model.predict(tx); ok && confidence >= minModelConfidence {
+		result.CategoryID = &categoryID
+		result.Confidence = confidence
+		result.Explanation = MatchExplanation{
+			Source: MatchSourceModel,
+			Reason: fmt.Sprintf("no rule, correction or known merchant matched; guessed from your categorized transactions with %.0f%% confidence",
+				confidence*100),
+		}
+		return result
+	}
+
+	result.Explanation = MatchExplanation{
+		Source: MatchSourceNone,
+		Reason: "no rule or known merchant matched",
+	}
+	return result
+}
+
+// applyPrior categorizes a transaction with the category the user most often
+// corrected its merchant to
+func applyPrior(result *CategorizationResult, key string, prior correctionPrior) *CategorizationResult {
+	result.CategoryID = &prior.categoryID
+	result.Confidence = float64(prior.corrections) / float64(prior.total)
+	result.Explanation = MatchExplanation{
+		Source:  MatchSourceCorrection,
+		Pattern: key,
+		Reason: fmt.Sprintf("no rule matched; you recategorized %d of %d similar transaction(s) this way",
+			prior.corrections, prior.total),
+	}
+	return result
+}
+
+// explainWin says why a rule beat the other matching rules, which are ranked
+func explainWin(winner compiledRule, others []compiledRule) string {
+	if len(others) == 0 {
+		return fmt.Sprintf("only rule matching: %q", winner.rule.MatchPattern)
+	}
+
+	next := others[0]
+	switch {
+	case winner.rule.Priority > next.rule.Priority:
+		return fmt.Sprintf("priority %d is above the %d other matching rule(s); next was %q at priority %d",
+			winner.rule.Priority, len(others), next.rule.MatchPattern, next.rule.Priority)
+	case winner.specificity() > next.specificity():
+		return fmt.Sprintf("same priority as %q but more specific (%d vs %d)",
+			next.rule.MatchPattern, winner.specificity(), next.specificity())
+	default:
+		return fmt.Sprintf("same priority and specificity as %q but created later", next.rule.MatchPattern)
+	}
+}
+
+// findConflicts runs ranked rules over sample transactions and reports every
+// pair where a rule matched transactions a higher-ranked rule took. Conflicts
+// come in rank order of the shadowed rule, largest overlap first.
+func findConflicts(rules []compiledRule, txs []Transaction) []RuleConflict {
+	type pair struct{ winner, shadowed int }
+	overlap := make(map[pair]int)
+	matches := make([]int, len(rules))
+	wins := make([]int, len(rules))
+
+	for _, tx := range txs {
+		winner := -1
+		for i, r := range rules {
+			if r.matcher == nil || !r.matcher.Match(tx) {
+				continue
+			}
+			matches[i]++
+			if winner < 0 {
+				winner = i
+				wins[i]++
+				continue
+			}
+			overlap[pair{winner, i}]++
+		}
+	}
+
+	conflicts := make([]RuleConflict, 0, len(overlap))
+	for p, count := range overlap {
+		winner, shadowed := rules[p.winner].rule, rules[p.shadowed].rule
+		conflicts = append(conflicts, RuleConflict{
+			Winner:          winner,
+			Shadowed:        shadowed,
+			Overlap:         count,
+			ShadowedMatches: matches[p.shadowed],
+			FullyShadowed:   wins[p.shadowed] == 0,
+			SameOutcome:     sameOutcome(winner, shadowed),
+		})
+	}
+
+	rank := make(map[uuid.UUID]int, len(rules))
+	for i, r := range rules {
+		rank[r.rule.ID] = i
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		a, b := conflicts[i], conflicts[j]
+		if rank[a.Shadowed.ID] != rank[b.Shadowed.ID] {
+			return rank[a.Shadowed.ID] < rank[b.Shadowed.ID]
+		}
+		if a.Overlap != b.Overlap {
+			return a.Overlap > b.Overlap
+		}
+		return rank[a.Winner.ID] < rank[b.Winner.ID]
+	})
+	return conflicts
+}
+
+func sameOutcome(a, b CategoryRule) bool {
+	sameCategory := (a.AssignedCategoryID == nil) == (b.AssignedCategoryID == nil) &&
+		(a.AssignedCategoryID == nil || *a.AssignedCategoryID == *b.AssignedCategoryID)
+	sameName := (a.CleanName == nil) == (b.CleanName == nil) &&
+		(a.CleanName == nil || *a.CleanName == *b.CleanName)
+	return sameCategory && sameName && a.IsRecurring == b.IsRecurring
+}
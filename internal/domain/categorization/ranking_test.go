@@ -0,0 +1,144 @@
+package categorization
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testRule compiles a stored rule the way Service.rules does
+func testRule(t *testing.T, pattern string, priority int, created time.Time, conditions RuleConditions) compiledRule {
+	t.Helper()
+	matcher, err := CompileRule(pattern, conditions)
+	if err != nil {
+		t.Fatalf("CompileRule(%q) failed: %v", pattern, err)
+	}
+	category := uuid.New()
+	name := strings.Trim(pattern, "^$%")
+	return compiledRule{
+		rule: CategoryRule{
+			ID:                 uuid.New(),
+			MatchPattern:       pattern,
+			CleanName:          &name,
+			AssignedCategoryID: &category,
+			Priority:           priority,
+			Conditions:         conditions,
+			CreatedAt:          created,
+		},
+		matcher: matcher,
+	}
+}
+
+func TestSpecificity(t *testing.T) {
+	hundred := int64(10000)
+	ordered := []struct {
+		pattern    string
+		conditions RuleConditions
+	}{
+		{"AMAZON", RuleConditions{MinAmountMinor: &hundred, Direction: DirectionExpense}},
+		{"^AMAZON$", RuleConditions{}},
+		{"^AMAZON", RuleConditions{}},
+		{"%AMAZON%", RuleConditions{}},
+		{"AMAZ*", RuleConditions{}},
+		{`re:AM(AZ|X)`, RuleConditions{}},
+	}
+
+	previous := -1
+	for i, tt := range ordered {
+		matcher, err := CompileRule(tt.pattern, tt.conditions)
+		if err != nil {
+			t.Fatalf("CompileRule(%q) failed: %v", tt.pattern, err)
+		}
+		if i > 0 && matcher.Specificity() >= previous {
+			t.Errorf("%q: specificity %d should be below %d", tt.pattern, matcher.Specificity(), previous)
+		}
+		previous = matcher.Specificity()
+	}
+}
+
+func TestCategorize_RanksRules(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	hundred := int64(10000)
+	broad := testRule(t, "AMAZON", 0, day, RuleConditions{})
+	large := testRule(t, "AMAZON", 0, day.AddDate(0, 0, -1), RuleConditions{MinAmountMinor: &hundred})
+	newer := testRule(t, "AMAZON", 0, day.AddDate(0, 0, 1), RuleConditions{})
+	pinned := testRule(t, "AMAZON PRIME", 5, day, RuleConditions{})
+
+	rules := []compiledRule{broad, large, newer, pinned}
+	rankRules(rules)
+
+	tests := []struct {
+		name      string
+		tx        Transaction
+		winner    compiledRule
+		reason    string
+		outranked int
+	}{
+		{"priority beats specificity", Transaction{Description: "AMAZON PRIME", AmountMinor: -20000}, pinned, "priority 5", 3},
+		{"more specific wins a tie", Transaction{Description: "AMAZON MKTPLACE", AmountMinor: -20000}, large, "more specific", 2},
+		{"newer wins an exact tie", Transaction{Description: "AMAZON MKTPLACE", AmountMinor: -500}, newer, "created later", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := categorize(tt.tx, rules, nil, nil, nil)
+			if result.RuleID == nil || *result.RuleID != tt.winner.rule.ID {
+				t.Fatalf("expected rule %q to win, got %+v", tt.winner.rule.MatchPattern, result.Explanation)
+			}
+			if result.Explanation.Source != MatchSourceRule || !strings.Contains(result.Explanation.Reason, tt.reason) {
+				t.Errorf("expected a rule explanation mentioning %q, got %+v", tt.reason, result.Explanation)
+			}
+			if len(result.Explanation.OutrankedRuleIDs) != tt.outranked {
+				t.Errorf("expected %d outranked rules, got %d", tt.outranked, len(result.Explanation.OutrankedRuleIDs))
+			}
+		})
+	}
+
+	merchant, err := CompileRule("%NETFLIX%", RuleConditions{})
+	if err != nil {
+		t.Fatalf("CompileRule failed: %v", err)
+	}
+	merchants := []compiledMerchant{{merchant: Merchant{ID: uuid.New(), RawPattern: "%NETFLIX%", CleanName: "Netflix"}, matcher: merchant}}
+	if result := categorize(Transaction{Description: "NETFLIX.COM"}, rules, nil, merchants, nil); result.Explanation.Source != MatchSourceMerchant || result.MerchantID == nil {
+		t.Errorf("expected a merchant match, got %+v", result.Explanation)
+	}
+	if result := categorize(Transaction{Description: "SPOTIFY"}, rules, nil, merchants, nil); result.Explanation.Source != MatchSourceNone {
+		t.Errorf("expected no match, got %+v", result.Explanation)
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	uber := testRule(t, "UBER", 0, day, RuleConditions{})
+	eats := testRule(t, "UBER EATS", 0, day, RuleConditions{})
+	trips := testRule(t, "^UBER", 1, day, RuleConditions{})
+	spotify := testRule(t, "SPOTIFY", 0, day, RuleConditions{})
+
+	rules := []compiledRule{uber, eats, trips, spotify}
+	rankRules(rules)
+
+	txs := []Transaction{
+		{Description: "UBER EATS LISBOA"},
+		{Description: "UBER *TRIP"},
+		{Description: "PAYPAL *UBER"},
+		{Description: "SPOTIFY"},
+	}
+	conflicts := findConflicts(rules, txs)
+
+	type key struct{ winner, shadowed string }
+	got := make(map[key]RuleConflict)
+	for _, c := range conflicts {
+		got[key{c.Winner.MatchPattern, c.Shadowed.MatchPattern}] = c
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 conflicts, got %+v", conflicts)
+	}
+
+	if c := got[key{"^UBER", "UBER EATS"}]; c.Overlap != 1 || !c.FullyShadowed {
+		t.Errorf("expected ^UBER to fully shadow UBER EATS, got %+v", c)
+	}
+	if c := got[key{"^UBER", "UBER"}]; c.Overlap != 2 || c.ShadowedMatches != 3 || c.FullyShadowed {
+		t.Errorf("expected ^UBER to shadow UBER on 2 of 3 matches, got %+v", c)
+	}
+}
@@ -2,7 +2,10 @@ package categorization
 
 import (
 	"context"
+	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -17,7 +20,22 @@ type CategoryRule struct {
 	CleanName          *string
 	AssignedCategoryID *uuid.UUID
 	IsRecurring        bool
-	Priority           int
+	Priority           int // Higher wins; ties go to the more specific rule
+	Conditions         RuleConditions
+	Enabled            bool // Disabled rules stay listed but never match
+	CreatedAt          time.Time
+}
+
+// RuleRun records one application of a rule to existing transactions and
+// what it changed, so that it can be rolled back.
+type RuleRun struct {
+	ID                  uuid.UUID
+	UserID              uuid.UUID
+	RuleID              *uuid.UUID // Nil once the rule is deleted
+	MatchPattern        string     // The rule's pattern when it ran
+	TransactionsChanged int
+	CreatedAt           time.Time
+	RolledBackAt        *time.Time
 }
 
 // Merchant represents a normalized merchant entry
@@ -38,6 +56,8 @@ type CategorizationResult struct {
 	IsRecurring       bool
 	RuleID            *uuid.UUID // Which rule matched, if any
 	MerchantID        *uuid.UUID // Which merchant matched, if any
+	Confidence        float64    // In CategoryID: 1 from rules and merchants, lower from corrections and the model, 0 without one
+	Explanation       MatchExplanation
 }
 
 // Repository handles database operations for categorization
@@ -50,10 +70,42 @@ func NewRepository(db *pgxpool.Pool) *Repository {
 	return &Repository{db: db}
 }
 
+// ruleColumns lists the category_rules columns in the order scanRule reads them
+const ruleColumns = `id, user_id, match_pattern, clean_name, assigned_category_id, is_recurring, priority,
+		min_amount_minor, max_amount_minor, COALESCE(direction, ''), account_id, institution_name, enabled, created_at`
+
+// rowScanner is satisfied by pgx.Row and pgx.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRule(row rowScanner) (*CategoryRule, error) {
+	var rule CategoryRule
+	if err := row.Scan(
+		&rule.ID,
+		&rule.UserID,
+		&rule.MatchPattern,
+		&rule.CleanName,
+		&rule.AssignedCategoryID,
+		&rule.IsRecurring,
+		&rule.Priority,
+		&rule.Conditions.MinAmountMinor,
+		&rule.Conditions.MaxAmountMinor,
+		&rule.Conditions.Direction,
+		&rule.Conditions.AccountID,
+		&rule.Conditions.InstitutionName,
+		&rule.Enabled,
+		&rule.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
 // GetUserRules fetches all categorization rules for a user, ordered by priority
 func (r *Repository) GetUserRules(ctx context.Context, userID uuid.UUID) ([]CategoryRule, error) {
 	query := `
-		SELECT id, user_id, match_pattern, clean_name, assigned_category_id, is_recurring, priority
+		SELECT ` + ruleColumns + `
 		FROM category_rules
 		WHERE user_id = $1
 		ORDER BY priority DESC, created_at DESC
@@ -67,19 +119,11 @@ func (r *Repository) GetUserRules(ctx context.Context, userID uuid.UUID) ([]Cate
 
 	var rules []CategoryRule
 	for rows.Next() {
-		var rule CategoryRule
-		if err := rows.Scan(
-			&rule.ID,
-			&rule.UserID,
-			&rule.MatchPattern,
-			&rule.CleanName,
-			&rule.AssignedCategoryID,
-			&rule.IsRecurring,
-			&rule.Priority,
-		); err != nil {
+		rule, err := scanRule(rows)
+		if err != nil {
 			return nil, err
 		}
-		rules = append(rules, rule)
+		rules = append(rules, *rule)
 	}
 
 	return rules, rows.Err()
@@ -125,11 +169,15 @@ func (r *Repository) GetMerchants(ctx context.Context, userID *uuid.UUID) ([]Mer
 // CreateRule creates a new categorization rule
 func (r *Repository) CreateRule(ctx context.Context, rule *CategoryRule) error {
 	query := `
-		INSERT INTO category_rules (user_id, match_pattern, clean_name, assigned_category_id, is_recurring, priority)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id
+		INSERT INTO category_rules (
+			user_id, match_pattern, clean_name, assigned_category_id, is_recurring, priority,
+			min_amount_minor, max_amount_minor, direction, account_id, institution_name, enabled
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11, $12)
+		RETURNING id, created_at
 	`
 
+	c := rule.Conditions
 	return r.db.QueryRow(ctx, query,
 		rule.UserID,
 		rule.MatchPattern,
@@ -137,43 +185,504 @@ func (r *Repository) CreateRule(ctx context.Context, rule *CategoryRule) error {
 		rule.AssignedCategoryID,
 		rule.IsRecurring,
 		rule.Priority,
-	).Scan(&rule.ID)
+		c.MinAmountMinor,
+		c.MaxAmountMinor,
+		c.Direction,
+		c.AccountID,
+		c.InstitutionName,
+		rule.Enabled,
+	).Scan(&rule.ID, &rule.CreatedAt)
 }
 
-// UpdateTransactionsMerchant updates merchant_name for matching transactions
-func (r *Repository) UpdateTransactionsMerchant(ctx context.Context, userID uuid.UUID, pattern, cleanName string, categoryID *uuid.UUID) (int64, error) {
+// GetRuleByID returns a rule, or nil when it does not exist
+func (r *Repository) GetRuleByID(ctx context.Context, ruleID uuid.UUID) (*CategoryRule, error) {
 	query := `
-		UPDATE transactions
-		SET merchant_name = $3, category_id = $4
-		WHERE user_id = $1 AND description ILIKE $2
+		SELECT ` + ruleColumns + `
+		FROM category_rules
+		WHERE id = $1
 	`
 
-	result, err := r.db.Exec(ctx, query, userID, pattern, cleanName, categoryID)
+	rule, err := scanRule(r.db.QueryRow(ctx, query, ruleID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateRule stores every editable field of one of the user's rules. It
+// reports whether the rule was found.
+func (r *Repository) UpdateRule(ctx context.Context, rule *CategoryRule) (bool, error) {
+	query := `
+		UPDATE category_rules
+		SET match_pattern = $3,
+		    clean_name = $4,
+		    assigned_category_id = $5,
+		    is_recurring = $6,
+		    priority = $7,
+		    min_amount_minor = $8,
+		    max_amount_minor = $9,
+		    direction = NULLIF($10, ''),
+		    account_id = $11,
+		    institution_name = $12,
+		    enabled = $13
+		WHERE id = $1 AND user_id = $2
+	`
+
+	c := rule.Conditions
+	result, err := r.db.Exec(ctx, query,
+		rule.ID,
+		rule.UserID,
+		rule.MatchPattern,
+		rule.CleanName,
+		rule.AssignedCategoryID,
+		rule.IsRecurring,
+		rule.Priority,
+		c.MinAmountMinor,
+		c.MaxAmountMinor,
+		c.Direction,
+		c.AccountID,
+		c.InstitutionName,
+		rule.Enabled,
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// SetRuleEnabled enables or disables one of the user's rules. It reports
+// whether the rule was found.
+func (r *Repository) SetRuleEnabled(ctx context.Context, userID uuid.UUID, ruleID uuid.UUID, enabled bool) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE category_rules SET enabled = $3 WHERE id = $1 AND user_id = $2
+	`, ruleID, userID, enabled)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// DeleteRule deletes one of the user's rules. Its runs are kept and can still
+// be rolled back. It reports whether the rule was found.
+func (r *Repository) DeleteRule(ctx context.Context, userID uuid.UUID, ruleID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM category_rules WHERE id = $1 AND user_id = $2
+	`, ruleID, userID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// backfillPageSize bounds the transactions read per page while looking for
+// the ones a rule wins
+const backfillPageSize = 1000
+
+// UpdateTransactionsMerchant updates merchant_name and category for the user's
+// transactions a rule wins and records them as a run of the rule. The rule's
+// pattern and conditions narrow the rows read in SQL, but every row is decided
+// by wins, which categorizes it the way new transactions are, so the backfill
+// and categorization agree exactly on what a rule covers. Candidates are read
+// a page at a time without locks; only the rows to update are locked, and
+// decided again once locked. Rows already carrying the rule's outcome are left
+// out of the run; nil means nothing changed. Split transactions keep their
+// category, which their splits already decide.
+func (r *Repository) UpdateTransactionsMerchant(ctx context.Context, rule *CategoryRule, wins func(Transaction) bool) (*RuleRun, error) {
+	userID := rule.UserID
+	whereSQL, args, err := ruleFilterSQL(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uuid.UUID
+	after := uuid.Nil
+	for {
+		page, err := r.db.Query(ctx, fmt.Sprintf(`
+			SELECT `+ruleTransactionColumns+`
+			FROM transactions t
+			WHERE %s AND t.id > $%d
+			ORDER BY t.id
+			LIMIT %d
+		`, whereSQL, len(args)+1, backfillPageSize), append(args, after)...)
+		if err != nil {
+			return nil, err
+		}
+		candidates, err := scanRuleTransactions(page)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range candidates {
+			if wins(c.Transaction) {
+				ids = append(ids, c.ID)
+			}
+		}
+		if len(candidates) < backfillPageSize {
+			break
+		}
+		after = candidates[len(candidates)-1].ID
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The rows may have changed since they were read; decide them again locked
+	rows, err := tx.Query(ctx, `
+		SELECT `+ruleTransactionColumns+`
+		FROM transactions t
+		WHERE t.user_id = $1 AND t.id = ANY($2) AND t.deleted_at IS NULL
+		ORDER BY t.id
+		FOR UPDATE
+	`, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := scanRuleTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
+	ids = ids[:0]
+	for _, c := range locked {
+		if wins(c.Transaction) {
+			ids = append(ids, c.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// A rule without a clean name keeps the merchant names it finds
+	var cleanName *string
+	if rule.CleanName != nil && *rule.CleanName != "" {
+		cleanName = rule.CleanName
+	}
+
+	run := &RuleRun{UserID: userID, RuleID: &rule.ID, MatchPattern: rule.MatchPattern}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO category_rule_runs (user_id, rule_id, match_pattern)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, userID, rule.ID, rule.MatchPattern).Scan(&run.ID, &run.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	// prev reads the rows before the update, which the FOR UPDATE above holds
+	result, err := tx.Exec(ctx, `
+		WITH prev AS (
+			SELECT t.id, t.merchant_name, t.category_id,
+			       EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id) AS is_split
+			FROM transactions t
+			WHERE t.user_id = $1 AND t.id = ANY($2)
+		),
+		changed AS (
+			UPDATE transactions t
+			SET merchant_name = COALESCE($3, prev.merchant_name),
+			    category_id = CASE WHEN prev.is_split THEN t.category_id ELSE $4 END
+			FROM prev
+			WHERE t.id = prev.id
+			  AND (($3::text IS NOT NULL AND prev.merchant_name IS DISTINCT FROM $3)
+			       OR (NOT prev.is_split AND prev.category_id IS DISTINCT FROM $4))
+			RETURNING t.id, prev.merchant_name AS previous_merchant_name, prev.category_id AS previous_category_id,
+			          t.merchant_name, t.category_id
+		)
+		INSERT INTO category_rule_run_changes (
+			run_id, transaction_id, previous_merchant_name, previous_category_id, merchant_name, category_id
+		)
+		SELECT $5, id, previous_merchant_name, previous_category_id, merchant_name, category_id
+		FROM changed
+	`, userID, ids, cleanName, rule.AssignedCategoryID, run.ID)
+	if err != nil {
+		return nil, err
+	}
+	run.TransactionsChanged = int(result.RowsAffected())
+	if run.TransactionsChanged == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE category_rule_runs SET transactions_changed = $2 WHERE id = $1
+	`, run.ID, run.TransactionsChanged); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// ruleFilterSQL returns a WHERE clause, with its arguments, over transactions
+// t that keeps every row the rule can match: its conditions, and the literal
+// text its pattern requires, with ILIKE for plain patterns and ~* for regular
+// expressions.
+func ruleFilterSQL(rule *CategoryRule) (string, []any, error) {
+	matcher, err := CompileRule(rule.MatchPattern, rule.Conditions)
+	if err != nil {
+		return "", nil, err
+	}
+	whereClauses := []string{"t.user_id = $1", "t.deleted_at IS NULL"}
+	args := []any{rule.UserID}
+
+	if fragments := matcher.Fragments(); len(fragments) > 0 {
+		if strings.HasPrefix(rule.MatchPattern, regexPrefix) {
+			quoted := make([]string, len(fragments))
+			for i, f := range fragments {
+				quoted[i] = regexp.QuoteMeta(f)
+			}
+			args = append(args, strings.Join(quoted, ".*"))
+			whereClauses = append(whereClauses, fmt.Sprintf("t.description ~* $%d", len(args)))
+		} else {
+			quoted := make([]string, len(fragments))
+			for i, f := range fragments {
+				quoted[i] = likeEscaper.Replace(f)
+			}
+			args = append(args, "%"+strings.Join(quoted, "%")+"%")
+			whereClauses = append(whereClauses, fmt.Sprintf("t.description ILIKE $%d", len(args)))
+		}
+	}
+
+	c := matcher.Conditions()
+	if c.MinAmountMinor != nil {
+		args = append(args, *c.MinAmountMinor)
+		whereClauses = append(whereClauses, fmt.Sprintf("abs(t.amount_minor) >= $%d", len(args)))
+	}
+	if c.MaxAmountMinor != nil {
+		args = append(args, *c.MaxAmountMinor)
+		whereClauses = append(whereClauses, fmt.Sprintf("abs(t.amount_minor) <= $%d", len(args)))
+	}
+	switch c.Direction {
+	case DirectionIncome:
+		whereClauses = append(whereClauses, "t.amount_minor > 0")
+	case DirectionExpense:
+		whereClauses = append(whereClauses, "t.amount_minor < 0")
+	}
+	if c.AccountID != nil {
+		args = append(args, *c.AccountID)
+		whereClauses = append(whereClauses, fmt.Sprintf("t.account_id = $%d", len(args)))
+	}
+	if c.InstitutionName != nil {
+		args = append(args, *c.InstitutionName)
+		whereClauses = append(whereClauses, fmt.Sprintf("lower(btrim(t.institution_name)) = lower($%d)", len(args)))
+	}
+	return strings.Join(whereClauses, " AND "), args, nil
+}
+
+// likeEscaper makes text match itself in a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ruleRunColumns lists the category_rule_runs columns in the order scanRuleRun reads them
+const ruleRunColumns = `id, user_id, rule_id, match_pattern, transactions_changed, created_at, rolled_back_at`
+
+func scanRuleRun(row rowScanner) (*RuleRun, error) {
+	var run RuleRun
+	if err := row.Scan(
+		&run.ID,
+		&run.UserID,
+		&run.RuleID,
+		&run.MatchPattern,
+		&run.TransactionsChanged,
+		&run.CreatedAt,
+		&run.RolledBackAt,
+	); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetRuleRun returns a rule run, or nil when it does not exist
+func (r *Repository) GetRuleRun(ctx context.Context, runID uuid.UUID) (*RuleRun, error) {
+	query := `
+		SELECT ` + ruleRunColumns + `
+		FROM category_rule_runs
+		WHERE id = $1
+	`
+
+	run, err := scanRuleRun(r.db.QueryRow(ctx, query, runID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// ListRuleRuns returns the user's rule runs, newest first, optionally for one
+// rule, and the total count.
+func (r *Repository) ListRuleRuns(ctx context.Context, userID uuid.UUID, ruleID *uuid.UUID, limit, offset int) ([]*RuleRun, int64, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	where := "user_id = $1 AND ($2::uuid IS NULL OR rule_id = $2)"
+
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM category_rule_runs WHERE `+where, userID, ruleID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + ruleRunColumns + `
+		FROM category_rule_runs
+		WHERE ` + where + `
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, ruleID, limit, offset)
+	if err != nil {
+		return nil, 0, err
 	}
+	defer rows.Close()
 
-	return result.RowsAffected(), nil
+	var runs []*RuleRun
+	for rows.Next() {
+		run, err := scanRuleRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, total, rows.Err()
 }
 
-// FindRuleByPattern checks if a rule already exists for this pattern
-func (r *Repository) FindRuleByPattern(ctx context.Context, userID uuid.UUID, pattern string) (*CategoryRule, error) {
+// RollbackRuleRun restores the merchant name and category a run replaced and
+// marks it rolled back. Transactions edited since the run, or deleted, are
+// left alone. It returns how many transactions were restored and whether an
+// unrolled run of the user's was found.
+func (r *Repository) RollbackRuleRun(ctx context.Context, userID uuid.UUID, runID uuid.UUID) (int, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id uuid.UUID
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM category_rule_runs
+		WHERE id = $1 AND user_id = $2 AND rolled_back_at IS NULL
+		FOR UPDATE
+	`, runID, userID).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE transactions t
+		SET merchant_name = c.previous_merchant_name,
+		    category_id = c.previous_category_id
+		FROM category_rule_run_changes c
+		WHERE c.run_id = $1
+		  AND t.id = c.transaction_id
+		  AND t.user_id = $2
+		  AND t.deleted_at IS NULL
+		  AND t.merchant_name IS NOT DISTINCT FROM c.merchant_name
+		  AND t.category_id IS NOT DISTINCT FROM c.category_id
+	`, runID, userID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE category_rule_runs SET rolled_back_at = NOW() WHERE id = $1
+	`, runID); err != nil {
+		return 0, false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+	return int(result.RowsAffected()), true, nil
+}
+
+// RuleTransaction is a stored transaction as rules see it
+type RuleTransaction struct {
+	ID uuid.UUID
+	Transaction
+}
+
+// ruleTransactionColumns lists the transaction columns scanRuleTransactions reads
+const ruleTransactionColumns = `t.id, t.description, t.amount_minor, t.account_id, COALESCE(t.institution_name, '')`
+
+func scanRuleTransactions(rows pgx.Rows) ([]RuleTransaction, error) {
+	defer rows.Close()
+
+	var txs []RuleTransaction
+	for rows.Next() {
+		var t RuleTransaction
+		if err := rows.Scan(&t.ID, &t.Description, &t.AmountMinor, &t.AccountID, &t.InstitutionName); err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, rows.Err()
+}
+
+// ListRuleTransactions returns the user's most recent live transactions, for
+// checking rules against real history.
+func (r *Repository) ListRuleTransactions(ctx context.Context, userID uuid.UUID, limit int) ([]RuleTransaction, error) {
 	query := `
-		SELECT id, user_id, match_pattern, clean_name, assigned_category_id, is_recurring, priority
+		SELECT ` + ruleTransactionColumns + `
+		FROM transactions t
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL
+		ORDER BY t.posted_at DESC, t.id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanRuleTransactions(rows)
+}
+
+// SetRulePriorities sets the priority of each listed rule the user owns and
+// returns how many rules were updated.
+func (r *Repository) SetRulePriorities(ctx context.Context, userID uuid.UUID, ruleIDs []uuid.UUID, priorities []int32) (int, error) {
+	query := `
+		UPDATE category_rules r
+		SET priority = p.priority
+		FROM unnest($2::uuid[], $3::int[]) AS p(id, priority)
+		WHERE r.id = p.id AND r.user_id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, ruleIDs, priorities)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// FindRule checks if a rule already exists for this pattern and conditions
+func (r *Repository) FindRule(ctx context.Context, userID uuid.UUID, pattern string, conditions RuleConditions) (*CategoryRule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
 		FROM category_rules
 		WHERE user_id = $1 AND match_pattern = $2
+		  AND min_amount_minor IS NOT DISTINCT FROM $3
+		  AND max_amount_minor IS NOT DISTINCT FROM $4
+		  AND COALESCE(direction, '') = $5
+		  AND account_id IS NOT DISTINCT FROM $6
+		  AND lower(COALESCE(institution_name, '')) = lower(COALESCE($7, ''))
 	`
 
-	var rule CategoryRule
-	err := r.db.QueryRow(ctx, query, userID, pattern).Scan(
-		&rule.ID,
-		&rule.UserID,
-		&rule.MatchPattern,
-		&rule.CleanName,
-		&rule.AssignedCategoryID,
-		&rule.IsRecurring,
-		&rule.Priority,
-	)
+	c := conditions
+	rule, err := scanRule(r.db.QueryRow(ctx, query, userID, pattern,
+		c.MinAmountMinor, c.MaxAmountMinor, c.Direction, c.AccountID, c.InstitutionName))
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -181,13 +690,145 @@ func (r *Repository) FindRuleByPattern(ctx context.Context, userID uuid.UUID, pa
 		return nil, err
 	}
 
-	return &rule, nil
+	return rule, nil
+}
+
+// Correction is a category the user set by hand on a single transaction
+type Correction struct {
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	TransactionID      *uuid.UUID
+	MerchantKey        string // MerchantKey of the description
+	Description        string
+	PreviousCategoryID *uuid.UUID
+	CategoryID         uuid.UUID
+	CreatedAt          time.Time
+}
+
+// CorrectionCount groups corrections of one merchant to one category
+type CorrectionCount struct {
+	MerchantKey     string
+	CategoryID      uuid.UUID
+	Count           int
+	LastDescription string // Description of the latest correction
+	LastCorrectedAt time.Time
+}
+
+// CreateCorrection stores a correction
+func (r *Repository) CreateCorrection(ctx context.Context, c *Correction) error {
+	query := `
+		INSERT INTO category_corrections (
+			user_id, transaction_id, merchant_key, description, previous_category_id, category_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query,
+		c.UserID,
+		c.TransactionID,
+		c.MerchantKey,
+		c.Description,
+		c.PreviousCategoryID,
+		c.CategoryID,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+// CountCorrections groups the user's corrections by merchant and category.
+// unresolvedOnly leaves out corrections whose suggestion was accepted or
+// dismissed; minCount drops smaller groups.
+func (r *Repository) CountCorrections(ctx context.Context, userID uuid.UUID, unresolvedOnly bool, minCount int) ([]CorrectionCount, error) {
+	query := `
+		SELECT merchant_key, category_id, COUNT(*),
+		       (ARRAY_AGG(description ORDER BY created_at DESC))[1],
+		       MAX(created_at)
+		FROM category_corrections
+		WHERE user_id = $1 AND (NOT $2 OR resolved_at IS NULL)
+		GROUP BY merchant_key, category_id
+		HAVING COUNT(*) >= $3
+		ORDER BY COUNT(*) DESC, MAX(created_at) DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, unresolvedOnly, minCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []CorrectionCount
+	for rows.Next() {
+		var c CorrectionCount
+		if err := rows.Scan(&c.MerchantKey, &c.CategoryID, &c.Count, &c.LastDescription, &c.LastCorrectedAt); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// ResolveCorrections marks the user's unresolved corrections of a merchant as
+// resolved and returns how many there were.
+func (r *Repository) ResolveCorrections(ctx context.Context, userID uuid.UUID, merchantKey string) (int, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE category_corrections
+		SET resolved_at = NOW()
+		WHERE user_id = $1 AND merchant_key = $2 AND resolved_at IS NULL
+	`, userID, merchantKey)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
 }
 
-// matchPattern checks if a description matches a SQL LIKE pattern
-func matchPattern(description, pattern string) bool {
-	// Convert SQL LIKE pattern to simple contains check
-	// Pattern like '%APPLE%' becomes case-insensitive contains check
-	cleanPattern := strings.Trim(pattern, "%")
-	return strings.Contains(strings.ToUpper(description), strings.ToUpper(cleanPattern))
+// ListTrainingExamples returns the user's most recently posted categorized
+// transactions to train the statistical model on. Split transactions and rows
+// still pending review, whose category may be a guess, are left out.
+func (r *Repository) ListTrainingExamples(ctx context.Context, userID uuid.UUID, limit int) ([]TrainingExample, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT t.description, t.amount_minor, t.posted_at, t.category_id
+		FROM transactions t
+		WHERE t.user_id = $1 AND t.category_id IS NOT NULL AND t.deleted_at IS NULL
+		  AND t.review_status IS DISTINCT FROM 'pending'
+		  AND NOT EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+		ORDER BY t.posted_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var examples []TrainingExample
+	for rows.Next() {
+		var ex TrainingExample
+		if err := rows.Scan(&ex.Description, &ex.AmountMinor, &ex.PostedAt, &ex.CategoryID); err != nil {
+			return nil, err
+		}
+		examples = append(examples, ex)
+	}
+	return examples, rows.Err()
+}
+
+// GetCategoryIDsByName returns the user's category IDs keyed by lowercase name
+func (r *Repository) GetCategoryIDsByName(ctx context.Context, userID uuid.UUID) (map[string]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT LOWER(name), id FROM categories WHERE user_id = $1 ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[string]uuid.UUID)
+	for rows.Next() {
+		var name string
+		var id uuid.UUID
+		if err := rows.Scan(&name, &id); err != nil {
+			return nil, err
+		}
+		if _, ok := categories[name]; !ok {
+			categories[name] = id
+		}
+	}
+	return categories, rows.Err()
 }
@@ -0,0 +1,317 @@
+package categorization
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidRule is returned when a rule's pattern or conditions cannot be compiled.
+var ErrInvalidRule = errors.New("invalid category rule")
+
+// Rule patterns match a transaction description, ignoring case:
+//
+//	AMAZON            contains AMAZON anywhere
+//	^UBER             starts with UBER
+//	UBER$             ends with UBER; ^UBER$ is the whole description
+//	UBER *TRIP        '*' matches any run of characters, '?' exactly one
+//	%NETFLIX%         '%' is read as '*', so SQL LIKE patterns keep working
+//	re:^UBER\s+\*     an RE2 regular expression
+//
+// A backslash makes the next character literal, e.g. UBER \*TRIP.
+const regexPrefix = "re:"
+
+// Transaction directions a rule can be limited to
+const (
+	DirectionIncome  = "income"
+	DirectionExpense = "expense"
+)
+
+// RuleConditions narrow a rule beyond its pattern. Nil or empty fields don't
+// constrain the match.
+type RuleConditions struct {
+	MinAmountMinor  *int64     // Absolute amount in minor units, inclusive
+	MaxAmountMinor  *int64     // Absolute amount in minor units, inclusive
+	Direction       string     // "income", "expense" or empty for both
+	AccountID       *uuid.UUID // Only transactions on this account
+	InstitutionName *string    // Only transactions from this bank, ignoring case
+}
+
+// Transaction is what a rule looks at when matching
+type Transaction struct {
+	Description     string
+	AmountMinor     int64 // Signed: negative for expenses, positive for income
+	AccountID       *uuid.UUID
+	InstitutionName string
+	PostedAt        time.Time // Zero when unknown; only the statistical model uses it
+}
+
+// Matcher is a compiled rule pattern with its conditions. It is the single
+// definition of what a rule matches: categorization and the backfill of
+// existing transactions both go through Match.
+type Matcher struct {
+	re          *regexp.Regexp
+	conditions  RuleConditions
+	specificity int
+}
+
+// CompileRule compiles a rule pattern and validates its conditions.
+func CompileRule(pattern string, conditions RuleConditions) (*Matcher, error) {
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	conditions, err = normalizeConditions(conditions)
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{re: re, conditions: conditions, specificity: specificity(re, conditions)}, nil
+}
+
+// Conditions returns the matcher's normalized conditions
+func (m *Matcher) Conditions() RuleConditions {
+	return m.conditions
+}
+
+// Specificity ranks how narrow a rule is, so that among rules of equal priority
+// the narrower one wins: each character the description must contain counts
+// one, each anchor two and each condition five.
+func (m *Matcher) Specificity() int {
+	return m.specificity
+}
+
+// Match reports whether the transaction satisfies the pattern and every condition
+func (m *Matcher) Match(tx Transaction) bool {
+	if !m.re.MatchString(tx.Description) {
+		return false
+	}
+
+	c := m.conditions
+	amount := tx.AmountMinor
+	if amount < 0 {
+		amount = -amount
+	}
+	if c.MinAmountMinor != nil && amount < *c.MinAmountMinor {
+		return false
+	}
+	if c.MaxAmountMinor != nil && amount > *c.MaxAmountMinor {
+		return false
+	}
+	switch c.Direction {
+	case DirectionIncome:
+		if tx.AmountMinor <= 0 {
+			return false
+		}
+	case DirectionExpense:
+		if tx.AmountMinor >= 0 {
+			return false
+		}
+	}
+	if c.AccountID != nil && (tx.AccountID == nil || *tx.AccountID != *c.AccountID) {
+		return false
+	}
+	if c.InstitutionName != nil && !strings.EqualFold(strings.TrimSpace(tx.InstitutionName), *c.InstitutionName) {
+		return false
+	}
+	return true
+}
+
+// Fragments returns literal text every description the pattern matches
+// contains, in order, for narrowing a search before matching. It is empty when
+// the pattern requires no particular text (e.g. an alternation).
+func (m *Matcher) Fragments() []string {
+	parsed, err := syntax.Parse(m.re.String(), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	parsed = parsed.Simplify()
+	for parsed.Op == syntax.OpCapture {
+		parsed = parsed.Sub[0]
+	}
+
+	subs := []*syntax.Regexp{parsed}
+	if parsed.Op == syntax.OpConcat {
+		subs = parsed.Sub
+	}
+	var fragments []string
+	var current []rune
+	for _, sub := range subs {
+		if sub.Op == syntax.OpLiteral {
+			current = append(current, sub.Rune...)
+			continue
+		}
+		if len(current) > 0 {
+			fragments = append(fragments, string(current))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		fragments = append(fragments, string(current))
+	}
+	return fragments
+}
+
+// QuotePattern returns a pattern matching text literally anywhere in a description
+func QuotePattern(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch r {
+		case '\\', '*', '?', '%', '^', '$':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// compilePattern turns a rule pattern into a case-insensitive regular expression
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(pattern, regexPrefix); ok {
+		if strings.TrimSpace(expr) == "" {
+			return nil, fmt.Errorf("%w: empty regular expression", ErrInvalidRule)
+		}
+		re, err := regexp.Compile("(?i)" + expr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		return re, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("(?is)")
+	rest := pattern
+	if strings.HasPrefix(rest, "^") {
+		b.WriteString("^")
+		rest = rest[1:]
+	}
+	anchorEnd := false
+	if strings.HasSuffix(rest, "$") && !escapedAt(rest, len(rest)-1) {
+		anchorEnd = true
+		rest = rest[:len(rest)-1]
+	}
+
+	literals := 0 // Characters other than spaces the description must contain
+	escaped := false
+	for _, r := range rest {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+			literals++
+		case r == '\\':
+			escaped = true
+		case r == '*' || r == '%':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			if !unicode.IsSpace(r) {
+				literals++
+			}
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("%w: pattern ends with an unfinished escape", ErrInvalidRule)
+	}
+	if literals == 0 {
+		return nil, fmt.Errorf("%w: pattern %q has no text to match", ErrInvalidRule, pattern)
+	}
+	if anchorEnd {
+		b.WriteString("$")
+	}
+
+	return regexp.Compile(b.String())
+}
+
+// Specificity weights
+const (
+	anchorSpecificity    = 2
+	conditionSpecificity = 5
+)
+
+func specificity(re *regexp.Regexp, c RuleConditions) int {
+	score := 0
+	if parsed, err := syntax.Parse(re.String(), syntax.Perl); err == nil {
+		score = patternSpecificity(parsed.Simplify())
+	}
+	for _, set := range []bool{
+		c.MinAmountMinor != nil, c.MaxAmountMinor != nil, c.Direction != "", c.AccountID != nil, c.InstitutionName != nil,
+	} {
+		if set {
+			score += conditionSpecificity
+		}
+	}
+	return score
+}
+
+// patternSpecificity counts what every match of a parsed pattern must contain
+func patternSpecificity(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune)
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText, syntax.OpWordBoundary:
+		return anchorSpecificity
+	case syntax.OpCapture, syntax.OpPlus:
+		return patternSpecificity(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min * patternSpecificity(re.Sub[0])
+	case syntax.OpConcat:
+		score := 0
+		for _, sub := range re.Sub {
+			score += patternSpecificity(sub)
+		}
+		return score
+	case syntax.OpAlternate:
+		score := -1
+		for _, sub := range re.Sub {
+			if s := patternSpecificity(sub); score < 0 || s < score {
+				score = s
+			}
+		}
+		return max(score, 0)
+	}
+	return 0
+}
+
+// escapedAt reports whether the byte at i is preceded by an odd number of backslashes
+func escapedAt(s string, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// normalizeConditions validates conditions and drops ones that don't constrain
+func normalizeConditions(c RuleConditions) (RuleConditions, error) {
+	if c.MinAmountMinor != nil && *c.MinAmountMinor < 0 {
+		return c, fmt.Errorf("%w: minimum amount must not be negative", ErrInvalidRule)
+	}
+	if c.MaxAmountMinor != nil && *c.MaxAmountMinor < 0 {
+		return c, fmt.Errorf("%w: maximum amount must not be negative", ErrInvalidRule)
+	}
+	if c.MinAmountMinor != nil && c.MaxAmountMinor != nil && *c.MinAmountMinor > *c.MaxAmountMinor {
+		return c, fmt.Errorf("%w: minimum amount exceeds maximum amount", ErrInvalidRule)
+	}
+	switch c.Direction {
+	case "", DirectionIncome, DirectionExpense:
+	default:
+		return c, fmt.Errorf("%w: direction must be income or expense", ErrInvalidRule)
+	}
+	if c.InstitutionName != nil {
+		name := strings.TrimSpace(*c.InstitutionName)
+		if name == "" {
+			c.InstitutionName = nil
+		} else {
+			c.InstitutionName = &name
+		}
+	}
+	return c, nil
+}
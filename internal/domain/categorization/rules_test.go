@@ -0,0 +1,259 @@
+package categorization
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		pattern     string
+		shouldMatch bool
+	}{
+		{
+			name:        "exact match",
+			description: "NETFLIX",
+			pattern:     "NETFLIX",
+			shouldMatch: true,
+		},
+		{
+			name:        "contains match with wildcards",
+			description: "COMPRAS C.DEB NETFLIX.COM/BILL",
+			pattern:     "%NETFLIX%",
+			shouldMatch: true,
+		},
+		{
+			name:        "suffix match",
+			description: "APPLE.COM/BILL",
+			pattern:     "%APPLE%",
+			shouldMatch: true,
+		},
+		{
+			name:        "no match",
+			description: "STARBUCKS COFFEE",
+			pattern:     "%NETFLIX%",
+			shouldMatch: false,
+		},
+		{
+			name:        "case insensitive",
+			description: "netflix subscription",
+			pattern:     "%NETFLIX%",
+			shouldMatch: true,
+		},
+		{
+			name:        "anchored on both ends",
+			description: "UBER EATS",
+			pattern:     "^UBER$",
+			shouldMatch: false,
+		},
+		{
+			name:        "start anchor",
+			description: "UBER *TRIP 1234",
+			pattern:     "^uber",
+			shouldMatch: true,
+		},
+		{
+			name:        "start anchor elsewhere in text",
+			description: "PAYPAL *UBER",
+			pattern:     "^UBER",
+			shouldMatch: false,
+		},
+		{
+			name:        "end anchor",
+			description: "SPOTIFY P1234 STOCKHOLM",
+			pattern:     "STOCKHOLM$",
+			shouldMatch: true,
+		},
+		{
+			name:        "star wildcard",
+			description: "UBER *TRIP HELP.UBER.COM",
+			pattern:     "UBER*HELP",
+			shouldMatch: true,
+		},
+		{
+			name:        "single character wildcard",
+			description: "TESCO STORE 12",
+			pattern:     "STORE ??$",
+			shouldMatch: true,
+		},
+		{
+			name:        "escaped star is literal",
+			description: "UBER TRIP",
+			pattern:     `UBER \*TRIP`,
+			shouldMatch: false,
+		},
+		{
+			name:        "regular expression",
+			description: "UBER *TRIP 1234",
+			pattern:     `re:^UBER\s+\*TRIP`,
+			shouldMatch: true,
+		},
+		{
+			name:        "regular expression rejects eats",
+			description: "UBER EATS 1234",
+			pattern:     `re:^UBER\s+\*TRIP`,
+			shouldMatch: false,
+		},
+		{
+			name:        "regex metacharacters are literal outside re:",
+			description: "AMAZON.DE",
+			pattern:     "AMAZON(DE)",
+			shouldMatch: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := CompileRule(tt.pattern, RuleConditions{})
+			if err != nil {
+				t.Fatalf("CompileRule(%q) failed: %v", tt.pattern, err)
+			}
+			result := matcher.Match(Transaction{Description: tt.description})
+			if result != tt.shouldMatch {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.description, tt.pattern, result, tt.shouldMatch)
+			}
+		})
+	}
+}
+
+func TestMatcherConditions(t *testing.T) {
+	account, other := uuid.New(), uuid.New()
+	hundred := int64(10000)
+	chase := " chase "
+	matcher, err := CompileRule("AMAZON", RuleConditions{
+		MinAmountMinor:  &hundred,
+		Direction:       DirectionExpense,
+		AccountID:       &account,
+		InstitutionName: &chase,
+	})
+	if err != nil {
+		t.Fatalf("CompileRule failed: %v", err)
+	}
+
+	base := Transaction{Description: "AMAZON MKTPLACE", AmountMinor: -12000, AccountID: &account, InstitutionName: "Chase"}
+	tests := []struct {
+		name        string
+		change      func(tx *Transaction)
+		shouldMatch bool
+	}{
+		{"all conditions hold", func(tx *Transaction) {}, true},
+		{"amount on the bound", func(tx *Transaction) { tx.AmountMinor = -10000 }, true},
+		{"amount too small", func(tx *Transaction) { tx.AmountMinor = -9999 }, false},
+		{"refund", func(tx *Transaction) { tx.AmountMinor = 12000 }, false},
+		{"other account", func(tx *Transaction) { tx.AccountID = &other }, false},
+		{"no account", func(tx *Transaction) { tx.AccountID = nil }, false},
+		{"other bank", func(tx *Transaction) { tx.InstitutionName = "Revolut" }, false},
+		{"other description", func(tx *Transaction) { tx.Description = "EBAY" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := base
+			tt.change(&tx)
+			if got := matcher.Match(tx); got != tt.shouldMatch {
+				t.Errorf("Match(%+v) = %v, want %v", tx, got, tt.shouldMatch)
+			}
+		})
+	}
+}
+
+func TestCompileRuleRejectsInvalidRules(t *testing.T) {
+	negative := int64(-1)
+	low, high := int64(100), int64(50)
+	tests := []struct {
+		name       string
+		pattern    string
+		conditions RuleConditions
+	}{
+		{"only wildcards", "%%", RuleConditions{}},
+		{"only anchors", "^$", RuleConditions{}},
+		{"unfinished escape", `UBER\`, RuleConditions{}},
+		{"broken regex", "re:(UBER", RuleConditions{}},
+		{"empty regex", "re: ", RuleConditions{}},
+		{"negative amount", "AMAZON", RuleConditions{MinAmountMinor: &negative}},
+		{"inverted range", "AMAZON", RuleConditions{MinAmountMinor: &low, MaxAmountMinor: &high}},
+		{"unknown direction", "AMAZON", RuleConditions{Direction: "out"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CompileRule(tt.pattern, tt.conditions); !errors.Is(err, ErrInvalidRule) {
+				t.Fatalf("expected ErrInvalidRule, got %v", err)
+			}
+		})
+	}
+}
+
+func TestQuotePattern(t *testing.T) {
+	text := `UBER *TRIP 50% ^$?\`
+	matcher, err := CompileRule("^"+QuotePattern(text)+"$", RuleConditions{})
+	if err != nil {
+		t.Fatalf("CompileRule failed: %v", err)
+	}
+	if !matcher.Match(Transaction{Description: text}) {
+		t.Errorf("quoted pattern does not match its own text")
+	}
+	if matcher.Match(Transaction{Description: "UBER XTRIP 50X ^$?\\"}) {
+		t.Errorf("quoted pattern treats special characters as wildcards")
+	}
+}
+
+func TestMatcherFragments(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"%NETFLIX%", []string{"NETFLIX"}},
+		{"^UBER$", []string{"UBER"}},
+		{"UBER *TRIP", []string{"UBER ", "TRIP"}},
+		{"CP?BOM", []string{"CP", "BOM"}},
+		{`50\% OFF`, []string{"50% OFF"}},
+		{`re:^UBER\s+\*`, []string{"UBER", "*"}},
+		{"re:kiosk", []string{"KIOSK"}},
+		{"re:(lidl|aldi)", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			matcher, err := CompileRule(tt.pattern, RuleConditions{})
+			if err != nil {
+				t.Fatalf("CompileRule failed: %v", err)
+			}
+			if got := matcher.Fragments(); !slices.Equal(got, tt.want) {
+				t.Errorf("Fragments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleFilterSQL(t *testing.T) {
+	minAmount := int64(1000)
+	bank := "ActivoBank"
+	whereSQL, args, err := ruleFilterSQL(&CategoryRule{
+		UserID:       uuid.New(),
+		MatchPattern: "PAY_PAL*50%",
+		Conditions:   RuleConditions{MinAmountMinor: &minAmount, Direction: DirectionExpense, InstitutionName: &bank},
+	})
+	if err != nil {
+		t.Fatalf("ruleFilterSQL failed: %v", err)
+	}
+	want := "t.user_id = $1 AND t.deleted_at IS NULL AND t.description ILIKE $2 AND abs(t.amount_minor) >= $3" +
+		" AND t.amount_minor < 0 AND lower(btrim(t.institution_name)) = lower($4)"
+	if whereSQL != want {
+		t.Errorf("where = %q, want %q", whereSQL, want)
+	}
+	if len(args) != 4 || args[1] != `%PAY\_PAL%50%` {
+		t.Errorf("args = %v", args)
+	}
+
+	whereSQL, args, err = ruleFilterSQL(&CategoryRule{UserID: uuid.New(), MatchPattern: `re:^UBER\s+\*TRIP`})
+	if err != nil {
+		t.Fatalf("ruleFilterSQL failed: %v", err)
+	}
+	if !strings.HasSuffix(whereSQL, "t.description ~* $2") || args[1] != `UBER.*\*TRIP` {
+		t.Errorf("where = %q, args = %v", whereSQL, args)
+	}
+}
@@ -0,0 +1,82 @@
+package categorization
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// seedWeight is what a seed example counts for next to one of the user's own
+// categorized transactions
+const seedWeight = 0.5
+
+// seedCategory is part of the global training set: descriptions typical of a
+// category. It applies to users who have a category with one of its names.
+type seedCategory struct {
+	names        []string // Lowercase
+	descriptions []string
+}
+
+var seedCategories = []seedCategory{
+	{
+		names:        []string{"groceries", "supermarket", "supermercado", "mercearia", "alimentação"},
+		descriptions: []string{"CONTINENTE", "PINGO DOCE", "LIDL", "ALDI", "AUCHAN", "MINIPRECO", "MERCADONA", "INTERMARCHE", "CARREFOUR", "TESCO", "SAINSBURYS"},
+	},
+	{
+		names:        []string{"restaurants", "eating out", "dining", "restaurantes", "restauração"},
+		descriptions: []string{"MCDONALDS", "BURGER KING", "KFC", "TELEPIZZA", "UBER EATS", "GLOVO", "BOLT FOOD", "RESTAURANTE", "PASTELARIA", "CAFE", "STARBUCKS"},
+	},
+	{
+		names:        []string{"transport", "transportation", "transportes"},
+		descriptions: []string{"UBER TRIP", "BOLT RIDE", "CP COMBOIOS", "METRO LISBOA", "METRO DO PORTO", "CARRIS", "VIA VERDE", "TFL TRAVEL", "FREENOW"},
+	},
+	{
+		names:        []string{"fuel", "gas", "combustível"},
+		descriptions: []string{"GALP", "BP", "REPSOL", "CEPSA", "SHELL", "PRIO"},
+	},
+	{
+		names:        []string{"subscriptions", "entertainment", "subscrições", "lazer"},
+		descriptions: []string{"NETFLIX", "SPOTIFY", "DISNEY PLUS", "HBO MAX", "APPLE COM BILL", "YOUTUBE PREMIUM", "AMAZON PRIME"},
+	},
+	{
+		names:        []string{"utilities", "bills", "serviços", "contas"},
+		descriptions: []string{"EDP COMERCIAL", "GALP ENERGIA", "EPAL", "AGUAS DO PORTO", "MEO", "NOS COMUNICACOES", "VODAFONE", "ENDESA"},
+	},
+	{
+		names:        []string{"health", "healthcare", "pharmacy", "saúde", "farmácia"},
+		descriptions: []string{"FARMACIA", "PHARMACY", "CUF", "LUZ SAUDE", "CLINICA", "HOSPITAL", "WELLS"},
+	},
+	{
+		names:        []string{"shopping", "compras"},
+		descriptions: []string{"AMAZON MKTPLACE", "ZARA", "PRIMARK", "IKEA", "FNAC", "WORTEN", "DECATHLON"},
+	},
+	{
+		names:        []string{"salary", "income", "salário", "ordenado"},
+		descriptions: []string{"SALARIO", "ORDENADO", "VENCIMENTO", "SALARY", "PAYROLL"},
+	},
+}
+
+// seedExamples returns the global training set for the user's categories,
+// keyed by lowercase name. Seeds for categories the user lacks are left out.
+func seedExamples(categories map[string]uuid.UUID) []TrainingExample {
+	var examples []TrainingExample
+	for _, seed := range seedCategories {
+		categoryID, ok := uuid.Nil, false
+		for _, name := range seed.names {
+			if categoryID, ok = categories[strings.ToLower(name)]; ok {
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		for _, description := range seed.descriptions {
+			examples = append(examples, TrainingExample{
+				Transaction: Transaction{Description: description},
+				CategoryID:  categoryID,
+				Weight:      seedWeight,
+			})
+		}
+	}
+	return examples
+}
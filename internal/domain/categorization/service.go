@@ -2,163 +2,251 @@ package categorization
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	// conflictSampleSize bounds the recent transactions RuleConflicts checks
+	conflictSampleSize = 5000
+	// modelTrainingSize bounds the recent transactions the model is trained on
+	modelTrainingSize = 5000
+	// modelMaxAge is how long a trained model is used before retraining
+	modelMaxAge = time.Hour
+)
+
 // Service handles transaction categorization logic
 type Service struct {
 	repo *Repository
 
-	// Cache for rules/merchants (refreshed periodically)
-	ruleCache     map[uuid.UUID][]CategoryRule
-	merchantCache []Merchant
+	// Create suggested rules without waiting for the user
+	autoLearn bool
+
+	// Cache for rules/merchants/correction priors/models (refreshed periodically)
+	ruleCache     map[uuid.UUID][]compiledRule
+	merchantCache []compiledMerchant
+	priorCache    map[uuid.UUID]map[string]correctionPrior
+	modelCache    map[uuid.UUID]trainedModel
 	cacheMu       sync.RWMutex
 }
 
+// compiledRule pairs a stored rule with its matcher; a nil matcher means the
+// rule is disabled or its stored pattern does not compile, so it never matches.
+type compiledRule struct {
+	rule    CategoryRule
+	matcher *Matcher
+}
+
+type compiledMerchant struct {
+	merchant Merchant
+	matcher  *Matcher
+}
+
+// RuleInput describes a categorization rule to create
+type RuleInput struct {
+	Pattern     string
+	CleanName   string
+	CategoryID  *uuid.UUID
+	IsRecurring bool
+	Priority    int // Higher wins over other matching rules
+	Conditions  RuleConditions
+}
+
 // NewService creates a new categorization service
 func NewService(repo *Repository) *Service {
 	return &Service{
 		repo:          repo,
-		ruleCache:     make(map[uuid.UUID][]CategoryRule),
+		ruleCache:     make(map[uuid.UUID][]compiledRule),
 		merchantCache: nil,
+		priorCache:    make(map[uuid.UUID]map[string]correctionPrior),
+		modelCache:    make(map[uuid.UUID]trainedModel),
 	}
 }
 
-// Categorize takes a raw transaction description and returns enriched data
-func (s *Service) Categorize(ctx context.Context, userID uuid.UUID, description string) (*CategorizationResult, error) {
-	result := &CategorizationResult{
-		CleanMerchantName: cleanDescription(description),
+// Categorize takes a raw transaction and returns enriched data
+func (s *Service) Categorize(ctx context.Context, userID uuid.UUID, tx Transaction) (*CategorizationResult, error) {
+	results, err := s.CategorizeBatch(ctx, userID, []Transaction{tx})
+	if err != nil {
+		return nil, err
 	}
+	return results[0], nil
+}
 
-	// 1. Check user's custom rules first (highest priority)
-	rules, err := s.GetUserRules(ctx, userID)
-	if err != nil {
-		return result, nil // Fail open - return cleaned name without category
-	}
-
-	for _, rule := range rules {
-		if matchPattern(description, rule.MatchPattern) {
-			if rule.CleanName != nil {
-				result.CleanMerchantName = *rule.CleanName
-			}
-			result.CategoryID = rule.AssignedCategoryID
-			result.IsRecurring = rule.IsRecurring
-			result.RuleID = &rule.ID
-			return result, nil
-		}
+// CategorizeBatch categorizes multiple transactions efficiently. User rules
+// come first, best ranked first, then the user's past corrections of the same
+// merchant, then the merchant database, then a statistical model trained on
+// the user's categorized history; failing to load any of them fails open with
+// cleaned descriptions. Each result explains its match and how confident it is.
+func (s *Service) CategorizeBatch(ctx context.Context, userID uuid.UUID, txs []Transaction) ([]*CategorizationResult, error) {
+	// Pre-fetch rules, priors, merchants and the model once
+	rules, _ := s.rules(ctx, userID)
+	priors, _ := s.priors(ctx, userID)
+	merchants, _ := s.getMerchants(ctx, &userID)
+	model, _ := s.model(ctx, userID)
+
+	results := make([]*CategorizationResult, len(txs))
+	for i, tx := range txs {
+		results[i] = categorize(tx, rules, priors, merchants, model)
 	}
 
-	// 2. Check global merchant database
-	merchants, err := s.getMerchants(ctx, &userID)
+	return results, nil
+}
+
+// CreateRule creates a new categorization rule with optional backfill. The
+// pattern and conditions are compiled first, so a rule that could never match
+// is rejected with ErrInvalidRule. A rule with the same pattern and conditions
+// is updated and enabled instead of duplicated. The backfill run is nil when
+// no transaction changed; if the backfill fails, the saved rule is returned
+// with the error.
+func (s *Service) CreateRule(ctx context.Context, userID uuid.UUID, input RuleInput, applyToExisting bool) (*CategoryRule, *RuleRun, error) {
+	defer s.invalidateRules(userID)
+
+	rule, err := buildRule(userID, input)
 	if err != nil {
-		return result, nil // Fail open
+		return nil, nil, err
 	}
 
-	for _, merchant := range merchants {
-		if matchPattern(description, merchant.RawPattern) {
-			result.CleanMerchantName = merchant.CleanName
-			result.CategoryID = merchant.DefaultCategoryID
-			result.MerchantID = &merchant.ID
-			return result, nil
+	// Check if rule already exists
+	existing, err := s.repo.FindRule(ctx, userID, rule.MatchPattern, rule.Conditions)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing != nil {
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+		if _, err := s.repo.UpdateRule(ctx, rule); err != nil {
+			return nil, nil, err
 		}
+	} else if err := s.repo.CreateRule(ctx, rule); err != nil {
+		return nil, nil, err
 	}
 
-	// 3. No match - return cleaned description
-	return result, nil
+	// Optionally apply to existing transactions
+	if !applyToExisting {
+		return rule, nil, nil
+	}
+	run, err := s.backfill(ctx, rule)
+	if err != nil {
+		return rule, nil, fmt.Errorf("failed to apply rule: %w", err)
+	}
+	return rule, run, nil
 }
 
-// CategorizeBatch categorizes multiple descriptions efficiently
-func (s *Service) CategorizeBatch(ctx context.Context, userID uuid.UUID, descriptions []string) ([]*CategorizationResult, error) {
-	// Pre-fetch rules and merchants once
-	rules, _ := s.GetUserRules(ctx, userID)
-	merchants, _ := s.getMerchants(ctx, &userID)
+// buildRule compiles a rule input into an enabled rule with normalized
+// pattern and conditions
+func buildRule(userID uuid.UUID, input RuleInput) (*CategoryRule, error) {
+	pattern := strings.TrimSpace(input.Pattern)
+	matcher, err := CompileRule(pattern, input.Conditions)
+	if err != nil {
+		return nil, err
+	}
 
-	results := make([]*CategorizationResult, len(descriptions))
+	cleanName := strings.TrimSpace(input.CleanName)
+	return &CategoryRule{
+		UserID:             userID,
+		MatchPattern:       pattern,
+		CleanName:          &cleanName,
+		AssignedCategoryID: input.CategoryID,
+		IsRecurring:        input.IsRecurring,
+		Priority:           input.Priority,
+		Conditions:         matcher.Conditions(),
+		Enabled:            true,
+	}, nil
+}
 
-	for i, desc := range descriptions {
-		result := &CategorizationResult{
-			CleanMerchantName: cleanDescription(desc),
-		}
+// backfill applies a rule to the user's existing transactions it wins against
+// the other rules, exactly as categorizing them again would, and records the
+// run.
+func (s *Service) backfill(ctx context.Context, rule *CategoryRule) (*RuleRun, error) {
+	s.invalidateRules(rule.UserID)
+	rules, err := s.rules(ctx, rule.UserID)
+	if err != nil {
+		return nil, err
+	}
+	wins := func(tx Transaction) bool {
+		result := categorize(tx, rules, nil, nil, nil)
+		return result.RuleID != nil && *result.RuleID == rule.ID
+	}
+	return s.repo.UpdateTransactionsMerchant(ctx, rule, wins)
+}
 
-		// Check rules
-		for _, rule := range rules {
-			if matchPattern(desc, rule.MatchPattern) {
-				if rule.CleanName != nil {
-					result.CleanMerchantName = *rule.CleanName
-				}
-				result.CategoryID = rule.AssignedCategoryID
-				result.IsRecurring = rule.IsRecurring
-				result.RuleID = &rule.ID
-				break
-			}
-		}
+// ReorderRules sets rule priorities from an ordering of all the user's rules,
+// first wins: the first rule gets the highest priority.
+func (s *Service) ReorderRules(ctx context.Context, userID uuid.UUID, ruleIDs []uuid.UUID) ([]CategoryRule, error) {
+	rules, err := s.GetUserRules(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ruleIDs) != len(rules) {
+		return nil, fmt.Errorf("%w: the order must list all %d rules", ErrInvalidRule, len(rules))
+	}
 
-		// If no rule matched, check merchants
-		if result.RuleID == nil {
-			for _, merchant := range merchants {
-				if matchPattern(desc, merchant.RawPattern) {
-					result.CleanMerchantName = merchant.CleanName
-					result.CategoryID = merchant.DefaultCategoryID
-					result.MerchantID = &merchant.ID
-					break
-				}
-			}
+	owned := make(map[uuid.UUID]bool, len(rules))
+	for _, r := range rules {
+		owned[r.ID] = true
+	}
+	priorities := make([]int32, len(ruleIDs))
+	for i, id := range ruleIDs {
+		if !owned[id] {
+			return nil, fmt.Errorf("%w: rule %s is unknown or listed twice", ErrInvalidRule, id)
 		}
+		delete(owned, id)
+		priorities[i] = int32(len(ruleIDs) - i)
+	}
 
-		results[i] = result
+	_, err = s.repo.SetRulePriorities(ctx, userID, ruleIDs, priorities)
+	s.invalidateRules(userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return results, nil
+	return s.GetUserRules(ctx, userID)
 }
 
-// CreateRule creates a new categorization rule with optional backfill
-func (s *Service) CreateRule(ctx context.Context, userID uuid.UUID, pattern, cleanName string, categoryID *uuid.UUID, isRecurring, applyToExisting bool) (*CategoryRule, int64, error) {
-	// Check if rule already exists
-	existing, err := s.repo.FindRuleByPattern(ctx, userID, pattern)
+// RuleConflicts checks the user's rules against their recent transactions and
+// reports rules that match transactions a higher-ranked rule takes.
+func (s *Service) RuleConflicts(ctx context.Context, userID uuid.UUID) ([]RuleConflict, error) {
+	rules, err := s.rules(ctx, userID)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	if existing != nil {
-		// Could update instead, but for now just return existing
-		return existing, 0, nil
+	if len(rules) < 2 {
+		return nil, nil
 	}
 
-	rule := &CategoryRule{
-		UserID:             userID,
-		MatchPattern:       pattern,
-		CleanName:          &cleanName,
-		AssignedCategoryID: categoryID,
-		IsRecurring:        isRecurring,
-		Priority:           0,
+	stored, err := s.repo.ListRuleTransactions(ctx, userID, conflictSampleSize)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := s.repo.CreateRule(ctx, rule); err != nil {
-		return nil, 0, err
+	txs := make([]Transaction, len(stored))
+	for i, t := range stored {
+		txs[i] = t.Transaction
 	}
 
-	// Invalidate cache
-	s.cacheMu.Lock()
-	delete(s.ruleCache, userID)
-	s.cacheMu.Unlock()
+	return findConflicts(rules, txs), nil
+}
 
-	// Optionally apply to existing transactions
-	var updated int64
-	if applyToExisting {
-		updated, err = s.repo.UpdateTransactionsMerchant(ctx, userID, pattern, cleanName, categoryID)
-		if err != nil {
-			// Rule was created, just log the backfill error
-			return rule, 0, nil
-		}
+// GetUserRules fetches rules with caching (exported for handler access), in
+// the order they are tried.
+func (s *Service) GetUserRules(ctx context.Context, userID uuid.UUID) ([]CategoryRule, error) {
+	compiled, err := s.rules(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return rule, updated, nil
+	rules := make([]CategoryRule, len(compiled))
+	for i, r := range compiled {
+		rules[i] = r.rule
+	}
+	return rules, nil
 }
 
-// GetUserRules fetches rules with caching (exported for handler access)
-func (s *Service) GetUserRules(ctx context.Context, userID uuid.UUID) ([]CategoryRule, error) {
+// rules fetches the user's compiled rules with caching
+func (s *Service) rules(ctx context.Context, userID uuid.UUID) ([]compiledRule, error) {
 	s.cacheMu.RLock()
 	if rules, ok := s.ruleCache[userID]; ok {
 		s.cacheMu.RUnlock()
@@ -166,11 +254,20 @@ func (s *Service) GetUserRules(ctx context.Context, userID uuid.UUID) ([]Categor
 	}
 	s.cacheMu.RUnlock()
 
-	rules, err := s.repo.GetUserRules(ctx, userID)
+	stored, err := s.repo.GetUserRules(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	rules := make([]compiledRule, len(stored))
+	for i, rule := range stored {
+		rules[i] = compiledRule{rule: rule}
+		if rule.Enabled {
+			rules[i].matcher, _ = CompileRule(rule.MatchPattern, rule.Conditions)
+		}
+	}
+	rankRules(rules)
+
 	s.cacheMu.Lock()
 	s.ruleCache[userID] = rules
 	s.cacheMu.Unlock()
@@ -178,8 +275,16 @@ func (s *Service) GetUserRules(ctx context.Context, userID uuid.UUID) ([]Categor
 	return rules, nil
 }
 
-// getMerchants fetches merchants with caching
-func (s *Service) getMerchants(ctx context.Context, userID *uuid.UUID) ([]Merchant, error) {
+// invalidateRules drops the user's cached rules. Every write to rules calls it,
+// whether or not the write succeeded.
+func (s *Service) invalidateRules(userID uuid.UUID) {
+	s.cacheMu.Lock()
+	delete(s.ruleCache, userID)
+	s.cacheMu.Unlock()
+}
+
+// getMerchants fetches compiled merchants with caching
+func (s *Service) getMerchants(ctx context.Context, userID *uuid.UUID) ([]compiledMerchant, error) {
 	s.cacheMu.RLock()
 	if s.merchantCache != nil {
 		s.cacheMu.RUnlock()
@@ -187,11 +292,17 @@ func (s *Service) getMerchants(ctx context.Context, userID *uuid.UUID) ([]Mercha
 	}
 	s.cacheMu.RUnlock()
 
-	merchants, err := s.repo.GetMerchants(ctx, userID)
+	stored, err := s.repo.GetMerchants(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	merchants := make([]compiledMerchant, len(stored))
+	for i, m := range stored {
+		matcher, _ := CompileRule(m.RawPattern, RuleConditions{})
+		merchants[i] = compiledMerchant{merchant: m, matcher: matcher}
+	}
+
 	s.cacheMu.Lock()
 	s.merchantCache = merchants
 	s.cacheMu.Unlock()
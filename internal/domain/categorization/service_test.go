@@ -1,7 +1,10 @@
 package categorization
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/google/uuid"
 )
 
 func TestCleanDescription(t *testing.T) {
@@ -52,55 +55,6 @@ func TestCleanDescription(t *testing.T) {
 	}
 }
 
-func TestMatchPattern(t *testing.T) {
-	tests := []struct {
-		name        string
-		description string
-		pattern     string
-		shouldMatch bool
-	}{
-		{
-			name:        "exact match",
-			description: "NETFLIX",
-			pattern:     "NETFLIX",
-			shouldMatch: true,
-		},
-		{
-			name:        "contains match with wildcards",
-			description: "COMPRAS C.DEB NETFLIX.COM/BILL",
-			pattern:     "%NETFLIX%",
-			shouldMatch: true,
-		},
-		{
-			name:        "suffix match",
-			description: "APPLE.COM/BILL",
-			pattern:     "%APPLE%",
-			shouldMatch: true,
-		},
-		{
-			name:        "no match",
-			description: "STARBUCKS COFFEE",
-			pattern:     "%NETFLIX%",
-			shouldMatch: false,
-		},
-		{
-			name:        "case insensitive",
-			description: "netflix subscription",
-			pattern:     "%NETFLIX%",
-			shouldMatch: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := matchPattern(tt.description, tt.pattern)
-			if result != tt.shouldMatch {
-				t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.description, tt.pattern, result, tt.shouldMatch)
-			}
-		})
-	}
-}
-
 func TestIsNumeric(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -144,3 +98,43 @@ func TestToTitleCase(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildRule(t *testing.T) {
+	userID := uuid.New()
+	bank := "  "
+	rule, err := buildRule(userID, RuleInput{
+		Pattern:    "  ^UBER  ",
+		CleanName:  " Uber ",
+		Priority:   3,
+		Conditions: RuleConditions{Direction: DirectionExpense, InstitutionName: &bank},
+	})
+	if err != nil {
+		t.Fatalf("buildRule failed: %v", err)
+	}
+	if rule.MatchPattern != "^UBER" || *rule.CleanName != "Uber" || rule.Priority != 3 || !rule.Enabled {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+	if rule.Conditions.InstitutionName != nil || rule.Conditions.Direction != DirectionExpense {
+		t.Errorf("expected normalized conditions, got %+v", rule.Conditions)
+	}
+
+	if _, err := buildRule(userID, RuleInput{Pattern: "re:("}); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("expected ErrInvalidRule, got %v", err)
+	}
+}
+
+func TestCategorize_SkipsDisabledRules(t *testing.T) {
+	empty := ""
+	enabled := compiledRule{rule: CategoryRule{ID: uuid.New(), MatchPattern: "UBER", CleanName: &empty}}
+	enabled.matcher, _ = CompileRule("UBER", RuleConditions{})
+	// A disabled rule is loaded without a matcher
+	disabled := compiledRule{rule: CategoryRule{ID: uuid.New(), MatchPattern: "^UBER", Priority: 9}}
+
+	result := categorize(Transaction{Description: "UBER *TRIP"}, []compiledRule{disabled, enabled}, nil, nil, nil)
+	if result.RuleID == nil || *result.RuleID != enabled.rule.ID {
+		t.Fatalf("expected the enabled rule to win, got %+v", result.Explanation)
+	}
+	if result.CleanMerchantName != "Uber *trip" {
+		t.Errorf("a rule without a clean name should keep the cleaned description, got %q", result.CleanMerchantName)
+	}
+}
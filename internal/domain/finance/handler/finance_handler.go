@@ -210,21 +210,13 @@ func (h *FinanceHandler) ListTransactions(
 
 	// Build filter from request
 	filter := repository.ListTransactionsFilter{
-		Limit:  50, // Default
-		Offset: 0,
+		Limit: 50, // Default
 	}
 
-	// Parse pagination
+	// Parse pagination: the page token is the opaque cursor of the previous page
 	if req.Msg.Page != nil {
 		filter.Limit = int(req.Msg.Page.PageSize)
-		// Token-based pagination: decode offset from page token if provided
-		if req.Msg.Page.PageToken != "" {
-			// Parse page token as offset
-			offset, err := strconv.Atoi(req.Msg.Page.PageToken)
-			if err == nil && offset > 0 {
-				filter.Offset = offset
-			}
-		}
+		filter.Cursor = req.Msg.Page.PageToken
 	}
 
 	// Parse optional filters
@@ -241,7 +233,7 @@ func (h *FinanceHandler) ListTransactions(
 		if err != nil {
 			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid category_id"))
 		}
-		filter.CategoryID = &parsed
+		filter.CategoryIDs = append(filter.CategoryIDs, parsed)
 	}
 
 	if req.Msg.ImportJobId != nil && *req.Msg.ImportJobId != "" {
@@ -264,7 +256,10 @@ func (h *FinanceHandler) ListTransactions(
 	}
 
 	// Query transactions
-	transactions, totalCount, err := h.importRepo.ListTransactions(ctx, userID, filter)
+	transactions, nextPageToken, err := h.importRepo.ListTransactions(ctx, userID, filter)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid page_token"))
+	}
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list transactions: %w", err))
 	}
@@ -276,12 +271,6 @@ func (h *FinanceHandler) ListTransactions(
 		protoTxs = append(protoTxs, protoTx)
 	}
 
-	// Build next page token
-	var nextPageToken string
-	if int64(filter.Offset+filter.Limit) < totalCount {
-		nextPageToken = strconv.Itoa(filter.Offset + filter.Limit)
-	}
-
 	return connect.NewResponse(&echov1.ListTransactionsResponse{
 		Transactions: protoTxs,
 		Page: &echov1.PageResponse{
@@ -335,7 +324,8 @@ func transactionToProto(tx *repository.Transaction) *echov1.Transaction {
 	return result
 }
 
-// DeleteImportBatch deletes all transactions from a specific import batch.
+// DeleteImportBatch rolls back an import batch: its transactions are soft
+// deleted and kept until the retention window purges them.
 func (h *FinanceHandler) DeleteImportBatch(
 	ctx context.Context,
 	req *connect.Request[echov1.DeleteImportBatchRequest],
@@ -356,9 +346,9 @@ func (h *FinanceHandler) DeleteImportBatch(
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid import_job_id"))
 	}
 
-	deletedCount, err := h.importRepo.DeleteByImportJobID(ctx, userID, importJobID)
+	deletedCount, err := h.importSvc.RollbackImportJob(ctx, userID, importJobID)
 	if err != nil {
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to delete import batch: %w", err))
+		return nil, importBatchError(err, "delete import batch")
 	}
 
 	return connect.NewResponse(&echov1.DeleteImportBatchResponse{
@@ -366,6 +356,17 @@ func (h *FinanceHandler) DeleteImportBatch(
 	}), nil
 }
 
+// importBatchError maps import batch rollback errors to connect errors
+func importBatchError(err error, action string) error {
+	switch {
+	case errors.Is(err, importservice.ErrImportJobNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, importservice.ErrImportJobActive):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	}
+	return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to %s: %w", action, err))
+}
+
 // CreateCategoryRule creates a new categorization rule for "Remember this" learning.
 func (h *FinanceHandler) CreateCategoryRule(
 	ctx context.Context,
@@ -390,15 +391,16 @@ func (h *FinanceHandler) CreateCategoryRule(
 		categoryID = &parsed
 	}
 
-	rule, updated, err := h.catService.CreateRule(
-		ctx, userID,
-		req.Msg.MatchPattern,
-		req.Msg.CleanName,
-		categoryID,
-		req.Msg.IsRecurring,
-		req.Msg.ApplyToExisting,
-	)
+	rule, run, err := h.catService.CreateRule(ctx, userID, categorization.RuleInput{
+		Pattern:     req.Msg.MatchPattern,
+		CleanName:   req.Msg.CleanName,
+		CategoryID:  categoryID,
+		IsRecurring: req.Msg.IsRecurring,
+	}, req.Msg.ApplyToExisting)
 	if err != nil {
+		if errors.Is(err, categorization.ErrInvalidRule) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create rule: %w", err))
 	}
 
@@ -408,7 +410,7 @@ func (h *FinanceHandler) CreateCategoryRule(
 		catIDStr = &s
 	}
 
-	return connect.NewResponse(&echov1.CreateCategoryRuleResponse{
+	resp := &echov1.CreateCategoryRuleResponse{
 		Rule: &echov1.CategoryRule{
 			Id:           rule.ID.String(),
 			UserId:       rule.UserID.String(),
@@ -418,8 +420,11 @@ func (h *FinanceHandler) CreateCategoryRule(
 			IsRecurring:  rule.IsRecurring,
 			Priority:     int32(rule.Priority),
 		},
-		TransactionsUpdated: updated,
-	}), nil
+	}
+	if run != nil {
+		resp.TransactionsUpdated = int64(run.TransactionsChanged)
+	}
+	return connect.NewResponse(resp), nil
 }
 
 // ListCategoryRules lists all categorization rules for the user.
@@ -0,0 +1,121 @@
+// Package fx keeps euro reference exchange rates and converts amounts between
+// currencies, so multi-currency data can be reported in a user's base currency.
+//
+// Rates come from European Central Bank CSV files (the daily eurofxref.csv or
+// the full eurofxref-hist.csv) that are loaded from disk; nothing is fetched
+// over the network.
+package fx
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BaseCurrency is the currency ECB reference rates are quoted against.
+const BaseCurrency = "EUR"
+
+// Rate is how many units of CurrencyCode one euro bought on Date.
+type Rate struct {
+	CurrencyCode string
+	Date         time.Time
+	Rate         float64
+}
+
+// ErrInvalidECBFile is returned when a file does not look like an ECB rates CSV.
+var ErrInvalidECBFile = errors.New("invalid ECB reference rates file")
+
+// ECB files use ISO dates in the history file and "17 October 2025" in the daily one.
+var ecbDateLayouts = []string{"2006-01-02", "2 January 2006", "02 January 2006"}
+
+// ParseECB reads an ECB reference rates CSV: a "Date" column followed by one
+// column per currency. Cells marked "N/A" (currencies no longer quoted) are skipped.
+func ParseECB(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty file", ErrInvalidECBFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidECBFile, err)
+	}
+	if len(header) < 2 || !strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(header[0], "\uFEFF")), "date") {
+		return nil, fmt.Errorf("%w: missing Date header", ErrInvalidECBFile)
+	}
+
+	codes := make([]string, len(header))
+	for i, cell := range header[1:] {
+		code := strings.ToUpper(strings.TrimSpace(cell))
+		if code == "" {
+			continue // trailing comma
+		}
+		if !isCurrencyCode(code) {
+			return nil, fmt.Errorf("%w: bad currency column %q", ErrInvalidECBFile, cell)
+		}
+		codes[i+1] = code
+	}
+
+	var rates []Rate
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidECBFile, line, err)
+		}
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		date, err := parseECBDate(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidECBFile, line, err)
+		}
+
+		for i := 1; i < len(record) && i < len(codes); i++ {
+			value := strings.TrimSpace(record[i])
+			if codes[i] == "" || value == "" || strings.EqualFold(value, "N/A") {
+				continue
+			}
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || rate <= 0 {
+				return nil, fmt.Errorf("%w: line %d: bad %s rate %q", ErrInvalidECBFile, line, codes[i], value)
+			}
+			rates = append(rates, Rate{CurrencyCode: codes[i], Date: date, Rate: rate})
+		}
+	}
+
+	return rates, nil
+}
+
+func parseECBDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range ecbDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad date %q", value)
+}
+
+func isCurrencyCode(value string) bool {
+	if len(value) != 3 {
+		return false
+	}
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
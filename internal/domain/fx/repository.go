@@ -0,0 +1,54 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RateRepository defines the interface for exchange rate data access
+type RateRepository interface {
+	UpsertRates(ctx context.Context, rates []Rate) (int, error)
+}
+
+// Ensure Repository implements RateRepository
+var _ RateRepository = (*Repository)(nil)
+
+// Repository handles database queries for exchange rates
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates a new exchange rate repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// UpsertRates stores rates, replacing any already stored for the same currency
+// and day. It returns how many rows were written.
+func (r *Repository) UpsertRates(ctx context.Context, rates []Rate) (int, error) {
+	if len(rates) == 0 {
+		return 0, nil
+	}
+
+	codes := make([]string, len(rates))
+	dates := make([]time.Time, len(rates))
+	values := make([]float64, len(rates))
+	for i, rate := range rates {
+		codes[i] = rate.CurrencyCode
+		dates[i] = rate.Date
+		values[i] = rate.Rate
+	}
+
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO fx_rates (currency_code, rate_date, rate)
+		SELECT * FROM unnest($1::text[], $2::date[], $3::numeric[])
+		ON CONFLICT (currency_code, rate_date) DO UPDATE SET rate = EXCLUDED.rate
+	`, codes, dates, values)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store fx rates: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,92 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// upsertBatchSize bounds the arrays sent per UpsertRates call; the ECB history
+// file holds a few hundred thousand rates.
+const upsertBatchSize = 5000
+
+// Service loads exchange rates. Amounts are converted in SQL by fx_convert.
+type Service struct {
+	repo   RateRepository
+	logger *slog.Logger
+}
+
+// NewService creates a new exchange rate service
+func NewService(repo RateRepository, logger *slog.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// LoadECB parses an ECB reference rates CSV and stores its rates.
+func (s *Service) LoadECB(ctx context.Context, r io.Reader) (int, error) {
+	rates, err := ParseECB(r)
+	if err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for start := 0; start < len(rates); start += upsertBatchSize {
+		end := min(start+upsertBatchSize, len(rates))
+		n, err := s.repo.UpsertRates(ctx, rates[start:end])
+		if err != nil {
+			return stored, err
+		}
+		stored += n
+	}
+	return stored, nil
+}
+
+// LoadPath loads an ECB CSV file, or every .csv file in a directory in name order.
+func (s *Service) LoadPath(ctx context.Context, path string) (int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return 0, err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".csv") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		sort.Strings(files)
+	}
+
+	total := 0
+	for _, name := range files {
+		n, err := s.loadFile(ctx, name)
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", filepath.Base(name), err)
+		}
+		s.logger.Info("loaded fx rates", "file", name, "rates", n)
+		total += n
+	}
+	return total, nil
+}
+
+func (s *Service) loadFile(ctx context.Context, name string) (int, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return s.LoadECB(ctx, f)
+}
@@ -0,0 +1,72 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockRateRepository keeps rates in memory
+type MockRateRepository struct {
+	rates []Rate
+}
+
+func (m *MockRateRepository) UpsertRates(ctx context.Context, rates []Rate) (int, error) {
+	m.rates = append(m.rates, rates...)
+	return len(rates), nil
+}
+
+const dailyECB = `Date, USD, JPY, BGN, GBP,
+17 October 2025, 1.1697, 175.50, 1.9558, 0.8700,
+`
+
+const historyECB = `Date,USD,JPY,CYP,GBP,
+2025-10-16,1.1650,175.10,N/A,0.8690,
+2025-10-15,1.1600,174.80,N/A,0.8680,
+`
+
+func TestParseECB(t *testing.T) {
+	daily, err := ParseECB(strings.NewReader(dailyECB))
+	require.NoError(t, err)
+	require.Len(t, daily, 4)
+	assert.Equal(t, Rate{CurrencyCode: "USD", Date: time.Date(2025, 10, 17, 0, 0, 0, 0, time.UTC), Rate: 1.1697}, daily[0])
+
+	history, err := ParseECB(strings.NewReader(historyECB))
+	require.NoError(t, err)
+	assert.Len(t, history, 6, "N/A cells are skipped")
+
+	_, err = ParseECB(strings.NewReader("Currency,Rate\nUSD,1.1\n"))
+	assert.True(t, errors.Is(err, ErrInvalidECBFile))
+	_, err = ParseECB(strings.NewReader("Date,USD\n2025-10-17,abc\n"))
+	assert.True(t, errors.Is(err, ErrInvalidECBFile))
+}
+
+func TestLoadPath(t *testing.T) {
+	repo := &MockRateRepository{}
+	svc := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "eurofxref-hist.csv"), []byte(historyECB), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "eurofxref.csv"), []byte(dailyECB), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.txt"), []byte("not rates"), 0o600))
+	loaded, err := svc.LoadPath(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, 10, loaded)
+	require.Len(t, repo.rates, 10)
+
+	// Files load in name order, so the daily rates follow the history
+	assert.Equal(t, Rate{CurrencyCode: "USD", Date: time.Date(2025, 10, 16, 0, 0, 0, 0, time.UTC), Rate: 1.1650}, repo.rates[0])
+	assert.Equal(t, Rate{CurrencyCode: "GBP", Date: time.Date(2025, 10, 17, 0, 0, 0, 0, time.UTC), Rate: 0.8700}, repo.rates[9])
+
+	_, err = svc.LoadPath(context.Background(), filepath.Join(dir, "missing.csv"))
+	assert.Error(t, err)
+}
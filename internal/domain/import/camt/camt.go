@@ -0,0 +1,357 @@
+// Package camt parses ISO 20022 CAMT.053 bank-to-customer statements into
+// transactions ready for import.
+package camt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/charset"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/dedup"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/normalizer"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+var (
+	ErrNotCAMT     = errors.New("file is not a CAMT.053 statement")
+	ErrInvalidDate = errors.New("invalid CAMT booking date")
+)
+
+// Statement holds the booked entries of every statement in a CAMT.053 document.
+type Statement struct {
+	IBAN         string // Account IBAN (or other id) of the first statement
+	CurrencyCode string // Account currency of the first statement
+	Transactions []*repository.ParsedTransaction
+	Errors       []string // Per-entry parse failures, e.g. "entry 3: invalid amount"
+}
+
+// IsCAMT053 reports whether data looks like a CAMT.053 document.
+func IsCAMT053(data []byte) bool {
+	head := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")), " \t\r\n")
+	if !bytes.HasPrefix(head, []byte("<")) {
+		return false
+	}
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	return bytes.Contains(head, []byte("camt.053")) || bytes.Contains(head, []byte("<BkToCstmrStmt"))
+}
+
+type document struct {
+	Statements []statement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type statement struct {
+	Account struct {
+		IBAN  string `xml:"Id>IBAN"`
+		Other string `xml:"Id>Othr>Id"`
+		Ccy   string `xml:"Ccy"`
+	} `xml:"Acct"`
+	Entries []entry `xml:"Ntry"`
+}
+
+type amount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type dateChoice struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type party struct {
+	Name    string `xml:"Nm"`
+	PtyName string `xml:"Pty>Nm"` // camt.053.001.08+ nests the party
+}
+
+func (p party) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PtyName
+}
+
+// status is a plain code before camt.053.001.08 and a <Cd> child afterwards.
+type status struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type entry struct {
+	Amount      amount     `xml:"Amt"`
+	CdtDbtInd   string     `xml:"CdtDbtInd"`
+	Status      status     `xml:"Sts"`
+	BookingDate dateChoice `xml:"BookgDt"`
+	ValueDate   dateChoice `xml:"ValDt"`
+	AcctSvcrRef string     `xml:"AcctSvcrRef"`
+	AddtlInfo   string     `xml:"AddtlNtryInf"`
+	Details     []txDetail `xml:"NtryDtls>TxDtls"`
+}
+
+type txDetail struct {
+	AcctSvcrRef string   `xml:"Refs>AcctSvcrRef"`
+	Amount      amount   `xml:"Amt"`
+	TxAmount    amount   `xml:"AmtDtls>TxAmt>Amt"`
+	CdtDbtInd   string   `xml:"CdtDbtInd"`
+	Creditor    party    `xml:"RltdPties>Cdtr"`
+	Debtor      party    `xml:"RltdPties>Dbtr"`
+	Unstructd   []string `xml:"RmtInf>Ustrd"`
+	AddtlInfo   string   `xml:"AddtlTxInf"`
+}
+
+func (d txDetail) amount() amount {
+	if strings.TrimSpace(d.Amount.Value) != "" {
+		return d.Amount
+	}
+	return d.TxAmount
+}
+
+// Parse reads a CAMT.053 document. Only booked entries are returned. Entries that
+// batch several transaction details with individual amounts are split, and each
+// transaction's ExternalID is the servicer's AcctSvcrRef scoped to the account.
+func Parse(data []byte) (*Statement, error) {
+	if !IsCAMT053(data) {
+		return nil, ErrNotCAMT
+	}
+
+	var doc document
+	decoder := xml.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))))
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse CAMT document: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, ErrNotCAMT
+	}
+
+	result := &Statement{}
+	index := 0
+	for _, stmt := range doc.Statements {
+		accountID := stmt.Account.IBAN
+		if accountID == "" {
+			accountID = stmt.Account.Other
+		}
+		if result.IBAN == "" {
+			result.IBAN = accountID
+		}
+		if result.CurrencyCode == "" {
+			result.CurrencyCode = strings.ToUpper(stmt.Account.Ccy)
+		}
+
+		for _, e := range stmt.Entries {
+			index++
+			if !e.isBooked() {
+				continue
+			}
+			if result.CurrencyCode == "" {
+				result.CurrencyCode = strings.ToUpper(e.Amount.Currency)
+			}
+
+			txs, err := e.transactions(accountID)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("entry %d: %v", index, err))
+				continue
+			}
+			result.Transactions = append(result.Transactions, txs...)
+		}
+	}
+
+	return result, nil
+}
+
+func (e entry) isBooked() bool {
+	code := strings.TrimSpace(e.Status.Code)
+	if code == "" {
+		code = strings.TrimSpace(e.Status.Text)
+	}
+	// Sts is optional in older versions; treat missing status as booked.
+	return code == "" || strings.EqualFold(code, "BOOK")
+}
+
+func (e entry) transactions(accountID string) ([]*repository.ParsedTransaction, error) {
+	date, err := parseDate(e.BookingDate)
+	if err != nil {
+		date, err = parseDate(e.ValueDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Batched entry: split when every detail carries its own amount.
+	if len(e.Details) > 1 && e.detailsHaveAmounts() {
+		txs := make([]*repository.ParsedTransaction, 0, len(e.Details))
+		for i, d := range e.Details {
+			indicator := d.CdtDbtInd
+			if indicator == "" {
+				indicator = e.CdtDbtInd
+			}
+			cents, err := signedAmount(d.amount().Value, indicator)
+			if err != nil {
+				return nil, err
+			}
+			ref := d.AcctSvcrRef
+			if ref == "" && e.AcctSvcrRef != "" {
+				ref = fmt.Sprintf("%s/%d", e.AcctSvcrRef, i+1)
+			}
+			raw := rawDescription(indicator, &d, e.AddtlInfo)
+			txs = append(txs, &repository.ParsedTransaction{
+				Date:        date,
+				Description: describe(raw),
+				AmountCents: cents,
+				ExternalID:  externalID(accountID, ref),
+				RowHash:     e.rowHash(accountID, d.amount().Value, indicator, raw),
+			})
+		}
+		return txs, nil
+	}
+
+	cents, err := signedAmount(e.Amount.Value, e.CdtDbtInd)
+	if err != nil {
+		return nil, err
+	}
+
+	var detail *txDetail
+	if len(e.Details) > 0 {
+		detail = &e.Details[0]
+	}
+
+	ref := e.AcctSvcrRef
+	if ref == "" && detail != nil {
+		ref = detail.AcctSvcrRef
+	}
+
+	raw := rawDescription(e.CdtDbtInd, detail, e.AddtlInfo)
+	return []*repository.ParsedTransaction{{
+		Date:        date,
+		Description: describe(raw),
+		AmountCents: cents,
+		ExternalID:  externalID(accountID, ref),
+		RowHash:     e.rowHash(accountID, e.Amount.Value, e.CdtDbtInd, raw),
+	}}, nil
+}
+
+// rowHash hashes the raw entry fields used for a transaction, for entries the
+// bank did not give a reference.
+func (e entry) rowHash(accountID, amount, indicator, rawDescription string) string {
+	return dedup.RowHash("camt", accountID,
+		e.BookingDate.Date, e.BookingDate.DateTime, e.ValueDate.Date, e.ValueDate.DateTime,
+		amount, indicator, rawDescription)
+}
+
+func (e entry) detailsHaveAmounts() bool {
+	for _, d := range e.Details {
+		if strings.TrimSpace(d.amount().Value) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// rawDescription joins the counterparty and remittance information, falling back
+// to the entry's additional information.
+func rawDescription(indicator string, detail *txDetail, entryInfo string) string {
+	var parts []string
+	if detail != nil {
+		// The counterparty is the creditor for debits and the debtor for credits.
+		counterparty := detail.Creditor.name()
+		if strings.EqualFold(indicator, "CRDT") {
+			counterparty = detail.Debtor.name()
+		}
+		if counterparty != "" {
+			parts = append(parts, counterparty)
+		}
+		for _, line := range detail.Unstructd {
+			if line = strings.TrimSpace(line); line != "" {
+				parts = append(parts, line)
+			}
+		}
+		if len(parts) == 0 && detail.AddtlInfo != "" {
+			parts = append(parts, detail.AddtlInfo)
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, entryInfo)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// describe cleans a raw description for display.
+func describe(raw string) string {
+	description := normalizer.CleanDescription(raw)
+	if description == "" {
+		return "Bank transaction"
+	}
+	return description
+}
+
+func signedAmount(raw, indicator string) (int64, error) {
+	cents, err := normalizer.ParseAmount(strings.TrimSpace(raw), false)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s': %w", raw, err)
+	}
+	if cents < 0 {
+		cents = -cents
+	}
+	switch strings.ToUpper(strings.TrimSpace(indicator)) {
+	case "DBIT":
+		return -cents, nil
+	case "CRDT":
+		return cents, nil
+	default:
+		return 0, fmt.Errorf("invalid credit/debit indicator '%s'", indicator)
+	}
+}
+
+func parseDate(d dateChoice) (time.Time, error) {
+	if value := strings.TrimSpace(d.Date); value != "" {
+		// ISODate may carry a zone suffix (e.g. 2024-01-02+01:00); keep the calendar date.
+		if len(value) >= 10 {
+			if t, err := time.Parse("2006-01-02", value[:10]); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDate, value)
+	}
+	if value := strings.TrimSpace(d.DateTime); value != "" {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDate, value)
+	}
+	return time.Time{}, ErrInvalidDate
+}
+
+// externalID scopes the servicer reference to its account.
+func externalID(accountID, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.EqualFold(ref, "NOTPROVIDED") {
+		return ""
+	}
+	if accountID == "" {
+		return "camt:" + ref
+	}
+	return "camt:" + accountID + ":" + ref
+}
+
+// charsetReader decodes the non-UTF-8 encodings a document may declare, such as
+// the ISO-8859-1 and windows-1252 some banks still emit.
+func charsetReader(name string, input io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := charset.Decode(data, name)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(decoded), nil
+}
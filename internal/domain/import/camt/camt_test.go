@@ -0,0 +1,151 @@
+package camt
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleCAMT = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG-1</MsgId></GrpHdr>
+    <Stmt>
+      <Id>STMT-1</Id>
+      <Acct>
+        <Id><IBAN>PT50000201231234567890154</IBAN></Id>
+        <Ccy>EUR</Ccy>
+      </Acct>
+      <Ntry>
+        <Amt Ccy="EUR">45.23</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2024-01-02</Dt></BookgDt>
+        <AcctSvcrRef>REF-001</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties><Cdtr><Nm>Pingo Doce</Nm></Cdtr></RltdPties>
+          <RmtInf><Ustrd>Compra 1234</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">500.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><DtTm>2024-01-05T10:15:00+01:00</DtTm></BookgDt>
+        <AcctSvcrRef>REF-002</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties><Dbtr><Pty><Nm>ACME Lda</Nm></Pty></Dbtr></RltdPties>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">9.99</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <BookgDt><Dt>2024-01-06</Dt></BookgDt>
+        <AcctSvcrRef>REF-003</AcctSvcrRef>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">30.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2024-01-07+01:00</Dt></BookgDt>
+        <AcctSvcrRef>REF-004</AcctSvcrRef>
+        <AddtlNtryInf>SEPA batch</AddtlNtryInf>
+        <NtryDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="EUR">10.00</Amt></TxAmt></AmtDtls>
+            <RltdPties><Cdtr><Nm>Gym</Nm></Cdtr></RltdPties>
+          </TxDtls>
+          <TxDtls>
+            <Refs><AcctSvcrRef>REF-004-B</AcctSvcrRef></Refs>
+            <AmtDtls><TxAmt><Amt Ccy="EUR">20.00</Amt></TxAmt></AmtDtls>
+            <RltdPties><Cdtr><Nm>Insurance</Nm></Cdtr></RltdPties>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">1.00</Amt>
+        <CdtDbtInd>XXXX</CdtDbtInd>
+        <BookgDt><Dt>2024-01-08</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func TestIsCAMT053(t *testing.T) {
+	if !IsCAMT053([]byte(sampleCAMT)) {
+		t.Error("expected CAMT.053 to be detected")
+	}
+	if IsCAMT053([]byte("Date,Description\n2024-01-02,camt.053 fee\n")) {
+		t.Error("expected CSV not to be detected as CAMT")
+	}
+}
+
+func TestParse(t *testing.T) {
+	stmt, err := Parse([]byte(sampleCAMT))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if stmt.IBAN != "PT50000201231234567890154" || stmt.CurrencyCode != "EUR" {
+		t.Errorf("unexpected statement header: %q %q", stmt.IBAN, stmt.CurrencyCode)
+	}
+	if len(stmt.Errors) != 1 {
+		t.Errorf("expected 1 error for the invalid indicator, got %v", stmt.Errors)
+	}
+
+	expected := []struct {
+		date       time.Time
+		desc       string
+		amount     int64
+		externalID string
+	}{
+		{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "Pingo Doce Compra 1234", -4523, "camt:PT50000201231234567890154:REF-001"},
+		{time.Date(2024, 1, 5, 9, 15, 0, 0, time.UTC), "ACME Lda", 50000, "camt:PT50000201231234567890154:REF-002"},
+		{time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), "Gym", -1000, "camt:PT50000201231234567890154:REF-004/1"},
+		{time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), "Insurance", -2000, "camt:PT50000201231234567890154:REF-004-B"},
+	}
+
+	if len(stmt.Transactions) != len(expected) {
+		t.Fatalf("expected %d transactions, got %d", len(expected), len(stmt.Transactions))
+	}
+	for i, want := range expected {
+		got := stmt.Transactions[i]
+		if !got.Date.Equal(want.date) || got.Description != want.desc || got.AmountCents != want.amount || got.ExternalID != want.externalID {
+			t.Errorf("transaction %d = %v %q %d %q, want %v %q %d %q", i,
+				got.Date.UTC(), got.Description, got.AmountCents, got.ExternalID,
+				want.date, want.desc, want.amount, want.externalID)
+		}
+	}
+}
+
+func TestParse_DeclaredCharset(t *testing.T) {
+	tests := []struct {
+		encoding string
+		name     string // Raw bytes in that encoding
+		expected string
+	}{
+		{"windows-1252", "Caf\xE9 \x80", "Café € Compra 1234"},
+		{"ISO-8859-1", "Caf\xE9", "Café Compra 1234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			data := strings.Replace(sampleCAMT, `encoding="UTF-8"`, `encoding="`+tt.encoding+`"`, 1)
+			data = strings.Replace(data, "Pingo Doce", tt.name, 1)
+
+			stmt, err := Parse([]byte(data))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if got := stmt.Transactions[0].Description; got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	data := strings.Replace(sampleCAMT, `encoding="UTF-8"`, `encoding="EBCDIC"`, 1)
+	if _, err := Parse([]byte(data)); err == nil {
+		t.Error("expected an unsupported charset to be rejected")
+	}
+}
@@ -0,0 +1,217 @@
+// Package charset detects the text encoding of uploaded bank exports and
+// transcodes them to UTF-8.
+//
+// Banks rarely declare an encoding. Detection looks at byte order marks first,
+// then at the byte patterns each supported encoding allows. Single-byte
+// encodings cannot be told apart reliably, so callers should let users override
+// the detected name.
+package charset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Canonical encoding names returned by Detect and accepted by Decode.
+const (
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	Windows1252 = "windows-1252"
+	ISO88591    = "iso-8859-1"
+	ISO885915   = "iso-8859-15"
+	ShiftJIS    = "shift_jis"
+)
+
+// ErrUnsupportedEncoding is returned for encoding names Decode does not know.
+var ErrUnsupportedEncoding = errors.New("unsupported encoding")
+
+var encodings = map[string]encoding.Encoding{
+	UTF8:        unicode.UTF8,
+	UTF16LE:     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	UTF16BE:     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	Windows1252: charmap.Windows1252,
+	ISO88591:    charmap.ISO8859_1,
+	ISO885915:   charmap.ISO8859_15,
+	ShiftJIS:    japanese.ShiftJIS,
+}
+
+var aliases = map[string]string{
+	"utf8":      UTF8,
+	"utf-16":    UTF16LE,
+	"utf16le":   UTF16LE,
+	"utf16be":   UTF16BE,
+	"cp1252":    Windows1252,
+	"latin1":    ISO88591,
+	"latin-1":   ISO88591,
+	"latin9":    ISO885915,
+	"shift-jis": ShiftJIS,
+	"sjis":      ShiftJIS,
+	"cp932":     ShiftJIS,
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Normalize returns the canonical name for a supported encoding name or alias.
+func Normalize(name string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := aliases[key]; ok {
+		key = canonical
+	}
+	if _, ok := encodings[key]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedEncoding, name)
+	}
+	return key, nil
+}
+
+// Detect guesses the encoding of data and returns its canonical name. It falls
+// back to windows-1252 for bytes that are neither UTF-8 nor UTF-16 nor plausible
+// Shift-JIS, since that is what most Western bank exports use.
+func Detect(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return UTF8
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return UTF16BE
+	}
+
+	if enc := detectUTF16(data); enc != "" {
+		return enc
+	}
+	if utf8.Valid(data) {
+		return UTF8
+	}
+	if looksShiftJIS(data) {
+		return ShiftJIS
+	}
+	// 0x80-0x9F are control codes in ISO-8859-1 but printable in windows-1252
+	// (the euro sign is 0x80). Without them both decode the same.
+	for _, b := range data {
+		if b >= 0x80 && b <= 0x9F {
+			return Windows1252
+		}
+	}
+	return ISO88591
+}
+
+// Decode transcodes data from the named encoding to UTF-8, dropping any byte
+// order mark.
+func Decode(data []byte, name string) ([]byte, error) {
+	canonical, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch canonical {
+	case UTF8:
+		data = bytes.TrimPrefix(data, bomUTF8)
+		if utf8.Valid(data) {
+			return data, nil
+		}
+	case UTF16LE:
+		data = bytes.TrimPrefix(data, bomUTF16LE)
+	case UTF16BE:
+		data = bytes.TrimPrefix(data, bomUTF16BE)
+	}
+
+	decoded, err := encodings[canonical].NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", canonical, err)
+	}
+	return decoded, nil
+}
+
+// ToUTF8 decodes data using override when set, otherwise the detected encoding.
+// It returns the UTF-8 text and the canonical name of the encoding used.
+func ToUTF8(data []byte, override string) ([]byte, string, error) {
+	name := override
+	if name == "" {
+		name = Detect(data)
+	}
+	name, err := Normalize(name)
+	if err != nil {
+		return nil, "", err
+	}
+	decoded, err := Decode(data, name)
+	if err != nil {
+		return nil, "", err
+	}
+	return decoded, name, nil
+}
+
+// detectUTF16 recognizes BOM-less UTF-16 by the zero bytes ASCII text leaves in
+// every other position.
+func detectUTF16(data []byte) string {
+	n := min(len(data), 4096) &^ 1
+	if n < 4 {
+		return ""
+	}
+
+	var evenZeros, oddZeros int
+	for i := 0; i < n; i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+
+	pairs := n / 2
+	switch {
+	case oddZeros*10 >= pairs*7 && evenZeros*10 < pairs:
+		return UTF16LE
+	case evenZeros*10 >= pairs*7 && oddZeros*10 < pairs:
+		return UTF16BE
+	}
+	return ""
+}
+
+// looksShiftJIS reports whether every non-ASCII byte in data forms a valid
+// Shift-JIS character and most of them start with a lead byte in 0x81-0x9F.
+// Kana and common kanji live there, while windows-1252 maps that range to rare
+// punctuation; its accented letters (0xE0-0xFF) can also pass as lead bytes, so
+// text dominated by those is left to the single-byte fallback.
+func looksShiftJIS(data []byte) bool {
+	var low, high int
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case b < 0x80:
+			continue
+		case b >= 0xA1 && b <= 0xDF: // half-width katakana
+			continue
+		case (b >= 0x81 && b <= 0x9F) || (b >= 0xE0 && b <= 0xFC):
+			if i+1 >= len(data) {
+				return false
+			}
+			trail := data[i+1]
+			if trail < 0x40 || trail == 0x7F || trail > 0xFC {
+				return false
+			}
+			if b <= 0x9F {
+				low++
+			} else {
+				high++
+			}
+			i++
+		default:
+			return false
+		}
+	}
+	return low >= 2 && low > high
+}
@@ -0,0 +1,70 @@
+package charset
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestToUTF8(t *testing.T) {
+	const text = "Data;Descrição;Montante\n02/01/2024;Café €;-3,50\n"
+
+	utf16le, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16be, err := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const japaneseText = "日付,摘要,金額\n2024/01/02,コンビニ,-350\n"
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(japaneseText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     string
+		encoding string
+	}{
+		{"utf-8", []byte(text), text, UTF8},
+		{"utf-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, text...), text, UTF8},
+		{"utf-16le with BOM", utf16le, text, UTF16LE},
+		{"utf-16be without BOM", utf16be, text, UTF16BE},
+		{"windows-1252", []byte("Data;Descri\xe7\xe3o;Montante\n02/01/2024;Caf\xe9 \x80;-3,50\n"), text, Windows1252},
+		{"latin-1", []byte("Descri\xe7\xe3o;Caf\xe9\n"), "Descrição;Café\n", ISO88591},
+		{"shift-jis", shiftJIS, japaneseText, ShiftJIS},
+	}
+	for _, tc := range tests {
+		got, encoding, err := ToUTF8(tc.data, "")
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if encoding != tc.encoding {
+			t.Errorf("%s: detected %s, want %s", tc.name, encoding, tc.encoding)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s: decoded %q", tc.name, got)
+		}
+	}
+}
+
+func TestToUTF8_Override(t *testing.T) {
+	// 0xA4 is the currency sign in Latin-1 and the euro sign in Latin-9.
+	got, encoding, err := ToUTF8([]byte("10 \xa4"), "Latin9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoding != ISO885915 || string(got) != "10 €" {
+		t.Errorf("got %q as %s", got, encoding)
+	}
+
+	if _, _, err := ToUTF8([]byte("x"), "ebcdic"); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
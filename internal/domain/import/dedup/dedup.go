@@ -0,0 +1,61 @@
+// Package dedup derives stable deduplication keys for imported transactions and
+// scores probable duplicates between sources (file imports vs aggregators).
+//
+// A row's key is a hash of its raw cells, so it does not change when description
+// cleaning changes, plus an occurrence index that keeps genuinely identical rows
+// in one file (two coffees on the same day) apart.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RowHash hashes the raw cells of a source row. Cells are trimmed; nothing else
+// is normalized, so the hash is independent of parsing and cleaning rules.
+func RowHash(cells ...string) string {
+	h := sha256.New()
+	for i, cell := range cells {
+		if i > 0 {
+			h.Write([]byte{0x1f}) // unit separator keeps "a,b" and "ab" apart
+		}
+		h.Write([]byte(strings.TrimSpace(cell)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ExternalID returns the external_id for the occurrence-th copy (0-based) of a
+// row within one file.
+func ExternalID(rowHash string, occurrence int) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("row:%s#%d", rowHash, occurrence)))
+	return "r1:" + hex.EncodeToString(hash[:16])
+}
+
+// LegacyExternalID reproduces the date|description|amount hash used before row
+// hashes, so rows imported with it are still recognized.
+func LegacyExternalID(date time.Time, description string, amountMinor int64) string {
+	data := fmt.Sprintf("%s|%s|%d", date.Format(time.RFC3339), description, amountMinor)
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:16])
+}
+
+// Occurrences numbers identical rows within one file. It is not safe for
+// concurrent use; rows must be fed in file order.
+type Occurrences struct {
+	seen map[string]int
+}
+
+// NewOccurrences creates an empty counter.
+func NewOccurrences() *Occurrences {
+	return &Occurrences{seen: make(map[string]int)}
+}
+
+// Next returns how many times rowHash was seen before and records this copy.
+func (o *Occurrences) Next(rowHash string) int {
+	n := o.seen[rowHash]
+	o.seen[rowHash] = n + 1
+	return n
+}
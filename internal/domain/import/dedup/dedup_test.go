@@ -0,0 +1,69 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExternalID_OccurrencesKeepIdenticalRowsApart(t *testing.T) {
+	coffee := RowHash("2024-01-02", "Coffee", "-3.50")
+	if coffee != RowHash(" 2024-01-02", "Coffee ", "-3.50") {
+		t.Error("expected surrounding whitespace to be ignored")
+	}
+	if RowHash("a,b", "c") == RowHash("a", "b,c") {
+		t.Error("expected cell boundaries to matter")
+	}
+
+	occ := NewOccurrences()
+	first := ExternalID(coffee, occ.Next(coffee))
+	second := ExternalID(coffee, occ.Next(coffee))
+	if first == second {
+		t.Fatal("expected two identical rows to get different external IDs")
+	}
+
+	// Re-reading the same file yields the same IDs.
+	again := NewOccurrences()
+	if ExternalID(coffee, again.Next(coffee)) != first || ExternalID(coffee, again.Next(coffee)) != second {
+		t.Fatal("expected external IDs to be stable across reads")
+	}
+
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if got := LegacyExternalID(date, "Coffee", -350); len(got) != 32 || got == first {
+		t.Errorf("unexpected legacy id %q", got)
+	}
+}
+
+func TestScore(t *testing.T) {
+	cfg := DefaultMatchConfig()
+	bank := Record{Date: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Description: "CARD PAYMENT TO TESCO STORES 3217", AmountMinor: -4523}
+
+	tests := []struct {
+		name  string
+		other Record
+		ok    bool
+		link  bool
+	}{
+		{"aggregator name, settled later", Record{Date: bank.Date.AddDate(0, 0, 2), Description: "Tesco", AmountMinor: -4523}, true, true},
+		{"same day, unrelated text", Record{Date: bank.Date, Description: "Shell Fuel", AmountMinor: -4523}, true, false},
+		{"different amount", Record{Date: bank.Date, Description: "Tesco", AmountMinor: -4524}, false, false},
+		{"too far apart", Record{Date: bank.Date.AddDate(0, 0, 5), Description: "Tesco", AmountMinor: -4523}, false, false},
+	}
+	for _, tc := range tests {
+		score, ok := Score(bank, tc.other, cfg)
+		if ok != tc.ok || (score >= cfg.MinScore) != tc.link {
+			t.Errorf("%s: score %.2f ok %v, want ok %v link %v", tc.name, score, ok, tc.ok, tc.link)
+		}
+	}
+}
+
+func TestAssign_OneToOne(t *testing.T) {
+	kept := Assign([]Pair{
+		{Left: 0, Right: 0, Score: 0.7},
+		{Left: 0, Right: 1, Score: 0.9},
+		{Left: 1, Right: 1, Score: 0.8},
+		{Left: 1, Right: 0, Score: 0.6},
+	})
+	if len(kept) != 2 || kept[0] != (Pair{Left: 0, Right: 1, Score: 0.9}) || kept[1] != (Pair{Left: 1, Right: 0, Score: 0.6}) {
+		t.Fatalf("unexpected assignment: %+v", kept)
+	}
+}
@@ -0,0 +1,111 @@
+package dedup
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Record is the part of a transaction the matcher compares.
+type Record struct {
+	Date        time.Time
+	Description string
+	AmountMinor int64
+}
+
+// MatchConfig tunes cross-source matching.
+type MatchConfig struct {
+	MaxDays  int     // Largest posting date difference considered
+	MinScore float64 // Pairs scoring below this are not linked
+}
+
+// DefaultMatchConfig allows for card settlement delays of a few days and needs
+// some description overlap on top of an exact amount match.
+func DefaultMatchConfig() MatchConfig {
+	return MatchConfig{MaxDays: 3, MinScore: 0.55}
+}
+
+// Score rates how likely a and b are the same transaction reported by two
+// sources, between 0 and 1. ok is false when the amounts differ or the dates are
+// more than cfg.MaxDays apart.
+func Score(a, b Record, cfg MatchConfig) (score float64, ok bool) {
+	if a.AmountMinor != b.AmountMinor {
+		return 0, false
+	}
+	days := math.Abs(a.Date.Sub(b.Date).Hours()) / 24
+	if days > float64(cfg.MaxDays) {
+		return 0, false
+	}
+
+	dateScore := 1 - days/float64(cfg.MaxDays+1)
+	return 0.6*Similarity(a.Description, b.Description) + 0.4*dateScore, true
+}
+
+// Similarity compares two descriptions by their word tokens, between 0 and 1.
+// It uses the overlap coefficient, so a short aggregator name ("Tesco") fully
+// matches a longer bank text ("CARD PAYMENT TESCO STORES 3217"). Tokens without
+// letters (card numbers, references) are ignored.
+func Similarity(a, b string) float64 {
+	ta, tb := tokens(a), tokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for token := range ta {
+		if tb[token] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(min(len(ta), len(tb)))
+}
+
+func tokens(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(field)) < 2 || !strings.ContainsFunc(field, unicode.IsLetter) {
+			continue
+		}
+		set[field] = true
+	}
+	return set
+}
+
+// Pair is a scored match between the Left-th and Right-th records of two sets.
+type Pair struct {
+	Left  int
+	Right int
+	Score float64
+}
+
+// Assign keeps the best pairs so that every record is matched at most once,
+// choosing greedily by descending score.
+func Assign(pairs []Pair) []Pair {
+	sorted := append([]Pair(nil), pairs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Score != sorted[j].Score {
+			return sorted[i].Score > sorted[j].Score
+		}
+		if sorted[i].Left != sorted[j].Left {
+			return sorted[i].Left < sorted[j].Left
+		}
+		return sorted[i].Right < sorted[j].Right
+	})
+
+	usedLeft := make(map[int]bool)
+	usedRight := make(map[int]bool)
+	var kept []Pair
+	for _, p := range sorted {
+		if usedLeft[p.Left] || usedRight[p.Right] {
+			continue
+		}
+		usedLeft[p.Left] = true
+		usedRight[p.Right] = true
+		kept = append(kept, p)
+	}
+	return kept
+}
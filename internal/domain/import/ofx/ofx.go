@@ -0,0 +1,303 @@
+// Package ofx parses OFX/QFX bank statements (both the SGML 1.x and XML 2.x flavors)
+// into transactions ready for import.
+package ofx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/charset"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/dedup"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/normalizer"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+var (
+	ErrNotOFX         = errors.New("file is not an OFX statement")
+	ErrInvalidDate    = errors.New("invalid OFX date")
+	ErrMissingPosting = errors.New("transaction has no DTPOSTED")
+	ErrMissingAmount  = errors.New("transaction has no TRNAMT")
+)
+
+// Statement holds the transactions of every statement in an OFX file.
+type Statement struct {
+	AccountID    string // ACCTID of the first statement
+	CurrencyCode string // CURDEF of the first statement
+	Transactions []*repository.ParsedTransaction
+	Errors       []string // Per-transaction parse failures, e.g. "transaction 3: invalid amount"
+}
+
+// IsOFX reports whether data looks like an OFX/QFX document: an SGML header
+// block (OFX 1.x) or an XML document with an <OFX> root (OFX 2.x).
+func IsOFX(data []byte) bool {
+	head := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")), " \t\r\n")
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	upper := bytes.ToUpper(head)
+	if bytes.HasPrefix(upper, []byte("OFXHEADER")) {
+		return true
+	}
+	return bytes.HasPrefix(upper, []byte("<")) && bytes.Contains(upper, []byte("<OFX>"))
+}
+
+// Parse reads an OFX document. Each STMTTRN becomes a ParsedTransaction whose
+// ExternalID is derived from the bank's FITID, scoped to the account. Transactions
+// without a FITID get an empty ExternalID so the repository falls back to a row hash.
+func Parse(data []byte) (*Statement, error) {
+	if !IsOFX(data) {
+		return nil, ErrNotOFX
+	}
+	if !utf8.Valid(data) {
+		decoded, err := charset.Decode(data, declaredCharset(data))
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+
+	body := string(data)
+	start := strings.Index(strings.ToUpper(body), "<OFX>")
+	if start < 0 {
+		return nil, ErrNotOFX
+	}
+
+	stmt := &Statement{}
+	var (
+		accountID string
+		current   *transaction
+		index     int
+	)
+
+	for _, el := range tokenize(body[start:]) {
+		switch el.name {
+		case "CURDEF":
+			if stmt.CurrencyCode == "" {
+				stmt.CurrencyCode = strings.ToUpper(el.value)
+			}
+		case "ACCTID":
+			if current == nil {
+				accountID = el.value
+				if stmt.AccountID == "" {
+					stmt.AccountID = el.value
+				}
+			}
+		case "STMTTRN":
+			index++
+			current = &transaction{}
+		case "/STMTTRN":
+			if current != nil {
+				tx, err := current.build(accountID)
+				if err != nil {
+					stmt.Errors = append(stmt.Errors, fmt.Sprintf("transaction %d: %v", index, err))
+				} else {
+					stmt.Transactions = append(stmt.Transactions, tx)
+				}
+				current = nil
+			}
+		default:
+			if current != nil {
+				current.set(el.name, el.value)
+			}
+		}
+	}
+
+	return stmt, nil
+}
+
+type element struct {
+	name  string // upper-case tag name; closing tags are prefixed with '/'
+	value string // text following the tag, up to the next tag
+}
+
+// tokenize splits an OFX body into tags and their trailing text. It works for
+// SGML (unclosed leaf elements) and XML (closed leaves) because leaf values are
+// always the text directly after the opening tag.
+func tokenize(body string) []element {
+	var elements []element
+	for {
+		open := strings.IndexByte(body, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(body[open:], '>')
+		if end < 0 {
+			break
+		}
+		name := strings.ToUpper(strings.TrimSpace(body[open+1 : open+end]))
+		body = body[open+end+1:]
+
+		next := strings.IndexByte(body, '<')
+		text := body
+		if next >= 0 {
+			text = body[:next]
+		}
+
+		if name == "" || strings.HasPrefix(name, "?") || strings.HasPrefix(name, "!") {
+			continue
+		}
+		elements = append(elements, element{name: name, value: html.UnescapeString(strings.TrimSpace(text))})
+	}
+	return elements
+}
+
+type transaction struct {
+	posted   string
+	amount   string
+	fitID    string
+	name     string
+	memo     string
+	checkNum string
+}
+
+func (t *transaction) set(name, value string) {
+	switch name {
+	case "DTPOSTED":
+		t.posted = value
+	case "TRNAMT":
+		t.amount = value
+	case "FITID":
+		t.fitID = value
+	case "NAME":
+		t.name = value
+	case "MEMO":
+		t.memo = value
+	case "CHECKNUM":
+		t.checkNum = value
+	}
+}
+
+func (t *transaction) build(accountID string) (*repository.ParsedTransaction, error) {
+	if t.posted == "" {
+		return nil, ErrMissingPosting
+	}
+	if t.amount == "" {
+		return nil, ErrMissingAmount
+	}
+	date, err := ParseDate(t.posted)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseAmount(t.amount)
+	if err != nil {
+		return nil, err
+	}
+
+	description := t.name
+	if description == "" {
+		description = t.memo
+	}
+	if description == "" && t.checkNum != "" {
+		description = "Check " + t.checkNum
+	}
+	description = normalizer.CleanDescription(description)
+	if description == "" {
+		return nil, fmt.Errorf("empty description")
+	}
+
+	return &repository.ParsedTransaction{
+		Date:        date,
+		Description: description,
+		AmountCents: amount,
+		ExternalID:  externalID(accountID, t.fitID),
+		RowHash:     dedup.RowHash("ofx", accountID, t.posted, t.amount, t.name, t.memo, t.checkNum),
+	}, nil
+}
+
+// parseAmount converts TRNAMT to cents. The spec uses '.' as decimal separator,
+// but some banks emit ',' instead.
+func parseAmount(raw string) (int64, error) {
+	value := strings.ReplaceAll(strings.TrimSpace(raw), " ", "")
+	if !strings.Contains(value, ".") {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s': %w", raw, normalizer.ErrInvalidAmount)
+	}
+	return int64(math.Round(f * 100)), nil
+}
+
+// externalID scopes a FITID to its account: FITIDs are only unique per account.
+func externalID(accountID, fitID string) string {
+	if fitID == "" {
+		return ""
+	}
+	if accountID == "" {
+		return "ofx:" + fitID
+	}
+	return "ofx:" + accountID + ":" + fitID
+}
+
+// ParseDate parses an OFX datetime: YYYYMMDD[HHMMSS[.XXX]][[offset[:TZ]]].
+// Without an explicit offset the value is interpreted as UTC.
+func ParseDate(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+
+	loc := time.UTC
+	if idx := strings.IndexByte(raw, '['); idx >= 0 {
+		tz := strings.TrimSuffix(raw[idx+1:], "]")
+		raw = raw[:idx]
+		if name, _, found := strings.Cut(tz, ":"); found {
+			tz = name
+		}
+		hours, err := strconv.ParseFloat(tz, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDate, raw)
+		}
+		loc = time.FixedZone("", int(hours*3600))
+	}
+	if idx := strings.IndexByte(raw, '.'); idx >= 0 {
+		raw = raw[:idx]
+	}
+
+	var layout string
+	switch len(raw) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDate, raw)
+	}
+
+	t, err := time.ParseInLocation(layout, raw, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDate, raw)
+	}
+	return t, nil
+}
+
+// declaredCharset returns the encoding of a file that is not valid UTF-8, as
+// named by the OFX 1.x CHARSET header or the XML declaration. It falls back to
+// detection when the name is missing, unsupported or wrongly claims UTF-8. OFX
+// 1.x names Windows code pages by number (CHARSET:1252).
+func declaredCharset(data []byte) string {
+	head := strings.ToLower(string(data[:min(len(data), 1024)]))
+
+	var name string
+	if i := strings.Index(head, "charset:"); i >= 0 {
+		name, _, _ = strings.Cut(head[i+len("charset:"):], "\n")
+		name = strings.TrimSpace(name)
+		if _, err := strconv.Atoi(name); err == nil {
+			name = "windows-" + name
+		}
+	} else if i := strings.Index(head, `encoding="`); i >= 0 {
+		name, _, _ = strings.Cut(head[i+len(`encoding="`):], `"`)
+	}
+
+	if canonical, err := charset.Normalize(name); err == nil && canonical != charset.UTF8 {
+		return canonical
+	}
+	return charset.Detect(data)
+}
@@ -0,0 +1,182 @@
+package ofx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// OFX 1.x SGML export (unclosed leaf elements)
+const sampleSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+CHARSET:1252
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20240131</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><BANKID>121000248<ACCTID>987654321<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101<DTEND>20240131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240102120000[-5:EST]
+<TRNAMT>-45.23
+<FITID>2024010201
+<NAME>STARBUCKS &amp; CO
+<MEMO>Card purchase
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240105
+<TRNAMT>2500.00
+<FITID>2024010502
+<MEMO>Payroll
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>bad-date
+<TRNAMT>-1.00
+<FITID>2024010603
+<NAME>Broken
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+// OFX 2.x XML export (closed leaf elements)
+const sampleXML = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <CURDEF>EUR</CURDEF>
+    <CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20240210093000.000</DTPOSTED>
+        <TRNAMT>-12,99</TRNAMT>
+        <FITID>abc-1</FITID>
+        <NAME>Netflix</NAME>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestIsOFX(t *testing.T) {
+	if !IsOFX([]byte(sampleSGML)) {
+		t.Error("expected SGML OFX to be detected")
+	}
+	if !IsOFX([]byte(sampleXML)) {
+		t.Error("expected XML OFX to be detected")
+	}
+	if IsOFX([]byte("Date,Description,Amount\n01/02/2024,<OFX> test,1.00\n")) {
+		t.Error("expected CSV not to be detected as OFX")
+	}
+}
+
+func TestParse_SGML(t *testing.T) {
+	stmt, err := Parse([]byte(sampleSGML))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if stmt.CurrencyCode != "USD" {
+		t.Errorf("expected USD, got %q", stmt.CurrencyCode)
+	}
+	if stmt.AccountID != "987654321" {
+		t.Errorf("expected account 987654321, got %q", stmt.AccountID)
+	}
+	if len(stmt.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(stmt.Transactions))
+	}
+	if len(stmt.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", stmt.Errors)
+	}
+
+	first := stmt.Transactions[0]
+	if first.Description != "STARBUCKS & CO" {
+		t.Errorf("unexpected description %q", first.Description)
+	}
+	if first.AmountCents != -4523 {
+		t.Errorf("expected -4523, got %d", first.AmountCents)
+	}
+	if first.ExternalID != "ofx:987654321:2024010201" {
+		t.Errorf("unexpected external id %q", first.ExternalID)
+	}
+	if want := time.Date(2024, 1, 2, 17, 0, 0, 0, time.UTC); !first.Date.Equal(want) {
+		t.Errorf("expected %v, got %v", want, first.Date.UTC())
+	}
+
+	second := stmt.Transactions[1]
+	if second.Description != "Payroll" || second.AmountCents != 250000 {
+		t.Errorf("unexpected second transaction: %+v", second)
+	}
+}
+
+func TestParse_Windows1252(t *testing.T) {
+	// 0xC9 is É in both Latin-1 and windows-1252; 0x80 is only € in windows-1252.
+	data := strings.Replace(sampleSGML, "STARBUCKS &amp; CO", "CAF\xC9 \x80 STORE", 1)
+
+	stmt, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := stmt.Transactions[0].Description; got != "CAFÉ € STORE" {
+		t.Errorf("expected windows-1252 description, got %q", got)
+	}
+}
+
+func TestParse_XML(t *testing.T) {
+	stmt, err := Parse([]byte(sampleXML))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if stmt.CurrencyCode != "EUR" || stmt.AccountID != "4111" {
+		t.Errorf("unexpected statement header: %q %q", stmt.CurrencyCode, stmt.AccountID)
+	}
+	if len(stmt.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(stmt.Transactions))
+	}
+
+	tx := stmt.Transactions[0]
+	if tx.AmountCents != -1299 || tx.Description != "Netflix" || tx.ExternalID != "ofx:4111:abc-1" {
+		t.Errorf("unexpected transaction: %+v", tx)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected time.Time
+		wantErr  bool
+	}{
+		{raw: "20240102", expected: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{raw: "20240102153000", expected: time.Date(2024, 1, 2, 15, 30, 0, 0, time.UTC)},
+		{raw: "20240102153000.123[+1:CET]", expected: time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)},
+		{raw: "20240102[-3.5]", expected: time.Date(2024, 1, 2, 3, 30, 0, 0, time.UTC)},
+		{raw: "2024-01-02", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDate(tt.raw)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidDate) {
+				t.Errorf("ParseDate(%q) expected ErrInvalidDate, got %v", tt.raw, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDate(%q) failed: %v", tt.raw, err)
+			continue
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("ParseDate(%q) = %v, want %v", tt.raw, got.UTC(), tt.expected)
+		}
+	}
+}
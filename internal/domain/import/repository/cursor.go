@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned when a page cursor was not produced by ListTransactions.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor returns the opaque cursor of the page that follows the
+// transaction at (postedAt, id) in (posted_at DESC, id DESC) order.
+func encodeCursor(postedAt time.Time, id uuid.UUID) string {
+	raw := postedAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor reverses encodeCursor
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	postedAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, postedAt)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return t, parsedID, nil
+}
+
+// searchQuery turns free text into a prefix tsquery requiring every word
+// ("pingo doc" -> "pingo:* & doc:*"); "" when the text has no words.
+// Only letters and digits are kept so user input cannot break the query syntax.
+func searchQuery(search string) string {
+	words := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
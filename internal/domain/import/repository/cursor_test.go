@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	postedAt := time.Date(2025, 3, 14, 9, 26, 53, 589793000, time.FixedZone("WET", 0))
+	id := uuid.New()
+
+	gotAt, gotID, err := decodeCursor(encodeCursor(postedAt, id))
+	require.NoError(t, err)
+	assert.True(t, postedAt.Equal(gotAt))
+	assert.Equal(t, id, gotID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, cursor := range []string{"50", "not base64!", "bm8tc2VwYXJhdG9y", encodeCursor(time.Now(), uuid.Nil)[:10]} {
+		_, _, err := decodeCursor(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		search string
+		want   string
+	}{
+		{"Pingo Doce", "pingo:* & doce:*"},
+		{"  uber  ", "uber:*"},
+		{"café & 'x' | !", "café:* & x:*"},
+		{"12/03", "12:* & 03:*"},
+		{"&|!():*", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, searchQuery(tt.search), tt.search)
+	}
+}
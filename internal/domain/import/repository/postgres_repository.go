@@ -2,14 +2,15 @@ package repository
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/dedup"
 )
 
 // PostgresImportRepository implements ImportRepository using PostgreSQL
@@ -53,6 +54,34 @@ func (r *PostgresImportRepository) GetMappingByFingerprint(ctx context.Context,
 	return &mapping, nil
 }
 
+// GetMappingByID looks up a bank mapping by its ID. Ownership is checked by the caller.
+func (r *PostgresImportRepository) GetMappingByID(ctx context.Context, id uuid.UUID) (*BankMapping, error) {
+	query := `
+		SELECT id, user_id, fingerprint, bank_name, delimiter, skip_lines, date_format,
+		       date_col, desc_col, category_col, amount_col, debit_col, credit_col,
+		       is_european_format, created_at, updated_at
+		FROM bank_mappings
+		WHERE id = $1
+	`
+
+	var mapping BankMapping
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&mapping.ID, &mapping.UserID, &mapping.Fingerprint, &mapping.BankName,
+		&mapping.Delimiter, &mapping.SkipLines, &mapping.DateFormat,
+		&mapping.DateCol, &mapping.DescCol, &mapping.CategoryCol,
+		&mapping.AmountCol, &mapping.DebitCol, &mapping.CreditCol,
+		&mapping.IsEuropeanFormat, &mapping.CreatedAt, &mapping.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mapping by id: %w", err)
+	}
+
+	return &mapping, nil
+}
+
 // CreateMapping inserts a new bank mapping
 func (r *PostgresImportRepository) CreateMapping(ctx context.Context, mapping *BankMapping) error {
 	if mapping.ID == uuid.Nil {
@@ -204,21 +233,121 @@ func (r *PostgresImportRepository) GetUserFileByID(ctx context.Context, id uuid.
 	return &file, nil
 }
 
+// ListUserFiles lists a user's uploaded files, newest first, with the total count
+func (r *PostgresImportRepository) ListUserFiles(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*UserFile, int64, error) {
+	var totalCount int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM user_files WHERE user_id = $1`, userID).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count user files: %w", err)
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `
+		SELECT id, user_id, type, mime_type, file_name, size_bytes, checksum_sha256, storage_url, created_at
+		FROM user_files
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list user files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*UserFile
+	for rows.Next() {
+		var file UserFile
+		if err := rows.Scan(
+			&file.ID, &file.UserID, &file.Type, &file.MimeType, &file.FileName,
+			&file.SizeBytes, &file.ChecksumSHA256, &file.StorageURL, &file.CreatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user file: %w", err)
+		}
+		files = append(files, &file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list user files: %w", err)
+	}
+
+	return files, totalCount, nil
+}
+
+// FindUserFileByChecksum returns the user's most recent file with the given
+// SHA-256 checksum, or nil if there is none
+func (r *PostgresImportRepository) FindUserFileByChecksum(ctx context.Context, userID uuid.UUID, checksum string) (*UserFile, error) {
+	query := `
+		SELECT id, user_id, type, mime_type, file_name, size_bytes, checksum_sha256, storage_url, created_at
+		FROM user_files
+		WHERE user_id = $1 AND checksum_sha256 = lower($2)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var file UserFile
+	err := r.pool.QueryRow(ctx, query, userID, checksum).Scan(
+		&file.ID, &file.UserID, &file.Type, &file.MimeType, &file.FileName,
+		&file.SizeBytes, &file.ChecksumSHA256, &file.StorageURL, &file.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user file by checksum: %w", err)
+	}
+
+	return &file, nil
+}
+
+// importJobColumns is the column list scanned by scanImportJob.
+const importJobColumns = `
+	id, user_id, file_id, kind, status, account_id, timezone, date_format,
+	institution_name, options, error_message, rows_total, rows_imported, rows_failed,
+	requested_at, started_at, finished_at, heartbeat_at, rolled_back_at, purged_at`
+
+func scanImportJob(row pgx.Row) (*ImportJob, error) {
+	var job ImportJob
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.FileID, &job.Kind, &job.Status,
+		&job.AccountID, &job.Timezone, &job.DateFormat,
+		&job.InstitutionName, &job.Options, &job.ErrorMessage,
+		&job.RowsTotal, &job.RowsImported, &job.RowsFailed,
+		&job.RequestedAt, &job.StartedAt, &job.FinishedAt, &job.HeartbeatAt,
+		&job.RolledBackAt, &job.PurgedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
 // CreateImportJob creates a new import job
 func (r *PostgresImportRepository) CreateImportJob(ctx context.Context, job *ImportJob) error {
 	if job.ID == uuid.Nil {
 		job.ID = uuid.New()
 	}
+	if len(job.Options) == 0 {
+		job.Options = json.RawMessage(`{}`)
+	}
 
 	query := `
-		INSERT INTO import_jobs (id, user_id, file_id, kind, status, account_id, timezone, date_format, rows_total)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO import_jobs (id, user_id, file_id, kind, status, account_id, timezone, date_format,
+		                         institution_name, options, rows_total)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING requested_at
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	err := r.pool.QueryRow(ctx, query,
 		job.ID, job.UserID, job.FileID, job.Kind, job.Status,
-		job.AccountID, job.Timezone, job.DateFormat, job.RowsTotal,
-	)
+		job.AccountID, job.Timezone, job.DateFormat,
+		job.InstitutionName, job.Options, job.RowsTotal,
+	).Scan(&job.RequestedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create import job: %w", err)
 	}
@@ -228,33 +357,152 @@ func (r *PostgresImportRepository) CreateImportJob(ctx context.Context, job *Imp
 
 // GetImportJobByID retrieves an import job by ID
 func (r *PostgresImportRepository) GetImportJobByID(ctx context.Context, id uuid.UUID) (*ImportJob, error) {
+	query := `SELECT ` + importJobColumns + ` FROM import_jobs WHERE id = $1`
+
+	job, err := scanImportJob(r.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ListImportJobs lists a user's import jobs, newest first
+func (r *PostgresImportRepository) ListImportJobs(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*ImportJob, int64, error) {
+	var totalCount int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM import_jobs WHERE user_id = $1`, userID).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count import jobs: %w", err)
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
 	query := `
-		SELECT id, user_id, file_id, kind, status, account_id, timezone, date_format,
-		       error_message, rows_total, rows_imported, rows_failed,
-		       requested_at, started_at, finished_at
-		FROM import_jobs WHERE id = $1
+		SELECT ` + importJobColumns + `
+		FROM import_jobs
+		WHERE user_id = $1
+		ORDER BY requested_at DESC
+		LIMIT $2 OFFSET $3
 	`
 
-	var job ImportJob
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&job.ID, &job.UserID, &job.FileID, &job.Kind, &job.Status,
-		&job.AccountID, &job.Timezone, &job.DateFormat,
-		&job.ErrorMessage, &job.RowsTotal, &job.RowsImported, &job.RowsFailed,
-		&job.RequestedAt, &job.StartedAt, &job.FinishedAt,
-	)
+	rows, err := r.pool.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list import jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*ImportJob
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan import job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list import jobs: %w", err)
+	}
+
+	return jobs, totalCount, nil
+}
+
+// CancelImportJob marks a pending or running job as canceled. It reports false
+// when the job does not belong to the user or has already finished.
+func (r *PostgresImportRepository) CancelImportJob(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE import_jobs SET status = 'canceled', finished_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status IN ('pending', 'running')
+	`
+	result, err := r.pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel import job: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ClaimPendingImportJob atomically moves the oldest pending job to running.
+// Concurrent workers skip rows locked by each other. Returns nil when the queue is empty.
+func (r *PostgresImportRepository) ClaimPendingImportJob(ctx context.Context) (*ImportJob, error) {
+	query := `
+		UPDATE import_jobs SET status = 'running', started_at = NOW(), heartbeat_at = NOW()
+		WHERE id = (
+			SELECT id FROM import_jobs
+			WHERE status = 'pending'
+			ORDER BY requested_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING ` + importJobColumns
+
+	job, err := scanImportJob(r.pool.QueryRow(ctx, query))
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get import job: %w", err)
+		return nil, fmt.Errorf("failed to claim import job: %w", err)
 	}
 
-	return &job, nil
+	return job, nil
+}
+
+// HeartbeatImportJob records that the worker running a job is still alive
+func (r *PostgresImportRepository) HeartbeatImportJob(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE import_jobs SET heartbeat_at = NOW() WHERE id = $1 AND status = 'running'`
+	_, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to heartbeat import job: %w", err)
+	}
+	return nil
+}
+
+// RequeueImportJob returns a running job to the queue, e.g. on worker shutdown
+func (r *PostgresImportRepository) RequeueImportJob(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE import_jobs SET status = 'pending', started_at = NULL, heartbeat_at = NULL
+		WHERE id = $1 AND status = 'running'
+	`
+	_, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to requeue import job: %w", err)
+	}
+	return nil
+}
+
+// RequeueStaleImportJobs returns running jobs whose worker stopped reporting
+// before staleBefore to the queue. It returns the number of jobs requeued.
+func (r *PostgresImportRepository) RequeueStaleImportJobs(ctx context.Context, staleBefore time.Time) (int, error) {
+	query := `
+		UPDATE import_jobs SET status = 'pending', started_at = NULL, heartbeat_at = NULL
+		WHERE status = 'running'
+		  AND COALESCE(heartbeat_at, started_at, requested_at) < $1
+	`
+	result, err := r.pool.Exec(ctx, query, staleBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale import jobs: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// CountTransactionsByImportJob counts the transactions already stored by an import job
+func (r *PostgresImportRepository) CountTransactionsByImportJob(ctx context.Context, importJobID uuid.UUID) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM transactions WHERE import_job_id = $1`, importJobID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count transactions by import job: %w", err)
+	}
+	return count, nil
 }
 
 // UpdateImportJobProgress updates the row counts for an import job
 func (r *PostgresImportRepository) UpdateImportJobProgress(ctx context.Context, id uuid.UUID, rowsImported, rowsFailed int) error {
-	query := `UPDATE import_jobs SET rows_imported = $2, rows_failed = $3 WHERE id = $1`
+	query := `UPDATE import_jobs SET rows_imported = $2, rows_failed = $3, heartbeat_at = NOW() WHERE id = $1`
 	_, err := r.pool.Exec(ctx, query, id, rowsImported, rowsFailed)
 	if err != nil {
 		return fmt.Errorf("failed to update import job progress: %w", err)
@@ -272,13 +520,13 @@ func (r *PostgresImportRepository) UpdateImportJobStatus(ctx context.Context, id
 	return nil
 }
 
-// FinishImportJob marks an import job as complete
+// FinishImportJob marks an import job as complete. Canceled jobs keep their status.
 func (r *PostgresImportRepository) FinishImportJob(ctx context.Context, id uuid.UUID, status string, rowsImported, rowsFailed int, errorMessage *string) error {
 	query := `
 		UPDATE import_jobs SET
 			status = $2, rows_imported = $3, rows_failed = $4,
 			error_message = $5, finished_at = NOW(), rows_total = $3::int + $4::int
-		WHERE id = $1
+		WHERE id = $1 AND status <> 'canceled'
 	`
 	_, err := r.pool.Exec(ctx, query, id, status, rowsImported, rowsFailed, errorMessage)
 	if err != nil {
@@ -311,22 +559,23 @@ func (r *PostgresImportRepository) BulkInsertTransactions(ctx context.Context, u
 		}
 		batch := txs[i:end]
 
-		// Build batch insert query (14 columns now including merchant_name and category_id)
+		// Build batch insert query (17 columns now including merchant_name, category_id, its confidence and review state)
 		query := `
-			INSERT INTO transactions (id, user_id, account_id, posted_at, description, original_description, merchant_name, amount_minor, currency_code, source, external_id, import_job_id, institution_name, category_id)
+			INSERT INTO transactions (id, user_id, account_id, posted_at, description, original_description, merchant_name, amount_minor, currency_code, source, external_id, import_job_id, institution_name, category_id, category_confidence, review_status, review_reasons)
 			VALUES `
 
-		args := make([]any, 0, len(batch)*14)
+		args := make([]any, 0, len(batch)*17)
 		for j, tx := range batch {
 			if j > 0 {
 				query += ", "
 			}
-			externalID := generateExternalID(tx)
-			argOffset := j * 14
-			query += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			externalID := GenerateExternalID(tx)
+			argOffset := j * 17
+			query += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
 				argOffset+1, argOffset+2, argOffset+3, argOffset+4, argOffset+5,
 				argOffset+6, argOffset+7, argOffset+8, argOffset+9, argOffset+10,
-				argOffset+11, argOffset+12, argOffset+13, argOffset+14)
+				argOffset+11, argOffset+12, argOffset+13, argOffset+14, argOffset+15,
+				argOffset+16, argOffset+17)
 
 			// Use MerchantName if set, otherwise fall back to Description
 			merchantName := tx.MerchantName
@@ -334,25 +583,43 @@ func (r *PostgresImportRepository) BulkInsertTransactions(ctx context.Context, u
 				merchantName = tx.Description
 			}
 
+			// Rows of multi-currency exports keep their own currency
+			rowCurrency := currencyCode
+			if tx.CurrencyCode != "" {
+				rowCurrency = tx.CurrencyCode
+			}
+
+			// Rows with a reason to doubt them wait in the review queue
+			var reviewStatus *string
+			reviewReasons := []string{}
+			if len(tx.ReviewReasons) > 0 {
+				pending := "pending"
+				reviewStatus = &pending
+				reviewReasons = tx.ReviewReasons
+			}
+
 			args = append(args,
-				uuid.New(),     // id
-				userID,         // user_id
-				accountID,      // account_id
-				tx.Date,        // posted_at
-				tx.Description, // description (raw)
-				tx.Description, // original_description
-				merchantName,   // merchant_name (cleaned)
-				tx.AmountCents, // amount_minor
-				currencyCode,   // currency_code
-				"csv",          // source
-				externalID,     // external_id
-				importJobID,    // import_job_id
-				instNamePtr,    // institution_name
-				tx.CategoryID,  // category_id
+				uuid.New(),            // id
+				userID,                // user_id
+				accountID,             // account_id
+				tx.Date,               // posted_at
+				tx.Description,        // description (raw)
+				tx.Description,        // original_description
+				merchantName,          // merchant_name (cleaned)
+				tx.AmountCents,        // amount_minor
+				rowCurrency,           // currency_code
+				SourceImport,          // source
+				externalID,            // external_id
+				importJobID,           // import_job_id
+				instNamePtr,           // institution_name
+				tx.CategoryID,         // category_id
+				tx.CategoryConfidence, // category_confidence
+				reviewStatus,          // review_status
+				reviewReasons,         // review_reasons
 			)
 		}
 
-		query += " ON CONFLICT (user_id, source, external_id) WHERE external_id IS NOT NULL DO NOTHING"
+		query += " ON CONFLICT (user_id, source, external_id) WHERE external_id IS NOT NULL AND deleted_at IS NULL DO NOTHING"
 
 		result, err := r.pool.Exec(ctx, query, args...)
 		if err != nil {
@@ -364,90 +631,481 @@ func (r *PostgresImportRepository) BulkInsertTransactions(ctx context.Context, u
 	return totalInserted, nil
 }
 
-// generateExternalID creates a unique identifier for deduplication
-func generateExternalID(tx *ParsedTransaction) string {
-	data := fmt.Sprintf("%s|%s|%d", tx.Date.Format(time.RFC3339), tx.Description, tx.AmountCents)
-	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:16]) // First 16 bytes for reasonable length
+// FindExistingExternalIDs returns which of the given external IDs the user already has
+// among transactions from source. For SourceImport these are the rows
+// BulkInsertTransactions would skip.
+func (r *PostgresImportRepository) FindExistingExternalIDs(ctx context.Context, userID uuid.UUID, source string, externalIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(externalIDs) == 0 {
+		return existing, nil
+	}
+
+	query := `
+		SELECT external_id FROM transactions
+		WHERE user_id = $1 AND source = $2 AND external_id = ANY($3) AND deleted_at IS NULL
+	`
+	rows, err := r.pool.Query(ctx, query, userID, source, externalIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find existing external ids: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var externalID string
+		if err := rows.Scan(&externalID); err != nil {
+			return nil, fmt.Errorf("failed to scan external id: %w", err)
+		}
+		existing[externalID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find existing external ids: %w", err)
+	}
+
+	return existing, nil
 }
 
-// ListTransactions retrieves transactions with filters and pagination
-func (r *PostgresImportRepository) ListTransactions(ctx context.Context, userID uuid.UUID, filter ListTransactionsFilter) ([]*Transaction, int64, error) {
-	// Build dynamic WHERE clauses
-	args := []any{userID}
-	argIdx := 2
-	whereClauses := []string{"t.user_id = $1"}
+// GetTransactionDateStats counts the user's transactions posted between from and
+// to (inclusive), optionally restricted to one account, with the earliest and
+// latest posting dates found
+func (r *PostgresImportRepository) GetTransactionDateStats(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, from, to time.Time) (*DateRangeStats, error) {
+	query := `
+		SELECT COUNT(*), MIN(posted_at), MAX(posted_at)
+		FROM transactions
+		WHERE user_id = $1 AND deleted_at IS NULL
+		  AND ($2::uuid IS NULL OR account_id = $2)
+		  AND posted_at BETWEEN $3 AND $4
+	`
 
-	if filter.AccountID != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("t.account_id = $%d", argIdx))
-		args = append(args, *filter.AccountID)
-		argIdx++
+	var stats DateRangeStats
+	if err := r.pool.QueryRow(ctx, query, userID, accountID, from, to).Scan(&stats.Count, &stats.First, &stats.Last); err != nil {
+		return nil, fmt.Errorf("failed to get transaction date stats: %w", err)
 	}
 
-	if filter.CategoryID != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("t.category_id = $%d", argIdx))
-		args = append(args, *filter.CategoryID)
-		argIdx++
+	return &stats, nil
+}
+
+// FindDuplicateCandidates pairs each transaction stored by an import job with
+// transactions from other sources that have the same amount and currency, a
+// posting date at most maxDays away and a compatible account. Pairs that were
+// already linked (pending or dismissed) are skipped.
+func (r *PostgresImportRepository) FindDuplicateCandidates(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID, maxDays int) ([]*DuplicateCandidate, error) {
+	query := `
+		SELECT t.id, t.posted_at, t.description, c.id, c.posted_at, c.description, c.source::text, t.amount_minor
+		FROM transactions t
+		JOIN transactions c
+		  ON c.user_id = t.user_id
+		 AND c.source <> t.source
+		 AND c.amount_minor = t.amount_minor
+		 AND c.currency_code = t.currency_code
+		 AND c.posted_at BETWEEN t.posted_at - make_interval(days => $3) AND t.posted_at + make_interval(days => $3)
+		 AND (t.account_id IS NULL OR c.account_id IS NULL OR c.account_id = t.account_id)
+		 AND c.deleted_at IS NULL
+		WHERE t.user_id = $1 AND t.import_job_id = $2 AND t.deleted_at IS NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM transaction_duplicate_links l
+			WHERE l.transaction_id = t.id AND l.duplicate_of_id = c.id
+		  )
+		ORDER BY t.posted_at, t.id
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, importJobID, maxDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate candidates: %w", err)
 	}
+	defer rows.Close()
 
-	if filter.ImportJobID != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("t.import_job_id = $%d", argIdx))
-		args = append(args, *filter.ImportJobID)
-		argIdx++
+	var candidates []*DuplicateCandidate
+	for rows.Next() {
+		var c DuplicateCandidate
+		if err := rows.Scan(
+			&c.TransactionID, &c.TransactionDate, &c.TransactionDesc,
+			&c.CandidateID, &c.CandidateDate, &c.CandidateDesc, &c.CandidateSource, &c.AmountMinor,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate candidate: %w", err)
+		}
+		candidates = append(candidates, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find duplicate candidates: %w", err)
 	}
 
-	if filter.StartDate != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("t.posted_at >= $%d", argIdx))
-		args = append(args, *filter.StartDate)
-		argIdx++
+	return candidates, nil
+}
+
+// CreateDuplicateLinks stores pending duplicate links, ignoring pairs that are
+// already linked. It returns how many links were created.
+func (r *PostgresImportRepository) CreateDuplicateLinks(ctx context.Context, links []*DuplicateLink) (int, error) {
+	if len(links) == 0 {
+		return 0, nil
 	}
 
-	if filter.EndDate != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("t.posted_at <= $%d", argIdx))
-		args = append(args, *filter.EndDate)
-		argIdx++
+	ids := make([]uuid.UUID, len(links))
+	userIDs := make([]uuid.UUID, len(links))
+	txIDs := make([]uuid.UUID, len(links))
+	dupIDs := make([]uuid.UUID, len(links))
+	scores := make([]float64, len(links))
+	for i, link := range links {
+		if link.ID == uuid.Nil {
+			link.ID = uuid.New()
+		}
+		ids[i], userIDs[i], txIDs[i], dupIDs[i], scores[i] = link.ID, link.UserID, link.TransactionID, link.DuplicateOfID, link.Score
 	}
 
-	if filter.Search != "" {
-		whereClauses = append(whereClauses, fmt.Sprintf("t.description ILIKE $%d", argIdx))
-		args = append(args, "%"+filter.Search+"%")
-		argIdx++
+	query := `
+		INSERT INTO transaction_duplicate_links (id, user_id, transaction_id, duplicate_of_id, score)
+		SELECT * FROM unnest($1::uuid[], $2::uuid[], $3::uuid[], $4::uuid[], $5::numeric[])
+		ON CONFLICT (transaction_id, duplicate_of_id) DO NOTHING
+	`
+	result, err := r.pool.Exec(ctx, query, ids, userIDs, txIDs, dupIDs, scores)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create duplicate links: %w", err)
 	}
 
-	whereSQL := "WHERE " + fmt.Sprintf("%s", joinStrings(whereClauses, " AND "))
+	return int(result.RowsAffected()), nil
+}
 
-	// Get total count
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM transactions t %s`, whereSQL)
+// liveDuplicateLink keeps duplicate links whose transactions were not soft
+// deleted with a rolled back import batch
+const liveDuplicateLink = `NOT EXISTS (
+			SELECT 1 FROM transactions d
+			WHERE d.id IN (transaction_id, duplicate_of_id) AND d.deleted_at IS NOT NULL
+		)`
+
+// ListDuplicateLinks lists a user's duplicate links with the given status
+// (all statuses when empty), newest first, with the total count
+func (r *PostgresImportRepository) ListDuplicateLinks(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]*DuplicateLink, int64, error) {
 	var totalCount int64
-	if err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&totalCount); err != nil {
-		return nil, 0, fmt.Errorf("failed to count transactions: %w", err)
+	countQuery := `SELECT COUNT(*) FROM transaction_duplicate_links WHERE user_id = $1 AND ($2 = '' OR status = $2) AND ` + liveDuplicateLink
+	if err := r.pool.QueryRow(ctx, countQuery, userID, status).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count duplicate links: %w", err)
 	}
 
-	// Apply pagination
-	limit := filter.Limit
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
-	offset := filter.Offset
 	if offset < 0 {
 		offset = 0
 	}
 
+	query := `
+		SELECT id, user_id, transaction_id, duplicate_of_id, score, status, created_at, resolved_at
+		FROM transaction_duplicate_links
+		WHERE user_id = $1 AND ($2 = '' OR status = $2) AND ` + liveDuplicateLink + `
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list duplicate links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []*DuplicateLink
+	for rows.Next() {
+		var link DuplicateLink
+		if err := rows.Scan(
+			&link.ID, &link.UserID, &link.TransactionID, &link.DuplicateOfID,
+			&link.Score, &link.Status, &link.CreatedAt, &link.ResolvedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan duplicate link: %w", err)
+		}
+		links = append(links, &link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list duplicate links: %w", err)
+	}
+
+	return links, totalCount, nil
+}
+
+// DismissDuplicateLink marks a pending link as not a duplicate so the matcher
+// does not propose it again. It reports false when no pending link matched.
+func (r *PostgresImportRepository) DismissDuplicateLink(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE transaction_duplicate_links SET status = 'dismissed', resolved_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'pending'
+	`
+	result, err := r.pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to dismiss duplicate link: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ConfirmDuplicateLink deletes the imported transaction of a pending link; the
+// link itself goes with it. It reports false when no pending link matched.
+func (r *PostgresImportRepository) ConfirmDuplicateLink(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error) {
+	query := `
+		DELETE FROM transactions t
+		USING transaction_duplicate_links l
+		WHERE l.id = $1 AND l.user_id = $2 AND l.status = 'pending'
+		  AND t.id = l.transaction_id AND t.user_id = l.user_id AND t.deleted_at IS NULL
+	`
+	result, err := r.pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm duplicate link: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// documentColumns is the column list scanned by scanDocument.
+const documentColumns = `
+	id, user_id, file_id, merchant_name, issued_at, total_amount_minor, currency_code, extracted_json,
+	transaction_id, match_source, match_score::float8, matched_at, created_at, updated_at`
+
+func scanDocument(row pgx.Row) (*Document, error) {
+	var doc Document
+	err := row.Scan(
+		&doc.ID, &doc.UserID, &doc.FileID, &doc.MerchantName, &doc.IssuedAt,
+		&doc.TotalAmountMinor, &doc.CurrencyCode, &doc.ExtractedJSON,
+		&doc.TransactionID, &doc.MatchSource, &doc.MatchScore, &doc.MatchedAt,
+		&doc.CreatedAt, &doc.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// CreateDocument records a receipt or invoice read from an uploaded file
+func (r *PostgresImportRepository) CreateDocument(ctx context.Context, doc *Document) error {
+	if doc.ID == uuid.Nil {
+		doc.ID = uuid.New()
+	}
+	if len(doc.ExtractedJSON) == 0 {
+		doc.ExtractedJSON = json.RawMessage(`{}`)
+	}
+
+	query := `
+		INSERT INTO documents (id, user_id, file_id, merchant_name, issued_at, total_amount_minor, currency_code, extracted_json)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		doc.ID, doc.UserID, doc.FileID, doc.MerchantName, doc.IssuedAt,
+		doc.TotalAmountMinor, doc.CurrencyCode, doc.ExtractedJSON,
+	).Scan(&doc.CreatedAt, &doc.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create document: %w", err)
+	}
+
+	return nil
+}
+
+// GetDocumentByID retrieves a document by ID
+func (r *PostgresImportRepository) GetDocumentByID(ctx context.Context, id uuid.UUID) (*Document, error) {
+	query := `SELECT ` + documentColumns + ` FROM documents WHERE id = $1`
+
+	doc, err := scanDocument(r.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// ListDocuments lists a user's documents, newest first, with the total count.
+// transactionID restricts the list to one transaction's attachments; status is
+// "attached", "unattached" or empty for all.
+func (r *PostgresImportRepository) ListDocuments(ctx context.Context, userID uuid.UUID, transactionID *uuid.UUID, status string, limit, offset int) ([]*Document, int64, error) {
+	where := `
+		WHERE user_id = $1
+		  AND ($2::uuid IS NULL OR transaction_id = $2)
+		  AND ($3 = '' OR ($3 = 'attached') = (transaction_id IS NOT NULL))
+	`
+
+	var totalCount int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM documents`+where, userID, transactionID, status).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `SELECT ` + documentColumns + ` FROM documents` + where + `
+		ORDER BY COALESCE(issued_at, created_at) DESC, id
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, transactionID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*Document
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	return docs, totalCount, nil
+}
+
+// AttachDocument links a user's document to one of their transactions that was
+// not soft deleted, replacing any earlier attachment. It reports false when the
+// document or the transaction does not exist.
+func (r *PostgresImportRepository) AttachDocument(ctx context.Context, userID uuid.UUID, documentID uuid.UUID, transactionID uuid.UUID, source string, score *float64) (bool, error) {
+	query := `
+		UPDATE documents d
+		SET transaction_id = t.id, match_source = $4, match_score = $5, matched_at = NOW()
+		FROM transactions t
+		WHERE d.id = $2 AND d.user_id = $1
+		  AND t.id = $3 AND t.user_id = $1 AND t.deleted_at IS NULL
+	`
+	result, err := r.pool.Exec(ctx, query, userID, documentID, transactionID, source, score)
+	if err != nil {
+		return false, fmt.Errorf("failed to attach document: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// DetachDocument unlinks a document from its transaction. The document keeps
+// match_source "manual" so the reconcile pass leaves it alone. It reports false
+// when the document does not exist.
+func (r *PostgresImportRepository) DetachDocument(ctx context.Context, userID uuid.UUID, documentID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE documents
+		SET transaction_id = NULL, match_source = 'manual', match_score = NULL, matched_at = NULL
+		WHERE id = $1 AND user_id = $2
+	`
+	result, err := r.pool.Exec(ctx, query, documentID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to detach document: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// FindDocumentCandidates pairs the user's unattached documents that have a
+// total, currency and date with live transactions of the same absolute amount
+// and currency, posted at most maxDays from the document date, that have no
+// document yet. Documents the user detached by hand are skipped.
+func (r *PostgresImportRepository) FindDocumentCandidates(ctx context.Context, userID uuid.UUID, maxDays int) ([]*DocumentCandidate, error) {
+	query := `
+		SELECT d.id, d.issued_at, COALESCE(d.merchant_name, ''),
+		       t.id, t.posted_at, COALESCE(t.merchant_name, t.description), d.total_amount_minor
+		FROM documents d
+		JOIN transactions t
+		  ON t.user_id = d.user_id
+		 AND ABS(t.amount_minor) = d.total_amount_minor
+		 AND t.currency_code = d.currency_code
+		 AND t.posted_at BETWEEN d.issued_at - make_interval(days => $2) AND d.issued_at + make_interval(days => $2)
+		 AND t.deleted_at IS NULL
+		WHERE d.user_id = $1 AND d.transaction_id IS NULL AND d.match_source IS NULL
+		  AND d.total_amount_minor IS NOT NULL AND d.currency_code IS NOT NULL AND d.issued_at IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM documents o WHERE o.transaction_id = t.id)
+		ORDER BY d.issued_at, d.id
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, maxDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find document candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []*DocumentCandidate
+	for rows.Next() {
+		var c DocumentCandidate
+		if err := rows.Scan(
+			&c.DocumentID, &c.DocumentDate, &c.DocumentMerchant,
+			&c.TransactionID, &c.TransactionDate, &c.TransactionDesc, &c.AmountMinor,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan document candidate: %w", err)
+		}
+		candidates = append(candidates, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find document candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// QueueForReview adds reason to the review reasons of the user's transactions
+// and marks them pending review, whatever their earlier review status
+func (r *PostgresImportRepository) QueueForReview(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, reason string) error {
+	if len(transactionIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE transactions
+		SET review_status = 'pending',
+		    review_reasons = CASE WHEN $3 = ANY(review_reasons) THEN review_reasons
+		                          ELSE array_append(review_reasons, $3) END,
+		    reviewed_at = NULL
+		WHERE user_id = $1 AND id = ANY($2) AND deleted_at IS NULL
+	`
+	if _, err := r.pool.Exec(ctx, query, userID, transactionIDs, reason); err != nil {
+		return fmt.Errorf("failed to queue transactions for review: %w", err)
+	}
+	return nil
+}
+
+// GenerateExternalID returns the external_id used to deduplicate an imported transaction.
+// Bank-provided IDs (OFX FITID, CAMT AcctSvcrRef) win, then the raw row hash with
+// its occurrence index. Rows without a row hash fall back to the legacy
+// date|description|amount hash.
+func GenerateExternalID(tx *ParsedTransaction) string {
+	if tx.ExternalID != "" {
+		return tx.ExternalID
+	}
+	if tx.RowHash != "" {
+		return dedup.ExternalID(tx.RowHash, tx.Occurrence)
+	}
+	return dedup.LegacyExternalID(tx.Date, tx.Description, tx.AmountCents)
+}
+
+// ListTransactions retrieves transactions with filters and keyset pagination on
+// (posted_at, id), so pages stay stable while imports insert new rows.
+func (r *PostgresImportRepository) ListTransactions(ctx context.Context, userID uuid.UUID, filter ListTransactionsFilter) ([]*Transaction, string, error) {
+	whereSQL, args := TransactionFilterSQL(userID, filter)
+
+	if filter.Cursor != "" {
+		postedAt, id, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, postedAt, id)
+		whereSQL += fmt.Sprintf(" AND (t.posted_at, t.id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	// Fetch one extra row to know whether another page follows
 	query := fmt.Sprintf(`
 		SELECT t.id, t.user_id, t.account_id, t.category_id, c.name as category_name,
 		       t.posted_at, t.description, t.merchant_name, t.original_description,
 		       t.amount_minor, t.currency_code, t.source,
-		       t.external_id, t.notes, t.institution_name, t.created_at, t.updated_at
+		       t.external_id, t.notes, t.institution_name, t.intent, t.review_status, t.created_at, t.updated_at
 		FROM transactions t
 		LEFT JOIN categories c ON t.category_id = c.id
 		%s
-		ORDER BY t.posted_at DESC
-		LIMIT %d OFFSET %d
-	`, whereSQL, limit, offset)
+		ORDER BY t.posted_at DESC, t.id DESC
+		LIMIT %d
+	`, whereSQL, limit+1)
 
 	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to list transactions: %w", err)
+		return nil, "", fmt.Errorf("failed to list transactions: %w", err)
 	}
 	defer rows.Close()
 
@@ -458,14 +1116,131 @@ func (r *PostgresImportRepository) ListTransactions(ctx context.Context, userID
 			&tx.ID, &tx.UserID, &tx.AccountID, &tx.CategoryID, &tx.CategoryName,
 			&tx.Date, &tx.Description, &tx.MerchantName, &tx.OriginalDescription,
 			&tx.AmountCents, &tx.CurrencyCode, &tx.Source,
-			&tx.ExternalID, &tx.Notes, &tx.InstitutionName, &tx.CreatedAt, &tx.UpdatedAt,
+			&tx.ExternalID, &tx.Notes, &tx.InstitutionName, &tx.Intent, &tx.ReviewStatus, &tx.CreatedAt, &tx.UpdatedAt,
 		); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan transaction: %w", err)
+			return nil, "", fmt.Errorf("failed to scan transaction: %w", err)
 		}
 		transactions = append(transactions, &tx)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	var nextCursor string
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		last := transactions[limit-1]
+		nextCursor = encodeCursor(last.Date, last.ID)
+	}
+
+	return transactions, nextCursor, nil
+}
+
+// TransactionFilterSQL builds the WHERE clause and arguments selecting a user's
+// transactions (aliased t) that match the filter. Limit and Cursor are ignored.
+func TransactionFilterSQL(userID uuid.UUID, filter ListTransactionsFilter) (string, []any) {
+	args := []any{userID}
+	argIdx := 2
+	whereClauses := []string{"t.user_id = $1", "t.deleted_at IS NULL"}
+
+	if filter.AccountID != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("t.account_id = $%d", argIdx))
+		args = append(args, *filter.AccountID)
+		argIdx++
+	}
+
+	if len(filter.CategoryIDs) > 0 {
+		// Split transactions match any of their split categories
+		whereClauses = append(whereClauses, fmt.Sprintf(
+			"(t.category_id = ANY($%[1]d) OR EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id AND s.category_id = ANY($%[1]d)))",
+			argIdx))
+		args = append(args, filter.CategoryIDs)
+		argIdx++
+	}
+
+	if filter.UncategorizedOnly {
+		whereClauses = append(whereClauses,
+			"t.category_id IS NULL AND NOT EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)")
+	}
+
+	if len(filter.TagIDs) > 0 {
+		whereClauses = append(whereClauses, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM transaction_tags tt WHERE tt.transaction_id = t.id AND tt.tag_id = ANY($%d))", argIdx))
+		args = append(args, filter.TagIDs)
+		argIdx++
+	}
+
+	if filter.ImportJobID != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("t.import_job_id = $%d", argIdx))
+		args = append(args, *filter.ImportJobID)
+		argIdx++
+	}
+
+	if filter.StartDate != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("t.posted_at >= $%d", argIdx))
+		args = append(args, *filter.StartDate)
+		argIdx++
+	}
+
+	if filter.EndDate != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("t.posted_at <= $%d", argIdx))
+		args = append(args, *filter.EndDate)
+		argIdx++
+	}
+
+	if filter.MinAmount != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("abs(t.amount_minor) >= $%d", argIdx))
+		args = append(args, *filter.MinAmount)
+		argIdx++
+	}
+
+	if filter.MaxAmount != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("abs(t.amount_minor) <= $%d", argIdx))
+		args = append(args, *filter.MaxAmount)
+		argIdx++
+	}
+
+	switch filter.Direction {
+	case "income":
+		whereClauses = append(whereClauses, "t.amount_minor > 0")
+	case "expense":
+		whereClauses = append(whereClauses, "t.amount_minor < 0")
+	}
+
+	if filter.Merchant != "" {
+		whereClauses = append(whereClauses, fmt.Sprintf("lower(t.merchant_name) = lower($%d)", argIdx))
+		args = append(args, filter.Merchant)
+		argIdx++
+	}
+
+	if filter.Source != "" {
+		whereClauses = append(whereClauses, fmt.Sprintf("t.source::text = $%d", argIdx))
+		args = append(args, filter.Source)
+		argIdx++
+	}
+
+	if filter.InstitutionName != "" {
+		whereClauses = append(whereClauses, fmt.Sprintf("t.institution_name = $%d", argIdx))
+		args = append(args, filter.InstitutionName)
+		argIdx++
+	}
+
+	if filter.Intent == "none" {
+		whereClauses = append(whereClauses, "t.intent IS NULL")
+	} else if filter.Intent != "" {
+		whereClauses = append(whereClauses, fmt.Sprintf("t.intent = $%d", argIdx))
+		args = append(args, filter.Intent)
+		argIdx++
+	}
+
+	if query := searchQuery(filter.Search); query != "" {
+		whereClauses = append(whereClauses, fmt.Sprintf("t.search_vector @@ to_tsquery('simple', $%d)", argIdx))
+		args = append(args, query)
+		argIdx++
+	}
 
-	return transactions, totalCount, nil
+	whereSQL := "WHERE " + fmt.Sprintf("%s", joinStrings(whereClauses, " AND "))
+	return whereSQL, args
 }
 
 // joinStrings joins strings with a separator (helper function)
@@ -480,12 +1255,145 @@ func joinStrings(strs []string, sep string) string {
 	return result
 }
 
-// DeleteByImportJobID deletes all transactions from a specific import batch
-func (r *PostgresImportRepository) DeleteByImportJobID(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, error) {
-	query := `DELETE FROM transactions WHERE user_id = $1 AND import_job_id = $2`
-	result, err := r.pool.Exec(ctx, query, userID, importJobID)
+// RollbackImportJob marks a finished import job rolled back and soft deletes
+// its transactions in one database transaction. It reports false when the job
+// does not exist, belongs to another user or is not finished.
+func (r *PostgresImportRepository) RollbackImportJob(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, bool, error) {
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to delete transactions by import job: %w", err)
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	return int(result.RowsAffected()), nil
+	defer tx.Rollback(ctx)
+
+	jobQuery := `
+		UPDATE import_jobs
+		SET status_before_rollback = status, status = 'rolled_back', rolled_back_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status IN ('succeeded', 'failed', 'canceled')
+	`
+	result, err := tx.Exec(ctx, jobQuery, importJobID, userID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to roll back import job: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, false, nil
+	}
+
+	deleteQuery := `
+		UPDATE transactions SET deleted_at = NOW()
+		WHERE user_id = $1 AND import_job_id = $2 AND deleted_at IS NULL
+	`
+	result, err = tx.Exec(ctx, deleteQuery, userID, importJobID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to soft delete import batch: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, fmt.Errorf("failed to commit import job rollback: %w", err)
+	}
+	return int(result.RowsAffected()), true, nil
+}
+
+// RestoreImportJob undoes a rollback: the job gets its earlier status back and
+// its soft-deleted transactions are restored. Rows whose external ID was
+// imported again in the meantime would be duplicates and are deleted instead.
+// It reports false when no rolled back, unpurged job matched.
+func (r *PostgresImportRepository) RestoreImportJob(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	jobQuery := `
+		UPDATE import_jobs
+		SET status = COALESCE(status_before_rollback, 'succeeded'), status_before_rollback = NULL, rolled_back_at = NULL
+		WHERE id = $1 AND user_id = $2 AND status = 'rolled_back' AND purged_at IS NULL
+	`
+	result, err := tx.Exec(ctx, jobQuery, importJobID, userID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to restore import job: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, false, nil
+	}
+
+	restoreQuery := `
+		UPDATE transactions t SET deleted_at = NULL
+		WHERE t.user_id = $1 AND t.import_job_id = $2 AND t.deleted_at IS NOT NULL
+		  AND (t.external_id IS NULL OR NOT EXISTS (
+			SELECT 1 FROM transactions live
+			WHERE live.user_id = t.user_id AND live.source = t.source
+			  AND live.external_id = t.external_id AND live.deleted_at IS NULL
+		  ))
+	`
+	result, err = tx.Exec(ctx, restoreQuery, userID, importJobID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to restore import batch: %w", err)
+	}
+
+	dropQuery := `DELETE FROM transactions WHERE user_id = $1 AND import_job_id = $2 AND deleted_at IS NOT NULL`
+	if _, err := tx.Exec(ctx, dropQuery, userID, importJobID); err != nil {
+		return 0, false, fmt.Errorf("failed to delete re-imported rows: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, fmt.Errorf("failed to commit import job restore: %w", err)
+	}
+	return int(result.RowsAffected()), true, nil
+}
+
+// PurgeImportJob permanently deletes the soft-deleted transactions of a rolled
+// back job and records the purge. It reports false when no rolled back,
+// unpurged job matched.
+func (r *PostgresImportRepository) PurgeImportJob(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	jobQuery := `
+		UPDATE import_jobs SET purged_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'rolled_back' AND purged_at IS NULL
+	`
+	result, err := tx.Exec(ctx, jobQuery, importJobID, userID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to purge import job: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, false, nil
+	}
+
+	deleteQuery := `DELETE FROM transactions WHERE user_id = $1 AND import_job_id = $2 AND deleted_at IS NOT NULL`
+	result, err = tx.Exec(ctx, deleteQuery, userID, importJobID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to purge import batch: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, fmt.Errorf("failed to commit import job purge: %w", err)
+	}
+	return int(result.RowsAffected()), true, nil
+}
+
+// PurgeExpiredImportJobs purges every job rolled back before rolledBackBefore,
+// across all users, and returns how many jobs were purged
+func (r *PostgresImportRepository) PurgeExpiredImportJobs(ctx context.Context, rolledBackBefore time.Time) (int, error) {
+	query := `
+		WITH purged AS (
+			UPDATE import_jobs SET purged_at = NOW()
+			WHERE status = 'rolled_back' AND purged_at IS NULL AND rolled_back_at < $1
+			RETURNING id
+		), deleted AS (
+			DELETE FROM transactions t
+			USING purged p
+			WHERE t.import_job_id = p.id AND t.deleted_at IS NOT NULL
+		)
+		SELECT COUNT(*) FROM purged
+	`
+	var purged int
+	if err := r.pool.QueryRow(ctx, query, rolledBackBefore).Scan(&purged); err != nil {
+		return 0, fmt.Errorf("failed to purge expired import jobs: %w", err)
+	}
+	return purged, nil
 }
@@ -3,6 +3,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,28 +31,33 @@ type BankMapping struct {
 
 // ImportJob tracks the status of a file import
 type ImportJob struct {
-	ID           uuid.UUID  `db:"id"`
-	UserID       uuid.UUID  `db:"user_id"`
-	FileID       uuid.UUID  `db:"file_id"`
-	Kind         string     `db:"kind"`   // "transactions", "invoice"
-	Status       string     `db:"status"` // "pending", "running", "succeeded", "failed"
-	AccountID    *uuid.UUID `db:"account_id"`
-	Timezone     *string    `db:"timezone"`
-	DateFormat   *string    `db:"date_format"`
-	ErrorMessage *string    `db:"error_message"`
-	RowsTotal    int        `db:"rows_total"`
-	RowsImported int        `db:"rows_imported"`
-	RowsFailed   int        `db:"rows_failed"`
-	RequestedAt  time.Time  `db:"requested_at"`
-	StartedAt    *time.Time `db:"started_at"`
-	FinishedAt   *time.Time `db:"finished_at"`
+	ID              uuid.UUID       `db:"id"`
+	UserID          uuid.UUID       `db:"user_id"`
+	FileID          uuid.UUID       `db:"file_id"`
+	Kind            string          `db:"kind"`   // "transactions", "invoice"
+	Status          string          `db:"status"` // "pending", "running", "succeeded", "failed", "canceled", "rolled_back"
+	AccountID       *uuid.UUID      `db:"account_id"`
+	Timezone        *string         `db:"timezone"`
+	DateFormat      *string         `db:"date_format"`
+	InstitutionName *string         `db:"institution_name"`
+	Options         json.RawMessage `db:"options"` // Service-defined settings for queued jobs
+	ErrorMessage    *string         `db:"error_message"`
+	RowsTotal       int             `db:"rows_total"`
+	RowsImported    int             `db:"rows_imported"`
+	RowsFailed      int             `db:"rows_failed"`
+	RequestedAt     time.Time       `db:"requested_at"`
+	StartedAt       *time.Time      `db:"started_at"`
+	FinishedAt      *time.Time      `db:"finished_at"`
+	HeartbeatAt     *time.Time      `db:"heartbeat_at"`   // Last progress or heartbeat from the worker
+	RolledBackAt    *time.Time      `db:"rolled_back_at"` // When the batch was deleted; its transactions are soft deleted
+	PurgedAt        *time.Time      `db:"purged_at"`      // When the soft-deleted transactions were removed for good
 }
 
 // UserFile represents an uploaded file
 type UserFile struct {
 	ID             uuid.UUID `db:"id"`
 	UserID         uuid.UUID `db:"user_id"`
-	Type           string    `db:"type"` // "csv", "xlsx", "pdf", "image"
+	Type           string    `db:"type"` // "csv", "xlsx", "ofx", "camt", "pdf", "image"
 	MimeType       string    `db:"mime_type"`
 	FileName       string    `db:"file_name"`
 	SizeBytes      int64     `db:"size_bytes"`
@@ -60,21 +66,107 @@ type UserFile struct {
 	CreatedAt      time.Time `db:"created_at"`
 }
 
+// Document is a receipt or invoice kept from an uploaded PDF or image,
+// optionally attached to the transaction that paid it
+type Document struct {
+	ID               uuid.UUID       `db:"id"`
+	UserID           uuid.UUID       `db:"user_id"`
+	FileID           uuid.UUID       `db:"file_id"`
+	MerchantName     *string         `db:"merchant_name"`
+	IssuedAt         *time.Time      `db:"issued_at"`
+	TotalAmountMinor *int64          `db:"total_amount_minor"` // Positive total as printed on the document
+	CurrencyCode     *string         `db:"currency_code"`
+	ExtractedJSON    json.RawMessage `db:"extracted_json"` // Raw fields read from the document
+	TransactionID    *uuid.UUID      `db:"transaction_id"`
+	MatchSource      *string         `db:"match_source"` // "manual" or "auto"; nil when unattached
+	MatchScore       *float64        `db:"match_score"`  // Reconcile score of auto matches
+	MatchedAt        *time.Time      `db:"matched_at"`
+	CreatedAt        time.Time       `db:"created_at"`
+	UpdatedAt        time.Time       `db:"updated_at"`
+}
+
+// How a document got attached to its transaction
+const (
+	DocumentMatchManual = "manual"
+	DocumentMatchAuto   = "auto"
+)
+
+// DocumentCandidate pairs an unattached document with a transaction of the same
+// absolute amount and currency posted close to the document's date
+type DocumentCandidate struct {
+	DocumentID       uuid.UUID
+	DocumentDate     time.Time
+	DocumentMerchant string
+	TransactionID    uuid.UUID
+	TransactionDate  time.Time
+	TransactionDesc  string // Merchant name, or the description when there is none
+	AmountMinor      int64  // Absolute amount shared by both
+}
+
 // ParsedTransaction represents a transaction extracted from a file
 type ParsedTransaction struct {
-	Date         time.Time
-	Description  string
-	MerchantName string     // Cleaned merchant name from categorization
-	AmountCents  int64      // Signed: negative for expenses, positive for income
-	Category     string     // Raw category from CSV
-	CategoryID   *uuid.UUID // Resolved category ID from categorization engine
-	ExternalID   string     // For deduplication (e.g., row hash)
+	Date               time.Time
+	Description        string
+	MerchantName       string     // Cleaned merchant name from categorization
+	AmountCents        int64      // Signed: negative for expenses, positive for income
+	CurrencyCode       string     // Native currency from a per-row currency column; empty uses the import's currency
+	Category           string     // Raw category from CSV
+	CategoryID         *uuid.UUID // Resolved category ID from categorization engine
+	CategoryConfidence *float64   // How sure the categorization engine was of CategoryID
+	ExternalID         string     // For deduplication: bank-provided ID (FITID, AcctSvcrRef) or empty for a row hash
+	RowHash            string     // dedup.RowHash of the raw source row; independent of description cleaning
+	Occurrence         int        // 0-based index among identical rows (same RowHash) in the file
+	ReviewReasons      []string   // Why the row waits in the review queue; none stores it as reviewed
+}
+
+// SourceImport is the transaction_source of rows stored by BulkInsertTransactions
+const SourceImport = "csv"
+
+// Reasons an imported transaction is held for review
+const (
+	ReviewUncategorized     = "uncategorized"      // No rule or merchant gave it a category
+	ReviewUnknownMerchant   = "unknown_merchant"   // The merchant name is only a cleaned description
+	ReviewPossibleDuplicate = "possible_duplicate" // Linked to a probable duplicate from another source
+)
+
+// DuplicateLink is a probable duplicate between an imported transaction and a
+// transaction from another source
+type DuplicateLink struct {
+	ID            uuid.UUID  `db:"id"`
+	UserID        uuid.UUID  `db:"user_id"`
+	TransactionID uuid.UUID  `db:"transaction_id"`  // The imported row
+	DuplicateOfID uuid.UUID  `db:"duplicate_of_id"` // The row from the other source
+	Score         float64    `db:"score"`
+	Status        string     `db:"status"` // "pending" or "dismissed"
+	CreatedAt     time.Time  `db:"created_at"`
+	ResolvedAt    *time.Time `db:"resolved_at"`
+}
+
+// DuplicateCandidate pairs a transaction from an import job with a transaction
+// from another source that has the same amount and currency and a close date
+type DuplicateCandidate struct {
+	TransactionID   uuid.UUID
+	TransactionDate time.Time
+	TransactionDesc string
+	CandidateID     uuid.UUID
+	CandidateDate   time.Time
+	CandidateDesc   string
+	CandidateSource string
+	AmountMinor     int64
+}
+
+// DateRangeStats summarizes stored transactions posted within a date window
+type DateRangeStats struct {
+	Count int64
+	First *time.Time // Earliest posted_at in the window; nil when Count is 0
+	Last  *time.Time // Latest posted_at in the window; nil when Count is 0
 }
 
 // ImportRepository defines data access operations for imports
 type ImportRepository interface {
 	// Bank Mappings
 	GetMappingByFingerprint(ctx context.Context, fingerprint string, userID *uuid.UUID) (*BankMapping, error)
+	GetMappingByID(ctx context.Context, id uuid.UUID) (*BankMapping, error)
 	CreateMapping(ctx context.Context, mapping *BankMapping) error
 	UpdateMapping(ctx context.Context, mapping *BankMapping) error
 	ListUserMappings(ctx context.Context, userID uuid.UUID) ([]*BankMapping, error)
@@ -85,6 +177,8 @@ type ImportRepository interface {
 	// User Files
 	CreateUserFile(ctx context.Context, file *UserFile) error
 	GetUserFileByID(ctx context.Context, id uuid.UUID) (*UserFile, error)
+	ListUserFiles(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*UserFile, int64, error)
+	FindUserFileByChecksum(ctx context.Context, userID uuid.UUID, checksum string) (*UserFile, error)
 
 	// Import Jobs
 	CreateImportJob(ctx context.Context, job *ImportJob) error
@@ -92,15 +186,62 @@ type ImportRepository interface {
 	UpdateImportJobProgress(ctx context.Context, id uuid.UUID, rowsImported, rowsFailed int) error
 	UpdateImportJobStatus(ctx context.Context, id uuid.UUID, status string, errorMessage *string) error
 	FinishImportJob(ctx context.Context, id uuid.UUID, status string, rowsImported, rowsFailed int, errorMessage *string) error
+	ListImportJobs(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*ImportJob, int64, error)
+	CancelImportJob(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error)
+
+	// Import job queue (background runner)
+	ClaimPendingImportJob(ctx context.Context) (*ImportJob, error)
+	HeartbeatImportJob(ctx context.Context, id uuid.UUID) error
+	RequeueImportJob(ctx context.Context, id uuid.UUID) error
+	RequeueStaleImportJobs(ctx context.Context, staleBefore time.Time) (int, error)
+	CountTransactionsByImportJob(ctx context.Context, importJobID uuid.UUID) (int, error)
 
 	// Transactions (bulk insert for imported data)
 	BulkInsertTransactions(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, currencyCode string, importJobID uuid.UUID, institutionName string, txs []*ParsedTransaction) (int, error)
+	FindExistingExternalIDs(ctx context.Context, userID uuid.UUID, source string, externalIDs []string) (map[string]bool, error)
+	GetTransactionDateStats(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, from, to time.Time) (*DateRangeStats, error)
+
+	// Cross-source duplicate links
+	FindDuplicateCandidates(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID, maxDays int) ([]*DuplicateCandidate, error)
+	CreateDuplicateLinks(ctx context.Context, links []*DuplicateLink) (int, error)
+	ListDuplicateLinks(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]*DuplicateLink, int64, error)
+	DismissDuplicateLink(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error)
+	ConfirmDuplicateLink(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error)
+
+	// Documents (receipts and invoices)
+	CreateDocument(ctx context.Context, doc *Document) error
+	GetDocumentByID(ctx context.Context, id uuid.UUID) (*Document, error)
+	// ListDocuments lists a user's documents, optionally only those attached to
+	// one transaction, with status "attached", "unattached" or empty for all.
+	ListDocuments(ctx context.Context, userID uuid.UUID, transactionID *uuid.UUID, status string, limit, offset int) ([]*Document, int64, error)
+	// AttachDocument links a document to one of the user's live transactions.
+	// It reports false when either does not exist.
+	AttachDocument(ctx context.Context, userID uuid.UUID, documentID uuid.UUID, transactionID uuid.UUID, source string, score *float64) (bool, error)
+	DetachDocument(ctx context.Context, userID uuid.UUID, documentID uuid.UUID) (bool, error)
+	FindDocumentCandidates(ctx context.Context, userID uuid.UUID, maxDays int) ([]*DocumentCandidate, error)
+
+	// Review queue
+	// QueueForReview adds a reason to the given transactions and marks them pending review.
+	QueueForReview(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, reason string) error
 
 	// Transactions (list/query)
-	ListTransactions(ctx context.Context, userID uuid.UUID, filter ListTransactionsFilter) ([]*Transaction, int64, error)
+	// ListTransactions returns a page of transactions, newest first, and the
+	// cursor of the next page ("" on the last page).
+	ListTransactions(ctx context.Context, userID uuid.UUID, filter ListTransactionsFilter) ([]*Transaction, string, error)
 
-	// Transactions (delete by import job)
-	DeleteByImportJobID(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, error)
+	// Import batch rollback
+	// RollbackImportJob soft deletes the transactions of a finished job and marks
+	// it rolled back. It reports false when no finished job matched.
+	RollbackImportJob(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, bool, error)
+	// RestoreImportJob brings back the transactions of a rolled back, unpurged job
+	// and its earlier status. It reports false when no such job matched.
+	RestoreImportJob(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, bool, error)
+	// PurgeImportJob permanently deletes the transactions of a rolled back job.
+	// It reports false when no unpurged rolled back job matched.
+	PurgeImportJob(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, bool, error)
+	// PurgeExpiredImportJobs purges every job rolled back before the given time
+	// and returns how many jobs were purged.
+	PurgeExpiredImportJobs(ctx context.Context, rolledBackBefore time.Time) (int, error)
 }
 
 // Transaction represents a stored transaction with full metadata
@@ -120,18 +261,29 @@ type Transaction struct {
 	ExternalID          *string    `db:"external_id"`
 	Notes               *string    `db:"notes"`
 	InstitutionName     *string    `db:"institution_name"`
+	Intent              *string    `db:"intent"`
+	ReviewStatus        *string    `db:"review_status"` // "pending", "approved", "skipped"; nil when never queued
 	CreatedAt           time.Time  `db:"created_at"`
 	UpdatedAt           time.Time  `db:"updated_at"`
 }
 
 // ListTransactionsFilter specifies filter/pagination options for listing transactions
 type ListTransactionsFilter struct {
-	AccountID   *uuid.UUID
-	CategoryID  *uuid.UUID
-	ImportJobID *uuid.UUID // Filter by import batch (for staging view)
-	StartDate   *time.Time
-	EndDate     *time.Time
-	Search      string // Search in description
-	Limit       int
-	Offset      int
+	AccountID         *uuid.UUID
+	CategoryIDs       []uuid.UUID // Any of these categories, including split categories
+	UncategorizedOnly bool        // Only transactions without a category or splits
+	TagIDs            []uuid.UUID // Any of these tags
+	ImportJobID       *uuid.UUID  // Filter by import batch (for staging view)
+	StartDate         *time.Time
+	EndDate           *time.Time
+	MinAmount         *int64 // Absolute amount in minor units, inclusive
+	MaxAmount         *int64 // Absolute amount in minor units, inclusive
+	Direction         string // "income", "expense" or empty for both
+	Merchant          string // Merchant name, case-insensitive
+	Source            string // "csv", "manual", ...
+	InstitutionName   string
+	Intent            string // An intent, or "none" for transactions without one
+	Search            string // Words (or word prefixes) in description, merchant or notes
+	Limit             int
+	Cursor            string // Opaque position returned by the previous page
 }
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/dedup"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+var (
+	// ErrDocumentNotFound is returned when a document does not exist or belongs to another user.
+	ErrDocumentNotFound = errors.New("document not found")
+	// ErrInvalidDocument is returned when a document's file or fields cannot be accepted.
+	ErrInvalidDocument = errors.New("invalid document")
+	// ErrDocumentTransactionNotFound is returned when attaching a document to a missing transaction.
+	ErrDocumentTransactionNotFound = errors.New("transaction not found")
+)
+
+// DocumentRequest describes a receipt or invoice kept from an uploaded PDF or
+// image. Fields the user or an extractor could not read are left empty.
+type DocumentRequest struct {
+	FileID           uuid.UUID
+	MerchantName     string
+	IssuedAt         *time.Time
+	TotalAmountMinor *int64 // Positive total as printed on the document
+	CurrencyCode     string
+	ExtractedJSON    json.RawMessage
+}
+
+// documentMatchConfig allows for receipts booked by the bank a few days after
+// they were issued. A same-day match needs no merchant overlap; further apart
+// the merchant has to agree.
+var documentMatchConfig = dedup.MatchConfig{MaxDays: 5, MinScore: 0.35}
+
+// CreateDocument records a receipt or invoice for an uploaded PDF or image and
+// runs the reconcile pass, so a document whose transaction is already stored
+// comes back attached.
+func (s *ImportService) CreateDocument(ctx context.Context, userID uuid.UUID, req DocumentRequest) (*repository.Document, error) {
+	file, err := s.GetUserFile(ctx, userID, req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.Type != fileTypePDF && file.Type != fileTypeImage {
+		return nil, fmt.Errorf("%w: %s files are not receipts or invoices", ErrInvalidDocument, file.Type)
+	}
+
+	doc := &repository.Document{
+		UserID:        userID,
+		FileID:        file.ID,
+		IssuedAt:      req.IssuedAt,
+		ExtractedJSON: req.ExtractedJSON,
+	}
+	if name := strings.TrimSpace(req.MerchantName); name != "" {
+		doc.MerchantName = &name
+	}
+	if req.TotalAmountMinor != nil {
+		if *req.TotalAmountMinor <= 0 {
+			return nil, fmt.Errorf("%w: total amount must be positive", ErrInvalidDocument)
+		}
+		doc.TotalAmountMinor = req.TotalAmountMinor
+	}
+	if req.CurrencyCode != "" {
+		code, ok := normalizeCurrencyCode(req.CurrencyCode)
+		if !ok {
+			return nil, fmt.Errorf("%w: currency %q", ErrInvalidDocument, req.CurrencyCode)
+		}
+		doc.CurrencyCode = &code
+	}
+	if len(req.ExtractedJSON) > 0 && !json.Valid(req.ExtractedJSON) {
+		return nil, fmt.Errorf("%w: extracted data is not valid JSON", ErrInvalidDocument)
+	}
+
+	if err := s.repo.CreateDocument(ctx, doc); err != nil {
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	// Reconciling never fails the upload; the next pass picks the document up
+	matched, err := s.ReconcileDocuments(ctx, userID)
+	if err != nil {
+		s.logger.Warn("failed to reconcile documents", "document_id", doc.ID, "error", err)
+	}
+	if matched == 0 {
+		return doc, nil
+	}
+	return s.GetDocument(ctx, userID, doc.ID)
+}
+
+// GetDocument returns one of the user's documents.
+func (s *ImportService) GetDocument(ctx context.Context, userID uuid.UUID, documentID uuid.UUID) (*repository.Document, error) {
+	doc, err := s.repo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	if doc == nil || doc.UserID != userID {
+		return nil, ErrDocumentNotFound
+	}
+	return doc, nil
+}
+
+// ListDocuments returns the user's documents, newest first, and the total
+// count. transactionID lists one transaction's attachments; status is
+// "attached", "unattached" or empty for all.
+func (s *ImportService) ListDocuments(ctx context.Context, userID uuid.UUID, transactionID *uuid.UUID, status string, limit, offset int) ([]*repository.Document, int64, error) {
+	switch status {
+	case "", "attached", "unattached":
+	default:
+		return nil, 0, fmt.Errorf("%w: unknown status %q", ErrInvalidDocument, status)
+	}
+
+	docs, total, err := s.repo.ListDocuments(ctx, userID, transactionID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+	return docs, total, nil
+}
+
+// AttachDocument attaches a document to one of the user's transactions,
+// replacing any earlier attachment, including one made by the reconcile pass.
+func (s *ImportService) AttachDocument(ctx context.Context, userID uuid.UUID, documentID uuid.UUID, transactionID uuid.UUID) (*repository.Document, error) {
+	if _, err := s.GetDocument(ctx, userID, documentID); err != nil {
+		return nil, err
+	}
+
+	attached, err := s.repo.AttachDocument(ctx, userID, documentID, transactionID, repository.DocumentMatchManual, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach document: %w", err)
+	}
+	if !attached {
+		return nil, ErrDocumentTransactionNotFound
+	}
+	return s.GetDocument(ctx, userID, documentID)
+}
+
+// DetachDocument removes a document from its transaction. The reconcile pass
+// does not attach it again; only the user can.
+func (s *ImportService) DetachDocument(ctx context.Context, userID uuid.UUID, documentID uuid.UUID) (*repository.Document, error) {
+	detached, err := s.repo.DetachDocument(ctx, userID, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detach document: %w", err)
+	}
+	if !detached {
+		return nil, ErrDocumentNotFound
+	}
+	return s.GetDocument(ctx, userID, documentID)
+}
+
+// ReconcileDocuments attaches the user's unattached documents to the
+// transactions that paid them: same absolute amount and currency, a posting
+// date close to the document date and, the further apart, a similar merchant.
+// Every document and transaction is used once, best scores first. It returns
+// how many documents were attached.
+func (s *ImportService) ReconcileDocuments(ctx context.Context, userID uuid.UUID) (int, error) {
+	candidates, err := s.repo.FindDocumentCandidates(ctx, userID, documentMatchConfig.MaxDays)
+	if err != nil {
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	docs := make(map[uuid.UUID]int)
+	txs := make(map[uuid.UUID]int)
+	var docIDs, txIDs []uuid.UUID
+	var pairs []dedup.Pair
+	for _, c := range candidates {
+		score, ok := dedup.Score(
+			dedup.Record{Date: c.DocumentDate, Description: c.DocumentMerchant, AmountMinor: c.AmountMinor},
+			dedup.Record{Date: c.TransactionDate, Description: c.TransactionDesc, AmountMinor: c.AmountMinor},
+			documentMatchConfig,
+		)
+		if !ok || score < documentMatchConfig.MinScore {
+			continue
+		}
+
+		d, seen := docs[c.DocumentID]
+		if !seen {
+			d = len(docIDs)
+			docs[c.DocumentID] = d
+			docIDs = append(docIDs, c.DocumentID)
+		}
+		t, seen := txs[c.TransactionID]
+		if !seen {
+			t = len(txIDs)
+			txs[c.TransactionID] = t
+			txIDs = append(txIDs, c.TransactionID)
+		}
+		pairs = append(pairs, dedup.Pair{Left: d, Right: t, Score: score})
+	}
+
+	matched := 0
+	for _, p := range dedup.Assign(pairs) {
+		score := p.Score
+		attached, err := s.repo.AttachDocument(ctx, userID, docIDs[p.Left], txIDs[p.Right], repository.DocumentMatchAuto, &score)
+		if err != nil {
+			return matched, fmt.Errorf("failed to attach document: %w", err)
+		}
+		if attached {
+			matched++
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+func TestReconcileDocuments_AttachesBestMatches(t *testing.T) {
+	userID := uuid.New()
+	day := time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	receipt, invoice, stale := uuid.New(), uuid.New(), uuid.New()
+	tesco, shell, other := uuid.New(), uuid.New(), uuid.New()
+
+	repo := &fakeImportRepo{
+		documents: []*repository.Document{{ID: receipt, UserID: userID}, {ID: invoice, UserID: userID}, {ID: stale, UserID: userID}},
+		docCandidates: []*repository.DocumentCandidate{
+			// The bank books the Tesco receipt two days later; the merchant agrees.
+			{DocumentID: receipt, DocumentDate: day, DocumentMerchant: "Tesco", TransactionID: tesco,
+				TransactionDate: day.AddDate(0, 0, 2), TransactionDesc: "CARD PAYMENT TESCO STORES 3217", AmountMinor: 4523},
+			{DocumentID: receipt, DocumentDate: day, DocumentMerchant: "Tesco", TransactionID: shell,
+				TransactionDate: day, TransactionDesc: "Shell Fuel", AmountMinor: 4523},
+			// Without a merchant only a same-day match is good enough.
+			{DocumentID: invoice, DocumentDate: day, TransactionID: shell,
+				TransactionDate: day, TransactionDesc: "Shell Fuel", AmountMinor: 4523},
+			{DocumentID: stale, DocumentDate: day, TransactionID: other,
+				TransactionDate: day.AddDate(0, 0, 2), TransactionDesc: "Amazon", AmountMinor: 1999},
+		},
+	}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	matched, err := svc.ReconcileDocuments(ctx, userID)
+	if err != nil {
+		t.Fatalf("ReconcileDocuments failed: %v", err)
+	}
+	if matched != 2 {
+		t.Fatalf("expected 2 documents attached, got %d", matched)
+	}
+
+	want := map[uuid.UUID]*uuid.UUID{receipt: &tesco, invoice: &shell, stale: nil}
+	for docID, txID := range want {
+		doc, err := svc.GetDocument(ctx, userID, docID)
+		if err != nil {
+			t.Fatalf("GetDocument failed: %v", err)
+		}
+		if txID == nil {
+			if doc.TransactionID != nil {
+				t.Errorf("document %s: expected no attachment, got %s", docID, *doc.TransactionID)
+			}
+			continue
+		}
+		if doc.TransactionID == nil || *doc.TransactionID != *txID {
+			t.Errorf("document %s: expected transaction %s, got %v", docID, *txID, doc.TransactionID)
+		}
+		if doc.MatchSource == nil || *doc.MatchSource != repository.DocumentMatchAuto || doc.MatchScore == nil {
+			t.Errorf("document %s: expected an auto match with a score, got %v %v", docID, doc.MatchSource, doc.MatchScore)
+		}
+	}
+}
+
+func TestCreateDocument_ValidatesFileAndFields(t *testing.T) {
+	userID := uuid.New()
+	repo := &fakeImportRepo{}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	pdf := &repository.UserFile{UserID: userID, Type: fileTypePDF, MimeType: "application/pdf", FileName: "receipt.pdf", SizeBytes: 10}
+	csv := &repository.UserFile{UserID: userID, Type: fileTypeCSV, MimeType: mimeTypeCSV, FileName: "export.csv", SizeBytes: 10}
+	for _, file := range []*repository.UserFile{pdf, csv} {
+		if err := repo.CreateUserFile(ctx, file); err != nil {
+			t.Fatalf("CreateUserFile failed: %v", err)
+		}
+	}
+
+	negative := int64(-500)
+	total := int64(4523)
+	issued := time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		req     DocumentRequest
+		wantErr error
+	}{
+		{"another user's file", uuid.New(), DocumentRequest{FileID: pdf.ID}, ErrUserFileNotFound},
+		{"statement file", userID, DocumentRequest{FileID: csv.ID}, ErrInvalidDocument},
+		{"negative total", userID, DocumentRequest{FileID: pdf.ID, TotalAmountMinor: &negative}, ErrInvalidDocument},
+		{"unknown currency", userID, DocumentRequest{FileID: pdf.ID, CurrencyCode: "euros"}, ErrInvalidDocument},
+		{"broken extracted data", userID, DocumentRequest{FileID: pdf.ID, ExtractedJSON: []byte("{")}, ErrInvalidDocument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.CreateDocument(ctx, tt.userID, tt.req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	doc, err := svc.CreateDocument(ctx, userID, DocumentRequest{
+		FileID:           pdf.ID,
+		MerchantName:     " Tesco ",
+		IssuedAt:         &issued,
+		TotalAmountMinor: &total,
+		CurrencyCode:     "gbp",
+	})
+	if err != nil {
+		t.Fatalf("CreateDocument failed: %v", err)
+	}
+	if doc.MerchantName == nil || *doc.MerchantName != "Tesco" || doc.CurrencyCode == nil || *doc.CurrencyCode != "GBP" {
+		t.Errorf("unexpected document fields: merchant %v, currency %v", doc.MerchantName, doc.CurrencyCode)
+	}
+}
+
+func TestAttachAndDetachDocument(t *testing.T) {
+	userID := uuid.New()
+	docID, txID := uuid.New(), uuid.New()
+	repo := &fakeImportRepo{documents: []*repository.Document{{ID: docID, UserID: userID}}}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	if _, err := svc.AttachDocument(ctx, uuid.New(), docID, txID); !errors.Is(err, ErrDocumentNotFound) {
+		t.Fatalf("expected ErrDocumentNotFound for another user, got %v", err)
+	}
+
+	doc, err := svc.AttachDocument(ctx, userID, docID, txID)
+	if err != nil {
+		t.Fatalf("AttachDocument failed: %v", err)
+	}
+	if doc.TransactionID == nil || *doc.TransactionID != txID || *doc.MatchSource != repository.DocumentMatchManual {
+		t.Fatalf("expected a manual attachment to %s, got %v (%v)", txID, doc.TransactionID, doc.MatchSource)
+	}
+
+	doc, err = svc.DetachDocument(ctx, userID, docID)
+	if err != nil {
+		t.Fatalf("DetachDocument failed: %v", err)
+	}
+	if doc.TransactionID != nil {
+		t.Fatalf("expected the document to be detached, got %s", *doc.TransactionID)
+	}
+
+	if _, _, err := svc.ListDocuments(ctx, userID, nil, "lost", 10, 0); !errors.Is(err, ErrInvalidDocument) {
+		t.Fatalf("expected ErrInvalidDocument for an unknown status, got %v", err)
+	}
+}
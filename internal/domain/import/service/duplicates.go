@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/dedup"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+// ErrDuplicateLinkNotFound is returned when a duplicate link does not exist, belongs
+// to another user or was already resolved.
+var ErrDuplicateLinkNotFound = errors.New("pending duplicate link not found")
+
+// adoptLegacyExternalIDs keeps re-imports of files stored before row hashes
+// idempotent. Those rows were keyed by date|description|amount, which collapsed
+// identical rows, so only the first occurrence of a row can match one; when it
+// does, the row takes over the legacy ID and BulkInsertTransactions skips it.
+func (s *ImportService) adoptLegacyExternalIDs(ctx context.Context, userID uuid.UUID, txs []*repository.ParsedTransaction) error {
+	legacy := make(map[string][]*repository.ParsedTransaction)
+	ids := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		if tx.ExternalID != "" || tx.RowHash == "" || tx.Occurrence != 0 {
+			continue
+		}
+		id := dedup.LegacyExternalID(tx.Date, tx.Description, tx.AmountCents)
+		if _, ok := legacy[id]; !ok {
+			ids = append(ids, id)
+		}
+		legacy[id] = append(legacy[id], tx)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	existing, err := s.repo.FindExistingExternalIDs(ctx, userID, repository.SourceImport, ids)
+	if err != nil {
+		return fmt.Errorf("failed to check legacy external ids: %w", err)
+	}
+	for id := range existing {
+		for _, tx := range legacy[id] {
+			tx.ExternalID = id
+		}
+	}
+	return nil
+}
+
+// linkCrossSourceDuplicates records probable duplicates between the rows an
+// import job stored and rows from other sources (aggregators, manual entries).
+// Nothing is dropped: links wait for the user to confirm or dismiss them, and
+// the imported rows wait in the review queue.
+func (s *ImportService) linkCrossSourceDuplicates(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, error) {
+	cfg := dedup.DefaultMatchConfig()
+
+	candidates, err := s.repo.FindDuplicateCandidates(ctx, userID, importJobID, cfg.MaxDays)
+	if err != nil {
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	// Index both sides so dedup.Assign can keep every transaction in one link at most.
+	left := make(map[uuid.UUID]int)
+	right := make(map[uuid.UUID]int)
+	var leftIDs, rightIDs []uuid.UUID
+	var pairs []dedup.Pair
+	for _, c := range candidates {
+		score, ok := dedup.Score(
+			dedup.Record{Date: c.TransactionDate, Description: c.TransactionDesc, AmountMinor: c.AmountMinor},
+			dedup.Record{Date: c.CandidateDate, Description: c.CandidateDesc, AmountMinor: c.AmountMinor},
+			cfg,
+		)
+		if !ok || score < cfg.MinScore {
+			continue
+		}
+
+		l, seen := left[c.TransactionID]
+		if !seen {
+			l = len(leftIDs)
+			left[c.TransactionID] = l
+			leftIDs = append(leftIDs, c.TransactionID)
+		}
+		r, seen := right[c.CandidateID]
+		if !seen {
+			r = len(rightIDs)
+			right[c.CandidateID] = r
+			rightIDs = append(rightIDs, c.CandidateID)
+		}
+		pairs = append(pairs, dedup.Pair{Left: l, Right: r, Score: score})
+	}
+
+	assigned := dedup.Assign(pairs)
+	links := make([]*repository.DuplicateLink, 0, len(assigned))
+	linkedIDs := make([]uuid.UUID, 0, len(assigned))
+	for _, p := range assigned {
+		linkedIDs = append(linkedIDs, leftIDs[p.Left])
+		links = append(links, &repository.DuplicateLink{
+			UserID:        userID,
+			TransactionID: leftIDs[p.Left],
+			DuplicateOfID: rightIDs[p.Right],
+			Score:         p.Score,
+			Status:        "pending",
+		})
+	}
+
+	created, err := s.repo.CreateDuplicateLinks(ctx, links)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.repo.QueueForReview(ctx, userID, linkedIDs, repository.ReviewPossibleDuplicate); err != nil {
+		return created, err
+	}
+	return created, nil
+}
+
+// ListDuplicateLinks returns the user's duplicate links with the given status
+// ("pending", "dismissed" or empty for all), newest first, and the total count.
+func (s *ImportService) ListDuplicateLinks(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]*repository.DuplicateLink, int64, error) {
+	links, total, err := s.repo.ListDuplicateLinks(ctx, userID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list duplicate links: %w", err)
+	}
+	return links, total, nil
+}
+
+// ConfirmDuplicateLink accepts a pending link: the imported copy is deleted and
+// the transaction from the other source is kept.
+func (s *ImportService) ConfirmDuplicateLink(ctx context.Context, userID uuid.UUID, linkID uuid.UUID) error {
+	confirmed, err := s.repo.ConfirmDuplicateLink(ctx, userID, linkID)
+	if err != nil {
+		return fmt.Errorf("failed to confirm duplicate link: %w", err)
+	}
+	if !confirmed {
+		return ErrDuplicateLinkNotFound
+	}
+	return nil
+}
+
+// DismissDuplicateLink rejects a pending link; both transactions are kept and the
+// pair is not proposed again.
+func (s *ImportService) DismissDuplicateLink(ctx context.Context, userID uuid.UUID, linkID uuid.UUID) error {
+	dismissed, err := s.repo.DismissDuplicateLink(ctx, userID, linkID)
+	if err != nil {
+		return fmt.Errorf("failed to dismiss duplicate link: %w", err)
+	}
+	if !dismissed {
+		return ErrDuplicateLinkNotFound
+	}
+	return nil
+}
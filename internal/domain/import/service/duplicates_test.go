@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+func TestImport_IdenticalRowsLegacyIDsAndDuplicateLinks(t *testing.T) {
+	data := []byte(strings.Join([]string{
+		"Date,Description,Amount",
+		"02/01/2024,Coffee,-3.50",
+		"02/01/2024,Coffee,-3.50",
+		"03/01/2024,Tesco Stores,-45.23",
+		"",
+	}, "\n"))
+
+	// The Tesco row was stored by an import that predates row hashes.
+	legacy := repository.GenerateExternalID(&repository.ParsedTransaction{
+		Date:        time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		Description: "Tesco Stores",
+		AmountCents: -4523,
+	})
+
+	imported, other := uuid.New(), uuid.New()
+	day := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	repo := &fakeImportRepo{
+		accountCurrency: "EUR",
+		existingIDs:     map[string]bool{legacy: true},
+		candidates: []*repository.DuplicateCandidate{
+			{TransactionID: imported, TransactionDate: day, TransactionDesc: "CARD PAYMENT TESCO STORES 3217",
+				CandidateID: other, CandidateDate: day.AddDate(0, 0, 1), CandidateDesc: "Tesco", AmountMinor: -4523},
+			{TransactionID: imported, TransactionDate: day, TransactionDesc: "CARD PAYMENT TESCO STORES 3217",
+				CandidateID: uuid.New(), CandidateDate: day, CandidateDesc: "Shell Fuel", AmountMinor: -4523},
+		},
+	}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	accountID := uuid.New()
+	userID := uuid.New()
+	mapping := ColumnMapping{DateCol: 0, DescCol: 1, CategoryCol: -1, AmountCol: 2, DebitCol: -1, CreditCol: -1, DateFormat: "DD/MM/YYYY"}
+	result, err := svc.ImportWithMapping(context.Background(), userID, &accountID, data, mapping)
+	if err != nil {
+		t.Fatalf("ImportWithMapping failed: %v", err)
+	}
+
+	if len(repo.inserted) != 3 {
+		t.Fatalf("expected 3 rows sent to the repository, got %d", len(repo.inserted))
+	}
+	first, second := repository.GenerateExternalID(repo.inserted[0]), repository.GenerateExternalID(repo.inserted[1])
+	if first == second {
+		t.Error("expected the two coffees to get different external IDs")
+	}
+	if got := repository.GenerateExternalID(repo.inserted[2]); got != legacy {
+		t.Errorf("expected the stored row to keep its legacy ID, got %q", got)
+	}
+
+	if result.PossibleDuplicates != 1 || len(repo.links) != 1 {
+		t.Fatalf("expected 1 duplicate link, got %d (%d stored)", result.PossibleDuplicates, len(repo.links))
+	}
+	link := repo.links[0]
+	if link.TransactionID != imported || link.DuplicateOfID != other || link.Status != "pending" {
+		t.Errorf("unexpected link: %+v", link)
+	}
+	if reasons := repo.reviewReasons[imported]; len(reasons) != 1 || reasons[0] != repository.ReviewPossibleDuplicate {
+		t.Errorf("expected the linked row to wait for review, got %v", reasons)
+	}
+
+	if err := svc.DismissDuplicateLink(context.Background(), uuid.New(), link.ID); !errors.Is(err, ErrDuplicateLinkNotFound) {
+		t.Errorf("expected another user's link to be hidden, got %v", err)
+	}
+	if err := svc.DismissDuplicateLink(context.Background(), userID, link.ID); err != nil {
+		t.Fatalf("DismissDuplicateLink failed: %v", err)
+	}
+	if err := svc.ConfirmDuplicateLink(context.Background(), userID, link.ID); !errors.Is(err, ErrDuplicateLinkNotFound) {
+		t.Errorf("expected a dismissed link not to be confirmable, got %v", err)
+	}
+	pending, total, err := svc.ListDuplicateLinks(context.Background(), userID, "pending", 50, 0)
+	if err != nil || total != 0 || len(pending) != 0 {
+		t.Errorf("expected no pending links, got %d (%v)", total, err)
+	}
+}
+
+func TestImport_RowHashesDifferPerAccount(t *testing.T) {
+	data := []byte("Date,Description,Amount\n02/01/2024,Coffee,-3.50\n")
+	mapping := ColumnMapping{DateCol: 0, DescCol: 1, CategoryCol: -1, AmountCol: 2, DebitCol: -1, CreditCol: -1, DateFormat: "DD/MM/YYYY"}
+	userID := uuid.New()
+
+	externalID := func(accountID uuid.UUID) string {
+		repo := &fakeImportRepo{accountCurrency: "EUR"}
+		svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if _, err := svc.ImportWithMapping(context.Background(), userID, &accountID, data, mapping); err != nil {
+			t.Fatalf("ImportWithMapping failed: %v", err)
+		}
+		if len(repo.inserted) != 1 {
+			t.Fatalf("expected 1 row sent to the repository, got %d", len(repo.inserted))
+		}
+		return repository.GenerateExternalID(repo.inserted[0])
+	}
+
+	checking, savings := uuid.New(), uuid.New()
+	if externalID(checking) == externalID(savings) {
+		t.Error("expected the same row in two accounts to get different external IDs")
+	}
+	if externalID(checking) != externalID(checking) {
+		t.Error("expected re-importing into the same account to keep the external ID")
+	}
+}
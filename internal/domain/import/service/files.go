@@ -0,0 +1,224 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/camt"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/ofx"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/xlsx"
+)
+
+// maxUserFileSize mirrors the user_files_size_bytes_chk constraint.
+const maxUserFileSize = 20_000_000
+
+const (
+	fileTypePDF   = "pdf"
+	fileTypeImage = "image"
+)
+
+var (
+	// ErrEmptyFile is returned when an upload has no content.
+	ErrEmptyFile = errors.New("file is empty")
+	// ErrFileTooLarge is returned when an upload exceeds maxUserFileSize.
+	ErrFileTooLarge = fmt.Errorf("file exceeds the %d byte limit", maxUserFileSize)
+	// ErrUnsupportedFileType is returned for uploads that are neither statements, spreadsheets, PDFs nor images.
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+	// ErrFileNotStored is returned when a file's content was not kept, e.g. it was imported before storage was configured.
+	ErrFileNotStored = errors.New("file content is not stored")
+	// ErrChecksumMismatch is returned when stored content no longer matches the recorded SHA-256.
+	ErrChecksumMismatch = errors.New("stored file does not match its checksum")
+	// ErrDuplicateFile is returned when the user already uploaded a file with identical content.
+	ErrDuplicateFile = errors.New("an identical file was already uploaded")
+)
+
+// DuplicateFileError reports the earlier upload an identical file matched.
+// errors.Is(err, ErrDuplicateFile) holds for it.
+type DuplicateFileError struct {
+	Existing *repository.UserFile
+}
+
+func (e *DuplicateFileError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", ErrDuplicateFile, e.Existing.FileName, e.Existing.ID)
+}
+
+func (e *DuplicateFileError) Is(target error) bool {
+	return target == ErrDuplicateFile
+}
+
+// UploadFile stores an uploaded file and records it in user_files. The file type
+// is detected from the content; mimeType is kept as given when set. Re-uploading
+// content the user already uploaded fails with a *DuplicateFileError.
+func (s *ImportService) UploadFile(ctx context.Context, userID uuid.UUID, fileName, mimeType string, data []byte) (*repository.UserFile, error) {
+	if err := checkFileSize(int64(len(data))); err != nil {
+		return nil, err
+	}
+	if s.storage == nil {
+		return nil, ErrFileStorageUnavailable
+	}
+
+	fileType, detectedMime, err := detectUploadType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := checksumSHA256(data)
+	existing, err := s.findDuplicateFile(ctx, userID, checksum)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, &DuplicateFileError{Existing: existing}
+	}
+
+	if mimeType == "" {
+		mimeType = detectedMime
+	}
+	if fileName == "" {
+		fileName = "upload." + fileType
+	}
+
+	file := &repository.UserFile{
+		UserID:         userID,
+		Type:           fileType,
+		MimeType:       mimeType,
+		FileName:       fileName,
+		SizeBytes:      int64(len(data)),
+		ChecksumSHA256: &checksum,
+	}
+	if err := s.storeUserFile(ctx, file, data); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// GetUserFile returns one of the user's uploaded files.
+func (s *ImportService) GetUserFile(ctx context.Context, userID uuid.UUID, fileID uuid.UUID) (*repository.UserFile, error) {
+	file, err := s.repo.GetUserFileByID(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	if file == nil || file.UserID != userID {
+		return nil, ErrUserFileNotFound
+	}
+	return file, nil
+}
+
+// ListUserFiles returns the user's uploaded files, newest first, and the total count.
+func (s *ImportService) ListUserFiles(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*repository.UserFile, int64, error) {
+	files, total, err := s.repo.ListUserFiles(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list files: %w", err)
+	}
+	return files, total, nil
+}
+
+// ReadUserFile loads a file's stored content and verifies its checksum. It makes
+// ImportService the FileSource for the JobRunner.
+func (s *ImportService) ReadUserFile(ctx context.Context, file *repository.UserFile) ([]byte, error) {
+	if s.storage == nil {
+		return nil, ErrFileStorageUnavailable
+	}
+	if file.StorageURL == nil {
+		return nil, ErrFileNotStored
+	}
+
+	data, err := s.storage.Get(ctx, *file.StorageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored file: %w", err)
+	}
+	if file.ChecksumSHA256 != nil && !strings.EqualFold(checksumSHA256(data), *file.ChecksumSHA256) {
+		return nil, ErrChecksumMismatch
+	}
+	return data, nil
+}
+
+// storeUserFile computes the checksum, writes the content to storage when one is
+// configured and inserts the user_files row. The stored object is removed again
+// if the row cannot be written.
+func (s *ImportService) storeUserFile(ctx context.Context, file *repository.UserFile, data []byte) error {
+	if file.ID == uuid.Nil {
+		file.ID = uuid.New()
+	}
+	if file.ChecksumSHA256 == nil {
+		checksum := checksumSHA256(data)
+		file.ChecksumSHA256 = &checksum
+	}
+
+	if s.storage != nil {
+		url, err := s.storage.Put(ctx, file.UserID.String()+"/"+file.ID.String(), data, file.MimeType)
+		if err != nil {
+			return fmt.Errorf("failed to store file: %w", err)
+		}
+		file.StorageURL = &url
+	}
+
+	if err := s.repo.CreateUserFile(ctx, file); err != nil {
+		if file.StorageURL != nil {
+			if delErr := s.storage.Delete(ctx, *file.StorageURL); delErr != nil {
+				s.logger.Warn("failed to remove orphaned file", "file_id", file.ID, "error", delErr)
+			}
+		}
+		return fmt.Errorf("failed to create file record: %w", err)
+	}
+
+	return nil
+}
+
+// findDuplicateFile returns the user's latest file with the given checksum, if any.
+func (s *ImportService) findDuplicateFile(ctx context.Context, userID uuid.UUID, checksum string) (*repository.UserFile, error) {
+	existing, err := s.repo.FindUserFileByChecksum(ctx, userID, checksum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for duplicate file: %w", err)
+	}
+	return existing, nil
+}
+
+func checkFileSize(size int64) error {
+	switch {
+	case size <= 0:
+		return ErrEmptyFile
+	case size > maxUserFileSize:
+		return ErrFileTooLarge
+	}
+	return nil
+}
+
+func checksumSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// detectUploadType maps content to a user_file_type value and MIME type.
+// Anything that looks like text is treated as delimited text.
+func detectUploadType(data []byte) (string, string, error) {
+	switch {
+	case ofx.IsOFX(data):
+		return fileTypeOFX, mimeTypeOFX, nil
+	case camt.IsCAMT053(data):
+		return fileTypeCAMT, mimeTypeCAMT, nil
+	case xlsx.IsWorkbook(data):
+		return fileTypeXLSX, mimeTypeXLSX, nil
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return fileTypePDF, "application/pdf", nil
+	}
+
+	detected := http.DetectContentType(data)
+	switch {
+	case strings.HasPrefix(detected, "image/"):
+		return fileTypeImage, detected, nil
+	case strings.HasPrefix(detected, "text/"):
+		return fileTypeCSV, mimeTypeCSV, nil
+	}
+	return "", "", fmt.Errorf("%w: %s", ErrUnsupportedFileType, detected)
+}
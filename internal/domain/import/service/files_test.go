@@ -0,0 +1,272 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+	"github.com/FACorreiaa/smart-finance-tracker/pkg/storage"
+)
+
+func TestUploadFile(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+	repo := &fakeImportRepo{}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil))).WithStorage(store)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	data := []byte("Date,Description,Amount\n13/02/2024,Coffee,-3.50\n")
+	file, err := svc.UploadFile(ctx, userID, "march.csv", "", data)
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if file.Type != fileTypeCSV || file.MimeType != mimeTypeCSV || file.FileName != "march.csv" || file.SizeBytes != int64(len(data)) {
+		t.Errorf("unexpected file record: %+v", file)
+	}
+	sum := sha256.Sum256(data)
+	if file.ChecksumSHA256 == nil || *file.ChecksumSHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected a SHA-256 checksum, got %v", file.ChecksumSHA256)
+	}
+	if file.StorageURL == nil {
+		t.Fatal("expected a storage URL")
+	}
+
+	stored, err := svc.ReadUserFile(ctx, file)
+	if err != nil || !bytes.Equal(stored, data) {
+		t.Fatalf("ReadUserFile = %q, %v", stored, err)
+	}
+	tampered := *file
+	other := checksumSHA256([]byte("something else"))
+	tampered.ChecksumSHA256 = &other
+	if _, err := svc.ReadUserFile(ctx, &tampered); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	if _, err := svc.GetUserFile(ctx, uuid.New(), file.ID); !errors.Is(err, ErrUserFileNotFound) {
+		t.Errorf("expected ErrUserFileNotFound for another user, got %v", err)
+	}
+	files, total, err := svc.ListUserFiles(ctx, userID, 10, 0)
+	if err != nil || total != 1 || files[0].ID != file.ID {
+		t.Errorf("ListUserFiles = %v, %d, %v", files, total, err)
+	}
+
+	pdf, err := svc.UploadFile(ctx, userID, "statement.pdf", "", []byte("%PDF-1.7\n..."))
+	if err != nil || pdf.Type != fileTypePDF {
+		t.Errorf("expected a pdf upload, got %+v, %v", pdf, err)
+	}
+
+	if _, err := svc.UploadFile(ctx, userID, "empty.csv", "", nil); !errors.Is(err, ErrEmptyFile) {
+		t.Errorf("expected ErrEmptyFile, got %v", err)
+	}
+	if _, err := svc.UploadFile(ctx, userID, "huge.csv", "", make([]byte, maxUserFileSize+1)); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("expected ErrFileTooLarge, got %v", err)
+	}
+	if _, err := svc.UploadFile(ctx, userID, "blob.bin", "", []byte{0x00, 0x01, 0x02, 0xff}); !errors.Is(err, ErrUnsupportedFileType) {
+		t.Errorf("expected ErrUnsupportedFileType, got %v", err)
+	}
+
+	unstored := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, err := unstored.UploadFile(ctx, userID, "march.csv", "", data); !errors.Is(err, ErrFileStorageUnavailable) {
+		t.Errorf("expected ErrFileStorageUnavailable without storage, got %v", err)
+	}
+}
+
+func TestRetryImportJob_RerunsFromStoredFile(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+	repo := &fakeImportRepo{accountCurrency: "USD"}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := NewImportService(repo, logger).WithStorage(store)
+	ctx := context.Background()
+	userID := uuid.New()
+	accountID := uuid.New()
+
+	data := []byte("Date,Description,Amount\n13/02/2024,Coffee,-3.50\n14/02/2024,Salary,1500.00\n")
+	mapping := ColumnMapping{DateCol: 0, DescCol: 1, CategoryCol: -1, AmountCol: 2, DebitCol: -1, CreditCol: -1, DateFormat: "DD/MM/YYYY"}
+
+	result, err := svc.ImportWithOptions(ctx, userID, &accountID, data, mapping, ImportOptions{FileName: "feb.csv", InstitutionName: "Chase"})
+	if err != nil {
+		t.Fatalf("ImportWithOptions failed: %v", err)
+	}
+
+	file := repo.userFiles[0]
+	if file.FileName != "feb.csv" || file.StorageURL == nil || file.ChecksumSHA256 == nil {
+		t.Fatalf("expected the import to store the original file, got %+v", file)
+	}
+
+	if _, err := svc.RetryImportJob(ctx, userID, result.JobID); !errors.Is(err, ErrImportJobNotRetryable) {
+		t.Fatalf("expected ErrImportJobNotRetryable for a succeeded job, got %v", err)
+	}
+
+	errMsg := "database unavailable"
+	if err := repo.FinishImportJob(ctx, result.JobID, "failed", 0, 0, &errMsg); err != nil {
+		t.Fatalf("FinishImportJob failed: %v", err)
+	}
+	if _, err := svc.RetryImportJob(ctx, uuid.New(), result.JobID); !errors.Is(err, ErrImportJobNotFound) {
+		t.Fatalf("expected ErrImportJobNotFound for another user, got %v", err)
+	}
+
+	retry, err := svc.RetryImportJob(ctx, userID, result.JobID)
+	if err != nil {
+		t.Fatalf("RetryImportJob failed: %v", err)
+	}
+	if retry.Status != "pending" || retry.FileID != file.ID || retry.InstitutionName == nil || *retry.InstitutionName != "Chase" {
+		t.Fatalf("unexpected retry job: %+v", retry)
+	}
+
+	runner := NewJobRunner(svc, svc, JobRunnerConfig{
+		Workers:           1,
+		PollInterval:      5 * time.Millisecond,
+		HeartbeatInterval: 5 * time.Millisecond,
+		StaleAfter:        time.Minute,
+	}, logger)
+	runner.Start(ctx)
+	defer runner.Stop()
+
+	job := waitForJob(t, svc, userID, retry.ID)
+	if job.Status != "succeeded" || job.RowsImported != 2 {
+		t.Fatalf("expected retry to import 2 rows, got %q with %d (%v)", job.Status, job.RowsImported, job.ErrorMessage)
+	}
+}
+
+func TestImportFileWithExistingMapping_QueuesJob(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+	repo := &fakeImportRepo{accountCurrency: "USD"}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := NewImportService(repo, logger).WithStorage(store)
+	ctx := context.Background()
+	userID := uuid.New()
+	accountID := uuid.New()
+
+	data := []byte("Date,Description,Amount\n13/02/2024,Coffee,-3.50\n14/02/2024,Salary,1500.00\n")
+	analysis, err := svc.AnalyzeFile(ctx, userID, data)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	mapping := ColumnMapping{DateCol: 0, DescCol: 1, CategoryCol: -1, AmountCol: 2, DebitCol: -1, CreditCol: -1, DateFormat: "DD/MM/YYYY", Delimiter: ','}
+	if err := svc.SaveMapping(ctx, userID, analysis.FileConfig.Fingerprint, "Chase", mapping); err != nil {
+		t.Fatalf("SaveMapping failed: %v", err)
+	}
+	if err := svc.SaveMapping(ctx, userID, "older layout", "Chase", mapping); err != nil {
+		t.Fatalf("SaveMapping failed: %v", err)
+	}
+	saved, stale := repo.mappings[0], repo.mappings[1]
+
+	file, err := svc.UploadFile(ctx, userID, "feb.csv", "", data)
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+
+	if _, err := svc.ImportFileWithExistingMapping(ctx, userID, file.ID, &accountID, stale.ID, ImportOptions{}); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Fatalf("expected ErrFingerprintMismatch, got %v", err)
+	}
+	if _, err := svc.ImportFileWithExistingMapping(ctx, uuid.New(), file.ID, &accountID, saved.ID, ImportOptions{}); !errors.Is(err, ErrUserFileNotFound) {
+		t.Fatalf("expected ErrUserFileNotFound for another user, got %v", err)
+	}
+	if _, err := svc.ImportFileWithExistingMapping(ctx, userID, file.ID, &accountID, uuid.New(), ImportOptions{}); !errors.Is(err, ErrMappingNotFound) {
+		t.Fatalf("expected ErrMappingNotFound, got %v", err)
+	}
+	if len(repo.jobs) != 0 {
+		t.Fatal("expected no import job for rejected requests")
+	}
+
+	queued, err := svc.ImportFileWithExistingMapping(ctx, userID, file.ID, &accountID, saved.ID, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportFileWithExistingMapping failed: %v", err)
+	}
+	if queued.Status != "pending" || queued.FileID != file.ID || queued.InstitutionName == nil || *queued.InstitutionName != "Chase" {
+		t.Fatalf("unexpected queued job: %+v", queued)
+	}
+
+	runner := NewJobRunner(svc, svc, JobRunnerConfig{
+		Workers:           1,
+		PollInterval:      5 * time.Millisecond,
+		HeartbeatInterval: 5 * time.Millisecond,
+		StaleAfter:        time.Minute,
+	}, logger)
+	runner.Start(ctx)
+	defer runner.Stop()
+
+	job := waitForJob(t, svc, userID, queued.ID)
+	if job.Status != "succeeded" || job.RowsImported != 2 {
+		t.Fatalf("expected the re-import to import 2 rows, got %q with %d (%v)", job.Status, job.RowsImported, job.ErrorMessage)
+	}
+	if repo.institutionName != "Chase" {
+		t.Errorf("expected institution name from the mapping, got %q", repo.institutionName)
+	}
+}
+
+func TestUploadDuplicateAndOverlap(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+	stored := &repository.ParsedTransaction{
+		Date:        time.Date(2024, 2, 14, 0, 0, 0, 0, time.UTC),
+		Description: "Salary",
+		AmountCents: 150000,
+	}
+	repo := &fakeImportRepo{
+		accountCurrency: "USD",
+		inserted:        []*repository.ParsedTransaction{stored},
+		existingIDs:     map[string]bool{repository.GenerateExternalID(stored): true},
+	}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil))).WithStorage(store)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	data := []byte("Date,Description,Amount\n2024-02-13,Coffee,-3.50\n2024-02-14,Salary,1500.00\n2024-02-15,Rent,-900.00\n")
+	file, err := svc.UploadFile(ctx, userID, "feb.csv", "", data)
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+
+	_, err = svc.UploadFile(ctx, userID, "feb-again.csv", "", data)
+	var dup *DuplicateFileError
+	if !errors.Is(err, ErrDuplicateFile) || !errors.As(err, &dup) || dup.Existing.ID != file.ID {
+		t.Fatalf("expected a DuplicateFileError for %s, got %v", file.ID, err)
+	}
+	if _, err := svc.UploadFile(ctx, uuid.New(), "feb.csv", "", data); err != nil {
+		t.Fatalf("expected another user to upload the same content, got %v", err)
+	}
+
+	analysis, err := svc.AnalyzeFile(ctx, userID, data)
+	if err != nil || analysis.DuplicateFile == nil || analysis.DuplicateFile.ID != file.ID {
+		t.Fatalf("expected AnalyzeFile to report the earlier upload, got %+v, %v", analysis, err)
+	}
+
+	accountID := uuid.New()
+	report, err := svc.CheckFileOverlap(ctx, userID, file.ID, &accountID)
+	if err != nil {
+		t.Fatalf("CheckFileOverlap failed: %v", err)
+	}
+	if report.DuplicateFile != nil {
+		t.Errorf("expected the file not to be reported as its own duplicate")
+	}
+	if report.RowsExisting != 1 || report.RowsNew != 2 {
+		t.Errorf("expected 1 existing and 2 new rows, got %d and %d", report.RowsExisting, report.RowsNew)
+	}
+	if report.FileFrom == nil || report.FileFrom.Day() != 13 || report.FileTo.Day() != 15 {
+		t.Errorf("unexpected file range %v - %v", report.FileFrom, report.FileTo)
+	}
+	if report.StoredInRange != 1 || report.OverlapFrom == nil || !report.OverlapFrom.Equal(stored.Date) || !report.OverlapTo.Equal(stored.Date) {
+		t.Errorf("unexpected overlap: %d stored, %v - %v", report.StoredInRange, report.OverlapFrom, report.OverlapTo)
+	}
+}
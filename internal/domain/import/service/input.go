@@ -0,0 +1,220 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/camt"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/charset"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/ofx"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/sniffer"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/xlsx"
+)
+
+const (
+	fileTypeCSV  = "csv"
+	fileTypeXLSX = "xlsx"
+	fileTypeOFX  = "ofx"
+	fileTypeCAMT = "camt"
+
+	mimeTypeCSV  = "text/csv"
+	mimeTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimeTypeOFX  = "application/x-ofx"
+	mimeTypeCAMT = "application/xml"
+)
+
+// preparedInput is an uploaded file converted to delimited text for the CSV
+// pipeline, or a structured statement that was parsed directly.
+type preparedInput struct {
+	data     []byte
+	raw      []byte // Original upload, kept for file storage
+	fileType string
+	mimeType string
+	size     int64
+	encoding string // Character encoding delimited text was decoded from
+
+	// Set for OFX/CAMT statements, which bypass column mapping entirely
+	statement *statementInput
+
+	// Spreadsheet-only fields
+	sheets    []string
+	sheet     string
+	headerRow int // -1 when the sniffer should locate the header row
+}
+
+// statementInput is a parsed OFX/CAMT statement.
+type statementInput struct {
+	currencyCode string
+	transactions []*repository.ParsedTransaction
+	errors       []string
+}
+
+// prepareInput detects the upload format. OFX and CAMT.053 statements are parsed
+// directly. Workbooks are flattened into comma-separated rows (blank rows dropped)
+// from the requested sheet, or from the first sheet with a recognizable header row
+// when sheet is empty. Anything else is treated as delimited text and transcoded
+// to UTF-8 from the given encoding, or from the detected one when it is empty.
+func prepareInput(fileData []byte, sheet, encoding string) (*preparedInput, error) {
+	size := int64(len(fileData))
+
+	switch {
+	case ofx.IsOFX(fileData):
+		stmt, err := ofx.Parse(fileData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse OFX statement: %w", err)
+		}
+		return &preparedInput{
+			fileType: fileTypeOFX,
+			mimeType: mimeTypeOFX,
+			size:     size,
+			raw:      fileData,
+			statement: &statementInput{
+				currencyCode: stmt.CurrencyCode,
+				transactions: stmt.Transactions,
+				errors:       stmt.Errors,
+			},
+		}, nil
+	case camt.IsCAMT053(fileData):
+		stmt, err := camt.Parse(fileData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CAMT.053 statement: %w", err)
+		}
+		return &preparedInput{
+			fileType: fileTypeCAMT,
+			mimeType: mimeTypeCAMT,
+			size:     size,
+			raw:      fileData,
+			statement: &statementInput{
+				currencyCode: stmt.CurrencyCode,
+				transactions: stmt.Transactions,
+				errors:       stmt.Errors,
+			},
+		}, nil
+	case !xlsx.IsWorkbook(fileData):
+		data, encoding, err := charset.ToUTF8(fileData, encoding)
+		if err != nil {
+			return nil, err
+		}
+		return &preparedInput{
+			data:      data,
+			fileType:  fileTypeCSV,
+			mimeType:  mimeTypeCSV,
+			size:      size,
+			encoding:  encoding,
+			raw:       fileData,
+			headerRow: -1,
+		}, nil
+	}
+
+	wb, err := xlsx.Open(fileData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+
+	input := &preparedInput{
+		fileType: fileTypeXLSX,
+		mimeType: mimeTypeXLSX,
+		size:     size,
+		raw:      fileData,
+		sheets:   wb.SheetNames(),
+	}
+
+	candidates := input.sheets
+	if sheet != "" {
+		candidates = []string{sheet}
+	}
+
+	var lastErr error
+	for _, name := range candidates {
+		rows, err := wb.Rows(name)
+		if err != nil {
+			return nil, err
+		}
+		rows = dropBlankRows(rows)
+
+		headerRow, err := sniffer.DetectHeaderRow(rows)
+		if err != nil {
+			lastErr = fmt.Errorf("sheet %q: %w", name, err)
+			continue
+		}
+
+		data, err := rowsToCSV(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert sheet %q: %w", name, err)
+		}
+
+		input.data = data
+		input.sheet = name
+		input.headerRow = headerRow
+		return input, nil
+	}
+
+	if lastErr == nil {
+		lastErr = xlsx.ErrEmptyWorkbook
+	}
+	return nil, lastErr
+}
+
+// detectOptions returns the sniffer overrides implied by the input format.
+// Spreadsheets always carry their own header position and delimiter.
+func (in *preparedInput) detectOptions() *sniffer.DetectOptions {
+	// prepareInput already transcoded the data.
+	if in.fileType == fileTypeXLSX {
+		return &sniffer.DetectOptions{HeaderRowIndex: in.headerRow, Delimiter: ',', Encoding: charset.UTF8}
+	}
+	return &sniffer.DetectOptions{HeaderRowIndex: -1, Encoding: charset.UTF8}
+}
+
+// extension returns the file extension used for the stored file name.
+func (in *preparedInput) extension() string {
+	if in.fileType == fileTypeCAMT {
+		return "xml"
+	}
+	return in.fileType
+}
+
+func dropBlankRows(rows [][]string) [][]string {
+	kept := rows[:0]
+	for _, row := range rows {
+		for _, cell := range row {
+			if strings.TrimSpace(cell) != "" {
+				kept = append(kept, row)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// rowsToCSV writes rows as comma-separated text, one physical line per row,
+// so line-based header detection stays aligned with record numbers. Rows are
+// padded to the widest row because the sheet reader drops trailing empty cells.
+func rowsToCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	lineBreaks := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+	for _, row := range rows {
+		record := make([]string, width)
+		for i, cell := range row {
+			record[i] = lineBreaks.Replace(cell)
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/charset"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+var (
+	// ErrImportJobNotFound is returned when an import job does not exist or belongs to another user.
+	ErrImportJobNotFound = errors.New("import job not found")
+	// ErrImportJobFinished is returned when canceling a job that already finished.
+	ErrImportJobFinished = errors.New("import job already finished")
+	// ErrUserFileNotFound is returned when an uploaded file does not exist or belongs to another user.
+	ErrUserFileNotFound = errors.New("file not found")
+	// ErrFileNotImportable is returned when a job is requested for a file type the importer cannot read.
+	ErrFileNotImportable = errors.New("file type cannot be imported as transactions")
+	// ErrImportJobNotRetryable is returned when retrying a job that has not failed or been canceled.
+	ErrImportJobNotRetryable = errors.New("only failed or canceled import jobs can be retried")
+)
+
+// ImportJobRequest describes a queued import of an uploaded file.
+type ImportJobRequest struct {
+	FileID    uuid.UUID
+	AccountID *uuid.UUID
+	MappingID *uuid.UUID // Stored bank mapping; takes precedence over Mapping
+	Mapping   ColumnMapping
+	Options   ImportOptions
+}
+
+// jobOptions is the JSON stored in import_jobs.options for queued imports.
+// Timezone, institution and date format live in their own columns.
+type jobOptions struct {
+	MappingID  *uuid.UUID  `json:"mapping_id,omitempty"`
+	Mapping    *jobMapping `json:"mapping,omitempty"`
+	Sheet      string      `json:"sheet,omitempty"`
+	HeaderRows int         `json:"header_rows,omitempty"`
+	Encoding   string      `json:"encoding,omitempty"`
+}
+
+// jobMapping is the serializable part of a ColumnMapping.
+type jobMapping struct {
+	DateCol          int    `json:"date_col"`
+	DescCol          int    `json:"desc_col"`
+	CategoryCol      int    `json:"category_col"`
+	AmountCol        int    `json:"amount_col"`
+	DebitCol         int    `json:"debit_col"`
+	CreditCol        int    `json:"credit_col"`
+	IsDoubleEntry    bool   `json:"is_double_entry"`
+	IsEuropeanFormat bool   `json:"is_european_format"`
+	Delimiter        string `json:"delimiter,omitempty"`
+	SkipLines        int    `json:"skip_lines,omitempty"`
+}
+
+func newJobMapping(m ColumnMapping) *jobMapping {
+	stored := &jobMapping{
+		DateCol:          m.DateCol,
+		DescCol:          m.DescCol,
+		CategoryCol:      m.CategoryCol,
+		AmountCol:        m.AmountCol,
+		DebitCol:         m.DebitCol,
+		CreditCol:        m.CreditCol,
+		IsDoubleEntry:    m.IsDoubleEntry,
+		IsEuropeanFormat: m.IsEuropeanFormat,
+		SkipLines:        m.SkipLines,
+	}
+	if m.Delimiter != 0 {
+		stored.Delimiter = string(m.Delimiter)
+	}
+	return stored
+}
+
+func (m *jobMapping) columnMapping(dateFormat string) ColumnMapping {
+	mapping := ColumnMapping{
+		DateCol:          m.DateCol,
+		DescCol:          m.DescCol,
+		CategoryCol:      m.CategoryCol,
+		AmountCol:        m.AmountCol,
+		DebitCol:         m.DebitCol,
+		CreditCol:        m.CreditCol,
+		IsDoubleEntry:    m.IsDoubleEntry,
+		IsEuropeanFormat: m.IsEuropeanFormat,
+		DateFormat:       dateFormat,
+		SkipLines:        m.SkipLines,
+	}
+	if delimiter, _ := utf8.DecodeRuneInString(m.Delimiter); delimiter != utf8.RuneError {
+		mapping.Delimiter = delimiter
+	}
+	return mapping
+}
+
+// importableFileTypes are the user_files types RunImportJob can read.
+var importableFileTypes = map[string]bool{
+	fileTypeCSV:  true,
+	fileTypeXLSX: true,
+	fileTypeOFX:  true,
+	fileTypeCAMT: true,
+}
+
+// CreateImportJob queues an import of a previously uploaded file. The job starts
+// as "pending" and is picked up by a JobRunner.
+func (s *ImportService) CreateImportJob(ctx context.Context, userID uuid.UUID, req ImportJobRequest) (*repository.ImportJob, error) {
+	file, err := s.repo.GetUserFileByID(ctx, req.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load file: %w", err)
+	}
+	if file == nil || file.UserID != userID {
+		return nil, ErrUserFileNotFound
+	}
+	if !importableFileTypes[file.Type] {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotImportable, file.Type)
+	}
+
+	if req.Options.Encoding != "" {
+		if _, err := charset.Normalize(req.Options.Encoding); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := jobOptions{
+		Sheet:      req.Options.Sheet,
+		HeaderRows: req.Options.HeaderRows,
+		Encoding:   req.Options.Encoding,
+	}
+	if req.MappingID != nil {
+		stored, err := s.repo.GetMappingByID(ctx, *req.MappingID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mapping: %w", err)
+		}
+		if stored == nil || (stored.UserID != nil && *stored.UserID != userID) {
+			return nil, ErrMappingNotFound
+		}
+		opts.MappingID = req.MappingID
+	} else {
+		opts.Mapping = newJobMapping(req.Mapping)
+	}
+
+	job := &repository.ImportJob{
+		UserID:    userID,
+		FileID:    file.ID,
+		Kind:      "transactions",
+		Status:    "pending",
+		AccountID: req.AccountID,
+	}
+	dateFormat := ""
+	if req.MappingID == nil {
+		dateFormat = req.Mapping.DateFormat
+	}
+	if err := describeJob(job, opts, req.Options, dateFormat); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateImportJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ImportFileWithExistingMapping queues an import of an uploaded file with a
+// stored bank mapping: the one-click re-import of a bank export the user mapped
+// before. The file is checked against the mapping's layout up front, so a stale
+// mapping is rejected here instead of failing the job.
+func (s *ImportService) ImportFileWithExistingMapping(ctx context.Context, userID uuid.UUID, fileID uuid.UUID, accountID *uuid.UUID, mappingID uuid.UUID, opts ImportOptions) (*repository.ImportJob, error) {
+	file, err := s.GetUserFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if !importableFileTypes[file.Type] {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotImportable, file.Type)
+	}
+
+	data, err := s.ReadUserFile(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	input, err := prepareInput(data, opts.Sheet, opts.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	_, stored, err := s.resolveStoredMapping(ctx, userID, mappingID, input)
+	if err != nil {
+		return nil, err
+	}
+	if opts.InstitutionName == "" && stored.BankName != nil {
+		opts.InstitutionName = *stored.BankName
+	}
+
+	return s.CreateImportJob(ctx, userID, ImportJobRequest{
+		FileID:    file.ID,
+		AccountID: accountID,
+		MappingID: &mappingID,
+		Options:   opts,
+	})
+}
+
+// describeJob records how an import was requested: the JSON options plus the
+// timezone, institution and date format columns.
+func describeJob(job *repository.ImportJob, stored jobOptions, opts ImportOptions, dateFormat string) error {
+	rawOptions, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode import options: %w", err)
+	}
+	job.Options = rawOptions
+
+	if opts.Timezone != "" {
+		job.Timezone = &opts.Timezone
+	}
+	if opts.InstitutionName != "" {
+		job.InstitutionName = &opts.InstitutionName
+	}
+	if dateFormat != "" {
+		job.DateFormat = &dateFormat
+	}
+	return nil
+}
+
+// GetImportJob returns one of the user's import jobs.
+func (s *ImportService) GetImportJob(ctx context.Context, userID uuid.UUID, jobID uuid.UUID) (*repository.ImportJob, error) {
+	job, err := s.repo.GetImportJobByID(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+	if job == nil || job.UserID != userID {
+		return nil, ErrImportJobNotFound
+	}
+	return job, nil
+}
+
+// ListImportJobs returns the user's import jobs, newest first, and the total count.
+func (s *ImportService) ListImportJobs(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*repository.ImportJob, int64, error) {
+	jobs, total, err := s.repo.ListImportJobs(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list import jobs: %w", err)
+	}
+	return jobs, total, nil
+}
+
+// CancelImportJob cancels a pending or running job. A running job stops at its
+// next heartbeat; transactions it already stored are kept.
+func (s *ImportService) CancelImportJob(ctx context.Context, userID uuid.UUID, jobID uuid.UUID) error {
+	canceled, err := s.repo.CancelImportJob(ctx, userID, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel import job: %w", err)
+	}
+	if canceled {
+		return nil
+	}
+
+	if _, err := s.GetImportJob(ctx, userID, jobID); err != nil {
+		return err
+	}
+	return ErrImportJobFinished
+}
+
+// RetryImportJob queues a new job that re-runs a failed or canceled import from
+// the stored file, with the same account, mapping and options. Rows the earlier
+// attempt already stored are skipped as duplicates.
+func (s *ImportService) RetryImportJob(ctx context.Context, userID uuid.UUID, jobID uuid.UUID) (*repository.ImportJob, error) {
+	previous, err := s.GetImportJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if previous.Status != "failed" && previous.Status != "canceled" {
+		return nil, ErrImportJobNotRetryable
+	}
+
+	file, err := s.GetUserFile(ctx, userID, previous.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.StorageURL == nil {
+		return nil, ErrFileNotStored
+	}
+	if !importableFileTypes[file.Type] {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotImportable, file.Type)
+	}
+
+	job := &repository.ImportJob{
+		UserID:          userID,
+		FileID:          file.ID,
+		Kind:            previous.Kind,
+		Status:          "pending",
+		AccountID:       previous.AccountID,
+		Timezone:        previous.Timezone,
+		DateFormat:      previous.DateFormat,
+		InstitutionName: previous.InstitutionName,
+		Options:         previous.Options,
+	}
+	if err := s.repo.CreateImportJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	return job, nil
+}
+
+// RunImportJob runs a claimed job against the file content. Rows stored by an
+// earlier, interrupted run are counted up front; re-inserting them is a no-op
+// because BulkInsertTransactions skips duplicates. If ctx is canceled the job is
+// left as is so the caller can requeue it.
+func (s *ImportService) RunImportJob(ctx context.Context, job *repository.ImportJob, fileData []byte) (*ImportResult, error) {
+	fail := func(err error) (*ImportResult, error) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errMsg := err.Error()
+		if finishErr := s.repo.FinishImportJob(ctx, job.ID, "failed", 0, 0, &errMsg); finishErr != nil {
+			s.logger.Warn("failed to mark import job as failed", "job_id", job.ID, "error", finishErr)
+		}
+		return nil, err
+	}
+
+	var opts jobOptions
+	if len(job.Options) > 0 {
+		if err := json.Unmarshal(job.Options, &opts); err != nil {
+			return fail(fmt.Errorf("invalid import job options: %w", err))
+		}
+	}
+
+	input, err := prepareInput(fileData, opts.Sheet, opts.Encoding)
+	if err != nil {
+		return fail(fmt.Errorf("failed to read file: %w", err))
+	}
+
+	importOpts := ImportOptions{
+		HeaderRows: opts.HeaderRows,
+		Sheet:      opts.Sheet,
+		Encoding:   opts.Encoding,
+	}
+	if job.Timezone != nil {
+		importOpts.Timezone = *job.Timezone
+	}
+	if job.InstitutionName != nil {
+		importOpts.InstitutionName = *job.InstitutionName
+	}
+
+	// Without a mapping every column is auto-detected.
+	mapping := ColumnMapping{DateCol: -1, DescCol: -1, CategoryCol: -1, AmountCol: -1, DebitCol: -1, CreditCol: -1}
+	switch {
+	case opts.MappingID != nil:
+		var stored *repository.BankMapping
+		mapping, stored, err = s.resolveStoredMapping(ctx, job.UserID, *opts.MappingID, input)
+		if err != nil {
+			return fail(err)
+		}
+		if importOpts.InstitutionName == "" && stored.BankName != nil {
+			importOpts.InstitutionName = *stored.BankName
+		}
+	case opts.Mapping != nil:
+		dateFormat := ""
+		if job.DateFormat != nil {
+			dateFormat = *job.DateFormat
+		}
+		mapping = opts.Mapping.columnMapping(dateFormat)
+	}
+
+	plan, err := s.planImport(ctx, job.UserID, job.AccountID, input, mapping, importOpts)
+	if err != nil {
+		return fail(err)
+	}
+
+	alreadyImported, err := s.repo.CountTransactionsByImportJob(ctx, job.ID)
+	if err != nil {
+		return fail(err)
+	}
+
+	return s.executeImport(ctx, job, plan, importOpts.InstitutionName, alreadyImported, true)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+// OverlapReport tells the user what an import would add on top of the data they
+// already have: an identical earlier upload, rows already stored under the same
+// external_id, and stored transactions inside the file's date range.
+type OverlapReport struct {
+	DuplicateFile *repository.UserFile // Earlier upload with byte-identical content, if any
+	RowsExisting  int                  // Parsed rows whose external_id is already stored
+	RowsNew       int                  // Parsed rows the import would insert
+
+	FileFrom *time.Time // First posting date in the file; nil when no row parsed
+	FileTo   *time.Time // Last posting date in the file
+
+	// Transactions already stored (for the account, when given) on the days the
+	// file covers, with the earliest and latest of their dates
+	StoredInRange int64
+	OverlapFrom   *time.Time
+	OverlapTo     *time.Time
+}
+
+// CheckFileOverlap reports how an uploaded file overlaps with stored transactions,
+// so the user can decide whether to queue an import. Columns are auto-detected.
+func (s *ImportService) CheckFileOverlap(ctx context.Context, userID uuid.UUID, fileID uuid.UUID, accountID *uuid.UUID) (*OverlapReport, error) {
+	file, err := s.GetUserFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if !importableFileTypes[file.Type] {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotImportable, file.Type)
+	}
+
+	data, err := s.ReadUserFile(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := ColumnMapping{DateCol: -1, DescCol: -1, CategoryCol: -1, AmountCol: -1, DebitCol: -1, CreditCol: -1}
+	preview, err := s.PreviewImport(ctx, userID, accountID, data, mapping, PreviewOptions{Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	report := preview.Overlap
+	// The stored file trivially matches itself.
+	if report.DuplicateFile != nil && report.DuplicateFile.ID == file.ID {
+		report.DuplicateFile = nil
+	}
+	return report, nil
+}
+
+// buildOverlapReport summarizes the preview rows against stored data. existing is
+// the number of rows whose external_id is already stored.
+func (s *ImportService) buildOverlapReport(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, checksum string, rows []PreviewRow, existing int) (*OverlapReport, error) {
+	duplicateFile, err := s.findDuplicateFile(ctx, userID, checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OverlapReport{
+		DuplicateFile: duplicateFile,
+		RowsExisting:  existing,
+	}
+
+	for _, row := range rows {
+		if row.Transaction == nil {
+			continue
+		}
+		if !row.Duplicate {
+			report.RowsNew++
+		}
+		date := row.Transaction.Date
+		if report.FileFrom == nil || date.Before(*report.FileFrom) {
+			report.FileFrom = &date
+		}
+		if report.FileTo == nil || date.After(*report.FileTo) {
+			report.FileTo = &date
+		}
+	}
+	if report.FileFrom == nil {
+		return report, nil
+	}
+
+	// Dates parsed from files are midnight; cover the whole last day.
+	to := report.FileTo.Add(24*time.Hour - time.Nanosecond)
+	stats, err := s.repo.GetTransactionDateStats(ctx, userID, accountID, *report.FileFrom, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check date range overlap: %w", err)
+	}
+	report.StoredInRange = stats.Count
+	report.OverlapFrom = stats.First
+	report.OverlapTo = stats.Last
+
+	return report, nil
+}
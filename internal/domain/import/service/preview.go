@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/sniffer"
+)
+
+const (
+	defaultPreviewRows = 50
+	maxPreviewRows     = 500
+)
+
+// PreviewOptions configures a dry-run import.
+type PreviewOptions struct {
+	ImportOptions
+	Limit int // Rows to return; defaults to 50, capped at 500
+}
+
+// PreviewRow is one row of a dry-run import.
+type PreviewRow struct {
+	LineNum     int
+	Transaction *repository.ParsedTransaction // nil when the row failed to parse
+	Error       string
+	Duplicate   bool // An existing transaction or an earlier row has the same external_id
+}
+
+// PreviewResult describes what ImportWithOptions would do with a file.
+type PreviewResult struct {
+	FileType     string
+	CurrencyCode string
+	FileConfig   *sniffer.FileConfig      // nil for structured statements (OFX, CAMT.053)
+	Mapping      ColumnMapping            // Resolved mapping, including detected date and number format
+	Dialect      *sniffer.RegionalDialect // Probed from the sample rows; nil for statements
+
+	Rows          []PreviewRow // First rows in file order, with categorization applied
+	RowsTotal     int
+	RowsValid     int // Parsed rows, including duplicates
+	RowsFailed    int
+	RowsDuplicate int
+	Errors        []string // File-level errors not tied to a single row
+
+	Overlap *OverlapReport // How the file overlaps with stored files and transactions
+}
+
+// PreviewImport runs the import pipeline without storing anything: the file is
+// parsed with the same mapping resolution as ImportWithOptions, every parsed row
+// is checked against existing external IDs, and the first opts.Limit rows are
+// categorized and returned.
+func (s *ImportService) PreviewImport(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, fileData []byte, mapping ColumnMapping, opts PreviewOptions) (*PreviewResult, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultPreviewRows
+	}
+	if limit > maxPreviewRows {
+		limit = maxPreviewRows
+	}
+
+	input, err := prepareInput(fileData, opts.Sheet, opts.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	plan, err := s.planImport(ctx, userID, accountID, input, mapping, opts.ImportOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	parseCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results, preErrors := plan.produce(parseCtx)
+
+	var rows []PreviewRow
+	for result := range results {
+		row := PreviewRow{LineNum: result.lineNum, Transaction: result.tx}
+		if result.err != nil {
+			row.Error = result.err.Error()
+		}
+		rows = append(rows, row)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Rows are parsed concurrently; restore file order.
+	sort.Slice(rows, func(i, j int) bool { return rows[i].LineNum < rows[j].LineNum })
+
+	preview := &PreviewResult{
+		FileType:     input.fileType,
+		CurrencyCode: plan.currencyCode,
+		FileConfig:   plan.config,
+		Mapping:      plan.mapping,
+		Errors:       preErrors,
+		RowsFailed:   len(preErrors),
+	}
+	if plan.config != nil {
+		amountIdx := plan.mapping.AmountCol
+		if plan.mapping.IsDoubleEntry && amountIdx < 0 {
+			amountIdx = plan.mapping.DebitCol
+		}
+		preview.Dialect = sniffer.ProbeDialect(plan.config.SampleRows, amountIdx, plan.mapping.DateCol)
+	}
+
+	existing, err := s.markDuplicates(ctx, userID, rows)
+	if err != nil {
+		return nil, err
+	}
+	preview.Overlap, err = s.buildOverlapReport(ctx, userID, accountID, checksumSHA256(input.raw), rows, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		switch {
+		case row.Transaction == nil:
+			preview.RowsFailed++
+		case row.Duplicate:
+			preview.RowsValid++
+			preview.RowsDuplicate++
+		default:
+			preview.RowsValid++
+		}
+	}
+	preview.RowsTotal = preview.RowsValid + preview.RowsFailed
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	sample := make([]*repository.ParsedTransaction, 0, len(rows))
+	for _, row := range rows {
+		if row.Transaction != nil {
+			sample = append(sample, row.Transaction)
+		}
+	}
+	s.enrichBatch(ctx, userID, accountID, opts.InstitutionName, sample)
+	preview.Rows = rows
+
+	return preview, nil
+}
+
+// markDuplicates flags rows that BulkInsertTransactions would skip: rows whose
+// external_id already exists for the user and repeats within the file itself.
+// It returns how many rows matched stored transactions.
+func (s *ImportService) markDuplicates(ctx context.Context, userID uuid.UUID, rows []PreviewRow) (int, error) {
+	parsed := make([]*repository.ParsedTransaction, 0, len(rows))
+	for _, row := range rows {
+		if row.Transaction != nil {
+			parsed = append(parsed, row.Transaction)
+		}
+	}
+	if err := s.adoptLegacyExternalIDs(ctx, userID, parsed); err != nil {
+		return 0, err
+	}
+
+	externalIDs := make([]string, len(rows))
+	for i, row := range rows {
+		if row.Transaction != nil {
+			externalIDs[i] = repository.GenerateExternalID(row.Transaction)
+		}
+	}
+
+	seen := make(map[string]bool, len(rows))
+	stored := 0
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := min(start+importBatchSize, len(rows))
+
+		batch := make([]string, 0, end-start)
+		for _, id := range externalIDs[start:end] {
+			if id != "" {
+				batch = append(batch, id)
+			}
+		}
+		existing, err := s.repo.FindExistingExternalIDs(ctx, userID, repository.SourceImport, batch)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check for duplicates: %w", err)
+		}
+
+		for i := start; i < end; i++ {
+			id := externalIDs[i]
+			if id == "" {
+				continue
+			}
+			if existing[id] {
+				stored++
+			}
+			rows[i].Duplicate = existing[id] || seen[id]
+			seen[id] = true
+		}
+	}
+
+	return stored, nil
+}
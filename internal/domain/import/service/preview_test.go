@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+type fakeCategorizer struct{}
+
+func (fakeCategorizer) CategorizeBatch(ctx context.Context, userID uuid.UUID, txs []CategorizationInput) ([]*CategorizationResult, error) {
+	results := make([]*CategorizationResult, len(txs))
+	for i, tx := range txs {
+		results[i] = &CategorizationResult{CleanMerchantName: strings.ToUpper(tx.Description)}
+	}
+	return results, nil
+}
+
+func TestPreviewImport(t *testing.T) {
+	data := []byte(strings.Join([]string{
+		"Data;Descrição;Montante",
+		"02/01/2024;Pingo Doce;-45,23",
+		"03/01/2024;Continente;-12,00",
+		"03/01/2024;Continente;-12,00",
+		"bad;Broken;-1,00",
+		"05/01/2024;Salário;1.500,00",
+		"",
+	}, "\n"))
+
+	already := &repository.ParsedTransaction{
+		Date:        time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Description: "Pingo Doce",
+		AmountCents: -4523,
+	}
+
+	repo := &fakeImportRepo{
+		accountCurrency: "EUR",
+		existingIDs:     map[string]bool{repository.GenerateExternalID(already): true},
+	}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.WithCategorizationService(fakeCategorizer{})
+
+	accountID := uuid.New()
+	mapping := ColumnMapping{DateCol: -1, DescCol: -1, CategoryCol: -1, AmountCol: -1, DebitCol: -1, CreditCol: -1}
+	preview, err := svc.PreviewImport(context.Background(), uuid.New(), &accountID, data, mapping, PreviewOptions{Limit: 3})
+	if err != nil {
+		t.Fatalf("PreviewImport failed: %v", err)
+	}
+
+	if len(repo.userFiles) != 0 || len(repo.jobs) != 0 || len(repo.inserted) != 0 {
+		t.Fatal("expected preview not to write anything")
+	}
+	if preview.CurrencyCode != "EUR" || preview.FileType != fileTypeCSV {
+		t.Errorf("unexpected currency/file type: %q %q", preview.CurrencyCode, preview.FileType)
+	}
+	if preview.Dialect == nil || !preview.Dialect.IsEuropeanFormat || !preview.Mapping.IsEuropeanFormat {
+		t.Errorf("expected European dialect, got %+v", preview.Dialect)
+	}
+	if preview.RowsTotal != 5 || preview.RowsValid != 4 || preview.RowsFailed != 1 || preview.RowsDuplicate != 1 {
+		t.Errorf("unexpected counts: total %d valid %d failed %d duplicate %d",
+			preview.RowsTotal, preview.RowsValid, preview.RowsFailed, preview.RowsDuplicate)
+	}
+	if preview.Overlap == nil || preview.Overlap.RowsExisting != 1 || preview.Overlap.RowsNew != 3 {
+		t.Errorf("unexpected overlap report: %+v", preview.Overlap)
+	}
+
+	if len(preview.Rows) != 3 {
+		t.Fatalf("expected 3 preview rows, got %d", len(preview.Rows))
+	}
+	// Pingo Doce was stored under its legacy ID; the second Continente row is a
+	// separate purchase, not a copy of the first.
+	wantDuplicate := []bool{true, false, false}
+	for i, row := range preview.Rows {
+		if row.Transaction == nil {
+			t.Fatalf("row %d: unexpected error %q", i, row.Error)
+		}
+		if row.Duplicate != wantDuplicate[i] {
+			t.Errorf("row %d (line %d): expected duplicate=%v", i, row.LineNum, wantDuplicate[i])
+		}
+		if row.Transaction.MerchantName != strings.ToUpper(row.Transaction.Description) {
+			t.Errorf("row %d: expected categorization to run, got merchant %q", i, row.Transaction.MerchantName)
+		}
+	}
+	if preview.Rows[1].Transaction.AmountCents != -1200 {
+		t.Errorf("expected -1200, got %d", preview.Rows[1].Transaction.AmountCents)
+	}
+}
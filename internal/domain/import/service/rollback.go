@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+// BatchRetention is how long the transactions of a rolled back import stay
+// restorable before they are purged.
+const BatchRetention = 30 * 24 * time.Hour
+
+var (
+	// ErrImportJobActive is returned when rolling back a job that is still pending or running.
+	ErrImportJobActive = errors.New("import job is still running")
+	// ErrImportJobNotRolledBack is returned when restoring or purging a job that was not rolled back.
+	ErrImportJobNotRolledBack = errors.New("import job was not rolled back")
+	// ErrImportBatchPurged is returned when restoring a batch whose transactions were already purged.
+	ErrImportBatchPurged = errors.New("import batch was purged")
+)
+
+// RollbackImportJob deletes the transactions a finished job stored and marks
+// the job rolled back. The transactions are only hidden: RestoreImportJob
+// brings them back until they are purged after BatchRetention. It returns the
+// number of transactions deleted.
+func (s *ImportService) RollbackImportJob(ctx context.Context, userID uuid.UUID, jobID uuid.UUID) (int, error) {
+	deleted, ok, err := s.repo.RollbackImportJob(ctx, userID, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll back import job: %w", err)
+	}
+	if ok {
+		return deleted, nil
+	}
+
+	job, err := s.GetImportJob(ctx, userID, jobID)
+	if err != nil {
+		return 0, err
+	}
+	if job.Status == "rolled_back" {
+		return 0, nil
+	}
+	return 0, ErrImportJobActive
+}
+
+// RestoreImportJob undoes a rollback within the retention window. Rows that
+// were imported again since the rollback stay deleted, so restoring never
+// creates duplicates. It returns the number of transactions restored.
+func (s *ImportService) RestoreImportJob(ctx context.Context, userID uuid.UUID, jobID uuid.UUID) (int, error) {
+	job, err := s.GetImportJob(ctx, userID, jobID)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkRolledBack(job); err != nil {
+		return 0, err
+	}
+	if until := RestorableUntil(job); until != nil && until.Before(time.Now()) {
+		return 0, ErrImportBatchPurged
+	}
+
+	restored, ok, err := s.repo.RestoreImportJob(ctx, userID, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore import job: %w", err)
+	}
+	if !ok {
+		return 0, ErrImportJobNotRolledBack
+	}
+	return restored, nil
+}
+
+// PurgeImportJob permanently deletes the transactions of a rolled back job
+// without waiting for the retention window. It returns the number purged.
+func (s *ImportService) PurgeImportJob(ctx context.Context, userID uuid.UUID, jobID uuid.UUID) (int, error) {
+	job, err := s.GetImportJob(ctx, userID, jobID)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkRolledBack(job); err != nil {
+		if errors.Is(err, ErrImportBatchPurged) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	purged, ok, err := s.repo.PurgeImportJob(ctx, userID, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge import job: %w", err)
+	}
+	if !ok {
+		return 0, ErrImportJobNotRolledBack
+	}
+	return purged, nil
+}
+
+// PurgeExpiredImportJobs purges every batch rolled back more than
+// BatchRetention before now and returns how many jobs were purged.
+func (s *ImportService) PurgeExpiredImportJobs(ctx context.Context, now time.Time) (int, error) {
+	purged, err := s.repo.PurgeExpiredImportJobs(ctx, now.Add(-BatchRetention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired import jobs: %w", err)
+	}
+	return purged, nil
+}
+
+// RestorableUntil returns when a rolled back job's transactions are purged,
+// or nil when the job was not rolled back or is already purged.
+func RestorableUntil(job *repository.ImportJob) *time.Time {
+	if job.RolledBackAt == nil || job.PurgedAt != nil {
+		return nil
+	}
+	until := job.RolledBackAt.Add(BatchRetention)
+	return &until
+}
+
+func checkRolledBack(job *repository.ImportJob) error {
+	if job.Status != "rolled_back" {
+		return ErrImportJobNotRolledBack
+	}
+	if job.PurgedAt != nil {
+		return ErrImportBatchPurged
+	}
+	return nil
+}
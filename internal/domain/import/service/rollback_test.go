@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+func TestRollbackImportJob_RestoreAndPurge(t *testing.T) {
+	userID := uuid.New()
+	repo := &fakeImportRepo{}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	job := &repository.ImportJob{UserID: userID, FileID: uuid.New(), Kind: "transactions", Status: "succeeded"}
+	if err := repo.CreateImportJob(ctx, job); err != nil {
+		t.Fatalf("CreateImportJob failed: %v", err)
+	}
+	repo.insertedByJob = map[uuid.UUID]int{job.ID: 3}
+
+	if _, err := svc.RollbackImportJob(ctx, uuid.New(), job.ID); !errors.Is(err, ErrImportJobNotFound) {
+		t.Fatalf("expected ErrImportJobNotFound for another user, got %v", err)
+	}
+
+	deleted, err := svc.RollbackImportJob(ctx, userID, job.ID)
+	if err != nil {
+		t.Fatalf("RollbackImportJob failed: %v", err)
+	}
+	if deleted != 3 {
+		t.Fatalf("expected 3 transactions deleted, got %d", deleted)
+	}
+	rolledBack, err := svc.GetImportJob(ctx, userID, job.ID)
+	if err != nil {
+		t.Fatalf("GetImportJob failed: %v", err)
+	}
+	if rolledBack.Status != "rolled_back" {
+		t.Fatalf("expected status rolled_back, got %q", rolledBack.Status)
+	}
+	until := RestorableUntil(rolledBack)
+	if until == nil || until.Sub(*rolledBack.RolledBackAt) != BatchRetention {
+		t.Fatalf("expected the batch to be restorable for %v, got %v", BatchRetention, until)
+	}
+
+	// Rolling back twice is a no-op.
+	if deleted, err := svc.RollbackImportJob(ctx, userID, job.ID); err != nil || deleted != 0 {
+		t.Fatalf("expected a repeated rollback to delete nothing, got %d, %v", deleted, err)
+	}
+
+	restored, err := svc.RestoreImportJob(ctx, userID, job.ID)
+	if err != nil {
+		t.Fatalf("RestoreImportJob failed: %v", err)
+	}
+	if restored != 3 {
+		t.Fatalf("expected 3 transactions restored, got %d", restored)
+	}
+	if job, _ := svc.GetImportJob(ctx, userID, job.ID); job.Status != "succeeded" || job.RolledBackAt != nil {
+		t.Fatalf("expected the job to be succeeded again, got %q (rolled back at %v)", job.Status, job.RolledBackAt)
+	}
+	if _, err := svc.RestoreImportJob(ctx, userID, job.ID); !errors.Is(err, ErrImportJobNotRolledBack) {
+		t.Fatalf("expected ErrImportJobNotRolledBack, got %v", err)
+	}
+	if _, err := svc.PurgeImportJob(ctx, userID, job.ID); !errors.Is(err, ErrImportJobNotRolledBack) {
+		t.Fatalf("expected ErrImportJobNotRolledBack when purging a live batch, got %v", err)
+	}
+
+	if _, err := svc.RollbackImportJob(ctx, userID, job.ID); err != nil {
+		t.Fatalf("RollbackImportJob failed: %v", err)
+	}
+	purged, err := svc.PurgeImportJob(ctx, userID, job.ID)
+	if err != nil {
+		t.Fatalf("PurgeImportJob failed: %v", err)
+	}
+	if purged != 3 {
+		t.Fatalf("expected 3 transactions purged, got %d", purged)
+	}
+	if _, err := svc.RestoreImportJob(ctx, userID, job.ID); !errors.Is(err, ErrImportBatchPurged) {
+		t.Fatalf("expected ErrImportBatchPurged, got %v", err)
+	}
+}
+
+func TestRollbackImportJob_RejectsActiveJobs(t *testing.T) {
+	userID := uuid.New()
+	repo := &fakeImportRepo{}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	for _, status := range []string{"pending", "running"} {
+		job := &repository.ImportJob{UserID: userID, FileID: uuid.New(), Kind: "transactions", Status: status}
+		if err := repo.CreateImportJob(ctx, job); err != nil {
+			t.Fatalf("CreateImportJob failed: %v", err)
+		}
+		if _, err := svc.RollbackImportJob(ctx, userID, job.ID); !errors.Is(err, ErrImportJobActive) {
+			t.Errorf("%s job: expected ErrImportJobActive, got %v", status, err)
+		}
+	}
+}
+
+func TestPurgeExpiredImportJobs(t *testing.T) {
+	userID := uuid.New()
+	repo := &fakeImportRepo{}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	var jobs []*repository.ImportJob
+	for range 2 {
+		job := &repository.ImportJob{UserID: userID, FileID: uuid.New(), Kind: "transactions", Status: "succeeded"}
+		if err := repo.CreateImportJob(ctx, job); err != nil {
+			t.Fatalf("CreateImportJob failed: %v", err)
+		}
+		if _, err := svc.RollbackImportJob(ctx, userID, job.ID); err != nil {
+			t.Fatalf("RollbackImportJob failed: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	expired := time.Now().Add(-BatchRetention - time.Hour)
+	repo.jobs[0].RolledBackAt = &expired
+
+	purged, err := svc.PurgeExpiredImportJobs(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("PurgeExpiredImportJobs failed: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected 1 expired job purged, got %d", purged)
+	}
+
+	if _, err := svc.RestoreImportJob(ctx, userID, jobs[0].ID); !errors.Is(err, ErrImportBatchPurged) {
+		t.Errorf("expected the expired batch to be purged, got %v", err)
+	}
+	if _, err := svc.RestoreImportJob(ctx, userID, jobs[1].ID); err != nil {
+		t.Errorf("expected the recent batch to stay restorable, got %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+// ErrFileStorageUnavailable is returned when a job's file content cannot be loaded
+// because no FileSource is configured.
+var ErrFileStorageUnavailable = errors.New("file storage is not configured")
+
+// FileSource loads the stored content of an uploaded file.
+type FileSource interface {
+	ReadUserFile(ctx context.Context, file *repository.UserFile) ([]byte, error)
+}
+
+// JobRunnerConfig tunes the background import worker pool.
+type JobRunnerConfig struct {
+	Workers           int           // Concurrent jobs per process
+	PollInterval      time.Duration // Wait between claims when the queue is empty
+	HeartbeatInterval time.Duration // How often a running job reports liveness and checks for cancellation
+	StaleAfter        time.Duration // Running jobs without a heartbeat for this long are requeued
+	PurgeInterval     time.Duration // How often rolled back batches past BatchRetention are purged
+}
+
+// DefaultJobRunnerConfig returns the settings used by the API server.
+func DefaultJobRunnerConfig() JobRunnerConfig {
+	return JobRunnerConfig{
+		Workers:           2,
+		PollInterval:      2 * time.Second,
+		HeartbeatInterval: 10 * time.Second,
+		StaleAfter:        time.Minute,
+		PurgeInterval:     time.Hour,
+	}
+}
+
+// JobRunner claims pending import jobs from the database and runs them in a
+// fixed pool of workers. Several processes can share the queue: claims use
+// SKIP LOCKED, and jobs whose worker stopped heartbeating are requeued.
+type JobRunner struct {
+	svc    *ImportService
+	files  FileSource
+	cfg    JobRunnerConfig
+	logger *slog.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJobRunner creates a runner for the import service. files may be nil, in
+// which case every claimed job fails with ErrFileStorageUnavailable.
+func NewJobRunner(svc *ImportService, files FileSource, cfg JobRunnerConfig, logger *slog.Logger) *JobRunner {
+	defaults := DefaultJobRunnerConfig()
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaults.Workers
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = defaults.HeartbeatInterval
+	}
+	if cfg.StaleAfter <= cfg.HeartbeatInterval {
+		cfg.StaleAfter = 6 * cfg.HeartbeatInterval
+	}
+	if cfg.PurgeInterval <= 0 {
+		cfg.PurgeInterval = defaults.PurgeInterval
+	}
+
+	return &JobRunner{
+		svc:    svc,
+		files:  files,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Start requeues orphaned jobs and launches the workers. It returns immediately;
+// call Stop to shut the workers down.
+func (r *JobRunner) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	r.recoverStale(ctx)
+
+	r.wg.Add(1)
+	go r.recoverLoop(ctx)
+
+	r.wg.Add(1)
+	go r.purgeLoop(ctx)
+
+	for i := 0; i < r.cfg.Workers; i++ {
+		r.wg.Add(1)
+		go r.work(ctx)
+	}
+
+	r.logger.Info("import job runner started", "workers", r.cfg.Workers)
+}
+
+// Stop signals the workers to exit and waits for them. Jobs interrupted by the
+// shutdown are returned to the queue.
+func (r *JobRunner) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+	r.logger.Info("import job runner stopped")
+}
+
+// recoverStale requeues running jobs whose worker stopped heartbeating,
+// e.g. because the process crashed mid-import.
+func (r *JobRunner) recoverStale(ctx context.Context) {
+	requeued, err := r.svc.repo.RequeueStaleImportJobs(ctx, time.Now().Add(-r.cfg.StaleAfter))
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Warn("failed to requeue stale import jobs", "error", err)
+		}
+		return
+	}
+	if requeued > 0 {
+		r.logger.Info("requeued stale import jobs", "count", requeued)
+	}
+}
+
+func (r *JobRunner) recoverLoop(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.cfg.StaleAfter)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.recoverStale(ctx)
+		}
+	}
+}
+
+// purgeExpired permanently deletes the transactions of batches rolled back
+// longer than BatchRetention ago.
+func (r *JobRunner) purgeExpired(ctx context.Context) {
+	purged, err := r.svc.PurgeExpiredImportJobs(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Warn("failed to purge rolled back import batches", "error", err)
+		}
+		return
+	}
+	if purged > 0 {
+		r.logger.Info("purged rolled back import batches", "count", purged)
+	}
+}
+
+func (r *JobRunner) purgeLoop(ctx context.Context) {
+	defer r.wg.Done()
+
+	r.purgeExpired(ctx)
+
+	ticker := time.NewTicker(r.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.purgeExpired(ctx)
+		}
+	}
+}
+
+func (r *JobRunner) work(ctx context.Context) {
+	defer r.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		// Keep draining while there is work; back off only when the queue is empty.
+		wait := time.Duration(0)
+		if !r.runNext(ctx) {
+			wait = r.cfg.PollInterval
+		}
+		timer.Reset(wait)
+	}
+}
+
+// runNext claims and runs one job. It reports whether a job was claimed.
+func (r *JobRunner) runNext(ctx context.Context) bool {
+	job, err := r.svc.repo.ClaimPendingImportJob(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Warn("failed to claim import job", "error", err)
+		}
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	r.run(ctx, job)
+	return true
+}
+
+func (r *JobRunner) run(ctx context.Context, job *repository.ImportJob) {
+	logger := r.logger.With("job_id", job.ID, "user_id", job.UserID)
+	start := time.Now()
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var canceled atomic.Bool
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		r.heartbeat(jobCtx, job.ID, func() {
+			canceled.Store(true)
+			cancel()
+		})
+	}()
+
+	result, err := r.execute(jobCtx, job)
+	cancel()
+	<-heartbeatDone
+
+	switch {
+	case canceled.Load():
+		logger.Info("import job canceled")
+	case ctx.Err() != nil:
+		// Shutdown: hand the job back so another worker resumes it.
+		requeueCtx, requeueCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer requeueCancel()
+		if err := r.svc.repo.RequeueImportJob(requeueCtx, job.ID); err != nil {
+			logger.Warn("failed to requeue import job", "error", err)
+		}
+	case err != nil:
+		logger.Error("import job failed", "error", err, "duration", time.Since(start))
+	default:
+		logger.Info("import job completed",
+			"rows_imported", result.RowsImported,
+			"rows_failed", result.RowsFailed,
+			"duration", time.Since(start),
+		)
+	}
+}
+
+func (r *JobRunner) execute(ctx context.Context, job *repository.ImportJob) (*ImportResult, error) {
+	data, err := r.readFile(ctx, job.FileID)
+	if err != nil {
+		if ctx.Err() == nil {
+			errMsg := err.Error()
+			if finishErr := r.svc.repo.FinishImportJob(ctx, job.ID, "failed", 0, 0, &errMsg); finishErr != nil {
+				r.logger.Warn("failed to mark import job as failed", "job_id", job.ID, "error", finishErr)
+			}
+		}
+		return nil, err
+	}
+
+	return r.svc.RunImportJob(ctx, job, data)
+}
+
+func (r *JobRunner) readFile(ctx context.Context, fileID uuid.UUID) ([]byte, error) {
+	if r.files == nil {
+		return nil, ErrFileStorageUnavailable
+	}
+
+	file, err := r.svc.repo.GetUserFileByID(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load file record: %w", err)
+	}
+	if file == nil {
+		return nil, ErrUserFileNotFound
+	}
+
+	data, err := r.files.ReadUserFile(ctx, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %w", err)
+	}
+	return data, nil
+}
+
+// heartbeat keeps the job's heartbeat fresh until ctx is done and calls onCancel
+// if the job was canceled through the API in the meantime.
+func (r *JobRunner) heartbeat(ctx context.Context, jobID uuid.UUID, onCancel func()) {
+	ticker := time.NewTicker(r.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := r.svc.repo.GetImportJobByID(ctx, jobID)
+		if err == nil && current != nil && current.Status == "canceled" {
+			onCancel()
+			return
+		}
+
+		if err := r.svc.repo.HeartbeatImportJob(ctx, jobID); err != nil && ctx.Err() == nil {
+			r.logger.Warn("failed to heartbeat import job", "job_id", jobID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+type memoryFileSource map[uuid.UUID][]byte
+
+func (m memoryFileSource) ReadUserFile(ctx context.Context, file *repository.UserFile) ([]byte, error) {
+	data, ok := m[file.ID]
+	if !ok {
+		return nil, errors.New("file content missing")
+	}
+	return data, nil
+}
+
+func TestJobRunner_RunsQueuedAndStaleJobs(t *testing.T) {
+	data := []byte("Date,Description,Amount\n13/02/2024,Coffee,-3.50\n14/02/2024,Salary,1500.00\nnot-a-date,Rent,-900.00\n")
+	mapping := ColumnMapping{DateCol: 0, DescCol: 1, CategoryCol: -1, AmountCol: 2, DebitCol: -1, CreditCol: -1, DateFormat: "DD/MM/YYYY"}
+
+	userID := uuid.New()
+	accountID := uuid.New()
+	repo := &fakeImportRepo{accountCurrency: "USD"}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := NewImportService(repo, logger)
+	ctx := context.Background()
+
+	file := &repository.UserFile{UserID: userID, Type: fileTypeCSV, MimeType: mimeTypeCSV, FileName: "export.csv", SizeBytes: int64(len(data))}
+	if err := repo.CreateUserFile(ctx, file); err != nil {
+		t.Fatalf("CreateUserFile failed: %v", err)
+	}
+
+	queued, err := svc.CreateImportJob(ctx, userID, ImportJobRequest{
+		FileID:    file.ID,
+		AccountID: &accountID,
+		Mapping:   mapping,
+		Options:   ImportOptions{InstitutionName: "Chase"},
+	})
+	if err != nil {
+		t.Fatalf("CreateImportJob failed: %v", err)
+	}
+	if queued.Status != "pending" || queued.InstitutionName == nil || *queued.InstitutionName != "Chase" {
+		t.Fatalf("unexpected queued job: status %q, institution %v", queued.Status, queued.InstitutionName)
+	}
+
+	// A job left running by a crashed process, with one row already stored.
+	orphan, err := svc.CreateImportJob(ctx, userID, ImportJobRequest{FileID: file.ID, AccountID: &accountID, Mapping: mapping})
+	if err != nil {
+		t.Fatalf("CreateImportJob failed: %v", err)
+	}
+	lastSeen := time.Now().Add(-time.Hour)
+	repo.jobs[1].Status = "running"
+	repo.jobs[1].HeartbeatAt = &lastSeen
+	repo.insertedByJob = map[uuid.UUID]int{orphan.ID: 1}
+
+	runner := NewJobRunner(svc, memoryFileSource{file.ID: data}, JobRunnerConfig{
+		Workers:           2,
+		PollInterval:      5 * time.Millisecond,
+		HeartbeatInterval: 5 * time.Millisecond,
+		StaleAfter:        time.Minute,
+	}, logger)
+	runner.Start(ctx)
+	defer runner.Stop()
+
+	for _, id := range []uuid.UUID{queued.ID, orphan.ID} {
+		job := waitForJob(t, svc, userID, id)
+		if job.Status != "succeeded" {
+			t.Fatalf("job %s: expected succeeded, got %q (%v)", id, job.Status, job.ErrorMessage)
+		}
+		if job.RowsFailed != 1 {
+			t.Fatalf("job %s: expected 1 failed row, got %d", id, job.RowsFailed)
+		}
+	}
+
+	// The resumed job counts rows stored before the crash on top of the re-run.
+	jobs, total, err := svc.ListImportJobs(ctx, userID, 10, 0)
+	if err != nil || total != 2 {
+		t.Fatalf("ListImportJobs = %d jobs, %v", total, err)
+	}
+	for _, job := range jobs {
+		want := 2
+		if job.ID == orphan.ID {
+			want = 3
+		}
+		if job.RowsImported != want {
+			t.Fatalf("job %s: expected %d imported rows, got %d", job.ID, want, job.RowsImported)
+		}
+	}
+}
+
+func TestImportJob_CancelAndOwnership(t *testing.T) {
+	userID := uuid.New()
+	otherUserID := uuid.New()
+	repo := &fakeImportRepo{}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	file := &repository.UserFile{UserID: userID, Type: fileTypeCSV}
+	image := &repository.UserFile{UserID: userID, Type: "image"}
+	_ = repo.CreateUserFile(ctx, file)
+	_ = repo.CreateUserFile(ctx, image)
+
+	if _, err := svc.CreateImportJob(ctx, otherUserID, ImportJobRequest{FileID: file.ID}); !errors.Is(err, ErrUserFileNotFound) {
+		t.Fatalf("expected ErrUserFileNotFound for another user's file, got %v", err)
+	}
+	if _, err := svc.CreateImportJob(ctx, userID, ImportJobRequest{FileID: image.ID}); !errors.Is(err, ErrFileNotImportable) {
+		t.Fatalf("expected ErrFileNotImportable, got %v", err)
+	}
+	missingMapping := uuid.New()
+	if _, err := svc.CreateImportJob(ctx, userID, ImportJobRequest{FileID: file.ID, MappingID: &missingMapping}); !errors.Is(err, ErrMappingNotFound) {
+		t.Fatalf("expected ErrMappingNotFound, got %v", err)
+	}
+
+	job, err := svc.CreateImportJob(ctx, userID, ImportJobRequest{FileID: file.ID})
+	if err != nil {
+		t.Fatalf("CreateImportJob failed: %v", err)
+	}
+
+	if _, err := svc.GetImportJob(ctx, otherUserID, job.ID); !errors.Is(err, ErrImportJobNotFound) {
+		t.Fatalf("expected ErrImportJobNotFound for another user, got %v", err)
+	}
+	if err := svc.CancelImportJob(ctx, otherUserID, job.ID); !errors.Is(err, ErrImportJobNotFound) {
+		t.Fatalf("expected ErrImportJobNotFound when canceling another user's job, got %v", err)
+	}
+	if err := svc.CancelImportJob(ctx, userID, job.ID); err != nil {
+		t.Fatalf("CancelImportJob failed: %v", err)
+	}
+	if err := svc.CancelImportJob(ctx, userID, job.ID); !errors.Is(err, ErrImportJobFinished) {
+		t.Fatalf("expected ErrImportJobFinished on second cancel, got %v", err)
+	}
+
+	claimed, err := repo.ClaimPendingImportJob(ctx)
+	if err != nil || claimed != nil {
+		t.Fatalf("expected canceled job not to be claimed, got %v, %v", claimed, err)
+	}
+}
+
+func waitForJob(t *testing.T, svc *ImportService, userID, jobID uuid.UUID) *repository.ImportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := svc.GetImportJob(context.Background(), userID, jobID)
+		if err != nil {
+			t.Fatalf("GetImportJob failed: %v", err)
+		}
+		if job.Status != "pending" && job.Status != "running" {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still %q", jobID, job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
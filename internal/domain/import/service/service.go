@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -18,9 +19,11 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/dedup"
 	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/normalizer"
 	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
 	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/sniffer"
+	"github.com/FACorreiaa/smart-finance-tracker/pkg/storage"
 )
 
 // ColumnMapping defines how to map CSV columns to transaction fields
@@ -46,6 +49,22 @@ type AnalyzeResult struct {
 	ColumnSuggestions *sniffer.ColumnSuggestions
 	ProbedDialect     *sniffer.RegionalDialect
 
+	// Source format ("csv", "xlsx", "ofx" or "camt"). For workbooks, Sheets lists
+	// every sheet and Sheet is the one that was analyzed.
+	FileType string
+	Sheets   []string
+	Sheet    string
+
+	// Character encoding of delimited text, detected or as overridden; pass it
+	// back in ImportOptions.Encoding to correct a wrong guess
+	Encoding string
+
+	// Set instead of FileConfig for structured statements (OFX, CAMT.053)
+	Statement *StatementSummary
+
+	// Earlier upload with identical content; importing it again adds nothing
+	DuplicateFile *repository.UserFile
+
 	// Existing mapping found
 	MappingFound bool
 	Mapping      *repository.BankMapping
@@ -61,6 +80,26 @@ type ImportResult struct {
 	RowsImported int
 	RowsFailed   int
 	Errors       []string
+
+	// Imported rows linked to a probable duplicate from another source
+	PossibleDuplicates int
+
+	// Imported rows paired with the other side of a transfer between accounts
+	TransfersDetected int
+}
+
+// StatementSummary describes a structured statement that needs no column mapping.
+type StatementSummary struct {
+	CurrencyCode       string
+	TransactionCount   int
+	SampleTransactions []*repository.ParsedTransaction // First few transactions for preview
+	Errors             []string
+}
+
+// AnalyzeOptions allows callers to steer file analysis.
+type AnalyzeOptions struct {
+	Sheet    string // Workbook sheet to analyze; empty picks the first sheet with headers
+	Encoding string // Character encoding of a CSV file; empty detects it
 }
 
 // ImportOptions allows callers to override detected file settings.
@@ -68,11 +107,28 @@ type ImportOptions struct {
 	HeaderRows      int
 	Timezone        string
 	InstitutionName string // Name of the bank/institution for this import
+	Sheet           string // Workbook sheet to import; empty picks the first sheet with headers
+	FileName        string // Original upload name; defaults to "import.<ext>"
+	Encoding        string // Character encoding of a CSV file; empty detects it
 }
 
 // CategorizationService defines the interface for transaction categorization
 type CategorizationService interface {
-	CategorizeBatch(ctx context.Context, userID uuid.UUID, descriptions []string) ([]*CategorizationResult, error)
+	CategorizeBatch(ctx context.Context, userID uuid.UUID, txs []CategorizationInput) ([]*CategorizationResult, error)
+}
+
+// CategorizationInput is what categorization rules can match on
+type CategorizationInput struct {
+	Description     string
+	AmountCents     int64 // Signed: negative for expenses, positive for income
+	AccountID       *uuid.UUID
+	InstitutionName string
+	PostedAt        time.Time
+}
+
+// TransferDetector pairs transfers between the user's accounts
+type TransferDetector interface {
+	DetectTransfers(ctx context.Context, userID uuid.UUID, importJobID *uuid.UUID) (int, error)
 }
 
 // CategorizationResult holds the result of categorizing a transaction
@@ -80,12 +136,23 @@ type CategorizationResult struct {
 	CleanMerchantName string
 	CategoryID        *uuid.UUID
 	IsRecurring       bool
+	KnownMerchant     bool    // A rule or merchant matched; false means the name is only a cleaned description
+	Confidence        float64 // How sure the category is, from 0 to 1
 }
 
+var (
+	// ErrMappingNotFound is returned when a bank mapping does not exist or belongs to another user.
+	ErrMappingNotFound = errors.New("bank mapping not found")
+	// ErrFingerprintMismatch is returned when a file's headers do not match the stored mapping.
+	ErrFingerprintMismatch = errors.New("file headers do not match the bank mapping fingerprint")
+)
+
 // ImportService orchestrates file analysis and import operations
 type ImportService struct {
 	repo       repository.ImportRepository
 	catService CategorizationService // Optional: nil if categorization not available
+	storage    storage.Storage       // Optional: nil keeps only file metadata
+	transfers  TransferDetector      // Optional: nil skips transfer pairing
 	logger     *slog.Logger
 }
 
@@ -95,8 +162,10 @@ const (
 )
 
 type parseJob struct {
-	lineNum int
-	record  []string
+	lineNum    int
+	record     []string
+	rowHash    string
+	occurrence int
 }
 
 type parseResult struct {
@@ -113,20 +182,54 @@ func NewImportService(repo repository.ImportRepository, logger *slog.Logger) *Im
 	}
 }
 
+// WithStorage keeps the original bytes of imported and uploaded files so failed
+// imports can be re-run. Without it only file metadata is recorded.
+func (s *ImportService) WithStorage(store storage.Storage) *ImportService {
+	s.storage = store
+	return s
+}
+
+// WithTransferDetector pairs imported rows with transfers on the user's other accounts
+func (s *ImportService) WithTransferDetector(detector TransferDetector) *ImportService {
+	s.transfers = detector
+	return s
+}
+
 // WithCategorizationService adds categorization support to the import service
 func (s *ImportService) WithCategorizationService(catService CategorizationService) *ImportService {
 	s.catService = catService
 	return s
 }
 
-// AnalyzeFile analyzes an uploaded CSV/TSV file and determines if it can be auto-imported
+// AnalyzeFile analyzes an uploaded CSV/TSV or XLSX file and determines if it can be auto-imported
 func (s *ImportService) AnalyzeFile(ctx context.Context, userID uuid.UUID, fileData []byte) (*AnalyzeResult, error) {
+	return s.AnalyzeFileWithOptions(ctx, userID, fileData, AnalyzeOptions{})
+}
+
+// AnalyzeFileWithOptions analyzes an uploaded file using the provided options.
+func (s *ImportService) AnalyzeFileWithOptions(ctx context.Context, userID uuid.UUID, fileData []byte, opts AnalyzeOptions) (*AnalyzeResult, error) {
 	// Step 1: Detect file configuration
-	normalizedData := normalizeCSVBytes(fileData)
-	config, err := sniffer.DetectConfig(normalizedData)
+	input, err := prepareInput(fileData, opts.Sheet, opts.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze file: %w", err)
+	}
+
+	duplicate, err := s.findDuplicateFile(ctx, userID, checksumSHA256(fileData))
+	if err != nil {
+		return nil, err
+	}
+	if input.statement != nil {
+		result := analyzeStatement(input)
+		result.DuplicateFile = duplicate
+		return result, nil
+	}
+
+	config, err := sniffer.DetectConfigWithOptions(input.data, input.detectOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze file: %w", err)
 	}
+	// The sniffer sees transcoded text; report the upload's encoding instead.
+	config.Encoding = input.encoding
 
 	// Step 2: Get column suggestions
 	suggestions := sniffer.SuggestColumns(config.Headers)
@@ -149,6 +252,11 @@ func (s *ImportService) AnalyzeFile(ctx context.Context, userID uuid.UUID, fileD
 		FileConfig:        config,
 		ColumnSuggestions: suggestions,
 		ProbedDialect:     dialect,
+		FileType:          input.fileType,
+		Sheets:            input.sheets,
+		Sheet:             input.sheet,
+		Encoding:          input.encoding,
+		DuplicateFile:     duplicate,
 		MappingFound:      mapping != nil,
 		Mapping:           mapping,
 		CanAutoImport:     mapping != nil,
@@ -157,8 +265,36 @@ func (s *ImportService) AnalyzeFile(ctx context.Context, userID uuid.UUID, fileD
 	return result, nil
 }
 
-// SaveMapping saves a user's column mapping for future use
+// analyzeStatement summarizes a parsed statement; it can always be imported without a mapping.
+func analyzeStatement(input *preparedInput) *AnalyzeResult {
+	stmt := input.statement
+	sample := stmt.transactions
+	if len(sample) > 5 {
+		sample = sample[:5]
+	}
+
+	return &AnalyzeResult{
+		FileType: input.fileType,
+		Statement: &StatementSummary{
+			CurrencyCode:       stmt.currencyCode,
+			TransactionCount:   len(stmt.transactions),
+			SampleTransactions: sample,
+			Errors:             stmt.errors,
+		},
+		CanAutoImport: len(stmt.transactions) > 0,
+	}
+}
+
+// SaveMapping saves a user's column mapping for future use. An existing mapping
+// owned by the user for the same fingerprint is updated in place.
 func (s *ImportService) SaveMapping(ctx context.Context, userID uuid.UUID, fingerprint string, bankName string, mapping ColumnMapping) error {
+	if mapping.Delimiter == 0 {
+		return fmt.Errorf("mapping delimiter is required")
+	}
+	if mapping.SkipLines < 0 {
+		return fmt.Errorf("mapping skip lines must not be negative")
+	}
+
 	bankNamePtr := &bankName
 	if bankName == "" {
 		bankNamePtr = nil
@@ -179,8 +315,8 @@ func (s *ImportService) SaveMapping(ctx context.Context, userID uuid.UUID, finge
 		UserID:           &userID,
 		Fingerprint:      fingerprint,
 		BankName:         bankNamePtr,
-		Delimiter:        ";", // Will be updated based on actual file
-		SkipLines:        0,   // Will be updated based on actual file
+		Delimiter:        string(mapping.Delimiter),
+		SkipLines:        mapping.SkipLines,
 		DateFormat:       mapping.DateFormat,
 		DateCol:          mapping.DateCol,
 		DescCol:          mapping.DescCol,
@@ -191,6 +327,15 @@ func (s *ImportService) SaveMapping(ctx context.Context, userID uuid.UUID, finge
 		IsEuropeanFormat: mapping.IsEuropeanFormat,
 	}
 
+	existing, err := s.repo.GetMappingByFingerprint(ctx, fingerprint, &userID)
+	if err != nil {
+		return fmt.Errorf("failed to lookup mapping: %w", err)
+	}
+	if existing != nil && existing.UserID != nil && *existing.UserID == userID {
+		m.ID = existing.ID
+		return s.repo.UpdateMapping(ctx, m)
+	}
+
 	return s.repo.CreateMapping(ctx, m)
 }
 
@@ -200,18 +345,70 @@ func (s *ImportService) ImportWithMapping(ctx context.Context, userID uuid.UUID,
 }
 
 // ImportWithOptions processes a file using the provided column mapping and options.
+// Structured statements (OFX, CAMT.053) carry their own layout and ignore the mapping.
 func (s *ImportService) ImportWithOptions(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, fileData []byte, mapping ColumnMapping, opts ImportOptions) (*ImportResult, error) {
-	normalizedData := normalizeCSVBytes(fileData)
+	input, err := prepareInput(fileData, opts.Sheet, opts.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return s.importPrepared(ctx, userID, accountID, input, mapping, opts)
+}
+
+// importPrepared imports an input already run through prepareInput.
+func (s *ImportService) importPrepared(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, input *preparedInput, mapping ColumnMapping, opts ImportOptions) (*ImportResult, error) {
+	if err := checkFileSize(input.size); err != nil {
+		return nil, err
+	}
+
+	plan, err := s.planImport(ctx, userID, accountID, input, mapping, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.createImportRecords(ctx, userID, accountID, input, mapping, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.executeImport(ctx, job, plan, opts.InstitutionName, 0, false)
+}
+
+// importPlan is a fully resolved import: the currency to store and where rows come from.
+type importPlan struct {
+	currencyCode string
+	produce      resultProducer
+
+	// Detected layout and resolved mapping; nil/zero for structured statements.
+	config  *sniffer.FileConfig
+	mapping ColumnMapping
+}
+
+// resultProducer starts streaming parse results once the import job exists.
+// The returned slice holds errors found before any row was produced.
+type resultProducer func(ctx context.Context) (<-chan parseResult, []string)
+
+// planImport resolves the file layout, column mapping and currency for an input.
+func (s *ImportService) planImport(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, input *preparedInput, mapping ColumnMapping, opts ImportOptions) (*importPlan, error) {
+	if input.statement != nil {
+		return s.planStatement(ctx, userID, accountID, input)
+	}
+
+	normalizedData := input.data
+
+	detectOpts := input.detectOptions()
 
-	detectOpts := &sniffer.DetectOptions{HeaderRowIndex: -1}
+	// Delimiter and skip lines describe text layouts; spreadsheets are
+	// flattened by prepareInput and only honor an explicit header row.
+	isText := input.fileType == fileTypeCSV
 
 	// Use delimiter from mapping if provided (from AnalyzeCsvFile)
-	if mapping.Delimiter != 0 {
+	if isText && mapping.Delimiter != 0 {
 		detectOpts.Delimiter = mapping.Delimiter
 	}
 
 	// Use skip lines from mapping if provided, otherwise use opts.HeaderRows
-	if mapping.SkipLines > 0 {
+	if isText && mapping.SkipLines > 0 {
 		detectOpts.HeaderRowIndex = mapping.SkipLines
 	} else if opts.HeaderRows > 0 {
 		detectOpts.HeaderRowIndex = opts.HeaderRows - 1
@@ -222,6 +419,7 @@ func (s *ImportService) ImportWithOptions(ctx context.Context, userID uuid.UUID,
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect file config: %w", err)
 	}
+	config.Encoding = input.encoding
 
 	resolvedMapping, err := resolveMapping(config, mapping)
 	if err != nil {
@@ -236,16 +434,59 @@ func (s *ImportService) ImportWithOptions(ctx context.Context, userID uuid.UUID,
 		return nil, err
 	}
 
-	// Create a file record
+	return &importPlan{
+		currencyCode: currencyCode,
+		produce: func(parseCtx context.Context) (<-chan parseResult, []string) {
+			return s.parseTransactionsStream(parseCtx, normalizedData, config, resolvedMapping, accountID)
+		},
+		config:  config,
+		mapping: resolvedMapping,
+	}, nil
+}
+
+// planStatement plans the import of an already-parsed OFX/CAMT statement.
+func (s *ImportService) planStatement(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, input *preparedInput) (*importPlan, error) {
+	currencyCode, err := s.resolveStatementCurrency(ctx, userID, accountID, input.statement.currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &importPlan{
+		currencyCode: currencyCode,
+		produce: func(context.Context) (<-chan parseResult, []string) {
+			results := make(chan parseResult, len(input.statement.transactions))
+			occurrences := dedup.NewOccurrences()
+			for i, tx := range input.statement.transactions {
+				if tx.RowHash != "" {
+					tx.Occurrence = occurrences.Next(tx.RowHash)
+				}
+				results <- parseResult{lineNum: i + 1, tx: tx}
+			}
+			close(results)
+			return results, input.statement.errors
+		},
+	}, nil
+}
+
+// createImportRecords records the uploaded file and a running import job for a
+// synchronous import. The job keeps the mapping and options it was started with
+// so RetryImportJob can re-run it from the stored file.
+func (s *ImportService) createImportRecords(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, input *preparedInput, mapping ColumnMapping, opts ImportOptions) (*repository.ImportJob, error) {
+	fileName := opts.FileName
+	if fileName == "" {
+		fileName = "import." + input.extension()
+	}
+
+	// Create a file record, storing the original bytes when storage is configured
 	fileRecord := &repository.UserFile{
 		UserID:    userID,
-		Type:      "csv",
-		MimeType:  "text/csv",
-		FileName:  "import.csv",
-		SizeBytes: int64(len(fileData)),
+		Type:      input.fileType,
+		MimeType:  input.mimeType,
+		FileName:  fileName,
+		SizeBytes: input.size,
 	}
-	if err := s.repo.CreateUserFile(ctx, fileRecord); err != nil {
-		return nil, fmt.Errorf("failed to create file record: %w", err)
+	if err := s.storeUserFile(ctx, fileRecord, input.raw); err != nil {
+		return nil, err
 	}
 
 	// Create import job
@@ -257,19 +498,37 @@ func (s *ImportService) ImportWithOptions(ctx context.Context, userID uuid.UUID,
 		AccountID: accountID,
 		RowsTotal: 0,
 	}
+	stored := jobOptions{Sheet: input.sheet, HeaderRows: opts.HeaderRows, Encoding: input.encoding}
+	dateFormat := ""
+	if input.statement == nil {
+		stored.Mapping = newJobMapping(mapping)
+		dateFormat = mapping.DateFormat
+	}
+	if err := describeJob(job, stored, opts, dateFormat); err != nil {
+		return nil, err
+	}
 	if err := s.repo.CreateImportJob(ctx, job); err != nil {
 		return nil, fmt.Errorf("failed to create import job: %w", err)
 	}
 
+	return job, nil
+}
+
+// executeImport batches the planned transactions through categorization and
+// BulkInsertTransactions, reporting progress on the job. alreadyImported seeds
+// the imported counter when a job is resumed. When ctx is canceled a job the
+// runner owns (requeue) is left running for it to requeue; any other job is
+// finished as canceled, since nobody would pick it up again.
+func (s *ImportService) executeImport(ctx context.Context, job *repository.ImportJob, plan *importPlan, institutionName string, alreadyImported int, requeue bool) (*ImportResult, error) {
 	parseCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	results, preErrors := s.parseTransactionsStream(parseCtx, normalizedData, config, resolvedMapping)
+	results, preErrors := plan.produce(parseCtx)
 
 	errors := make([]string, 0, len(preErrors))
 	errors = append(errors, preErrors...)
 	rowsFailed := len(preErrors)
-	rowsImported := 0
+	rowsImported := alreadyImported
 
 	type parseError struct {
 		lineNum int
@@ -292,9 +551,13 @@ func (s *ImportService) ImportWithOptions(ctx context.Context, userID uuid.UUID,
 		}
 		// Enrich transactions with categorization if service is available
 		if s.catService != nil {
-			s.enrichBatch(ctx, userID, batch)
+			s.enrichBatch(ctx, job.UserID, job.AccountID, institutionName, batch)
+		}
+		queueUncategorized(batch)
+		if err := s.adoptLegacyExternalIDs(ctx, job.UserID, batch); err != nil {
+			return err
 		}
-		imported, err := s.repo.BulkInsertTransactions(ctx, userID, accountID, currencyCode, job.ID, opts.InstitutionName, batch)
+		imported, err := s.repo.BulkInsertTransactions(ctx, job.UserID, job.AccountID, plan.currencyCode, job.ID, institutionName, batch)
 		if err != nil {
 			return err
 		}
@@ -349,12 +612,42 @@ func (s *ImportService) ImportWithOptions(ctx context.Context, userID uuid.UUID,
 		}
 	}
 
+	if ctx.Err() != nil {
+		if !requeue {
+			errMsg := "import canceled: " + ctx.Err().Error()
+			if err := s.repo.FinishImportJob(context.WithoutCancel(ctx), job.ID, "canceled", rowsImported, rowsFailed, &errMsg); err != nil {
+				s.logger.Warn("failed to mark import job as canceled", "job_id", job.ID, "error", err)
+			}
+		}
+		return nil, ctx.Err()
+	}
+
 	if insertErr != nil {
 		errMsg := insertErr.Error()
 		s.repo.FinishImportJob(ctx, job.ID, "failed", rowsImported, rowsFailed, &errMsg)
 		return nil, fmt.Errorf("failed to insert transactions: %w", insertErr)
 	}
 
+	// Link rows other sources already reported; linking never fails the import
+	possibleDuplicates, err := s.linkCrossSourceDuplicates(ctx, job.UserID, job.ID)
+	if err != nil {
+		s.logger.Warn("failed to link cross-source duplicates", "job_id", job.ID, "error", err)
+	}
+
+	// Pair transfers with the user's other accounts; like linking, never fatal
+	var transfersDetected int
+	if s.transfers != nil {
+		transfersDetected, err = s.transfers.DetectTransfers(ctx, job.UserID, &job.ID)
+		if err != nil {
+			s.logger.Warn("failed to detect transfers", "job_id", job.ID, "error", err)
+		}
+	}
+
+	// Attach receipts uploaded before their transactions arrived; never fatal either
+	if _, err := s.ReconcileDocuments(ctx, job.UserID); err != nil {
+		s.logger.Warn("failed to reconcile documents", "job_id", job.ID, "error", err)
+	}
+
 	// Mark job as complete
 	status := "succeeded"
 	if err := s.repo.FinishImportJob(ctx, job.ID, status, rowsImported, rowsFailed, nil); err != nil {
@@ -362,23 +655,131 @@ func (s *ImportService) ImportWithOptions(ctx context.Context, userID uuid.UUID,
 	}
 
 	return &ImportResult{
-		JobID:        job.ID,
-		RowsTotal:    rowsImported + rowsFailed,
-		RowsImported: rowsImported,
-		RowsFailed:   rowsFailed,
-		Errors:       errors,
+		JobID:              job.ID,
+		RowsTotal:          rowsImported + rowsFailed,
+		RowsImported:       rowsImported,
+		RowsFailed:         rowsFailed,
+		PossibleDuplicates: possibleDuplicates,
+		TransfersDetected:  transfersDetected,
+		Errors:             errors,
 	}, nil
 }
 
-// ImportWithExistingMapping uses a pre-existing bank mapping to import
+// ImportWithExistingMapping uses a stored bank mapping (the user's own or a global
+// template) to import a CSV/XLSX file whose headers still match the mapping's fingerprint.
 func (s *ImportService) ImportWithExistingMapping(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, fileData []byte, mappingID uuid.UUID) (*ImportResult, error) {
-	// This would fetch the mapping and call ImportWithMapping
-	// For now, return an error indicating it's not implemented
-	return nil, fmt.Errorf("import with existing mapping not yet implemented")
+	input, err := prepareInput(fileData, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	mapping, stored, err := s.resolveStoredMapping(ctx, userID, mappingID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := ImportOptions{}
+	if stored.BankName != nil {
+		opts.InstitutionName = *stored.BankName
+	}
+
+	return s.importPrepared(ctx, userID, accountID, input, mapping, opts)
+}
+
+// resolveStoredMapping loads a bank mapping visible to the user and checks that the
+// input still matches its layout. The returned ColumnMapping is ready for planImport.
+func (s *ImportService) resolveStoredMapping(ctx context.Context, userID uuid.UUID, mappingID uuid.UUID, input *preparedInput) (ColumnMapping, *repository.BankMapping, error) {
+	stored, err := s.repo.GetMappingByID(ctx, mappingID)
+	if err != nil {
+		return ColumnMapping{}, nil, fmt.Errorf("failed to load mapping: %w", err)
+	}
+	// Other users' mappings are reported as missing rather than forbidden.
+	if stored == nil || (stored.UserID != nil && *stored.UserID != userID) {
+		return ColumnMapping{}, nil, ErrMappingNotFound
+	}
+
+	mapping, err := columnMappingFromBank(stored)
+	if err != nil {
+		return ColumnMapping{}, nil, err
+	}
+
+	if input.statement != nil {
+		return ColumnMapping{}, nil, fmt.Errorf("bank mappings apply to CSV/XLSX files, not %s statements", input.fileType)
+	}
+
+	config, err := matchMappingLayout(input, mapping, stored.Fingerprint)
+	if err != nil {
+		return ColumnMapping{}, nil, err
+	}
+	if input.fileType == fileTypeCSV {
+		mapping.SkipLines = config.SkipLines
+	}
+
+	return mapping, stored, nil
+}
+
+// columnMappingFromBank converts a stored BankMapping into a ColumnMapping.
+func columnMappingFromBank(m *repository.BankMapping) (ColumnMapping, error) {
+	mapping := ColumnMapping{
+		DateCol:          m.DateCol,
+		DescCol:          m.DescCol,
+		CategoryCol:      -1,
+		AmountCol:        -1,
+		DebitCol:         -1,
+		CreditCol:        -1,
+		IsEuropeanFormat: m.IsEuropeanFormat,
+		DateFormat:       m.DateFormat,
+		SkipLines:        m.SkipLines,
+	}
+
+	if delimiter, _ := utf8.DecodeRuneInString(m.Delimiter); delimiter != utf8.RuneError {
+		mapping.Delimiter = delimiter
+	}
+	if m.CategoryCol != nil {
+		mapping.CategoryCol = *m.CategoryCol
+	}
+
+	switch {
+	case m.AmountCol != nil:
+		mapping.AmountCol = *m.AmountCol
+	case m.DebitCol != nil && m.CreditCol != nil:
+		mapping.DebitCol = *m.DebitCol
+		mapping.CreditCol = *m.CreditCol
+		mapping.IsDoubleEntry = true
+	default:
+		return mapping, fmt.Errorf("bank mapping %s has no amount or debit/credit columns", m.ID)
+	}
+
+	return mapping, nil
 }
 
-// parseTransactionsStream streams parsed rows from a CSV file.
-func (s *ImportService) parseTransactionsStream(ctx context.Context, fileData []byte, config *sniffer.FileConfig, mapping ColumnMapping) (<-chan parseResult, []string) {
+// matchMappingLayout detects the file layout using the mapping's delimiter and skip
+// lines and checks the header fingerprint. If the header moved (e.g. the bank added
+// a metadata line), an auto-detected header with the same fingerprint is accepted.
+func matchMappingLayout(input *preparedInput, mapping ColumnMapping, fingerprint string) (*sniffer.FileConfig, error) {
+	detectOpts := input.detectOptions()
+	if input.fileType == fileTypeCSV {
+		detectOpts.HeaderRowIndex = mapping.SkipLines
+		detectOpts.Delimiter = mapping.Delimiter
+	}
+
+	config, err := sniffer.DetectConfigWithOptions(input.data, detectOpts)
+	if err == nil && config.Fingerprint == fingerprint {
+		return config, nil
+	}
+
+	detected, detectErr := sniffer.DetectConfigWithOptions(input.data, input.detectOptions())
+	if detectErr == nil && detected.Fingerprint == fingerprint {
+		return detected, nil
+	}
+
+	return nil, ErrFingerprintMismatch
+}
+
+// parseTransactionsStream streams parsed rows from a CSV file. Row hashes include
+// the target account, so identical rows imported into different accounts keep
+// their own external IDs.
+func (s *ImportService) parseTransactionsStream(ctx context.Context, fileData []byte, config *sniffer.FileConfig, mapping ColumnMapping, accountID *uuid.UUID) (<-chan parseResult, []string) {
 	results := make(chan parseResult, 1)
 
 	reader := csv.NewReader(bytes.NewReader(fileData))
@@ -403,6 +804,8 @@ func (s *ImportService) parseTransactionsStream(ctx context.Context, fileData []
 		workerCount = 1
 	}
 
+	currencyCol := rowCurrencyColumn(config, mapping)
+
 	results = make(chan parseResult, workerCount*4)
 	jobs := make(chan parseJob, workerCount*4)
 
@@ -416,6 +819,13 @@ func (s *ImportService) parseTransactionsStream(ctx context.Context, fileData []
 					return
 				}
 				tx, err := s.parseRow(job.record, mapping, job.lineNum)
+				if tx != nil {
+					tx.RowHash = job.rowHash
+					tx.Occurrence = job.occurrence
+					if tx.CurrencyCode, err = parseRowCurrency(job.record, currencyCol); err != nil {
+						tx = nil
+					}
+				}
 				select {
 				case results <- parseResult{lineNum: job.lineNum, tx: tx, err: err}:
 				case <-ctx.Done():
@@ -428,6 +838,8 @@ func (s *ImportService) parseTransactionsStream(ctx context.Context, fileData []
 	go func() {
 		defer close(jobs)
 		lineNum := config.SkipLines + 2 // 1-indexed, after header
+		// Identical rows are numbered in file order so each gets its own external_id.
+		occurrences := dedup.NewOccurrences()
 		for {
 			if ctx.Err() != nil {
 				return
@@ -445,8 +857,9 @@ func (s *ImportService) parseTransactionsStream(ctx context.Context, fileData []
 				lineNum++
 				continue
 			}
+			rowHash := csvRowHash(accountID, record)
 			select {
-			case jobs <- parseJob{lineNum: lineNum, record: record}:
+			case jobs <- parseJob{lineNum: lineNum, record: record, rowHash: rowHash, occurrence: occurrences.Next(rowHash)}:
 			case <-ctx.Done():
 				return
 			}
@@ -462,6 +875,16 @@ func (s *ImportService) parseTransactionsStream(ctx context.Context, fileData []
 	return results, errors
 }
 
+// csvRowHash hashes a CSV row together with the account it is imported into,
+// like the OFX and CAMT parsers do with the statement's account.
+func csvRowHash(accountID *uuid.UUID, record []string) string {
+	account := ""
+	if accountID != nil {
+		account = accountID.String()
+	}
+	return dedup.RowHash(append([]string{"csv", account}, record...)...)
+}
+
 // parseRow converts a CSV row into a ParsedTransaction
 func (s *ImportService) parseRow(record []string, mapping ColumnMapping, _ int) (*repository.ParsedTransaction, error) {
 	// Validate column indices
@@ -637,27 +1060,26 @@ func (s *ImportService) resolveCurrencyCode(ctx context.Context, userID uuid.UUI
 	return "", fmt.Errorf("currency code not found; provide account_id or include currency in CSV")
 }
 
-func normalizeCSVBytes(data []byte) []byte {
-	data = stripUTF8BOM(data)
-	if utf8.Valid(data) {
-		return data
-	}
-	return decodeLatin1(data)
-}
+// resolveStatementCurrency prefers the account currency and rejects statements in a different currency.
+func (s *ImportService) resolveStatementCurrency(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, statementCurrency string) (string, error) {
+	statementCode, hasStatementCode := normalizeCurrencyCode(statementCurrency)
 
-func stripUTF8BOM(data []byte) []byte {
-	if len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF {
-		return data[3:]
+	if accountID != nil {
+		code, err := s.resolveCurrencyCode(ctx, userID, accountID, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		if hasStatementCode && statementCode != code {
+			return "", fmt.Errorf("statement currency %s does not match account currency %s", statementCode, code)
+		}
+		return code, nil
 	}
-	return data
-}
 
-func decodeLatin1(data []byte) []byte {
-	runes := make([]rune, len(data))
-	for i, b := range data {
-		runes[i] = rune(b)
+	if hasStatementCode {
+		return statementCode, nil
 	}
-	return []byte(string(runes))
+
+	return "", fmt.Errorf("currency code not found; provide account_id or include currency in statement")
 }
 
 func detectEuropeanFormat(sampleRows [][]string, mapping ColumnMapping) (bool, bool) {
@@ -804,6 +1226,41 @@ func currencyColumnIndex(headers []string) int {
 	return -1
 }
 
+// rowCurrencyColumn returns the per-row currency column of multi-currency exports
+// (Wise, Revolut), or -1 when the file has a single currency. The header match is
+// loose ("valuta" is also a value date), so the sample rows must hold currencies.
+func rowCurrencyColumn(config *sniffer.FileConfig, mapping ColumnMapping) int {
+	idx := currencyColumnIndex(config.Headers)
+	switch idx {
+	case -1, mapping.DateCol, mapping.DescCol, mapping.AmountCol, mapping.DebitCol, mapping.CreditCol:
+		return -1
+	}
+	for _, row := range config.SampleRows {
+		if _, err := parseRowCurrency(row, idx); err != nil {
+			return -1
+		}
+	}
+	return idx
+}
+
+// parseRowCurrency reads a row's currency; blank cells use the import's currency.
+func parseRowCurrency(record []string, col int) (string, error) {
+	if col < 0 || col >= len(record) {
+		return "", nil
+	}
+	value := strings.TrimSpace(record[col])
+	if value == "" {
+		return "", nil
+	}
+	if code, ok := normalizeCurrencyCode(value); ok {
+		return code, nil
+	}
+	if code, ok := detectCurrencyFromSymbols(value); ok {
+		return code, nil
+	}
+	return "", fmt.Errorf("invalid currency '%s'", value)
+}
+
 func normalizeCurrencyCode(value string) (string, bool) {
 	if value == "" {
 		return "", false
@@ -922,19 +1379,25 @@ func hasDecimalSuffix(value string, sep rune) bool {
 }
 
 // enrichBatch calls the categorization service to populate MerchantName and CategoryID
-func (s *ImportService) enrichBatch(ctx context.Context, userID uuid.UUID, batch []*repository.ParsedTransaction) {
+func (s *ImportService) enrichBatch(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, institutionName string, batch []*repository.ParsedTransaction) {
 	if s.catService == nil || len(batch) == 0 {
 		return
 	}
 
-	// Collect descriptions
-	descriptions := make([]string, len(batch))
+	// Collect what the rules match on
+	inputs := make([]CategorizationInput, len(batch))
 	for i, tx := range batch {
-		descriptions[i] = tx.Description
+		inputs[i] = CategorizationInput{
+			Description:     tx.Description,
+			AmountCents:     tx.AmountCents,
+			AccountID:       accountID,
+			InstitutionName: institutionName,
+			PostedAt:        tx.Date,
+		}
 	}
 
 	// Call categorization service
-	results, err := s.catService.CategorizeBatch(ctx, userID, descriptions)
+	results, err := s.catService.CategorizeBatch(ctx, userID, inputs)
 	if err != nil {
 		s.logger.Warn("categorization failed, using raw descriptions", "error", err)
 		return
@@ -945,6 +1408,22 @@ func (s *ImportService) enrichBatch(ctx context.Context, userID uuid.UUID, batch
 		if i < len(batch) && result != nil {
 			batch[i].MerchantName = result.CleanMerchantName
 			batch[i].CategoryID = result.CategoryID
+			if result.CategoryID != nil {
+				confidence := result.Confidence
+				batch[i].CategoryConfidence = &confidence
+			}
+			if !result.KnownMerchant {
+				batch[i].ReviewReasons = append(batch[i].ReviewReasons, repository.ReviewUnknownMerchant)
+			}
+		}
+	}
+}
+
+// queueUncategorized holds rows that ended up without a category for review
+func queueUncategorized(batch []*repository.ParsedTransaction) {
+	for _, tx := range batch {
+		if tx.CategoryID == nil {
+			tx.ReviewReasons = append(tx.ReviewReasons, repository.ReviewUncategorized)
 		}
 	}
 }
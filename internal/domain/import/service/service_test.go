@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,9 +13,12 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/charset"
 	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
 	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/sniffer"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/xlsx/xlsxtest"
 	"github.com/google/uuid"
 )
 
@@ -44,7 +48,7 @@ func TestParseTransactions_OrderAndErrors(t *testing.T) {
 	}
 
 	svc := &ImportService{}
-	results, preErrors := svc.parseTransactionsStream(context.Background(), []byte(data), config, mapping)
+	results, preErrors := svc.parseTransactionsStream(context.Background(), []byte(data), config, mapping, nil)
 	if len(preErrors) != 0 {
 		t.Fatalf("unexpected pre-parse errors: %v", preErrors)
 	}
@@ -119,7 +123,7 @@ func TestParseTransactions_DoubleEntryWithSkipLines(t *testing.T) {
 	}
 
 	svc := &ImportService{}
-	results, preErrors := svc.parseTransactionsStream(context.Background(), []byte(data), config, mapping)
+	results, preErrors := svc.parseTransactionsStream(context.Background(), []byte(data), config, mapping, nil)
 	if len(preErrors) != 0 {
 		t.Fatalf("unexpected pre-parse errors: %v", preErrors)
 	}
@@ -201,6 +205,426 @@ func TestImportWithMapping_BatchesAndProgress(t *testing.T) {
 	}
 }
 
+func TestAnalyzeFile_XLSXMatchesCSVFingerprint(t *testing.T) {
+	workbook := xlsxtest.MustBuild(
+		xlsxtest.Sheet{Name: "Resumo", Rows: [][]any{{"Saldo final", 850.0}}},
+		xlsxtest.Sheet{Name: "Movimentos", Rows: [][]any{
+			{"Conta", "12345678901"},
+			{},
+			{"Data mov.", "Descrição", "Débito", "Crédito", "Saldo"},
+			{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "Compra MB - Pingo Doce", 45.23, nil, 954.77},
+			{time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), "Transferência recebida", nil, 500.0, 1454.77},
+		}},
+	)
+	csvData := []byte("Data mov.;Descrição;Débito;Crédito;Saldo\n02-01-2024;Pingo Doce;45,23;;954,77\n")
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := NewImportService(&fakeImportRepo{}, logger)
+
+	xlsxResult, err := svc.AnalyzeFile(context.Background(), uuid.New(), workbook)
+	if err != nil {
+		t.Fatalf("AnalyzeFile(xlsx) failed: %v", err)
+	}
+	csvResult, err := svc.AnalyzeFile(context.Background(), uuid.New(), csvData)
+	if err != nil {
+		t.Fatalf("AnalyzeFile(csv) failed: %v", err)
+	}
+
+	if xlsxResult.FileType != "xlsx" || xlsxResult.Sheet != "Movimentos" {
+		t.Fatalf("unexpected file type/sheet: %q/%q", xlsxResult.FileType, xlsxResult.Sheet)
+	}
+	if len(xlsxResult.Sheets) != 2 {
+		t.Fatalf("expected 2 sheets, got %v", xlsxResult.Sheets)
+	}
+	if xlsxResult.FileConfig.SkipLines != 1 {
+		t.Fatalf("expected header after 1 metadata row, got %d", xlsxResult.FileConfig.SkipLines)
+	}
+	if xlsxResult.FileConfig.Fingerprint != csvResult.FileConfig.Fingerprint {
+		t.Fatal("expected XLSX and CSV exports with the same headers to share a fingerprint")
+	}
+	if !xlsxResult.ColumnSuggestions.IsDoubleEntry {
+		t.Fatal("expected debit/credit columns to be suggested")
+	}
+}
+
+func TestAnalyzeAndImport_Encoding(t *testing.T) {
+	// Windows-1252 export: ç, ã and é are single bytes, the euro sign is 0x80.
+	data := []byte("Data;Descri\xe7\xe3o;Montante\n02/01/2024;Caf\xe9 \x80;-3,50\n")
+
+	repo := &fakeImportRepo{accountCurrency: "EUR"}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := svc.AnalyzeFile(context.Background(), uuid.New(), data)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if result.Encoding != charset.Windows1252 || result.FileConfig.Encoding != charset.Windows1252 {
+		t.Fatalf("expected windows-1252, got %q", result.Encoding)
+	}
+	if result.FileConfig.Headers[1] != "Descrição" {
+		t.Fatalf("expected decoded headers, got %v", result.FileConfig.Headers)
+	}
+
+	overridden, err := svc.AnalyzeFileWithOptions(context.Background(), uuid.New(), data, AnalyzeOptions{Encoding: "latin1"})
+	if err != nil {
+		t.Fatalf("AnalyzeFileWithOptions failed: %v", err)
+	}
+	if overridden.Encoding != charset.ISO88591 {
+		t.Fatalf("expected the override to be reported, got %q", overridden.Encoding)
+	}
+	if _, err := svc.AnalyzeFileWithOptions(context.Background(), uuid.New(), data, AnalyzeOptions{Encoding: "ebcdic"}); !errors.Is(err, charset.ErrUnsupportedEncoding) {
+		t.Fatalf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+
+	accountID := uuid.New()
+	mapping := ColumnMapping{DateCol: -1, DescCol: -1, CategoryCol: -1, AmountCol: -1, DebitCol: -1, CreditCol: -1}
+	if _, err := svc.ImportWithOptions(context.Background(), uuid.New(), &accountID, data, mapping, ImportOptions{Encoding: result.Encoding}); err != nil {
+		t.Fatalf("ImportWithOptions failed: %v", err)
+	}
+	if len(repo.inserted) != 1 || repo.inserted[0].Description != "Café €" {
+		t.Fatalf("expected the description to be transcoded, got %+v", repo.inserted)
+	}
+}
+
+func TestImportWithMapping_PerRowCurrency(t *testing.T) {
+	data := []byte(strings.Join([]string{
+		"Type,Started Date,Description,Amount,Currency,State",
+		"CARD_PAYMENT,2024-01-02 10:00:00,Coffee,-3.50,EUR,COMPLETED",
+		"CARD_PAYMENT,2024-01-03 12:00:00,Pret,-4.20,GBP,COMPLETED",
+		"TOPUP,2024-01-04 09:00:00,Top-up,100.00,,COMPLETED",
+		"CARD_PAYMENT,2024-01-04 18:00:00,Wise fee,-0.50,USD,COMPLETED",
+		"CARD_PAYMENT,2024-01-05 08:00:00,Metro,-1.80,EUR,COMPLETED",
+		// Past the sample rows the column was recognized from
+		"CARD_PAYMENT,2024-01-05 09:00:00,Broken,-1.00,XX1,COMPLETED",
+		"",
+	}, "\n"))
+
+	repo := &fakeImportRepo{accountCurrency: "EUR"}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	accountID := uuid.New()
+	mapping := ColumnMapping{DateCol: 1, DescCol: 2, CategoryCol: -1, AmountCol: 3, DebitCol: -1, CreditCol: -1, DateFormat: "YYYY-MM-DD HH:mm:ss"}
+	result, err := svc.ImportWithMapping(context.Background(), uuid.New(), &accountID, data, mapping)
+	if err != nil {
+		t.Fatalf("ImportWithMapping failed: %v", err)
+	}
+	if result.RowsImported != 5 || result.RowsFailed != 1 {
+		t.Fatalf("expected 5 imported and 1 failed row, got %d/%d (%v)", result.RowsImported, result.RowsFailed, result.Errors)
+	}
+
+	got := make(map[string]string)
+	for _, tx := range repo.inserted {
+		got[tx.Description] = tx.CurrencyCode
+	}
+	want := map[string]string{"Coffee": "EUR", "Pret": "GBP", "Top-up": "", "Wise fee": "USD"}
+	for desc, code := range want {
+		if got[desc] != code {
+			t.Errorf("%s: expected currency %q, got %q", desc, code, got[desc])
+		}
+	}
+}
+
+// reviewCategorizer knows "Netflix" by a rule and guesses "Cafe"'s category
+type reviewCategorizer struct {
+	category uuid.UUID
+}
+
+func (c reviewCategorizer) CategorizeBatch(ctx context.Context, userID uuid.UUID, txs []CategorizationInput) ([]*CategorizationResult, error) {
+	results := make([]*CategorizationResult, len(txs))
+	for i, tx := range txs {
+		if tx.PostedAt.IsZero() {
+			return nil, errors.New("posting date missing")
+		}
+		result := &CategorizationResult{CleanMerchantName: tx.Description}
+		switch tx.Description {
+		case "Netflix":
+			result.CategoryID = &c.category
+			result.KnownMerchant = true
+			result.Confidence = 1
+		case "Cafe":
+			result.CategoryID = &c.category
+			result.Confidence = 0.55
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+func TestImportWithMapping_QueuesDoubtfulRowsForReview(t *testing.T) {
+	data := []byte(strings.Join([]string{
+		"Date,Description,Amount",
+		"02/01/2024,Netflix,-12.99",
+		"03/01/2024,Cafe,-3.20",
+		"04/01/2024,POS 4411 XYZ,-20.00",
+		"",
+	}, "\n"))
+
+	repo := &fakeImportRepo{accountCurrency: "EUR"}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.WithCategorizationService(reviewCategorizer{category: uuid.New()})
+
+	accountID := uuid.New()
+	mapping := ColumnMapping{DateCol: 0, DescCol: 1, CategoryCol: -1, AmountCol: 2, DebitCol: -1, CreditCol: -1, DateFormat: "DD/MM/YYYY"}
+	if _, err := svc.ImportWithMapping(context.Background(), uuid.New(), &accountID, data, mapping); err != nil {
+		t.Fatalf("ImportWithMapping failed: %v", err)
+	}
+
+	want := map[string][]string{
+		"Netflix":      nil,
+		"Cafe":         {repository.ReviewUnknownMerchant},
+		"POS 4411 XYZ": {repository.ReviewUnknownMerchant, repository.ReviewUncategorized},
+	}
+	confidence := map[string]float64{"Netflix": 1, "Cafe": 0.55}
+	for _, tx := range repo.inserted {
+		if got := tx.ReviewReasons; strings.Join(got, ",") != strings.Join(want[tx.Description], ",") {
+			t.Errorf("%s: expected review reasons %v, got %v", tx.Description, want[tx.Description], got)
+		}
+		expected, categorized := confidence[tx.Description]
+		switch {
+		case !categorized && tx.CategoryConfidence != nil:
+			t.Errorf("%s: expected no confidence without a category, got %v", tx.Description, *tx.CategoryConfidence)
+		case categorized && (tx.CategoryConfidence == nil || *tx.CategoryConfidence != expected):
+			t.Errorf("%s: expected confidence %v, got %v", tx.Description, expected, tx.CategoryConfidence)
+		}
+	}
+}
+
+func TestImportWithMapping_CanceledCallerCancelsJob(t *testing.T) {
+	data := []byte("Date,Description,Amount\n02/01/2024,Netflix,-12.99\n")
+	repo := &fakeImportRepo{accountCurrency: "EUR"}
+	svc := NewImportService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	accountID := uuid.New()
+	mapping := ColumnMapping{DateCol: 0, DescCol: 1, CategoryCol: -1, AmountCol: 2, DebitCol: -1, CreditCol: -1, DateFormat: "DD/MM/YYYY"}
+	if _, err := svc.ImportWithMapping(ctx, uuid.New(), &accountID, data, mapping); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// No runner owns a synchronous import, so it must not be left running
+	if len(repo.jobs) != 1 || repo.jobs[0].Status != "canceled" {
+		t.Fatalf("expected one canceled job, got %+v", repo.jobs)
+	}
+}
+
+func TestImportWithOptions_XLSX(t *testing.T) {
+	workbook := xlsxtest.MustBuild(xlsxtest.Sheet{Name: "Transactions", Rows: [][]any{
+		{"Date", "Description", "Amount", "Category"},
+		{time.Date(2024, 2, 13, 0, 0, 0, 0, time.UTC), "Store A", -10.5, "Food"},
+		{time.Date(2024, 2, 14, 9, 30, 0, 0, time.UTC), "Payroll, monthly", 2500, "Income"},
+		{"15/02/2024", "Store C", "1,234.56", "Shopping"},
+	}})
+
+	mapping := ColumnMapping{DateCol: -1, DescCol: -1, CategoryCol: -1, AmountCol: -1, DebitCol: -1, CreditCol: -1}
+
+	repo := &fakeImportRepo{accountCurrency: "USD"}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := NewImportService(repo, logger)
+
+	accountID := uuid.New()
+	result, err := svc.ImportWithOptions(context.Background(), uuid.New(), &accountID, workbook, mapping, ImportOptions{Sheet: "Transactions"})
+	if err != nil {
+		t.Fatalf("ImportWithOptions failed: %v", err)
+	}
+	if result.RowsImported != 3 || result.RowsFailed != 0 {
+		t.Fatalf("expected 3 imported rows, got %d imported / %d failed: %v", result.RowsImported, result.RowsFailed, result.Errors)
+	}
+
+	sort.Slice(repo.inserted, func(i, j int) bool { return repo.inserted[i].Date.Before(repo.inserted[j].Date) })
+	expected := []struct {
+		date   time.Time
+		desc   string
+		amount int64
+	}{
+		{time.Date(2024, 2, 13, 0, 0, 0, 0, time.UTC), "Store A", -1050},
+		{time.Date(2024, 2, 14, 9, 30, 0, 0, time.UTC), "Payroll, monthly", 250000},
+		{time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), "Store C", 123456},
+	}
+	for i, want := range expected {
+		got := repo.inserted[i]
+		if !got.Date.Equal(want.date) || got.Description != want.desc || got.AmountCents != want.amount {
+			t.Errorf("row %d = %v %q %d, want %v %q %d", i, got.Date, got.Description, got.AmountCents, want.date, want.desc, want.amount)
+		}
+	}
+
+	if len(repo.userFiles) != 1 || repo.userFiles[0].Type != "xlsx" {
+		t.Fatalf("expected one xlsx user file, got %+v", repo.userFiles)
+	}
+
+	if _, err := svc.ImportWithOptions(context.Background(), uuid.New(), &accountID, workbook, mapping, ImportOptions{Sheet: "Missing"}); err == nil {
+		t.Fatal("expected error for unknown sheet")
+	}
+}
+
+func TestImportWithOptions_OFXStatement(t *testing.T) {
+	data := []byte(`OFXHEADER:100
+DATA:OFXSGML
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><ACCTID>1234</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20240102<TRNAMT>-5.40<FITID>A1<NAME>Starbucks</STMTTRN>
+<STMTTRN><DTPOSTED>20240103<TRNAMT>-29.99<FITID>A2<NAME>Amazon</STMTTRN>
+<STMTTRN><DTPOSTED>20240104<TRNAMT>oops<FITID>A3<NAME>Broken</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>
+`)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	analyzeSvc := NewImportService(&fakeImportRepo{}, logger)
+	analysis, err := analyzeSvc.AnalyzeFile(context.Background(), uuid.New(), data)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if analysis.FileType != "ofx" || analysis.Statement == nil || !analysis.CanAutoImport {
+		t.Fatalf("expected auto-importable OFX statement, got %+v", analysis)
+	}
+	if analysis.Statement.TransactionCount != 2 || analysis.Statement.CurrencyCode != "USD" {
+		t.Fatalf("unexpected statement summary: %+v", analysis.Statement)
+	}
+
+	// Mapping is ignored for statements
+	mapping := ColumnMapping{DateCol: 7, DescCol: 7, AmountCol: 7, CategoryCol: -1}
+
+	repo := &fakeImportRepo{}
+	svc := NewImportService(repo, logger)
+	result, err := svc.ImportWithOptions(context.Background(), uuid.New(), nil, data, mapping, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportWithOptions failed: %v", err)
+	}
+	if result.RowsImported != 2 || result.RowsFailed != 1 {
+		t.Fatalf("expected 2 imported / 1 failed, got %d / %d", result.RowsImported, result.RowsFailed)
+	}
+	if len(repo.inserted) != 2 || repo.inserted[0].ExternalID != "ofx:1234:A1" || repo.inserted[1].ExternalID != "ofx:1234:A2" {
+		t.Fatalf("expected FITIDs to be used as external ids, got %+v", repo.inserted)
+	}
+	if len(repo.userFiles) != 1 || repo.userFiles[0].Type != "ofx" {
+		t.Fatalf("expected one ofx user file, got %+v", repo.userFiles)
+	}
+
+	accountID := uuid.New()
+	eurRepo := &fakeImportRepo{accountCurrency: "EUR"}
+	if _, err := NewImportService(eurRepo, logger).ImportWithOptions(context.Background(), uuid.New(), &accountID, data, mapping, ImportOptions{}); err == nil {
+		t.Fatal("expected currency mismatch error")
+	}
+	if len(eurRepo.userFiles) != 0 {
+		t.Fatal("expected no import job on currency mismatch")
+	}
+}
+
+func TestSaveMapping_PersistsLayout(t *testing.T) {
+	repo := &fakeImportRepo{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := NewImportService(repo, logger)
+	userID := uuid.New()
+
+	mapping := ColumnMapping{DateCol: 0, DescCol: 2, CategoryCol: -1, DebitCol: 3, CreditCol: 4, IsDoubleEntry: true, Delimiter: '\t', SkipLines: 6}
+	if err := svc.SaveMapping(context.Background(), userID, "fp", "CGD", mapping); err != nil {
+		t.Fatalf("SaveMapping failed: %v", err)
+	}
+	if len(repo.mappings) != 1 || repo.mappings[0].Delimiter != "\t" || repo.mappings[0].SkipLines != 6 {
+		t.Fatalf("unexpected stored mapping: %+v", repo.mappings)
+	}
+
+	// Saving again for the same fingerprint updates the user's mapping
+	mapping.SkipLines = 7
+	if err := svc.SaveMapping(context.Background(), userID, "fp", "CGD", mapping); err != nil {
+		t.Fatalf("SaveMapping (update) failed: %v", err)
+	}
+	if len(repo.mappings) != 1 || repo.mappings[0].SkipLines != 7 {
+		t.Fatalf("expected mapping to be updated in place, got %+v", repo.mappings)
+	}
+
+	mapping.Delimiter = 0
+	if err := svc.SaveMapping(context.Background(), userID, "fp", "CGD", mapping); err == nil {
+		t.Fatal("expected error for missing delimiter")
+	}
+}
+
+func TestImportWithExistingMapping(t *testing.T) {
+	data := []byte(strings.Join([]string{
+		"Conta;12345678901",
+		"Moeda;EUR",
+		"Data mov.;Descrição;Débito;Crédito;Saldo",
+		"02-01-2024;Pingo Doce;45,23;;954,77",
+		"05-01-2024;Transferência recebida;;500,00;1454,77",
+		"",
+	}, "\n"))
+
+	config, err := sniffer.DetectConfig(data)
+	if err != nil {
+		t.Fatalf("DetectConfig failed: %v", err)
+	}
+
+	userID := uuid.New()
+	otherUserID := uuid.New()
+	debitCol, creditCol := 2, 3
+	bankName := "Caixa"
+	newMapping := func(owner *uuid.UUID, fingerprint string, skipLines int) *repository.BankMapping {
+		return &repository.BankMapping{
+			ID:               uuid.New(),
+			UserID:           owner,
+			Fingerprint:      fingerprint,
+			BankName:         &bankName,
+			Delimiter:        ";",
+			SkipLines:        skipLines,
+			DateFormat:       "DD-MM-YYYY",
+			DateCol:          0,
+			DescCol:          1,
+			DebitCol:         &debitCol,
+			CreditCol:        &creditCol,
+			IsEuropeanFormat: true,
+		}
+	}
+
+	own := newMapping(&userID, config.Fingerprint, 2)
+	global := newMapping(nil, config.Fingerprint, 1) // header moved since the template was saved
+	foreign := newMapping(&otherUserID, config.Fingerprint, 2)
+	stale := newMapping(&userID, "different", 2)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	accountID := uuid.New()
+
+	for _, mapping := range []*repository.BankMapping{own, global} {
+		repo := &fakeImportRepo{accountCurrency: "EUR", mappings: []*repository.BankMapping{own, global, foreign, stale}}
+		svc := NewImportService(repo, logger)
+
+		result, err := svc.ImportWithExistingMapping(context.Background(), userID, &accountID, data, mapping.ID)
+		if err != nil {
+			t.Fatalf("ImportWithExistingMapping failed: %v", err)
+		}
+		if result.RowsImported != 2 || result.RowsFailed != 0 {
+			t.Fatalf("expected 2 imported rows, got %d / %d: %v", result.RowsImported, result.RowsFailed, result.Errors)
+		}
+		sort.Slice(repo.inserted, func(i, j int) bool { return repo.inserted[i].Date.Before(repo.inserted[j].Date) })
+		if repo.inserted[0].AmountCents != -4523 || repo.inserted[1].AmountCents != 50000 {
+			t.Fatalf("unexpected amounts: %d, %d", repo.inserted[0].AmountCents, repo.inserted[1].AmountCents)
+		}
+		if repo.institutionName != bankName {
+			t.Fatalf("expected institution name %q, got %q", bankName, repo.institutionName)
+		}
+	}
+
+	repo := &fakeImportRepo{accountCurrency: "EUR", mappings: []*repository.BankMapping{own, global, foreign, stale}}
+	svc := NewImportService(repo, logger)
+
+	if _, err := svc.ImportWithExistingMapping(context.Background(), userID, &accountID, data, foreign.ID); !errors.Is(err, ErrMappingNotFound) {
+		t.Fatalf("expected ErrMappingNotFound for another user's mapping, got %v", err)
+	}
+	if _, err := svc.ImportWithExistingMapping(context.Background(), userID, &accountID, data, uuid.New()); !errors.Is(err, ErrMappingNotFound) {
+		t.Fatalf("expected ErrMappingNotFound for unknown mapping, got %v", err)
+	}
+	if _, err := svc.ImportWithExistingMapping(context.Background(), userID, &accountID, data, stale.ID); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Fatalf("expected ErrFingerprintMismatch, got %v", err)
+	}
+	if len(repo.userFiles) != 0 {
+		t.Fatal("expected no import job for rejected mappings")
+	}
+}
+
 func BenchmarkParseTransactionsSequential(b *testing.B) {
 	data, config, mapping := benchmarkCSVFixture(5000)
 	svc := &ImportService{}
@@ -224,7 +648,7 @@ func BenchmarkParseTransactionsConcurrent(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping)
+		results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping, nil)
 		txCount := 0
 		errCount := len(preErrors)
 		for result := range results {
@@ -343,17 +767,62 @@ type fakeImportRepo struct {
 	bulkInserts       []int
 	progressSnapshots []progressSnapshot
 	accountCurrency   string
+	userFiles         []*repository.UserFile
+	inserted          []*repository.ParsedTransaction
+	mappings          []*repository.BankMapping
+	institutionName   string
+	jobs              []*repository.ImportJob
+	insertedByJob     map[uuid.UUID]int
+	existingIDs       map[string]bool
+	candidates        []*repository.DuplicateCandidate
+	links             []*repository.DuplicateLink
+	reviewReasons     map[uuid.UUID][]string
+	softDeleted       map[uuid.UUID]int    // Rows hidden by a rollback, by job
+	statusBefore      map[uuid.UUID]string // Job status before its rollback
+	documents         []*repository.Document
+	docCandidates     []*repository.DocumentCandidate
 }
 
 func (f *fakeImportRepo) GetMappingByFingerprint(ctx context.Context, fingerprint string, userID *uuid.UUID) (*repository.BankMapping, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range f.mappings {
+		if m.Fingerprint == fingerprint && (m.UserID == nil || (userID != nil && *m.UserID == *userID)) {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeImportRepo) GetMappingByID(ctx context.Context, id uuid.UUID) (*repository.BankMapping, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range f.mappings {
+		if m.ID == id {
+			return m, nil
+		}
+	}
 	return nil, nil
 }
 
 func (f *fakeImportRepo) CreateMapping(ctx context.Context, mapping *repository.BankMapping) error {
+	if mapping.ID == uuid.Nil {
+		mapping.ID = uuid.New()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mappings = append(f.mappings, mapping)
 	return nil
 }
 
 func (f *fakeImportRepo) UpdateMapping(ctx context.Context, mapping *repository.BankMapping) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, m := range f.mappings {
+		if m.ID == mapping.ID {
+			f.mappings[i] = mapping
+		}
+	}
 	return nil
 }
 
@@ -369,10 +838,52 @@ func (f *fakeImportRepo) CreateUserFile(ctx context.Context, file *repository.Us
 	if file.ID == uuid.Nil {
 		file.ID = uuid.New()
 	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.userFiles = append(f.userFiles, file)
 	return nil
 }
 
 func (f *fakeImportRepo) GetUserFileByID(ctx context.Context, id uuid.UUID) (*repository.UserFile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, file := range f.userFiles {
+		if file.ID == id {
+			return file, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeImportRepo) ListUserFiles(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*repository.UserFile, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var files []*repository.UserFile
+	for i := len(f.userFiles) - 1; i >= 0; i-- {
+		if f.userFiles[i].UserID == userID {
+			files = append(files, f.userFiles[i])
+		}
+	}
+	total := int64(len(files))
+	if offset >= len(files) {
+		return nil, total, nil
+	}
+	files = files[offset:]
+	if limit > 0 && limit < len(files) {
+		files = files[:limit]
+	}
+	return files, total, nil
+}
+
+func (f *fakeImportRepo) FindUserFileByChecksum(ctx context.Context, userID uuid.UUID, checksum string) (*repository.UserFile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.userFiles) - 1; i >= 0; i-- {
+		file := f.userFiles[i]
+		if file.UserID == userID && file.ChecksumSHA256 != nil && *file.ChecksumSHA256 == checksum {
+			return file, nil
+		}
+	}
 	return nil, nil
 }
 
@@ -380,13 +891,124 @@ func (f *fakeImportRepo) CreateImportJob(ctx context.Context, job *repository.Im
 	if job.ID == uuid.Nil {
 		job.ID = uuid.New()
 	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stored := *job
+	stored.RequestedAt = time.Now()
+	f.jobs = append(f.jobs, &stored)
 	return nil
 }
 
 func (f *fakeImportRepo) GetImportJobByID(ctx context.Context, id uuid.UUID) (*repository.ImportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if job := f.findJob(id); job != nil {
+		copied := *job
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeImportRepo) ListImportJobs(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*repository.ImportJob, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var jobs []*repository.ImportJob
+	for _, job := range f.jobs {
+		if job.UserID == userID {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	total := int64(len(jobs))
+	if offset >= len(jobs) {
+		return nil, total, nil
+	}
+	jobs = jobs[offset:]
+	if limit > 0 && limit < len(jobs) {
+		jobs = jobs[:limit]
+	}
+	return jobs, total, nil
+}
+
+func (f *fakeImportRepo) CancelImportJob(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job := f.findJob(id)
+	if job == nil || job.UserID != userID || (job.Status != "pending" && job.Status != "running") {
+		return false, nil
+	}
+	job.Status = "canceled"
+	return true, nil
+}
+
+func (f *fakeImportRepo) ClaimPendingImportJob(ctx context.Context) (*repository.ImportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, job := range f.jobs {
+		if job.Status == "pending" {
+			now := time.Now()
+			job.Status = "running"
+			job.StartedAt = &now
+			job.HeartbeatAt = &now
+			copied := *job
+			return &copied, nil
+		}
+	}
 	return nil, nil
 }
 
+func (f *fakeImportRepo) HeartbeatImportJob(ctx context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if job := f.findJob(id); job != nil && job.Status == "running" {
+		now := time.Now()
+		job.HeartbeatAt = &now
+	}
+	return nil
+}
+
+func (f *fakeImportRepo) RequeueImportJob(ctx context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if job := f.findJob(id); job != nil && job.Status == "running" {
+		job.Status = "pending"
+		job.StartedAt = nil
+		job.HeartbeatAt = nil
+	}
+	return nil
+}
+
+func (f *fakeImportRepo) RequeueStaleImportJobs(ctx context.Context, staleBefore time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requeued := 0
+	for _, job := range f.jobs {
+		if job.Status == "running" && (job.HeartbeatAt == nil || job.HeartbeatAt.Before(staleBefore)) {
+			job.Status = "pending"
+			job.StartedAt = nil
+			job.HeartbeatAt = nil
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (f *fakeImportRepo) CountTransactionsByImportJob(ctx context.Context, importJobID uuid.UUID) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.insertedByJob[importJobID], nil
+}
+
+// findJob must be called with f.mu held.
+func (f *fakeImportRepo) findJob(id uuid.UUID) *repository.ImportJob {
+	for _, job := range f.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
 func (f *fakeImportRepo) UpdateImportJobProgress(ctx context.Context, id uuid.UUID, rowsImported, rowsFailed int) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -402,6 +1024,15 @@ func (f *fakeImportRepo) UpdateImportJobStatus(ctx context.Context, id uuid.UUID
 }
 
 func (f *fakeImportRepo) FinishImportJob(ctx context.Context, id uuid.UUID, status string, rowsImported, rowsFailed int, errorMessage *string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if job := f.findJob(id); job != nil && job.Status != "canceled" {
+		job.Status = status
+		job.RowsImported = rowsImported
+		job.RowsFailed = rowsFailed
+		job.RowsTotal = rowsImported + rowsFailed
+		job.ErrorMessage = errorMessage
+	}
 	return nil
 }
 
@@ -409,15 +1040,255 @@ func (f *fakeImportRepo) BulkInsertTransactions(ctx context.Context, userID uuid
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.bulkInserts = append(f.bulkInserts, len(txs))
+	f.inserted = append(f.inserted, txs...)
+	f.institutionName = institutionName
+	if f.insertedByJob == nil {
+		f.insertedByJob = make(map[uuid.UUID]int)
+	}
+	f.insertedByJob[importJobID] += len(txs)
 	return len(txs), nil
 }
 
-func (f *fakeImportRepo) ListTransactions(ctx context.Context, userID uuid.UUID, filter repository.ListTransactionsFilter) ([]*repository.Transaction, int64, error) {
-	return nil, 0, nil
+func (f *fakeImportRepo) FindExistingExternalIDs(ctx context.Context, userID uuid.UUID, source string, externalIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	for _, id := range externalIDs {
+		if f.existingIDs[id] {
+			existing[id] = true
+		}
+	}
+	return existing, nil
+}
+
+func (f *fakeImportRepo) GetTransactionDateStats(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, from, to time.Time) (*repository.DateRangeStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stats := &repository.DateRangeStats{}
+	for _, tx := range f.inserted {
+		if tx.Date.Before(from) || tx.Date.After(to) {
+			continue
+		}
+		date := tx.Date
+		stats.Count++
+		if stats.First == nil || date.Before(*stats.First) {
+			stats.First = &date
+		}
+		if stats.Last == nil || date.After(*stats.Last) {
+			stats.Last = &date
+		}
+	}
+	return stats, nil
+}
+
+func (f *fakeImportRepo) FindDuplicateCandidates(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID, maxDays int) ([]*repository.DuplicateCandidate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.candidates, nil
+}
+
+func (f *fakeImportRepo) CreateDuplicateLinks(ctx context.Context, links []*repository.DuplicateLink) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, link := range links {
+		link.ID = uuid.New()
+		f.links = append(f.links, link)
+	}
+	return len(links), nil
+}
+
+func (f *fakeImportRepo) ListDuplicateLinks(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]*repository.DuplicateLink, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var links []*repository.DuplicateLink
+	for _, link := range f.links {
+		if link.UserID == userID && (status == "" || link.Status == status) {
+			links = append(links, link)
+		}
+	}
+	return links, int64(len(links)), nil
+}
+
+func (f *fakeImportRepo) DismissDuplicateLink(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, link := range f.links {
+		if link.ID == id && link.UserID == userID && link.Status == "pending" {
+			link.Status = "dismissed"
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeImportRepo) ConfirmDuplicateLink(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, link := range f.links {
+		if link.ID == id && link.UserID == userID && link.Status == "pending" {
+			f.links = append(f.links[:i], f.links[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeImportRepo) CreateDocument(ctx context.Context, doc *repository.Document) error {
+	if doc.ID == uuid.Nil {
+		doc.ID = uuid.New()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stored := *doc
+	stored.CreatedAt = time.Now()
+	f.documents = append(f.documents, &stored)
+	return nil
+}
+
+func (f *fakeImportRepo) GetDocumentByID(ctx context.Context, id uuid.UUID) (*repository.Document, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, doc := range f.documents {
+		if doc.ID == id {
+			copied := *doc
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeImportRepo) ListDocuments(ctx context.Context, userID uuid.UUID, transactionID *uuid.UUID, status string, limit, offset int) ([]*repository.Document, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var docs []*repository.Document
+	for _, doc := range f.documents {
+		attached := doc.TransactionID != nil
+		if doc.UserID != userID || (transactionID != nil && (!attached || *doc.TransactionID != *transactionID)) ||
+			(status == "attached" && !attached) || (status == "unattached" && attached) {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs, int64(len(docs)), nil
+}
+
+func (f *fakeImportRepo) AttachDocument(ctx context.Context, userID uuid.UUID, documentID uuid.UUID, transactionID uuid.UUID, source string, score *float64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, doc := range f.documents {
+		if doc.ID == documentID && doc.UserID == userID {
+			doc.TransactionID = &transactionID
+			doc.MatchSource = &source
+			doc.MatchScore = score
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeImportRepo) DetachDocument(ctx context.Context, userID uuid.UUID, documentID uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, doc := range f.documents {
+		if doc.ID == documentID && doc.UserID == userID {
+			manual := repository.DocumentMatchManual
+			doc.TransactionID = nil
+			doc.MatchSource = &manual
+			doc.MatchScore = nil
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeImportRepo) FindDocumentCandidates(ctx context.Context, userID uuid.UUID, maxDays int) ([]*repository.DocumentCandidate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.docCandidates, nil
+}
+
+func (f *fakeImportRepo) QueueForReview(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, reason string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.reviewReasons == nil {
+		f.reviewReasons = make(map[uuid.UUID][]string)
+	}
+	for _, id := range transactionIDs {
+		f.reviewReasons[id] = append(f.reviewReasons[id], reason)
+	}
+	return nil
+}
+
+func (f *fakeImportRepo) ListTransactions(ctx context.Context, userID uuid.UUID, filter repository.ListTransactionsFilter) ([]*repository.Transaction, string, error) {
+	return nil, "", nil
+}
+
+func (f *fakeImportRepo) RollbackImportJob(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job := f.findJob(importJobID)
+	if job == nil || job.UserID != userID || (job.Status != "succeeded" && job.Status != "failed" && job.Status != "canceled") {
+		return 0, false, nil
+	}
+	if f.softDeleted == nil {
+		f.softDeleted = make(map[uuid.UUID]int)
+		f.statusBefore = make(map[uuid.UUID]string)
+	}
+	now := time.Now()
+	f.statusBefore[job.ID] = job.Status
+	job.Status = "rolled_back"
+	job.RolledBackAt = &now
+	deleted := f.insertedByJob[job.ID]
+	f.softDeleted[job.ID] += deleted
+	delete(f.insertedByJob, job.ID)
+	return deleted, true, nil
+}
+
+func (f *fakeImportRepo) RestoreImportJob(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job := f.findJob(importJobID)
+	if job == nil || job.UserID != userID || job.Status != "rolled_back" || job.PurgedAt != nil {
+		return 0, false, nil
+	}
+	job.Status = f.statusBefore[job.ID]
+	job.RolledBackAt = nil
+	restored := f.softDeleted[job.ID]
+	if f.insertedByJob == nil {
+		f.insertedByJob = make(map[uuid.UUID]int)
+	}
+	f.insertedByJob[job.ID] += restored
+	delete(f.softDeleted, job.ID)
+	return restored, true, nil
+}
+
+func (f *fakeImportRepo) PurgeImportJob(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job := f.findJob(importJobID)
+	if job == nil || job.UserID != userID || job.Status != "rolled_back" || job.PurgedAt != nil {
+		return 0, false, nil
+	}
+	return f.purgeLocked(job), true, nil
+}
+
+func (f *fakeImportRepo) PurgeExpiredImportJobs(ctx context.Context, rolledBackBefore time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	purged := 0
+	for _, job := range f.jobs {
+		if job.Status == "rolled_back" && job.PurgedAt == nil && job.RolledBackAt.Before(rolledBackBefore) {
+			f.purgeLocked(job)
+			purged++
+		}
+	}
+	return purged, nil
 }
 
-func (f *fakeImportRepo) DeleteByImportJobID(ctx context.Context, userID uuid.UUID, importJobID uuid.UUID) (int, error) {
-	return 0, nil
+func (f *fakeImportRepo) purgeLocked(job *repository.ImportJob) int {
+	now := time.Now()
+	job.PurgedAt = &now
+	purged := f.softDeleted[job.ID]
+	delete(f.softDeleted, job.ID)
+	return purged
 }
 
 func (f *fakeImportRepo) bulkSizes() []int {
@@ -476,7 +1347,7 @@ func TestImportRealFile_Revolut(t *testing.T) {
 	}
 
 	svc := &ImportService{}
-	results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping)
+	results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping, nil)
 	if len(preErrors) != 0 {
 		t.Logf("pre-parse warnings: %v", preErrors)
 	}
@@ -513,7 +1384,10 @@ func TestImportRealFile_CaixaPortuguese(t *testing.T) {
 	}
 
 	// Normalize encoding (Portuguese files often use Latin-1)
-	data = normalizeCSVBytes(data)
+	data, _, err = charset.ToUTF8(data, "")
+	if err != nil {
+		t.Fatalf("ToUTF8 failed: %v", err)
+	}
 
 	config, err := sniffer.DetectConfig(data)
 	if err != nil {
@@ -551,7 +1425,7 @@ func TestImportRealFile_CaixaPortuguese(t *testing.T) {
 	}
 
 	svc := &ImportService{}
-	results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping)
+	results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping, nil)
 	if len(preErrors) != 0 {
 		t.Logf("pre-parse warnings: %v", preErrors)
 	}
@@ -612,7 +1486,7 @@ func BenchmarkParseTransactionsConcurrent_Revolut(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping)
+		results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping, nil)
 		txCount := 0
 		errCount := len(preErrors)
 		for result := range results {
@@ -649,7 +1523,7 @@ func BenchmarkParseTransactionsConcurrent_Caixa(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping)
+		results, preErrors := svc.parseTransactionsStream(context.Background(), data, config, mapping, nil)
 		txCount := 0
 		errCount := len(preErrors)
 		for result := range results {
@@ -703,7 +1577,10 @@ func loadCaixaFixture(b *testing.B) ([]byte, *sniffer.FileConfig, ColumnMapping)
 		b.Skipf("Skipping benchmark: %v", err)
 	}
 
-	data = normalizeCSVBytes(data)
+	data, _, err = charset.ToUTF8(data, "")
+	if err != nil {
+		b.Fatalf("ToUTF8 failed: %v", err)
+	}
 
 	config, err := sniffer.DetectConfig(data)
 	if err != nil {
@@ -11,6 +11,8 @@ import (
 	"io"
 	"strings"
 	"unicode"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/charset"
 )
 
 // Common bank statement header keywords (multi-language)
@@ -31,6 +33,7 @@ type FileConfig struct {
 	Headers     []string   // Detected header names
 	Fingerprint string     // SHA256 hash of normalized headers
 	SampleRows  [][]string // First few data rows for preview
+	Encoding    string     // Character encoding the file was decoded from (see package charset)
 }
 
 // DetectOptions allows callers to override header row or delimiter detection.
@@ -39,6 +42,8 @@ type DetectOptions struct {
 	HeaderRowIndex int
 	// Delimiter overrides the detected delimiter when non-zero.
 	Delimiter rune
+	// Encoding overrides the detected character encoding when non-empty.
+	Encoding string
 }
 
 // ColumnSuggestions provides auto-detected column indices
@@ -246,11 +251,21 @@ func DetectConfig(data []byte) (*FileConfig, error) {
 }
 
 // DetectConfigWithOptions analyzes a CSV/TSV file with optional overrides.
+// The data is transcoded to UTF-8 before detection.
 func DetectConfigWithOptions(data []byte, opts *DetectOptions) (*FileConfig, error) {
 	if len(data) == 0 {
 		return nil, ErrEmptyFile
 	}
 
+	var encodingOverride string
+	if opts != nil {
+		encodingOverride = opts.Encoding
+	}
+	data, encoding, err := charset.ToUTF8(data, encodingOverride)
+	if err != nil {
+		return nil, err
+	}
+
 	lines := strings.Split(string(data), "\n")
 	if len(lines) == 0 {
 		return nil, ErrEmptyFile
@@ -259,7 +274,6 @@ func DetectConfigWithOptions(data []byte, opts *DetectOptions) (*FileConfig, err
 	var (
 		delimiter rune
 		skipLines int
-		err       error
 	)
 	if opts != nil && opts.HeaderRowIndex >= 0 {
 		if opts.HeaderRowIndex >= len(lines) {
@@ -311,6 +325,7 @@ func DetectConfigWithOptions(data []byte, opts *DetectOptions) (*FileConfig, err
 		Headers:     headers,
 		Fingerprint: fingerprint,
 		SampleRows:  sampleRows,
+		Encoding:    encoding,
 	}, nil
 }
 
@@ -452,6 +467,62 @@ func findHeaderRow(lines []string) (rune, int, error) {
 	return 0, 0, ErrNoHeadersFound
 }
 
+// DetectHeaderRow locates the header row in already-split rows (e.g. spreadsheet
+// cells), using the same keyword scoring as findHeaderRow. It returns the 0-based row index.
+func DetectHeaderRow(rows [][]string) (int, error) {
+	fallbackIndex := -1
+	fallbackCount := 0
+
+	keywordIndex := -1
+	keywordCount := 0
+	keywordScore := 0
+
+	for i, row := range rows {
+		if i > 20 { // Don't search more than 20 rows
+			break
+		}
+
+		count := 0
+		keywordMatches := 0
+		for _, cell := range row {
+			cell = strings.ToLower(strings.TrimSpace(cell))
+			if cell == "" {
+				continue
+			}
+			count++
+			for _, kw := range headerKeywords {
+				if strings.Contains(cell, kw) {
+					keywordMatches++
+				}
+			}
+		}
+		if count < 2 {
+			continue
+		}
+
+		if keywordMatches > 0 {
+			score := count*10 + keywordMatches
+			if keywordIndex == -1 || score > keywordScore {
+				keywordIndex = i
+				keywordCount = count
+				keywordScore = score
+			}
+		} else if count > fallbackCount {
+			fallbackCount = count
+			fallbackIndex = i
+		}
+	}
+
+	if keywordIndex >= 0 && keywordCount >= 3 {
+		return keywordIndex, nil
+	}
+	if fallbackIndex >= 0 && fallbackCount >= 3 {
+		return fallbackIndex, nil
+	}
+
+	return 0, ErrNoHeadersFound
+}
+
 func cleanLine(line string, firstLine bool) string {
 	line = strings.TrimRight(line, "\r")
 	if firstLine {
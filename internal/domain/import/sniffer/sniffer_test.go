@@ -98,6 +98,30 @@ func TestDetectConfig_TSV(t *testing.T) {
 	}
 }
 
+func TestDetectConfig_Windows1252(t *testing.T) {
+	data := []byte("Data mov.;Descri\xe7\xe3o;Montante\n02-01-2024;Caf\xe9 \x80 5;-5,00\n")
+
+	config, err := DetectConfig(data)
+	if err != nil {
+		t.Fatalf("DetectConfig failed: %v", err)
+	}
+	if config.Encoding != "windows-1252" {
+		t.Errorf("Expected windows-1252, got %q", config.Encoding)
+	}
+	if config.Headers[1] != "Descrição" || config.SampleRows[0][1] != "Café € 5" {
+		t.Errorf("Expected transcoded text, got %q and %q", config.Headers[1], config.SampleRows[0][1])
+	}
+
+	// Decoding with the wrong encoding still works but yields a different fingerprint.
+	latin9, err := DetectConfigWithOptions(data, &DetectOptions{HeaderRowIndex: -1, Encoding: "iso-8859-15"})
+	if err != nil {
+		t.Fatalf("DetectConfigWithOptions failed: %v", err)
+	}
+	if latin9.Encoding != "iso-8859-15" || latin9.SampleRows[0][1] == config.SampleRows[0][1] {
+		t.Errorf("Expected the override to be used, got %q %q", latin9.Encoding, latin9.SampleRows[0][1])
+	}
+}
+
 func TestDetectConfig_EmptyFile(t *testing.T) {
 	_, err := DetectConfig([]byte{})
 	if err != ErrEmptyFile {
@@ -214,3 +238,54 @@ func TestGetSampleRows(t *testing.T) {
 		t.Errorf("First sample row description should contain 'Pingo Doce', got %s", rows[0][2])
 	}
 }
+
+func TestDetectHeaderRow(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     [][]string
+		expected int
+		wantErr  bool
+	}{
+		{
+			name: "metadata before headers",
+			rows: [][]string{
+				{"Conta", "12345678901"},
+				{"Moeda", "EUR"},
+				{"Data mov.", "Descrição", "Débito", "Crédito", "Saldo"},
+				{"2024-01-02", "Pingo Doce", "45.23", "", "954.77"},
+			},
+			expected: 2,
+		},
+		{
+			name: "headers without keywords fall back to widest row",
+			rows: [][]string{
+				{"Export"},
+				{"Quando", "Onde", "Quanto"},
+			},
+			expected: 1,
+		},
+		{
+			name:    "no header row",
+			rows:    [][]string{{"only"}, {"two", "cells"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectHeaderRow(tt.rows)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DetectHeaderRow failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected header row %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
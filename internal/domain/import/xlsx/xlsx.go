@@ -0,0 +1,534 @@
+// Package xlsx reads transaction tables out of Excel (.xlsx) workbooks.
+// It only understands cell values (no formulas or formatting beyond dates) and
+// turns each sheet into string rows so the CSV import pipeline can consume them.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotWorkbook    = errors.New("file is not an xlsx workbook")
+	ErrSheetNotFound  = errors.New("sheet not found in workbook")
+	ErrEmptyWorkbook  = errors.New("workbook has no sheets")
+	ErrPartTooLarge   = errors.New("workbook part exceeds size limit")
+	ErrCellOutOfRange = errors.New("cell is outside the sheet size limits")
+	errMissingPartFmt = "workbook is missing %s"
+)
+
+// maxPartSize caps how much uncompressed XML we read from a single zip entry.
+const maxPartSize = 256 << 20
+
+// Excel's own sheet limits. Row and cell references beyond them can only come
+// from a crafted file, and padding up to them would exhaust memory.
+const (
+	maxRows    = 1 << 20 // 1,048,576
+	maxColumns = 1 << 14 // 16,384 (XFD)
+)
+
+// Workbook is an opened .xlsx file.
+type Workbook struct {
+	zip           *zip.Reader
+	sheets        []sheetRef
+	sharedStrings []string
+	dateStyles    map[int]bool // cellXfs index -> formats as date/time
+	date1904      bool
+}
+
+type sheetRef struct {
+	name string
+	path string
+}
+
+// IsWorkbook reports whether data looks like an .xlsx (zip container with a workbook part).
+func IsWorkbook(data []byte) bool {
+	if len(data) < 4 || !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return false
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		if f.Name == "xl/workbook.xml" {
+			return true
+		}
+	}
+	return false
+}
+
+// Open parses the workbook structure, shared strings and date styles.
+func Open(data []byte) (*Workbook, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrNotWorkbook
+	}
+
+	wb := &Workbook{zip: zr, dateStyles: make(map[int]bool)}
+
+	if err := wb.readWorkbook(); err != nil {
+		return nil, err
+	}
+	if err := wb.readSharedStrings(); err != nil {
+		return nil, err
+	}
+	if err := wb.readStyles(); err != nil {
+		return nil, err
+	}
+
+	return wb, nil
+}
+
+// SheetNames returns the sheet names in workbook order.
+func (w *Workbook) SheetNames() []string {
+	names := make([]string, len(w.sheets))
+	for i, s := range w.sheets {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Rows returns every row of the named sheet as strings. An empty name selects
+// the first sheet. Numeric cells are rendered with '.' as decimal separator and
+// date-formatted cells as ISO dates ("2006-01-02" or "2006-01-02 15:04:05").
+func (w *Workbook) Rows(sheet string) ([][]string, error) {
+	if len(w.sheets) == 0 {
+		return nil, ErrEmptyWorkbook
+	}
+
+	ref := w.sheets[0]
+	if sheet != "" {
+		found := false
+		for _, s := range w.sheets {
+			if strings.EqualFold(s.name, sheet) {
+				ref = s
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrSheetNotFound, sheet)
+		}
+	}
+
+	raw, err := w.readPart(ref.path)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf(errMissingPartFmt, ref.path)
+	}
+
+	return w.parseSheet(raw)
+}
+
+// SerialToTime converts an Excel date serial into a UTC time.
+// Excel's 1900 system counts from 1899-12-30 (absorbing the fictitious 1900-02-29).
+func SerialToTime(serial float64, date1904 bool) time.Time {
+	base := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		base = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	// Round to the nearest second to avoid 23:59:59.999 artifacts.
+	seconds := math.Round((serial - days) * 86400)
+	return base.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+}
+
+func (w *Workbook) readPart(name string) ([]byte, error) {
+	for _, f := range w.zip.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if len(data) > maxPartSize {
+			return nil, ErrPartTooLarge
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+func (w *Workbook) readWorkbook() error {
+	raw, err := w.readPart("xl/workbook.xml")
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return ErrNotWorkbook
+	}
+
+	var doc struct {
+		Props struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("failed to parse workbook: %w", err)
+	}
+	w.date1904 = doc.Props.Date1904 == "1" || strings.EqualFold(doc.Props.Date1904, "true")
+
+	rels, err := w.readRelationships("xl/_rels/workbook.xml.rels")
+	if err != nil {
+		return err
+	}
+
+	for i, s := range doc.Sheets {
+		target, ok := rels[s.RID]
+		if !ok {
+			// Fall back to the conventional part name.
+			target = fmt.Sprintf("worksheets/sheet%d.xml", i+1)
+		}
+		w.sheets = append(w.sheets, sheetRef{name: s.Name, path: resolvePartPath("xl", target)})
+	}
+
+	return nil
+}
+
+func (w *Workbook) readRelationships(name string) (map[string]string, error) {
+	raw, err := w.readPart(name)
+	if err != nil {
+		return nil, err
+	}
+	rels := make(map[string]string)
+	if raw == nil {
+		return rels, nil
+	}
+
+	var doc struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	for _, r := range doc.Relationships {
+		rels[r.ID] = r.Target
+	}
+	return rels, nil
+}
+
+func resolvePartPath(base, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Clean(path.Join(base, target))
+}
+
+func (w *Workbook) readSharedStrings() error {
+	raw, err := w.readPart("xl/sharedStrings.xml")
+	if err != nil || raw == nil {
+		return err
+	}
+
+	var doc struct {
+		Items []richText `xml:"si"`
+	}
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("failed to parse shared strings: %w", err)
+	}
+
+	w.sharedStrings = make([]string, len(doc.Items))
+	for i, item := range doc.Items {
+		w.sharedStrings[i] = item.String()
+	}
+	return nil
+}
+
+// richText is either a plain <t> or a sequence of <r><t> runs.
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r richText) String() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var b strings.Builder
+	b.WriteString(r.Text)
+	for _, run := range r.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+func (w *Workbook) readStyles() error {
+	raw, err := w.readPart("xl/styles.xml")
+	if err != nil || raw == nil {
+		return err
+	}
+
+	var doc struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("failed to parse styles: %w", err)
+	}
+
+	custom := make(map[int]string, len(doc.NumFmts))
+	for _, f := range doc.NumFmts {
+		custom[f.ID] = f.Code
+	}
+
+	for i, xf := range doc.CellXfs {
+		if isBuiltinDateFormat(xf.NumFmtID) {
+			w.dateStyles[i] = true
+			continue
+		}
+		if code, ok := custom[xf.NumFmtID]; ok && isDateFormatCode(code) {
+			w.dateStyles[i] = true
+		}
+	}
+	return nil
+}
+
+// isBuiltinDateFormat reports whether a built-in number format id is a date/time format.
+func isBuiltinDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 45 && id <= 47)
+}
+
+// isDateFormatCode inspects a custom format code for date/time tokens,
+// ignoring quoted literals, escaped characters and [bracketed] sections.
+func isDateFormatCode(code string) bool {
+	inQuote := false
+	inBracket := false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case inQuote:
+			if c == '"' {
+				inQuote = false
+			}
+		case inBracket:
+			if c == ']' {
+				inBracket = false
+			}
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\' || c == '_' || c == '*':
+			i++ // skip the escaped/padding character
+		default:
+			switch c | 0x20 { // lower-case ASCII letters
+			case 'd', 'm', 'y', 'h', 's':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (w *Workbook) parseSheet(raw []byte) ([][]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+
+	var (
+		rows    [][]string
+		current []string
+		inRow   bool
+		nextCol int
+	)
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sheet: %w", err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "row":
+				rowIdx := len(rows)
+				if r := attr(el, "r"); r != "" {
+					if n, err := strconv.Atoi(r); err == nil && n > 0 {
+						rowIdx = n - 1
+					}
+				}
+				if rowIdx >= maxRows {
+					return nil, fmt.Errorf("%w: row %d", ErrCellOutOfRange, rowIdx+1)
+				}
+				// Materialize skipped (absent) rows so row indices match the sheet.
+				for len(rows) < rowIdx {
+					rows = append(rows, nil)
+				}
+				current = nil
+				nextCol = 0
+				inRow = true
+			case "c":
+				if !inRow {
+					continue
+				}
+				var c cellXML
+				if err := decoder.DecodeElement(&c, &el); err != nil {
+					return nil, fmt.Errorf("failed to parse cell: %w", err)
+				}
+				col := nextCol
+				if idx, ok := columnIndex(c.Ref); ok {
+					col = idx
+				}
+				if col >= maxColumns {
+					return nil, fmt.Errorf("%w: cell %q", ErrCellOutOfRange, c.Ref)
+				}
+				for len(current) < col {
+					current = append(current, "")
+				}
+				current = append(current, w.cellValue(c))
+				nextCol = col + 1
+			}
+		case xml.EndElement:
+			if el.Name.Local == "row" && inRow {
+				rows = append(rows, trimTrailingEmpty(current))
+				inRow = false
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+type cellXML struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  string   `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline richText `xml:"is"`
+}
+
+func (w *Workbook) cellValue(c cellXML) string {
+	switch c.Type {
+	case "s":
+		idx, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || idx < 0 || idx >= len(w.sharedStrings) {
+			return ""
+		}
+		return w.sharedStrings[idx]
+	case "inlineStr":
+		return c.Inline.String()
+	case "str", "e":
+		return c.Value
+	case "b":
+		if strings.TrimSpace(c.Value) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "d":
+		// ISO 8601 date cell (rare, written by some non-Excel producers).
+		if t, err := time.Parse(time.RFC3339, c.Value); err == nil {
+			return formatDate(t)
+		}
+		return c.Value
+	}
+
+	// Numeric (t="n" or omitted)
+	value := strings.TrimSpace(c.Value)
+	if value == "" {
+		return ""
+	}
+	num, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+
+	if style, err := strconv.Atoi(c.Style); err == nil && w.dateStyles[style] {
+		return formatDate(SerialToTime(num, w.date1904))
+	}
+
+	return formatNumber(num)
+}
+
+func formatDate(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// formatNumber renders a float the way Excel displays it in General format:
+// at most 15 significant digits, no exponent, '.' as decimal separator.
+func formatNumber(v float64) string {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 15, 64), 64)
+	if err != nil {
+		rounded = v
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+// columnIndex converts a cell reference like "AB12" to a 0-based column index.
+// Indexes past maxColumns are reported as maxColumns so long references cannot
+// overflow.
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r >= 'A' && r <= 'Z' {
+			col = min(col*26+int(r-'A'+1), maxColumns+1)
+			n++
+			continue
+		}
+		if r >= 'a' && r <= 'z' {
+			col = min(col*26+int(r-'a'+1), maxColumns+1)
+			n++
+			continue
+		}
+		break
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func trimTrailingEmpty(row []string) []string {
+	end := len(row)
+	for end > 0 && strings.TrimSpace(row[end-1]) == "" {
+		end--
+	}
+	return row[:end]
+}
@@ -0,0 +1,167 @@
+package xlsx
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/xlsx/xlsxtest"
+)
+
+func TestIsWorkbook(t *testing.T) {
+	data := xlsxtest.MustBuild(xlsxtest.Sheet{Name: "Sheet1", Rows: [][]any{{"a"}}})
+	if !IsWorkbook(data) {
+		t.Error("expected built workbook to be detected")
+	}
+	if IsWorkbook([]byte("Date,Description,Amount\n")) {
+		t.Error("expected CSV not to be detected as workbook")
+	}
+	if IsWorkbook([]byte("PK\x03\x04garbage")) {
+		t.Error("expected truncated zip not to be detected as workbook")
+	}
+}
+
+func TestRows_CellTypes(t *testing.T) {
+	data := xlsxtest.MustBuild(xlsxtest.Sheet{
+		Name: "Movimentos",
+		Rows: [][]any{
+			{"Data", "Descrição", "Valor", "Pago"},
+			{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "Pingo Doce", -45.23, true},
+			{time.Date(2024, 1, 3, 14, 30, 0, 0, time.UTC), "Salary", 2500, false},
+			{nil, "only description", nil, nil},
+		},
+	})
+
+	wb, err := Open(data)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if got := wb.SheetNames(); !reflect.DeepEqual(got, []string{"Movimentos"}) {
+		t.Errorf("SheetNames = %v", got)
+	}
+
+	rows, err := wb.Rows("")
+	if err != nil {
+		t.Fatalf("Rows failed: %v", err)
+	}
+
+	expected := [][]string{
+		{"Data", "Descrição", "Valor", "Pago"},
+		{"2024-01-02", "Pingo Doce", "-45.23", "TRUE"},
+		{"2024-01-03 14:30:00", "Salary", "2500", "FALSE"},
+		{"", "only description"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows =\n%q\nwant\n%q", rows, expected)
+	}
+}
+
+func TestRows_SelectSheet(t *testing.T) {
+	data := xlsxtest.MustBuild(
+		xlsxtest.Sheet{Name: "Summary", Rows: [][]any{{"Total", 10}}},
+		xlsxtest.Sheet{Name: "Transactions", Rows: [][]any{{"Date", "Amount"}}},
+	)
+
+	wb, err := Open(data)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	rows, err := wb.Rows("transactions")
+	if err != nil {
+		t.Fatalf("Rows failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0][0] != "Date" {
+		t.Errorf("unexpected rows: %q", rows)
+	}
+
+	if _, err := wb.Rows("Missing"); !errors.Is(err, ErrSheetNotFound) {
+		t.Errorf("expected ErrSheetNotFound, got %v", err)
+	}
+}
+
+func TestSerialToTime(t *testing.T) {
+	tests := []struct {
+		serial   float64
+		date1904 bool
+		expected time.Time
+	}{
+		{45293, false, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{45293.5, false, time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)},
+		{61, false, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{0, true, time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := SerialToTime(tt.serial, tt.date1904); !got.Equal(tt.expected) {
+			t.Errorf("SerialToTime(%v, %v) = %v, want %v", tt.serial, tt.date1904, got, tt.expected)
+		}
+	}
+}
+
+func TestIsDateFormatCode(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected bool
+	}{
+		{"dd/mm/yyyy", true},
+		{"yyyy-mm-dd hh:mm", true},
+		{"#,##0.00", false},
+		{`"Days: "0`, false},
+		{`[Red]0.00`, false},
+		{`0.00\d`, false},
+	}
+
+	for _, tt := range tests {
+		if got := isDateFormatCode(tt.code); got != tt.expected {
+			t.Errorf("isDateFormatCode(%q) = %v, want %v", tt.code, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{12.99, "12.99"},
+		{0.1 + 0.2, "0.3"},
+		{-1234.5, "-1234.5"},
+		{1e6, "1000000"},
+	}
+
+	for _, tt := range tests {
+		if got := formatNumber(tt.value); got != tt.expected {
+			t.Errorf("formatNumber(%v) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestParseSheet_RejectsCellsBeyondExcelLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		sheet string
+	}{
+		{"huge row number", `<worksheet><sheetData><row r="2000000000"><c><v>1</v></c></row></sheetData></worksheet>`},
+		{"row past the last", `<worksheet><sheetData><row r="1048577"><c><v>1</v></c></row></sheetData></worksheet>`},
+		{"huge column", `<worksheet><sheetData><row r="1"><c r="ZZZZZZZ1"><v>1</v></c></row></sheetData></worksheet>`},
+		{"column past XFD", `<worksheet><sheetData><row r="1"><c r="XFE1"><v>1</v></c></row></sheetData></worksheet>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&Workbook{}).parseSheet([]byte(tt.sheet))
+			if !errors.Is(err, ErrCellOutOfRange) {
+				t.Fatalf("expected ErrCellOutOfRange, got %v", err)
+			}
+		})
+	}
+
+	rows, err := (&Workbook{}).parseSheet([]byte(`<worksheet><sheetData><row r="3"><c r="XFD3"><v>7</v></c></row></sheetData></worksheet>`))
+	if err != nil {
+		t.Fatalf("cells within the limits should parse: %v", err)
+	}
+	if len(rows) != 3 || len(rows[2]) != maxColumns || rows[2][maxColumns-1] != "7" {
+		t.Errorf("expected the value in the last column of row 3, got %d rows", len(rows))
+	}
+}
@@ -0,0 +1,185 @@
+// Package xlsxtest builds small .xlsx workbooks in memory for tests.
+package xlsxtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sheet describes one worksheet. Cell values may be string, int, float64,
+// bool, time.Time (written as a date serial with a date style) or nil (empty).
+type Sheet struct {
+	Name string
+	Rows [][]any
+}
+
+// Build returns the bytes of a workbook containing the given sheets.
+func Build(sheets ...Sheet) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	var shared []string
+	sharedIndex := make(map[string]int)
+	stringRef := func(s string) int {
+		if idx, ok := sharedIndex[s]; ok {
+			return idx
+		}
+		sharedIndex[s] = len(shared)
+		shared = append(shared, s)
+		return len(shared) - 1
+	}
+
+	var workbook, rels, contentTypes strings.Builder
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	contentTypes.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+
+	for i, sheet := range sheets {
+		n := i + 1
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sheet.Name), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+
+		body, err := sheetXML(sheet, stringRef)
+		if err != nil {
+			return nil, err
+		}
+		if err := writePart(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", n), body); err != nil {
+			return nil, err
+		}
+	}
+
+	workbook.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`<Relationship Id="rIdStrings" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.xml"/>` +
+		`</Relationships>`)
+	contentTypes.WriteString(`</Types>`)
+
+	var sst strings.Builder
+	fmt.Fprintf(&sst, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+
+		`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="%d" uniqueCount="%d">`, len(shared), len(shared))
+	for _, s := range shared {
+		fmt.Fprintf(&sst, `<si><t xml:space="preserve">%s</t></si>`, escape(s))
+	}
+	sst.WriteString(`</sst>`)
+
+	// Style 0 is General, style 1 is a built-in date (numFmtId 14),
+	// style 2 is a custom date-time format.
+	styles := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="dd/mm/yyyy\ hh:mm"/></numFmts>` +
+		`<cellXfs count="3"><xf numFmtId="0"/><xf numFmtId="14" applyNumberFormat="1"/><xf numFmtId="164" applyNumberFormat="1"/></cellXfs>` +
+		`</styleSheet>`
+
+	parts := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+		{"xl/sharedStrings.xml", sst.String()},
+		{"xl/styles.xml", styles},
+	}
+	for _, p := range parts {
+		if err := writePart(zw, p.name, p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MustBuild is like Build but panics on error.
+func MustBuild(sheets ...Sheet) []byte {
+	data, err := Build(sheets...)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func sheetXML(sheet Sheet, stringRef func(string) int) (string, error) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	for r, row := range sheet.Rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, value := range row {
+			ref := columnName(c) + strconv.Itoa(r+1)
+			switch v := value.(type) {
+			case nil:
+				continue
+			case string:
+				fmt.Fprintf(&b, `<c r="%s" t="s"><v>%d</v></c>`, ref, stringRef(v))
+			case int:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+			case float64:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+			case bool:
+				val := 0
+				if v {
+					val = 1
+				}
+				fmt.Fprintf(&b, `<c r="%s" t="b"><v>%d</v></c>`, ref, val)
+			case time.Time:
+				style := 1
+				if v.Hour() != 0 || v.Minute() != 0 || v.Second() != 0 {
+					style = 2
+				}
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(timeToSerial(v), 'f', -1, 64))
+			default:
+				return "", fmt.Errorf("xlsxtest: unsupported cell type %T", value)
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String(), nil
+}
+
+func writePart(zw *zip.Writer, name, body string) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte(body))
+	return err
+}
+
+func timeToSerial(t time.Time) float64 {
+	base := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return t.Sub(base).Hours() / 24
+}
+
+func columnName(idx int) string {
+	name := ""
+	for idx >= 0 {
+		name = string(rune('A'+idx%26)) + name
+		idx = idx/26 - 1
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package insights
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// intentRegret is the intent users give spending they wish they had not done
+const intentRegret = "regret"
+
+// IntentBreakdown is one month's spending split by the intent users gave it
+type IntentBreakdown struct {
+	Month            time.Time     // First day of the month
+	TotalCents       int64         // All spending, with or without an intent
+	UntaggedCents    int64         // Spending without an intent
+	UnconvertedCount int           // Spending left out of the amounts for lack of an exchange rate
+	Intents          []IntentSpend // Largest first; intents without spending are left out
+}
+
+// RegretPoint is one month of the regret spend trend
+type RegretPoint struct {
+	Month        time.Time
+	AmountCents  int64
+	SharePercent float64 // Of the month's spending, so partial months compare fairly
+}
+
+// GetIntentBreakdown returns the spending by intent for the given number of
+// months up to asOf, oldest first. Months without spending are included.
+func (s *Service) GetIntentBreakdown(ctx context.Context, userID uuid.UUID, asOf time.Time, months int) ([]IntentBreakdown, error) {
+	if months < 1 {
+		months = 1
+	}
+	spends, err := s.repo.GetMonthlyIntentSpend(ctx, userID, asOf, months)
+	if err != nil {
+		return nil, err
+	}
+
+	year, month, _ := asOf.Date()
+	first := time.Date(year, month, 1, 0, 0, 0, 0, asOf.Location()).AddDate(0, -(months - 1), 0)
+	breakdowns := make([]IntentBreakdown, months)
+	for i := range breakdowns {
+		breakdowns[i].Month = first.AddDate(0, i, 0)
+	}
+
+	for _, spend := range spends {
+		i := monthIndex(first, spend.Month)
+		if i < 0 || i >= months {
+			continue
+		}
+		b := &breakdowns[i]
+		b.TotalCents += spend.AmountCents
+		b.UnconvertedCount += spend.UnconvertedCount
+		if spend.Intent == "" {
+			b.UntaggedCents += spend.AmountCents
+			continue
+		}
+		b.Intents = append(b.Intents, spend)
+	}
+	for i := range breakdowns {
+		sort.SliceStable(breakdowns[i].Intents, func(a, b int) bool {
+			return breakdowns[i].Intents[a].AmountCents > breakdowns[i].Intents[b].AmountCents
+		})
+	}
+
+	return breakdowns, nil
+}
+
+// GetRegretTrend returns the spending marked as regret per month, oldest first
+func (s *Service) GetRegretTrend(ctx context.Context, userID uuid.UUID, asOf time.Time, months int) ([]RegretPoint, error) {
+	breakdowns, err := s.GetIntentBreakdown(ctx, userID, asOf, months)
+	if err != nil {
+		return nil, err
+	}
+	return RegretTrend(breakdowns), nil
+}
+
+// RegretTrend picks the regret spend out of monthly intent breakdowns
+func RegretTrend(breakdowns []IntentBreakdown) []RegretPoint {
+	points := make([]RegretPoint, 0, len(breakdowns))
+	for _, b := range breakdowns {
+		point := RegretPoint{Month: b.Month}
+		for _, spend := range b.Intents {
+			if spend.Intent == intentRegret {
+				point.AmountCents = spend.AmountCents
+			}
+		}
+		if b.TotalCents > 0 {
+			point.SharePercent = float64(point.AmountCents) / float64(b.TotalCents) * 100
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// intentBlock builds the regret spend dashboard block comparing this month
+// with last month, or returns false until the user has marked any intent.
+func intentBlock(breakdowns []IntentBreakdown) (DashboardBlock, bool) {
+	tagged := false
+	for _, b := range breakdowns {
+		if len(b.Intents) > 0 {
+			tagged = true
+		}
+	}
+	if !tagged || len(breakdowns) < 2 {
+		return DashboardBlock{}, false
+	}
+
+	trend := RegretTrend(breakdowns)
+	current, last := trend[len(trend)-1], trend[len(trend)-2]
+
+	block := DashboardBlock{
+		Type:   "intent",
+		Title:  "Regret Spend",
+		Value:  formatMoney(current.AmountCents),
+		Icon:   "thumbs-down",
+		Color:  "green",
+		Action: "review_intents",
+	}
+	switch {
+	case current.SharePercent > last.SharePercent:
+		block.Subtitle = formatFloat(current.SharePercent, 1) + "% of spending, up from " + formatFloat(last.SharePercent, 1) + "%"
+		block.Color = "red"
+	case current.SharePercent < last.SharePercent:
+		block.Subtitle = formatFloat(current.SharePercent, 1) + "% of spending, down from " + formatFloat(last.SharePercent, 1) + "%"
+	default:
+		block.Subtitle = formatFloat(current.SharePercent, 1) + "% of spending, same as last month"
+	}
+	return block, true
+}
+
+func monthIndex(first, month time.Time) int {
+	return (month.Year()-first.Year())*12 + int(month.Month()) - int(first.Month())
+}
@@ -0,0 +1,86 @@
+package insights_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/insights"
+)
+
+var (
+	february = time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	march    = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func TestGetIntentBreakdown(t *testing.T) {
+	repo := NewMockInsightsRepo()
+	repo.intentSpend = []insights.IntentSpend{
+		{Month: february, Intent: "", AmountCents: 60000, TxCount: 20, UnconvertedCount: 2},
+		{Month: february, Intent: "regret", AmountCents: 10000, TxCount: 2, UnconvertedCount: 1},
+		{Month: february, Intent: "necessary", AmountCents: 30000, TxCount: 5},
+		{Month: march, Intent: "splurge", AmountCents: 5000, TxCount: 1},
+	}
+	svc := insights.NewService(repo, nil, nil, nil)
+
+	breakdowns, err := svc.GetIntentBreakdown(context.Background(), uuid.New(), time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), 3)
+	require.NoError(t, err)
+	require.Len(t, breakdowns, 3)
+
+	// January had no spending but is still reported
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), breakdowns[0].Month)
+	assert.Zero(t, breakdowns[0].TotalCents)
+
+	feb := breakdowns[1]
+	assert.Equal(t, int64(100000), feb.TotalCents)
+	assert.Equal(t, int64(60000), feb.UntaggedCents)
+	assert.Equal(t, 3, feb.UnconvertedCount, "amounts without a rate are reported, not dropped")
+	require.Len(t, feb.Intents, 2)
+	assert.Equal(t, "necessary", feb.Intents[0].Intent)
+
+	trend, err := svc.GetRegretTrend(context.Background(), uuid.New(), time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), 3)
+	require.NoError(t, err)
+	require.Len(t, trend, 3)
+	assert.Equal(t, int64(10000), trend[1].AmountCents)
+	assert.InDelta(t, 10.0, trend[1].SharePercent, 0.01)
+	assert.Zero(t, trend[2].AmountCents)
+}
+
+func TestGetDashboardBlocks_IntentBlock(t *testing.T) {
+	asOf := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	repo := NewMockInsightsRepo()
+	svc := insights.NewService(repo, nil, nil, nil)
+
+	// No intents marked yet: no intent block
+	blocks, err := svc.GetDashboardBlocks(context.Background(), uuid.New(), asOf)
+	require.NoError(t, err)
+	for _, b := range blocks {
+		assert.NotEqual(t, "intent", b.Type)
+	}
+
+	repo.intentSpend = []insights.IntentSpend{
+		{Month: february, Intent: "", AmountCents: 90000},
+		{Month: february, Intent: "regret", AmountCents: 10000},
+		{Month: march, Intent: "", AmountCents: 16000},
+		{Month: march, Intent: "regret", AmountCents: 4000},
+	}
+	blocks, err = svc.GetDashboardBlocks(context.Background(), uuid.New(), asOf)
+	require.NoError(t, err)
+
+	var intent *insights.DashboardBlock
+	for i := range blocks {
+		if blocks[i].Type == "intent" {
+			intent = &blocks[i]
+		}
+	}
+	require.NotNil(t, intent)
+	assert.Equal(t, "$40.00", intent.Value)
+	assert.Equal(t, "20.0% of spending, up from 10.0%", intent.Subtitle)
+	assert.Equal(t, "red", intent.Color)
+	assert.Equal(t, "cta", blocks[len(blocks)-1].Type)
+}
@@ -8,10 +8,13 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// SpendingPulseData contains the raw data for pulse calculation
+// SpendingPulseData contains the raw data for pulse calculation. Amounts here and
+// in SurpriseExpense, TopCategory and BundleSpend are converted to the user's base currency;
+// transactions without an exchange rate for their day are left out and counted instead.
 type SpendingPulseData struct {
 	CurrentMonthSpend int64     // Spend this month through asOf day
 	LastMonthSpend    int64     // Spend last month through same day
+	UnconvertedCount  int       // Spending of either period left out for lack of an exchange rate
 	CurrentMonthStart time.Time // Start of current month
 	LastMonthStart    time.Time // Start of last month
 	AsOfDate          time.Time // The reference date
@@ -36,17 +39,38 @@ type TopCategory struct {
 	TxCount      int
 }
 
+// BundleSpend represents spending on a user-defined tag bundle
+type BundleSpend struct {
+	BundleID    uuid.UUID
+	BundleName  string
+	AmountCents int64
+	TxCount     int
+}
+
+// IntentSpend is a month's spending with one intent ("" for spending without one)
+type IntentSpend struct {
+	Month            time.Time // First day of the month
+	Intent           string
+	AmountCents      int64
+	TxCount          int
+	UnconvertedCount int // Transactions left out of AmountCents for lack of an exchange rate
+}
+
 // InsightsRepository defines the interface for insights data access
 type InsightsRepository interface {
 	GetSpendingPulseData(ctx context.Context, userID uuid.UUID, asOf time.Time) (*SpendingPulseData, error)
 	GetTransactionCount(ctx context.Context, userID uuid.UUID, asOf time.Time) (int, error)
 	GetTopCategories(ctx context.Context, userID uuid.UUID, asOf time.Time, limit int) ([]TopCategory, error)
+	GetBundleSpending(ctx context.Context, userID uuid.UUID, asOf time.Time) ([]BundleSpend, error)
+	GetMonthlyIntentSpend(ctx context.Context, userID uuid.UUID, asOf time.Time, months int) ([]IntentSpend, error)
 	GetSurpriseExpenses(ctx context.Context, userID uuid.UUID, asOf time.Time, limit int) ([]SurpriseExpense, error)
 	HasAlertToday(ctx context.Context, userID uuid.UUID, alertType AlertType, date time.Time) (bool, error)
 	CreateAlert(ctx context.Context, alert *Alert) error
 	GetUnreadAlerts(ctx context.Context, userID uuid.UUID, limit int) ([]Alert, error)
 	MarkAlertRead(ctx context.Context, alertID uuid.UUID) error
 	MarkAlertDismissed(ctx context.Context, alertID uuid.UUID) error
+	GetReviewedOnly(ctx context.Context, userID uuid.UUID) (bool, error)
+	SetReviewedOnly(ctx context.Context, userID uuid.UUID, reviewedOnly bool) error
 }
 
 // Ensure Repository implements InsightsRepository
@@ -62,6 +86,29 @@ func NewRepository(db *pgxpool.Pool) *Repository {
 	return &Repository{db: db}
 }
 
+// excludeTransfers leaves out transactions paired as transfers between the
+// user's own accounts, which are neither spending nor income. Queries using it
+// alias transactions as t.
+const excludeTransfers = `NOT EXISTS (
+	SELECT 1 FROM transaction_transfers tr
+	WHERE tr.status <> 'dismissed'
+	  AND t.id IN (tr.outflow_transaction_id, tr.inflow_transaction_id)
+)`
+
+// convertedAmount is a transaction amount in the user's base currency at the
+// rate of its posting day, NULL when no rate is known. Sums skip those rows, so
+// queries report them with unconvertedCount. Queries using it join users u.
+const convertedAmount = `fx_convert(t.amount_minor, t.currency_code, u.base_currency, t.posted_at::date)`
+
+// unconvertedCount counts the rows convertedAmount has no rate for
+const unconvertedCount = `COUNT(*) FILTER (WHERE ` + convertedAmount + ` IS NULL)`
+
+// reviewScope leaves out transactions skipped in the review queue and, for
+// users who only want reviewed data in their insights, those still pending
+// review. Queries using it alias transactions as t and users as u.
+const reviewScope = `t.review_status IS DISTINCT FROM 'skipped'
+	AND (NOT u.insights_reviewed_only OR t.review_status IS DISTINCT FROM 'pending')`
+
 // GetSpendingPulseData fetches spending data for current vs last month comparison
 func (r *Repository) GetSpendingPulseData(ctx context.Context, userID uuid.UUID, asOf time.Time) (*SpendingPulseData, error) {
 	// Calculate date ranges
@@ -82,28 +129,36 @@ func (r *Repository) GetSpendingPulseData(ctx context.Context, userID uuid.UUID,
 
 	// Query current month spend (expenses only, negative amounts)
 	var currentSpend int64
+	var currentUnconverted int
 	err := r.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(ABS(amount_minor)), 0)
-		FROM transactions
-		WHERE user_id = $1
-		  AND posted_at >= $2
-		  AND posted_at < $3
-		  AND amount_minor < 0
-	`, userID, currentMonthStart, currentMonthEnd).Scan(&currentSpend)
+		SELECT COALESCE(SUM(ABS(`+convertedAmount+`)), 0), `+unconvertedCount+`
+		FROM transactions t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL
+		  AND t.posted_at >= $2
+		  AND t.posted_at < $3
+		  AND t.amount_minor < 0
+		  AND `+excludeTransfers+`
+		  AND `+reviewScope+`
+	`, userID, currentMonthStart, currentMonthEnd).Scan(&currentSpend, &currentUnconverted)
 	if err != nil {
 		return nil, err
 	}
 
 	// Query last month spend through same day
 	var lastSpend int64
+	var lastUnconverted int
 	err = r.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(ABS(amount_minor)), 0)
-		FROM transactions
-		WHERE user_id = $1
-		  AND posted_at >= $2
-		  AND posted_at <= $3
-		  AND amount_minor < 0
-	`, userID, lastMonthStart, lastMonthSameDay).Scan(&lastSpend)
+		SELECT COALESCE(SUM(ABS(`+convertedAmount+`)), 0), `+unconvertedCount+`
+		FROM transactions t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL
+		  AND t.posted_at >= $2
+		  AND t.posted_at <= $3
+		  AND t.amount_minor < 0
+		  AND `+excludeTransfers+`
+		  AND `+reviewScope+`
+	`, userID, lastMonthStart, lastMonthSameDay).Scan(&lastSpend, &lastUnconverted)
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +166,7 @@ func (r *Repository) GetSpendingPulseData(ctx context.Context, userID uuid.UUID,
 	return &SpendingPulseData{
 		CurrentMonthSpend: currentSpend,
 		LastMonthSpend:    lastSpend,
+		UnconvertedCount:  currentUnconverted + lastUnconverted,
 		CurrentMonthStart: currentMonthStart,
 		LastMonthStart:    lastMonthStart,
 		AsOfDate:          asOf,
@@ -128,24 +184,30 @@ func (r *Repository) GetSurpriseExpenses(ctx context.Context, userID uuid.UUID,
 	// Find transactions this month that have no similar merchant in last month
 	query := `
 		WITH current_month_txs AS (
-			SELECT t.id, t.description, t.merchant_name, t.amount_minor, t.posted_at, c.name as category_name
+			SELECT t.id, t.description, t.merchant_name,
+			       ` + convertedAmount + ` as amount_minor,
+			       t.posted_at, c.name as category_name
 			FROM transactions t
+			JOIN users u ON u.id = t.user_id
 			LEFT JOIN categories c ON t.category_id = c.id
-			WHERE t.user_id = $1
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL
 			  AND t.posted_at >= $2
 			  AND t.posted_at < $3
 			  AND t.amount_minor < 0
+			  AND ` + excludeTransfers + `
+			  AND ` + reviewScope + `
 		),
 		last_month_merchants AS (
 			SELECT DISTINCT COALESCE(merchant_name, description) as merchant
 			FROM transactions
-			WHERE user_id = $1
+			WHERE user_id = $1 AND deleted_at IS NULL
 			  AND posted_at >= $4
 			  AND posted_at < $5
 		)
 		SELECT cm.id, cm.description, cm.merchant_name, cm.amount_minor, cm.posted_at, cm.category_name
 		FROM current_month_txs cm
-		WHERE COALESCE(cm.merchant_name, cm.description) NOT IN (SELECT merchant FROM last_month_merchants)
+		WHERE cm.amount_minor IS NOT NULL
+		  AND COALESCE(cm.merchant_name, cm.description) NOT IN (SELECT merchant FROM last_month_merchants)
 		ORDER BY ABS(cm.amount_minor) DESC
 		LIMIT $6
 	`
@@ -181,22 +243,33 @@ func (r *Repository) GetSurpriseExpenses(ctx context.Context, userID uuid.UUID,
 	return expenses, rows.Err()
 }
 
-// GetTopCategories returns spending by category for current month
+// GetTopCategories returns spending by category for current month. Split
+// transactions count towards the categories of their splits.
 func (r *Repository) GetTopCategories(ctx context.Context, userID uuid.UUID, asOf time.Time, limit int) ([]TopCategory, error) {
 	year, month, _ := asOf.Date()
 	currentMonthStart := time.Date(year, month, 1, 0, 0, 0, 0, asOf.Location())
 
 	query := `
-		SELECT t.category_id, COALESCE(c.name, 'Uncategorized') as category_name,
-		       SUM(ABS(t.amount_minor)) as total_amount,
-		       COUNT(*) as tx_count
-		FROM transactions t
-		LEFT JOIN categories c ON t.category_id = c.id
-		WHERE t.user_id = $1
-		  AND t.posted_at >= $2
-		  AND t.posted_at < $3
-		  AND t.amount_minor < 0
-		GROUP BY t.category_id, c.name
+		WITH allocations AS (
+			SELECT t.id,
+			       CASE WHEN s.id IS NULL THEN t.category_id ELSE s.category_id END as category_id,
+			       fx_convert(COALESCE(s.amount_minor, t.amount_minor), t.currency_code, u.base_currency, t.posted_at::date) as amount_minor
+			FROM transactions t
+			JOIN users u ON u.id = t.user_id
+			LEFT JOIN transaction_splits s ON s.transaction_id = t.id
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL
+			  AND t.posted_at >= $2
+			  AND t.posted_at < $3
+			  AND t.amount_minor < 0
+			  AND ` + excludeTransfers + `
+			  AND ` + reviewScope + `
+		)
+		SELECT a.category_id, COALESCE(c.name, 'Uncategorized') as category_name,
+		       COALESCE(SUM(ABS(a.amount_minor)), 0) as total_amount,
+		       COUNT(DISTINCT a.id) as tx_count
+		FROM allocations a
+		LEFT JOIN categories c ON a.category_id = c.id
+		GROUP BY a.category_id, c.name
 		ORDER BY total_amount DESC
 		LIMIT $4
 	`
@@ -219,6 +292,106 @@ func (r *Repository) GetTopCategories(ctx context.Context, userID uuid.UUID, asO
 	return categories, rows.Err()
 }
 
+// GetBundleSpending returns spending per tag bundle for the current month. A
+// transaction counts towards a bundle when one of its tags, its category (or a
+// split's category) or its merchant is a member; each amount counts once per
+// bundle even when several members match. Bundles without spending are
+// included with zero.
+func (r *Repository) GetBundleSpending(ctx context.Context, userID uuid.UUID, asOf time.Time) ([]BundleSpend, error) {
+	year, month, _ := asOf.Date()
+	currentMonthStart := time.Date(year, month, 1, 0, 0, 0, 0, asOf.Location())
+
+	query := `
+		WITH allocations AS (
+			SELECT t.id, t.merchant_name,
+			       CASE WHEN s.id IS NULL THEN t.category_id ELSE s.category_id END as category_id,
+			       fx_convert(COALESCE(s.amount_minor, t.amount_minor), t.currency_code, u.base_currency, t.posted_at::date) as amount_minor
+			FROM transactions t
+			JOIN users u ON u.id = t.user_id
+			LEFT JOIN transaction_splits s ON s.transaction_id = t.id
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL
+			  AND t.posted_at >= $2
+			  AND t.posted_at < $3
+			  AND t.amount_minor < 0
+			  AND ` + excludeTransfers + `
+			  AND ` + reviewScope + `
+		)
+		SELECT b.id, b.name::text,
+		       COALESCE(SUM(ABS(a.amount_minor)), 0) as total_amount,
+		       COUNT(DISTINCT a.id) as tx_count
+		FROM tag_bundles b
+		LEFT JOIN allocations a ON EXISTS (
+			SELECT 1 FROM tag_bundle_members m
+			WHERE m.bundle_id = b.id
+			  AND (m.category_id = a.category_id
+			       OR m.merchant_name = a.merchant_name::citext
+			       OR m.tag_id IN (SELECT tt.tag_id FROM transaction_tags tt WHERE tt.transaction_id = a.id))
+		)
+		WHERE b.user_id = $1
+		GROUP BY b.id, b.name
+		ORDER BY total_amount DESC, b.name
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, currentMonthStart, asOf.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bundles []BundleSpend
+	for rows.Next() {
+		var b BundleSpend
+		if err := rows.Scan(&b.BundleID, &b.BundleName, &b.AmountCents, &b.TxCount); err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, b)
+	}
+
+	return bundles, rows.Err()
+}
+
+// GetMonthlyIntentSpend returns spending per month and intent for the given
+// number of months up to asOf, the current month included.
+func (r *Repository) GetMonthlyIntentSpend(ctx context.Context, userID uuid.UUID, asOf time.Time, months int) ([]IntentSpend, error) {
+	year, month, _ := asOf.Date()
+	start := time.Date(year, month, 1, 0, 0, 0, 0, asOf.Location()).AddDate(0, -(months - 1), 0)
+
+	query := `
+		SELECT date_trunc('month', t.posted_at)::date as month,
+		       COALESCE(t.intent, '') as intent,
+		       COALESCE(SUM(ABS(` + convertedAmount + `)), 0) as total_amount,
+		       COUNT(*) as tx_count,
+		       ` + unconvertedCount + ` as unconverted
+		FROM transactions t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL
+		  AND t.posted_at >= $2
+		  AND t.posted_at < $3
+		  AND t.amount_minor < 0
+		  AND ` + excludeTransfers + `
+		  AND ` + reviewScope + `
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, start, asOf.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spends []IntentSpend
+	for rows.Next() {
+		var s IntentSpend
+		if err := rows.Scan(&s.Month, &s.Intent, &s.AmountCents, &s.TxCount, &s.UnconvertedCount); err != nil {
+			return nil, err
+		}
+		spends = append(spends, s)
+	}
+
+	return spends, rows.Err()
+}
+
 // GetTransactionCount returns the number of transactions for current month
 func (r *Repository) GetTransactionCount(ctx context.Context, userID uuid.UUID, asOf time.Time) (int, error) {
 	year, month, _ := asOf.Date()
@@ -228,7 +401,7 @@ func (r *Repository) GetTransactionCount(ctx context.Context, userID uuid.UUID,
 	err := r.db.QueryRow(ctx, `
 		SELECT COUNT(*)
 		FROM transactions
-		WHERE user_id = $1
+		WHERE user_id = $1 AND deleted_at IS NULL
 		  AND posted_at >= $2
 		  AND posted_at < $3
 	`, userID, currentMonthStart, asOf.AddDate(0, 0, 1)).Scan(&count)
@@ -236,6 +409,19 @@ func (r *Repository) GetTransactionCount(ctx context.Context, userID uuid.UUID,
 	return count, err
 }
 
+// GetReviewedOnly reports whether the user's insights leave out transactions pending review
+func (r *Repository) GetReviewedOnly(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var reviewedOnly bool
+	err := r.db.QueryRow(ctx, `SELECT insights_reviewed_only FROM users WHERE id = $1`, userID).Scan(&reviewedOnly)
+	return reviewedOnly, err
+}
+
+// SetReviewedOnly sets whether the user's insights leave out transactions pending review
+func (r *Repository) SetReviewedOnly(ctx context.Context, userID uuid.UUID, reviewedOnly bool) error {
+	_, err := r.db.Exec(ctx, `UPDATE users SET insights_reviewed_only = $2 WHERE id = $1`, userID, reviewedOnly)
+	return err
+}
+
 // AlertType defines the type of alert
 type AlertType string
 
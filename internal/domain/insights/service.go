@@ -19,6 +19,7 @@ type SpendingPulse struct {
 	LastMonthSpend    int64   // In cents (through same day)
 	SpendDelta        int64   // Current - Last
 	PacePercent       float64 // (Current / Last) * 100, 100 = on track
+	UnconvertedCount  int     // Spending left out of both months for lack of an exchange rate
 
 	// Alerts
 	IsOverPace  bool    // True if spending pace > threshold
@@ -29,6 +30,7 @@ type SpendingPulse struct {
 	DayOfMonth       int
 	TransactionCount int
 	TopCategories    []TopCategory
+	Bundles          []BundleSpend
 	SurpriseExpenses []SurpriseExpense
 
 	// Timestamps
@@ -39,7 +41,7 @@ type SpendingPulse struct {
 
 // DashboardBlock represents a single block for the bento grid dashboard
 type DashboardBlock struct {
-	Type     string // "status", "hook", "cta"
+	Type     string // "status", "hook", "intent", "cta"
 	Title    string
 	Subtitle string
 	Value    string
@@ -94,6 +96,12 @@ func (s *Service) GetSpendingPulse(ctx context.Context, userID uuid.UUID, asOf t
 		categories = nil // Non-critical
 	}
 
+	// Get tag bundle spending
+	bundles, err := s.repo.GetBundleSpending(ctx, userID, asOf)
+	if err != nil {
+		bundles = nil // Non-critical
+	}
+
 	// Get surprise expenses
 	surprises, err := s.repo.GetSurpriseExpenses(ctx, userID, asOf, 3)
 	if err != nil {
@@ -105,9 +113,11 @@ func (s *Service) GetSpendingPulse(ctx context.Context, userID uuid.UUID, asOf t
 		CurrentMonthSpend: data.CurrentMonthSpend,
 		LastMonthSpend:    data.LastMonthSpend,
 		SpendDelta:        data.CurrentMonthSpend - data.LastMonthSpend,
+		UnconvertedCount:  data.UnconvertedCount,
 		DayOfMonth:        data.DayOfMonth,
 		TransactionCount:  txCount,
 		TopCategories:     categories,
+		Bundles:           bundles,
 		SurpriseExpenses:  surprises,
 		AsOfDate:          data.AsOfDate,
 		CurrentMonthStart: data.CurrentMonthStart,
@@ -145,7 +155,7 @@ func (s *Service) GetDashboardBlocks(ctx context.Context, userID uuid.UUID, asOf
 		return nil, err
 	}
 
-	blocks := make([]DashboardBlock, 0, 3)
+	blocks := make([]DashboardBlock, 0, 4)
 
 	// Block 1: Status - Pace indicator
 	statusColor := "green"
@@ -188,7 +198,14 @@ func (s *Service) GetDashboardBlocks(ctx context.Context, userID uuid.UUID, asOf
 		})
 	}
 
-	// Block 3: CTA - Action item
+	// Block 3: Intent - Regret spend trend, once the user marks intents
+	if breakdowns, err := s.GetIntentBreakdown(ctx, userID, asOf, 2); err == nil {
+		if block, ok := intentBlock(breakdowns); ok {
+			blocks = append(blocks, block)
+		}
+	}
+
+	// Block 4: CTA - Action item
 	// TODO: Check for uncategorized transactions
 	blocks = append(blocks, DashboardBlock{
 		Type:     "cta",
@@ -377,3 +394,14 @@ func (s *Service) MarkAlertRead(ctx context.Context, alertID uuid.UUID) error {
 func (s *Service) MarkAlertDismissed(ctx context.Context, alertID uuid.UUID) error {
 	return s.repo.MarkAlertDismissed(ctx, alertID)
 }
+
+// GetReviewedOnly reports whether the user's insights only use reviewed
+// transactions, leaving out those still pending in the review queue
+func (s *Service) GetReviewedOnly(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return s.repo.GetReviewedOnly(ctx, userID)
+}
+
+// SetReviewedOnly sets whether the user's insights only use reviewed transactions
+func (s *Service) SetReviewedOnly(ctx context.Context, userID uuid.UUID, reviewedOnly bool) error {
+	return s.repo.SetReviewedOnly(ctx, userID, reviewedOnly)
+}
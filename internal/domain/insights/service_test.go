@@ -17,6 +17,8 @@ type MockInsightsRepo struct {
 	alerts       []insights.Alert
 	alertsByUser map[uuid.UUID][]insights.Alert
 	alertToday   bool
+	intentSpend  []insights.IntentSpend
+	reviewedOnly bool
 }
 
 func NewMockInsightsRepo() *MockInsightsRepo {
@@ -30,6 +32,7 @@ func (m *MockInsightsRepo) GetSpendingPulseData(ctx context.Context, userID uuid
 	return &insights.SpendingPulseData{
 		CurrentMonthSpend: 50000, // $500
 		LastMonthSpend:    40000, // $400
+		UnconvertedCount:  2,
 		DayOfMonth:        15,
 		AsOfDate:          asOf,
 	}, nil
@@ -46,6 +49,16 @@ func (m *MockInsightsRepo) GetTopCategories(ctx context.Context, userID uuid.UUI
 	}, nil
 }
 
+func (m *MockInsightsRepo) GetBundleSpending(ctx context.Context, userID uuid.UUID, asOf time.Time) ([]insights.BundleSpend, error) {
+	return []insights.BundleSpend{
+		{BundleID: uuid.New(), BundleName: "Self-care", AmountCents: 6000, TxCount: 3},
+	}, nil
+}
+
+func (m *MockInsightsRepo) GetMonthlyIntentSpend(ctx context.Context, userID uuid.UUID, asOf time.Time, months int) ([]insights.IntentSpend, error) {
+	return m.intentSpend, nil
+}
+
 func (m *MockInsightsRepo) GetSurpriseExpenses(ctx context.Context, userID uuid.UUID, asOf time.Time, limit int) ([]insights.SurpriseExpense, error) {
 	return []insights.SurpriseExpense{}, nil
 }
@@ -114,6 +127,15 @@ func (m *MockInsightsRepo) MarkAlertDismissed(ctx context.Context, alertID uuid.
 	return nil
 }
 
+func (m *MockInsightsRepo) GetReviewedOnly(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return m.reviewedOnly, nil
+}
+
+func (m *MockInsightsRepo) SetReviewedOnly(ctx context.Context, userID uuid.UUID, reviewedOnly bool) error {
+	m.reviewedOnly = reviewedOnly
+	return nil
+}
+
 // SetAlertToday sets whether an alert exists today (for deduplication tests)
 func (m *MockInsightsRepo) SetAlertToday(exists bool) {
 	m.alertToday = exists
@@ -329,4 +351,8 @@ func TestSpendingPulse_CalculatesPaceCorrectly(t *testing.T) {
 	// IsOverPace is true when pace > PaceThreshold (125%)
 	// At exactly 125%, IsOverPace is false (not strictly over)
 	assert.False(t, pulse.IsOverPace) // 125% == threshold, not over
+	assert.Equal(t, 2, pulse.UnconvertedCount)
+
+	require.Len(t, pulse.Bundles, 1)
+	assert.Equal(t, "Self-care", pulse.Bundles[0].BundleName)
 }
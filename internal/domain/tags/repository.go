@@ -0,0 +1,396 @@
+// Package tags manages transaction tags and the bundles that group tags,
+// categories and merchants for reporting.
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tag is a user-defined label attached to transactions
+type Tag struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	Name             string
+	Color            *string
+	TransactionCount int // Set by ListTags
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// Bundle groups tags, categories and merchant names ("Self-care" = gym +
+// therapy + spa); a transaction belongs to it when any member matches.
+type Bundle struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	Description *string
+	Color       *string
+	TagIDs      []uuid.UUID
+	CategoryIDs []uuid.UUID
+	Merchants   []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TagRepository defines the interface for tag and bundle data access
+type TagRepository interface {
+	// Tags
+	CreateTag(ctx context.Context, tag *Tag) error
+	// GetTag returns a user's tag, or nil when it does not exist.
+	GetTag(ctx context.Context, userID, id uuid.UUID) (*Tag, error)
+	// FindTagByName returns the user's tag with the name (case-insensitive), or nil.
+	FindTagByName(ctx context.Context, userID uuid.UUID, name string) (*Tag, error)
+	ListTags(ctx context.Context, userID uuid.UUID) ([]*Tag, error)
+	UpdateTag(ctx context.Context, tag *Tag) error
+	DeleteTag(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	// CountTags returns how many of the IDs are tags of the user.
+	CountTags(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int, error)
+
+	// Transaction tags
+	// SetTransactionTags replaces a transaction's tags; false when the transaction does not exist.
+	SetTransactionTags(ctx context.Context, userID, transactionID uuid.UUID, tagIDs []uuid.UUID) (bool, error)
+	// ListTransactionTags returns tags keyed by transaction ID, sorted by name.
+	ListTransactionTags(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (map[uuid.UUID][]Tag, error)
+
+	// Bundles
+	CreateBundle(ctx context.Context, bundle *Bundle) error
+	// GetBundle returns a user's bundle with its members, or nil when it does not exist.
+	GetBundle(ctx context.Context, userID, id uuid.UUID) (*Bundle, error)
+	// FindBundleByName returns the user's bundle with the name (case-insensitive), or nil.
+	FindBundleByName(ctx context.Context, userID uuid.UUID, name string) (*Bundle, error)
+	ListBundles(ctx context.Context, userID uuid.UUID) ([]*Bundle, error)
+	// UpdateBundle saves the bundle and replaces its members.
+	UpdateBundle(ctx context.Context, bundle *Bundle) error
+	DeleteBundle(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	// CountCategories returns how many of the IDs are categories of the user.
+	CountCategories(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int, error)
+}
+
+// Ensure Repository implements TagRepository
+var _ TagRepository = (*Repository)(nil)
+
+// Repository handles database queries for tags and bundles
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates a new tags repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateTag inserts a tag and fills in its ID and timestamps
+func (r *Repository) CreateTag(ctx context.Context, tag *Tag) error {
+	if err := r.db.QueryRow(ctx, `
+		INSERT INTO tags (user_id, name, color)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`, tag.UserID, tag.Name, tag.Color).Scan(&tag.ID, &tag.CreatedAt, &tag.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+	return nil
+}
+
+// GetTag returns a user's tag, or nil when it does not exist
+func (r *Repository) GetTag(ctx context.Context, userID, id uuid.UUID) (*Tag, error) {
+	return r.getTag(ctx, `WHERE user_id = $1 AND id = $2`, userID, id)
+}
+
+// FindTagByName returns the user's tag with the name, or nil
+func (r *Repository) FindTagByName(ctx context.Context, userID uuid.UUID, name string) (*Tag, error) {
+	return r.getTag(ctx, `WHERE user_id = $1 AND name = $2`, userID, name)
+}
+
+func (r *Repository) getTag(ctx context.Context, where string, args ...any) (*Tag, error) {
+	var tag Tag
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, name::text, color, created_at, updated_at
+		FROM tags
+		`+where, args...).Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+	return &tag, nil
+}
+
+// ListTags returns the user's tags by name with how many transactions carry each
+func (r *Repository) ListTags(ctx context.Context, userID uuid.UUID) ([]*Tag, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT tg.id, tg.user_id, tg.name::text, tg.color, tg.created_at, tg.updated_at,
+		       (SELECT COUNT(*) FROM transaction_tags tt WHERE tt.tag_id = tg.id)
+		FROM tags tg
+		WHERE tg.user_id = $1
+		ORDER BY tg.name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []*Tag
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.UpdatedAt, &tag.TransactionCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, &tag)
+	}
+	return tags, rows.Err()
+}
+
+// UpdateTag saves a tag's name and color
+func (r *Repository) UpdateTag(ctx context.Context, tag *Tag) error {
+	if err := r.db.QueryRow(ctx, `
+		UPDATE tags SET name = $3, color = $4
+		WHERE id = $1 AND user_id = $2
+		RETURNING updated_at
+	`, tag.ID, tag.UserID, tag.Name, tag.Color).Scan(&tag.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update tag: %w", err)
+	}
+	return nil
+}
+
+// DeleteTag deletes a tag; its transaction links and bundle memberships go with it
+func (r *Repository) DeleteTag(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tag: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// CountTags returns how many of the IDs are tags of the user
+func (r *Repository) CountTags(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM tags WHERE user_id = $1 AND id = ANY($2)
+	`, userID, ids).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tags: %w", err)
+	}
+	return count, nil
+}
+
+// SetTransactionTags replaces a transaction's tags in one database transaction
+func (r *Repository) SetTransactionTags(ctx context.Context, userID, transactionID uuid.UUID, tagIDs []uuid.UUID) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the transaction row so concurrent edits apply one after the other
+	var locked uuid.UUID
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM transactions WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE
+	`, transactionID, userID).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM transaction_tags WHERE transaction_id = $1`, transactionID); err != nil {
+		return false, fmt.Errorf("failed to delete transaction tags: %w", err)
+	}
+	if len(tagIDs) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO transaction_tags (transaction_id, tag_id)
+			SELECT $1, id FROM tags WHERE user_id = $2 AND id = ANY($3)
+		`, transactionID, userID, tagIDs); err != nil {
+			return false, fmt.Errorf("failed to insert transaction tags: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction tags: %w", err)
+	}
+	return true, nil
+}
+
+// ListTransactionTags returns tags keyed by transaction ID, sorted by name
+func (r *Repository) ListTransactionTags(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (map[uuid.UUID][]Tag, error) {
+	result := make(map[uuid.UUID][]Tag)
+	if len(transactionIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT tt.transaction_id, tg.id, tg.user_id, tg.name::text, tg.color, tg.created_at, tg.updated_at
+		FROM transaction_tags tt
+		JOIN tags tg ON tg.id = tt.tag_id
+		WHERE tg.user_id = $1 AND tt.transaction_id = ANY($2)
+		ORDER BY tt.transaction_id, tg.name
+	`, userID, transactionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transaction tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionID uuid.UUID
+		var tag Tag
+		if err := rows.Scan(&transactionID, &tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction tag: %w", err)
+		}
+		result[transactionID] = append(result[transactionID], tag)
+	}
+	return result, rows.Err()
+}
+
+// CreateBundle inserts a bundle with its members
+func (r *Repository) CreateBundle(ctx context.Context, bundle *Bundle) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO tag_bundles (user_id, name, description, color)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, bundle.UserID, bundle.Name, bundle.Description, bundle.Color).Scan(&bundle.ID, &bundle.CreatedAt, &bundle.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	if err := insertBundleMembers(ctx, tx, bundle); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit bundle: %w", err)
+	}
+	return nil
+}
+
+// UpdateBundle saves the bundle and replaces its members
+func (r *Repository) UpdateBundle(ctx context.Context, bundle *Bundle) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, `
+		UPDATE tag_bundles SET name = $3, description = $4, color = $5
+		WHERE id = $1 AND user_id = $2
+		RETURNING updated_at
+	`, bundle.ID, bundle.UserID, bundle.Name, bundle.Description, bundle.Color).Scan(&bundle.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update bundle: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM tag_bundle_members WHERE bundle_id = $1`, bundle.ID); err != nil {
+		return fmt.Errorf("failed to delete bundle members: %w", err)
+	}
+	if err := insertBundleMembers(ctx, tx, bundle); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit bundle: %w", err)
+	}
+	return nil
+}
+
+func insertBundleMembers(ctx context.Context, tx pgx.Tx, bundle *Bundle) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO tag_bundle_members (bundle_id, tag_id)
+		SELECT $1, unnest($2::uuid[])
+	`, bundle.ID, bundle.TagIDs); err != nil {
+		return fmt.Errorf("failed to insert bundle tags: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO tag_bundle_members (bundle_id, category_id)
+		SELECT $1, unnest($2::uuid[])
+	`, bundle.ID, bundle.CategoryIDs); err != nil {
+		return fmt.Errorf("failed to insert bundle categories: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO tag_bundle_members (bundle_id, merchant_name)
+		SELECT $1, unnest($2::text[])
+	`, bundle.ID, bundle.Merchants); err != nil {
+		return fmt.Errorf("failed to insert bundle merchants: %w", err)
+	}
+	return nil
+}
+
+// GetBundle returns a user's bundle with its members, or nil when it does not exist
+func (r *Repository) GetBundle(ctx context.Context, userID, id uuid.UUID) (*Bundle, error) {
+	bundles, err := r.listBundles(ctx, `AND b.id = $2`, userID, id)
+	if err != nil || len(bundles) == 0 {
+		return nil, err
+	}
+	return bundles[0], nil
+}
+
+// FindBundleByName returns the user's bundle with the name, or nil
+func (r *Repository) FindBundleByName(ctx context.Context, userID uuid.UUID, name string) (*Bundle, error) {
+	bundles, err := r.listBundles(ctx, `AND b.name = $2`, userID, name)
+	if err != nil || len(bundles) == 0 {
+		return nil, err
+	}
+	return bundles[0], nil
+}
+
+// ListBundles returns the user's bundles by name with their members
+func (r *Repository) ListBundles(ctx context.Context, userID uuid.UUID) ([]*Bundle, error) {
+	return r.listBundles(ctx, "", userID)
+}
+
+func (r *Repository) listBundles(ctx context.Context, where string, args ...any) ([]*Bundle, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT b.id, b.user_id, b.name::text, b.description, b.color, b.created_at, b.updated_at,
+		       COALESCE(array_agg(m.tag_id ORDER BY m.tag_id) FILTER (WHERE m.tag_id IS NOT NULL), '{}'),
+		       COALESCE(array_agg(m.category_id ORDER BY m.category_id) FILTER (WHERE m.category_id IS NOT NULL), '{}'),
+		       COALESCE(array_agg(m.merchant_name::text ORDER BY m.merchant_name) FILTER (WHERE m.merchant_name IS NOT NULL), '{}')
+		FROM tag_bundles b
+		LEFT JOIN tag_bundle_members m ON m.bundle_id = b.id
+		WHERE b.user_id = $1 `+where+`
+		GROUP BY b.id
+		ORDER BY b.name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bundles: %w", err)
+	}
+	defer rows.Close()
+
+	var bundles []*Bundle
+	for rows.Next() {
+		var b Bundle
+		if err := rows.Scan(&b.ID, &b.UserID, &b.Name, &b.Description, &b.Color, &b.CreatedAt, &b.UpdatedAt,
+			&b.TagIDs, &b.CategoryIDs, &b.Merchants); err != nil {
+			return nil, fmt.Errorf("failed to scan bundle: %w", err)
+		}
+		bundles = append(bundles, &b)
+	}
+	return bundles, rows.Err()
+}
+
+// DeleteBundle deletes a bundle; its tags and categories are kept
+func (r *Repository) DeleteBundle(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM tag_bundles WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete bundle: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// CountCategories returns how many of the IDs are categories of the user
+func (r *Repository) CountCategories(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM categories WHERE user_id = $1 AND id = ANY($2)
+	`, userID, ids).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count categories: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,308 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidTag is returned when a tag name is empty or too long.
+	ErrInvalidTag = errors.New("invalid tag")
+	// ErrTagNotFound is returned when a tag does not exist for the user.
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrTagExists is returned when the user already has a tag with the name.
+	ErrTagExists = errors.New("tag already exists")
+	// ErrInvalidBundle is returned when a bundle has no name or no members, or
+	// references tags or categories the user does not have.
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrBundleNotFound is returned when a bundle does not exist for the user.
+	ErrBundleNotFound = errors.New("bundle not found")
+	// ErrBundleExists is returned when the user already has a bundle with the name.
+	ErrBundleExists = errors.New("bundle already exists")
+	// ErrTransactionNotFound is returned when tagging a transaction the user does not have.
+	ErrTransactionNotFound = errors.New("transaction not found")
+)
+
+// maxNameLength bounds tag and bundle names so they fit on a chip in the UI
+const maxNameLength = 50
+
+// Service handles tag and bundle business logic
+type Service struct {
+	repo TagRepository
+}
+
+// NewService creates a new tags service
+func NewService(repo TagRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// CreateTag creates a tag; names are unique per user regardless of case.
+func (s *Service) CreateTag(ctx context.Context, userID uuid.UUID, name string, color *string) (*Tag, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTag, err)
+	}
+
+	existing, err := s.repo.FindTagByName(ctx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrTagExists
+	}
+
+	tag := &Tag{UserID: userID, Name: name, Color: cleanOptional(color)}
+	if err := s.repo.CreateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// ListTags returns the user's tags with their usage counts
+func (s *Service) ListTags(ctx context.Context, userID uuid.UUID) ([]*Tag, error) {
+	return s.repo.ListTags(ctx, userID)
+}
+
+// UpdateTag renames or recolors a tag; nil leaves a field unchanged and an
+// empty color clears it.
+func (s *Service) UpdateTag(ctx context.Context, userID, id uuid.UUID, name, color *string) (*Tag, error) {
+	tag, err := s.repo.GetTag(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+
+	if name != nil {
+		cleaned, err := cleanName(*name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTag, err)
+		}
+		existing, err := s.repo.FindTagByName(ctx, userID, cleaned)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.ID != tag.ID {
+			return nil, ErrTagExists
+		}
+		tag.Name = cleaned
+	}
+	if color != nil {
+		tag.Color = cleanOptional(color)
+	}
+
+	if err := s.repo.UpdateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// DeleteTag deletes a tag, removing it from transactions and bundles
+func (s *Service) DeleteTag(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.repo.DeleteTag(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// SetTransactionTags replaces the tags on a transaction; none clears them.
+func (s *Service) SetTransactionTags(ctx context.Context, userID, transactionID uuid.UUID, tagIDs []uuid.UUID) ([]Tag, error) {
+	tagIDs = uniqueIDs(tagIDs)
+	if len(tagIDs) > 0 {
+		count, err := s.repo.CountTags(ctx, userID, tagIDs)
+		if err != nil {
+			return nil, err
+		}
+		if count != len(tagIDs) {
+			return nil, ErrTagNotFound
+		}
+	}
+
+	found, err := s.repo.SetTransactionTags(ctx, userID, transactionID, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrTransactionNotFound
+	}
+
+	tags, err := s.repo.ListTransactionTags(ctx, userID, []uuid.UUID{transactionID})
+	if err != nil {
+		return nil, err
+	}
+	return tags[transactionID], nil
+}
+
+// ListTransactionTags returns the tags of the given transactions keyed by transaction ID
+func (s *Service) ListTransactionTags(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (map[uuid.UUID][]Tag, error) {
+	return s.repo.ListTransactionTags(ctx, userID, transactionIDs)
+}
+
+// BundleInput describes a bundle to create or the full new state of one to update
+type BundleInput struct {
+	Name        string
+	Description *string
+	Color       *string
+	TagIDs      []uuid.UUID
+	CategoryIDs []uuid.UUID
+	Merchants   []string
+}
+
+// CreateBundle creates a bundle; it needs a name and at least one member.
+func (s *Service) CreateBundle(ctx context.Context, userID uuid.UUID, input BundleInput) (*Bundle, error) {
+	bundle := &Bundle{UserID: userID}
+	if err := s.applyBundleInput(ctx, bundle, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateBundle(ctx, bundle); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// UpdateBundle replaces a bundle's name, description, color and members
+func (s *Service) UpdateBundle(ctx context.Context, userID, id uuid.UUID, input BundleInput) (*Bundle, error) {
+	bundle, err := s.repo.GetBundle(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return nil, ErrBundleNotFound
+	}
+
+	if err := s.applyBundleInput(ctx, bundle, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateBundle(ctx, bundle); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// ListBundles returns the user's bundles with their members
+func (s *Service) ListBundles(ctx context.Context, userID uuid.UUID) ([]*Bundle, error) {
+	return s.repo.ListBundles(ctx, userID)
+}
+
+// DeleteBundle deletes a bundle; its tags and categories are kept
+func (s *Service) DeleteBundle(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.repo.DeleteBundle(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrBundleNotFound
+	}
+	return nil
+}
+
+// applyBundleInput validates input and copies it onto bundle
+func (s *Service) applyBundleInput(ctx context.Context, bundle *Bundle, input BundleInput) error {
+	name, err := cleanName(input.Name)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	existing, err := s.repo.FindBundleByName(ctx, bundle.UserID, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != bundle.ID {
+		return ErrBundleExists
+	}
+
+	tagIDs := uniqueIDs(input.TagIDs)
+	categoryIDs := uniqueIDs(input.CategoryIDs)
+	merchants := uniqueMerchants(input.Merchants)
+	if len(tagIDs)+len(categoryIDs)+len(merchants) == 0 {
+		return fmt.Errorf("%w: add at least one tag, category or merchant", ErrInvalidBundle)
+	}
+
+	if len(tagIDs) > 0 {
+		count, err := s.repo.CountTags(ctx, bundle.UserID, tagIDs)
+		if err != nil {
+			return err
+		}
+		if count != len(tagIDs) {
+			return fmt.Errorf("%w: unknown tag", ErrInvalidBundle)
+		}
+	}
+	if len(categoryIDs) > 0 {
+		count, err := s.repo.CountCategories(ctx, bundle.UserID, categoryIDs)
+		if err != nil {
+			return err
+		}
+		if count != len(categoryIDs) {
+			return fmt.Errorf("%w: unknown category", ErrInvalidBundle)
+		}
+	}
+
+	bundle.Name = name
+	bundle.Description = cleanOptional(input.Description)
+	bundle.Color = cleanOptional(input.Color)
+	bundle.TagIDs = tagIDs
+	bundle.CategoryIDs = categoryIDs
+	bundle.Merchants = merchants
+	return nil
+}
+
+func cleanName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return "", fmt.Errorf("name is longer than %d characters", maxNameLength)
+	}
+	return name, nil
+}
+
+// cleanOptional trims value; nil and blank both become nil
+func cleanOptional(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// uniqueMerchants trims merchant names and drops blanks and case-insensitive repeats
+func uniqueMerchants(merchants []string) []string {
+	seen := make(map[string]bool, len(merchants))
+	unique := make([]string, 0, len(merchants))
+	for _, m := range merchants {
+		m = strings.TrimSpace(m)
+		key := strings.ToLower(m)
+		if m == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, m)
+	}
+	return unique
+}
@@ -0,0 +1,296 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockTagRepository keeps tags, bundles and transaction tags in memory
+type MockTagRepository struct {
+	tags            map[uuid.UUID]*Tag
+	bundles         map[uuid.UUID]*Bundle
+	categories      map[uuid.UUID]bool
+	transactions    map[uuid.UUID]bool
+	transactionTags map[uuid.UUID][]uuid.UUID
+}
+
+func newMockRepo() *MockTagRepository {
+	return &MockTagRepository{
+		tags:            make(map[uuid.UUID]*Tag),
+		bundles:         make(map[uuid.UUID]*Bundle),
+		categories:      make(map[uuid.UUID]bool),
+		transactions:    make(map[uuid.UUID]bool),
+		transactionTags: make(map[uuid.UUID][]uuid.UUID),
+	}
+}
+
+func (m *MockTagRepository) CreateTag(ctx context.Context, tag *Tag) error {
+	tag.ID = uuid.New()
+	stored := *tag
+	m.tags[tag.ID] = &stored
+	return nil
+}
+
+func (m *MockTagRepository) GetTag(ctx context.Context, userID, id uuid.UUID) (*Tag, error) {
+	tag, ok := m.tags[id]
+	if !ok || tag.UserID != userID {
+		return nil, nil
+	}
+	copied := *tag
+	return &copied, nil
+}
+
+func (m *MockTagRepository) FindTagByName(ctx context.Context, userID uuid.UUID, name string) (*Tag, error) {
+	for _, tag := range m.tags {
+		if tag.UserID == userID && strings.EqualFold(tag.Name, name) {
+			copied := *tag
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockTagRepository) ListTags(ctx context.Context, userID uuid.UUID) ([]*Tag, error) {
+	var result []*Tag
+	for _, tag := range m.tags {
+		if tag.UserID == userID {
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTagRepository) UpdateTag(ctx context.Context, tag *Tag) error {
+	stored := *tag
+	m.tags[tag.ID] = &stored
+	return nil
+}
+
+func (m *MockTagRepository) DeleteTag(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	_, ok := m.tags[id]
+	delete(m.tags, id)
+	return ok, nil
+}
+
+func (m *MockTagRepository) CountTags(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int, error) {
+	count := 0
+	for _, id := range ids {
+		if tag, ok := m.tags[id]; ok && tag.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockTagRepository) SetTransactionTags(ctx context.Context, userID, transactionID uuid.UUID, tagIDs []uuid.UUID) (bool, error) {
+	if !m.transactions[transactionID] {
+		return false, nil
+	}
+	m.transactionTags[transactionID] = tagIDs
+	return true, nil
+}
+
+func (m *MockTagRepository) ListTransactionTags(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (map[uuid.UUID][]Tag, error) {
+	result := make(map[uuid.UUID][]Tag)
+	for _, txID := range transactionIDs {
+		for _, tagID := range m.transactionTags[txID] {
+			result[txID] = append(result[txID], *m.tags[tagID])
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTagRepository) CreateBundle(ctx context.Context, bundle *Bundle) error {
+	bundle.ID = uuid.New()
+	stored := *bundle
+	m.bundles[bundle.ID] = &stored
+	return nil
+}
+
+func (m *MockTagRepository) GetBundle(ctx context.Context, userID, id uuid.UUID) (*Bundle, error) {
+	bundle, ok := m.bundles[id]
+	if !ok || bundle.UserID != userID {
+		return nil, nil
+	}
+	copied := *bundle
+	return &copied, nil
+}
+
+func (m *MockTagRepository) FindBundleByName(ctx context.Context, userID uuid.UUID, name string) (*Bundle, error) {
+	for _, bundle := range m.bundles {
+		if bundle.UserID == userID && strings.EqualFold(bundle.Name, name) {
+			copied := *bundle
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockTagRepository) ListBundles(ctx context.Context, userID uuid.UUID) ([]*Bundle, error) {
+	var result []*Bundle
+	for _, bundle := range m.bundles {
+		if bundle.UserID == userID {
+			result = append(result, bundle)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTagRepository) UpdateBundle(ctx context.Context, bundle *Bundle) error {
+	stored := *bundle
+	m.bundles[bundle.ID] = &stored
+	return nil
+}
+
+func (m *MockTagRepository) DeleteBundle(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	_, ok := m.bundles[id]
+	delete(m.bundles, id)
+	return ok, nil
+}
+
+func (m *MockTagRepository) CountCategories(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int, error) {
+	count := 0
+	for _, id := range ids {
+		if m.categories[id] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func TestCreateTag(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	svc := NewService(newMockRepo())
+
+	color := " #22c55e "
+	tag, err := svc.CreateTag(ctx, userID, "  therapy   sessions ", &color)
+	require.NoError(t, err)
+	assert.Equal(t, "therapy sessions", tag.Name)
+	assert.Equal(t, "#22c55e", *tag.Color)
+
+	_, err = svc.CreateTag(ctx, userID, "Therapy Sessions", nil)
+	assert.True(t, errors.Is(err, ErrTagExists), "got %v", err)
+
+	// Another user may use the same name
+	_, err = svc.CreateTag(ctx, uuid.New(), "therapy sessions", nil)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"   ", strings.Repeat("x", maxNameLength+1)} {
+		_, err = svc.CreateTag(ctx, userID, name, nil)
+		assert.True(t, errors.Is(err, ErrInvalidTag), "got %v", err)
+	}
+}
+
+func TestUpdateTag(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	svc := NewService(newMockRepo())
+
+	gym, err := svc.CreateTag(ctx, userID, "gym", nil)
+	require.NoError(t, err)
+	_, err = svc.CreateTag(ctx, userID, "spa", nil)
+	require.NoError(t, err)
+
+	name := "Spa"
+	_, err = svc.UpdateTag(ctx, userID, gym.ID, &name, nil)
+	assert.True(t, errors.Is(err, ErrTagExists), "got %v", err)
+
+	// Changing only the case of its own name is allowed
+	name = "Gym"
+	updated, err := svc.UpdateTag(ctx, userID, gym.ID, &name, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Gym", updated.Name)
+
+	_, err = svc.UpdateTag(ctx, uuid.New(), gym.ID, &name, nil)
+	assert.True(t, errors.Is(err, ErrTagNotFound), "got %v", err)
+}
+
+func TestSetTransactionTags(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	repo := newMockRepo()
+	svc := NewService(repo)
+
+	txID := uuid.New()
+	repo.transactions[txID] = true
+	gym, err := svc.CreateTag(ctx, userID, "gym", nil)
+	require.NoError(t, err)
+
+	tags, err := svc.SetTransactionTags(ctx, userID, txID, []uuid.UUID{gym.ID, gym.ID})
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, "gym", tags[0].Name)
+
+	_, err = svc.SetTransactionTags(ctx, userID, txID, []uuid.UUID{uuid.New()})
+	assert.True(t, errors.Is(err, ErrTagNotFound), "got %v", err)
+
+	_, err = svc.SetTransactionTags(ctx, userID, uuid.New(), nil)
+	assert.True(t, errors.Is(err, ErrTransactionNotFound), "got %v", err)
+}
+
+func TestCreateBundle(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	repo := newMockRepo()
+	svc := NewService(repo)
+
+	gym, err := svc.CreateTag(ctx, userID, "gym", nil)
+	require.NoError(t, err)
+	health := uuid.New()
+	repo.categories[health] = true
+
+	bundle, err := svc.CreateBundle(ctx, userID, BundleInput{
+		Name:        "Self-care",
+		TagIDs:      []uuid.UUID{gym.ID},
+		CategoryIDs: []uuid.UUID{health},
+		Merchants:   []string{" Spa Lisboa ", "spa lisboa", ""},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Spa Lisboa"}, bundle.Merchants)
+
+	_, err = svc.CreateBundle(ctx, userID, BundleInput{Name: "self-care", Merchants: []string{"Yoga"}})
+	assert.True(t, errors.Is(err, ErrBundleExists), "got %v", err)
+
+	tests := []struct {
+		name  string
+		input BundleInput
+	}{
+		{"no name", BundleInput{Merchants: []string{"Yoga"}}},
+		{"no members", BundleInput{Name: "Empty", Merchants: []string{"  "}}},
+		{"unknown tag", BundleInput{Name: "Tags", TagIDs: []uuid.UUID{uuid.New()}}},
+		{"unknown category", BundleInput{Name: "Categories", CategoryIDs: []uuid.UUID{uuid.New()}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateBundle(ctx, userID, tt.input)
+			assert.True(t, errors.Is(err, ErrInvalidBundle), "got %v", err)
+		})
+	}
+}
+
+func TestUpdateBundle(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	svc := NewService(newMockRepo())
+
+	bundle, err := svc.CreateBundle(ctx, userID, BundleInput{Name: "Self-care", Merchants: []string{"Yoga"}})
+	require.NoError(t, err)
+
+	updated, err := svc.UpdateBundle(ctx, userID, bundle.ID, BundleInput{Name: "Self care", Merchants: []string{"Yoga", "Spa"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Self care", updated.Name)
+	assert.Equal(t, []string{"Yoga", "Spa"}, updated.Merchants)
+
+	_, err = svc.UpdateBundle(ctx, userID, uuid.New(), BundleInput{Name: "Other", Merchants: []string{"Yoga"}})
+	assert.True(t, errors.Is(err, ErrBundleNotFound), "got %v", err)
+
+	require.NoError(t, svc.DeleteBundle(ctx, userID, bundle.ID))
+	assert.True(t, errors.Is(svc.DeleteBundle(ctx, userID, bundle.ID), ErrBundleNotFound))
+}
@@ -0,0 +1,235 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/categorization"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+// ErrInvalidBulkUpdate is returned when a bulk update selects nothing or changes nothing.
+var ErrInvalidBulkUpdate = errors.New("invalid bulk update")
+
+const (
+	// minPatternLength keeps suggested rule patterns from matching almost anything
+	minPatternLength = 4
+	// patternSampleSize bounds the descriptions compared for a suggested pattern
+	patternSampleSize = 50
+)
+
+// RuleCreator creates categorization rules
+type RuleCreator interface {
+	CreateRule(ctx context.Context, userID uuid.UUID, input categorization.RuleInput, applyToExisting bool) (*categorization.CategoryRule, *categorization.RuleRun, error)
+}
+
+// BulkUpdateRequest selects transactions by IDs or by a ListTransactions filter
+// (or both) and sets category, merchant name, notes, intent or tags on all of them.
+type BulkUpdateRequest struct {
+	IDs    []uuid.UUID
+	Filter *repository.ListTransactionsFilter
+
+	CategoryID    *uuid.UUID
+	ClearCategory bool
+	MerchantName  *string // Empty clears
+	Notes         *string // Empty clears
+	Intent        *string // Empty clears
+	AddTagIDs     []uuid.UUID
+	RemoveTagIDs  []uuid.UUID
+
+	// CreateRule stores a categorization rule for the change so future imports
+	// get it too. RulePattern overrides the suggested pattern.
+	CreateRule  bool
+	RulePattern string
+}
+
+// BulkUpdateResult reports what a bulk update changed
+type BulkUpdateResult struct {
+	Updated         int
+	CategorySkipped int // Split transactions whose category the splits decide
+
+	// SuggestedPattern is text shared by the updated descriptions that a rule
+	// could match ("%CONTINENTE%"); empty when there is none.
+	SuggestedPattern string
+	Rule             *categorization.CategoryRule // Set when CreateRule was requested
+}
+
+// WithRuleCreator lets bulk updates turn a change into a categorization rule
+func (s *Service) WithRuleCreator(rules RuleCreator) *Service {
+	s.rules = rules
+	return s
+}
+
+// BulkUpdate applies one change to many transactions atomically and suggests
+// a categorization rule for it.
+func (s *Service) BulkUpdate(ctx context.Context, userID uuid.UUID, req BulkUpdateRequest) (*BulkUpdateResult, error) {
+	selection := BulkSelection{IDs: req.IDs}
+	if req.Filter != nil {
+		selection.Filter = *req.Filter
+	}
+	if len(selection.IDs) == 0 && !hasFilter(selection.Filter) {
+		return nil, fmt.Errorf("%w: select transactions by id or filter", ErrInvalidBulkUpdate)
+	}
+
+	change := BulkChange{
+		SetCategory:  req.CategoryID != nil || req.ClearCategory,
+		SetMerchant:  req.MerchantName != nil,
+		SetNotes:     req.Notes != nil,
+		SetIntent:    req.Intent != nil,
+		AddTagIDs:    req.AddTagIDs,
+		RemoveTagIDs: req.RemoveTagIDs,
+	}
+	if !req.ClearCategory {
+		change.CategoryID = req.CategoryID
+	}
+	if req.MerchantName != nil {
+		change.MerchantName = trimmedOrNil(*req.MerchantName)
+	}
+	if req.Notes != nil {
+		change.Notes = trimmedOrNil(*req.Notes)
+	}
+	if req.Intent != nil {
+		intent, err := normalizeIntent(*req.Intent)
+		if err != nil {
+			return nil, err
+		}
+		change.Intent = intentOrNil(intent)
+	}
+	if !change.SetCategory && !change.SetMerchant && !change.SetNotes && !change.SetIntent &&
+		len(change.AddTagIDs) == 0 && len(change.RemoveTagIDs) == 0 {
+		return nil, fmt.Errorf("%w: nothing to change", ErrInvalidBulkUpdate)
+	}
+	if req.CreateRule && !change.SetCategory && !change.SetMerchant {
+		return nil, fmt.Errorf("%w: a rule needs a category or merchant name", ErrInvalidBulkUpdate)
+	}
+
+	rows, err := s.repo.BulkUpdateTransactions(ctx, userID, selection, change)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkUpdateResult{Updated: len(rows)}
+	descriptions := make([]string, 0, min(len(rows), patternSampleSize))
+	for _, row := range rows {
+		if row.IsSplit && change.SetCategory {
+			result.CategorySkipped++
+		}
+		if len(descriptions) < patternSampleSize {
+			descriptions = append(descriptions, row.Description)
+		}
+	}
+	if change.SetCategory || change.SetMerchant {
+		result.SuggestedPattern = commonPattern(descriptions)
+	}
+	if change.SetCategory && change.CategoryID != nil {
+		s.recordBulkCorrections(ctx, userID, rows, *change.CategoryID)
+	}
+
+	if req.CreateRule && s.rules != nil {
+		pattern := strings.TrimSpace(req.RulePattern)
+		if pattern == "" {
+			pattern = result.SuggestedPattern
+		}
+		if pattern == "" {
+			return result, fmt.Errorf("%w: no common text to build a rule from", ErrInvalidBulkUpdate)
+		}
+		cleanName := strings.Trim(strings.ReplaceAll(pattern, `\`, ""), "%*")
+		if change.MerchantName != nil {
+			cleanName = *change.MerchantName
+		}
+		// The selected rows are already updated, so the rule is not backfilled
+		rule, _, err := s.rules.CreateRule(ctx, userID, categorization.RuleInput{
+			Pattern:    pattern,
+			CleanName:  cleanName,
+			CategoryID: change.CategoryID,
+		}, false)
+		if err != nil {
+			return result, fmt.Errorf("failed to create rule: %w", err)
+		}
+		result.Rule = rule
+	}
+
+	return result, nil
+}
+
+// recordBulkCorrections tells the learner about a bulk recategorization, once
+// per merchant: one decision about many rows of the same merchant counts as
+// one correction, not as many.
+func (s *Service) recordBulkCorrections(ctx context.Context, userID uuid.UUID, rows []BulkUpdatedRow, categoryID uuid.UUID) {
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		if row.IsSplit || (row.PreviousCategoryID != nil && *row.PreviousCategoryID == categoryID) {
+			continue
+		}
+		key := categorization.MerchantKey(row.Description)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		s.recordCorrection(ctx, userID, categorization.Correction{
+			TransactionID:      &row.ID,
+			Description:        row.Description,
+			PreviousCategoryID: row.PreviousCategoryID,
+			CategoryID:         categoryID,
+		})
+	}
+}
+
+// hasFilter reports whether a filter narrows the selection beyond the user
+func hasFilter(f repository.ListTransactionsFilter) bool {
+	return f.AccountID != nil || len(f.CategoryIDs) > 0 || f.UncategorizedOnly ||
+		len(f.TagIDs) > 0 || f.ImportJobID != nil || f.StartDate != nil || f.EndDate != nil ||
+		f.MinAmount != nil || f.MaxAmount != nil || f.Direction != "" || f.Merchant != "" ||
+		f.Source != "" || f.InstitutionName != "" || f.Intent != "" || f.Search != ""
+}
+
+func trimmedOrNil(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// commonPattern returns the longest text, with at least one letter, that every
+// description contains, as a rule pattern; "" when there is none long enough.
+func commonPattern(descriptions []string) string {
+	if len(descriptions) == 0 {
+		return ""
+	}
+
+	upper := make([]string, len(descriptions))
+	shortest := 0
+	for i, d := range descriptions {
+		upper[i] = strings.ToUpper(d)
+		if len([]rune(upper[i])) < len([]rune(upper[shortest])) {
+			shortest = i
+		}
+	}
+
+	base := []rune(upper[shortest])
+	for length := len(base); length >= minPatternLength; length-- {
+		for start := 0; start+length <= len(base); start++ {
+			candidate := strings.TrimSpace(string(base[start : start+length]))
+			if len([]rune(candidate)) < minPatternLength || !strings.ContainsFunc(candidate, unicode.IsLetter) {
+				continue
+			}
+			shared := true
+			for _, d := range upper {
+				if !strings.Contains(d, candidate) {
+					shared = false
+					break
+				}
+			}
+			if shared {
+				return "%" + categorization.QuotePattern(candidate) + "%"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/categorization"
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+type mockRuleCreator struct {
+	pattern    string
+	cleanName  string
+	categoryID *uuid.UUID
+}
+
+func (m *mockRuleCreator) CreateRule(ctx context.Context, userID uuid.UUID, input categorization.RuleInput, applyToExisting bool) (*categorization.CategoryRule, *categorization.RuleRun, error) {
+	m.pattern, m.cleanName, m.categoryID = input.Pattern, input.CleanName, input.CategoryID
+	return &categorization.CategoryRule{ID: uuid.New(), MatchPattern: input.Pattern, CleanName: &input.CleanName, AssignedCategoryID: input.CategoryID}, nil, nil
+}
+
+func TestBulkUpdate(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	groceries := uuid.New()
+
+	repo := newMockRepo()
+	repo.bulkRows = []BulkUpdatedRow{
+		{ID: uuid.New(), Description: "COMPRA CONTINENTE LISBOA"},
+		{ID: uuid.New(), Description: "Compra Continente Porto", IsSplit: true},
+		{ID: uuid.New(), Description: "CONTINENTE BOM DIA"},
+	}
+	rules := &mockRuleCreator{}
+	svc := NewService(repo, testLogger).WithRuleCreator(rules)
+
+	merchant := " Continente "
+	result, err := svc.BulkUpdate(ctx, userID, BulkUpdateRequest{
+		Filter:       &repository.ListTransactionsFilter{Search: "continente"},
+		CategoryID:   &groceries,
+		MerchantName: &merchant,
+		CreateRule:   true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Updated)
+	assert.Equal(t, 1, result.CategorySkipped)
+	assert.Equal(t, "%CONTINENTE%", result.SuggestedPattern)
+	require.NotNil(t, result.Rule)
+
+	assert.Equal(t, "%CONTINENTE%", rules.pattern)
+	assert.Equal(t, "Continente", rules.cleanName)
+	assert.Equal(t, &groceries, rules.categoryID)
+
+	require.NotNil(t, repo.bulkChange)
+	assert.True(t, repo.bulkChange.SetCategory)
+	assert.Equal(t, "Continente", *repo.bulkChange.MerchantName)
+	assert.False(t, repo.bulkChange.SetNotes)
+}
+
+func TestBulkUpdate_RecordsCorrectionPerMerchant(t *testing.T) {
+	groceries, restaurants := uuid.New(), uuid.New()
+	repo := newMockRepo()
+	repo.bulkRows = []BulkUpdatedRow{
+		{ID: uuid.New(), Description: "PINGO DOCE LISBOA", PreviousCategoryID: &restaurants},
+		{ID: uuid.New(), Description: "PINGO DOCE LISBOA 2024-03-01"},
+		{ID: uuid.New(), Description: "LIDL PORTO", PreviousCategoryID: &groceries},
+		{ID: uuid.New(), Description: "CONTINENTE BOM DIA", IsSplit: true},
+		{ID: uuid.New(), Description: "MERCADONA GAIA"},
+	}
+	learner := &fakeCorrectionRecorder{}
+	svc := NewService(repo, testLogger).WithCorrectionRecorder(learner)
+
+	_, err := svc.BulkUpdate(context.Background(), uuid.New(), BulkUpdateRequest{
+		IDs:        []uuid.UUID{uuid.New()},
+		CategoryID: &groceries,
+	})
+	require.NoError(t, err, "learning failures do not fail the update")
+
+	// Unchanged and split rows are skipped; repeated merchants count once
+	require.Len(t, learner.corrections, 2)
+	assert.Equal(t, repo.bulkRows[0].ID, *learner.corrections[0].TransactionID)
+	assert.Equal(t, &restaurants, learner.corrections[0].PreviousCategoryID)
+	assert.Equal(t, groceries, learner.corrections[0].CategoryID)
+	assert.Equal(t, "MERCADONA GAIA", learner.corrections[1].Description)
+
+	learner.corrections = nil
+	_, err = svc.BulkUpdate(context.Background(), uuid.New(), BulkUpdateRequest{
+		IDs:           []uuid.UUID{uuid.New()},
+		ClearCategory: true,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, learner.corrections, "clearing a category teaches nothing")
+}
+
+func TestBulkUpdate_ClearsFields(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, testLogger)
+
+	empty := ""
+	_, err := svc.BulkUpdate(context.Background(), uuid.New(), BulkUpdateRequest{
+		IDs:           []uuid.UUID{uuid.New()},
+		ClearCategory: true,
+		Notes:         &empty,
+	})
+	require.NoError(t, err)
+	assert.True(t, repo.bulkChange.SetCategory)
+	assert.Nil(t, repo.bulkChange.CategoryID)
+	assert.True(t, repo.bulkChange.SetNotes)
+	assert.Nil(t, repo.bulkChange.Notes)
+}
+
+func TestBulkUpdate_Tags(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, testLogger)
+
+	gym, spa := uuid.New(), uuid.New()
+	result, err := svc.BulkUpdate(context.Background(), uuid.New(), BulkUpdateRequest{
+		Filter:       &repository.ListTransactionsFilter{TagIDs: []uuid.UUID{spa}},
+		AddTagIDs:    []uuid.UUID{gym},
+		RemoveTagIDs: []uuid.UUID{spa},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.SuggestedPattern)
+	assert.False(t, repo.bulkChange.SetCategory)
+	assert.Equal(t, []uuid.UUID{gym}, repo.bulkChange.AddTagIDs)
+	assert.Equal(t, []uuid.UUID{spa}, repo.bulkChange.RemoveTagIDs)
+}
+
+func TestBulkUpdate_Validation(t *testing.T) {
+	svc := NewService(newMockRepo(), testLogger)
+	category := uuid.New()
+	notes := "work trip"
+
+	tests := []struct {
+		name string
+		req  BulkUpdateRequest
+	}{
+		{"no selection", BulkUpdateRequest{CategoryID: &category}},
+		{"empty filter", BulkUpdateRequest{Filter: &repository.ListTransactionsFilter{}, CategoryID: &category}},
+		{"no change", BulkUpdateRequest{IDs: []uuid.UUID{uuid.New()}}},
+		{"rule from notes", BulkUpdateRequest{IDs: []uuid.UUID{uuid.New()}, Notes: &notes, CreateRule: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.BulkUpdate(context.Background(), uuid.New(), tt.req)
+			assert.True(t, errors.Is(err, ErrInvalidBulkUpdate), "got %v", err)
+		})
+	}
+}
+
+func TestCommonPattern(t *testing.T) {
+	tests := []struct {
+		name         string
+		descriptions []string
+		want         string
+	}{
+		{"shared merchant", []string{"PINGO DOCE 123", "pingo doce lisboa"}, "%PINGO DOCE%"},
+		{"single description", []string{"UBER TRIP"}, "%UBER TRIP%"},
+		{"digits only", []string{"REF 12345 A", "REF 12345 B"}, "%REF 12345%"},
+		{"wildcard characters", []string{"UBER *TRIP AB", "UBER *TRIP CD"}, `%UBER \*TRIP%`},
+		{"nothing shared", []string{"NETFLIX", "SPOTIFY"}, ""},
+		{"too short", []string{"BP 1", "BP 2"}, ""},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, commonPattern(tt.descriptions))
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Intents a user can mark a transaction with, recording why the money was spent
+const (
+	IntentNecessary  = "necessary"
+	IntentSplurge    = "splurge"
+	IntentRegret     = "regret"
+	IntentInvestment = "investment"
+)
+
+// ErrInvalidIntent is returned for an intent other than the ones above.
+var ErrInvalidIntent = errors.New("invalid intent")
+
+// normalizeIntent lowercases and checks an intent; "" stays "" (no intent).
+func normalizeIntent(intent string) (string, error) {
+	intent = strings.ToLower(strings.TrimSpace(intent))
+	switch intent {
+	case "", IntentNecessary, IntentSplurge, IntentRegret, IntentInvestment:
+		return intent, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidIntent, intent)
+}
+
+// SetIntent marks transactions with an intent, or clears it when intent is
+// empty. It is the quick action behind reviewing transactions one by one or
+// several at a time, and returns how many were updated.
+func (s *Service) SetIntent(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, intent string) (int, error) {
+	intent, err := normalizeIntent(intent)
+	if err != nil {
+		return 0, err
+	}
+	if len(transactionIDs) == 0 {
+		return 0, fmt.Errorf("%w: no transactions given", ErrInvalidTransaction)
+	}
+
+	updated, err := s.repo.SetIntent(ctx, userID, transactionIDs, intentOrNil(intent))
+	if err != nil {
+		return 0, err
+	}
+	if updated == 0 {
+		return 0, ErrTransactionNotFound
+	}
+	return updated, nil
+}
+
+func intentOrNil(intent string) *string {
+	if intent == "" {
+		return nil
+	}
+	return &intent
+}
@@ -0,0 +1,58 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetIntent(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	repo := newMockRepo()
+	svc := NewService(repo, testLogger)
+
+	first, err := svc.CreateTransaction(ctx, userID, TransactionInput{Description: "Concert tickets", AmountMinor: -8000, CurrencyCode: "EUR"})
+	require.NoError(t, err)
+	second, err := svc.CreateTransaction(ctx, userID, TransactionInput{Description: "Late night taxi", AmountMinor: -2500, CurrencyCode: "EUR", Intent: "Regret"})
+	require.NoError(t, err)
+	assert.Equal(t, IntentRegret, *repo.transactions[second.ID].Intent)
+
+	updated, err := svc.SetIntent(ctx, userID, []uuid.UUID{first.ID, second.ID}, " splurge ")
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated)
+	assert.Equal(t, IntentSplurge, *repo.transactions[first.ID].Intent)
+
+	// Empty clears
+	_, err = svc.SetIntent(ctx, userID, []uuid.UUID{first.ID}, "")
+	require.NoError(t, err)
+	assert.Nil(t, repo.transactions[first.ID].Intent)
+
+	_, err = svc.SetIntent(ctx, userID, []uuid.UUID{first.ID}, "impulse")
+	assert.True(t, errors.Is(err, ErrInvalidIntent), "got %v", err)
+
+	_, err = svc.SetIntent(ctx, userID, []uuid.UUID{uuid.New()}, IntentNecessary)
+	assert.True(t, errors.Is(err, ErrTransactionNotFound), "got %v", err)
+
+	_, err = svc.CreateTransaction(ctx, userID, TransactionInput{Description: "Gym", AmountMinor: -3000, CurrencyCode: "EUR", Intent: "maybe"})
+	assert.True(t, errors.Is(err, ErrInvalidIntent), "got %v", err)
+}
+
+func TestUpdateTransaction_IntentOnImported(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	repo := newMockRepo()
+	svc := NewService(repo, testLogger)
+
+	id := uuid.New()
+	repo.transactions[id] = &Transaction{ID: id, UserID: userID, Description: "COURSERA", AmountMinor: -4900, CurrencyCode: "EUR", Source: "csv"}
+
+	intent := IntentInvestment
+	tx, err := svc.UpdateTransaction(ctx, userID, id, TransactionUpdate{Intent: &intent})
+	require.NoError(t, err)
+	assert.Equal(t, IntentInvestment, *tx.Intent)
+}
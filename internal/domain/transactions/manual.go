@@ -0,0 +1,302 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/categorization"
+)
+
+var (
+	// ErrInvalidTransaction is returned when a manual transaction is missing or has bad fields.
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	// ErrAccountNotFound is returned when the account does not exist for the user.
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrCurrencyMismatch is returned when a transaction's currency differs from its account's.
+	ErrCurrencyMismatch = errors.New("currency does not match the account")
+	// ErrNotManual is returned when changing fields of a transaction its source owns.
+	ErrNotManual = errors.New("only manual transactions can change amount, date, currency or account")
+)
+
+// Categorizer suggests a merchant name and category for a transaction
+type Categorizer interface {
+	Categorize(ctx context.Context, userID uuid.UUID, tx categorization.Transaction) (*categorization.CategorizationResult, error)
+}
+
+// CorrectionRecorder learns from categories the user sets by hand
+type CorrectionRecorder interface {
+	RecordCorrection(ctx context.Context, userID uuid.UUID, correction categorization.Correction) error
+}
+
+// TransactionInput describes a manually entered transaction
+type TransactionInput struct {
+	AccountID    *uuid.UUID
+	PostedAt     time.Time // Zero uses the current time
+	Description  string
+	AmountMinor  int64  // Negative for spending
+	CurrencyCode string // Empty uses the account's currency, or the user's base currency
+	CategoryID   *uuid.UUID
+	Notes        string
+	Intent       string // Optional: "necessary", "splurge", "regret" or "investment"
+}
+
+// TransactionUpdate lists the fields to change; nil fields are kept. Imported
+// transactions only accept category, merchant name, notes and intent changes.
+type TransactionUpdate struct {
+	AccountID     *uuid.UUID
+	PostedAt      *time.Time
+	Description   *string
+	AmountMinor   *int64
+	CurrencyCode  *string
+	CategoryID    *uuid.UUID
+	ClearCategory bool
+	MerchantName  *string
+	Notes         *string
+	Intent        *string // Empty clears
+}
+
+// CreateTransaction stores a manual transaction (cash spending, a missed
+// charge). Unless given a category, it is categorized like imported rows.
+func (s *Service) CreateTransaction(ctx context.Context, userID uuid.UUID, input TransactionInput) (*Transaction, error) {
+	description := strings.TrimSpace(input.Description)
+	if description == "" {
+		return nil, fmt.Errorf("%w: description is required", ErrInvalidTransaction)
+	}
+	if input.AmountMinor == 0 {
+		return nil, fmt.Errorf("%w: amount is required", ErrInvalidTransaction)
+	}
+	intent, err := normalizeIntent(input.Intent)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := s.resolveCurrency(ctx, userID, input.AccountID, input.CurrencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	postedAt := input.PostedAt
+	if postedAt.IsZero() {
+		postedAt = time.Now()
+	}
+
+	tx := &Transaction{
+		UserID:       userID,
+		AccountID:    input.AccountID,
+		CategoryID:   input.CategoryID,
+		PostedAt:     postedAt,
+		Description:  description,
+		AmountMinor:  input.AmountMinor,
+		CurrencyCode: currency,
+		Intent:       intentOrNil(intent),
+	}
+	if notes := strings.TrimSpace(input.Notes); notes != "" {
+		tx.Notes = &notes
+	}
+	s.categorize(ctx, tx, true, input.CategoryID == nil)
+
+	if err := s.repo.CreateTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// UpdateTransaction applies an update to a user's transaction
+func (s *Service) UpdateTransaction(ctx context.Context, userID, id uuid.UUID, update TransactionUpdate) (*Transaction, error) {
+	tx, err := s.repo.GetTransaction(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, ErrTransactionNotFound
+	}
+	previousCategoryID := tx.CategoryID
+
+	ownedBySource := update.AccountID != nil || update.PostedAt != nil || update.Description != nil ||
+		update.AmountMinor != nil || update.CurrencyCode != nil
+	if ownedBySource && tx.Source != "manual" {
+		return nil, ErrNotManual
+	}
+
+	if update.Description != nil {
+		description := strings.TrimSpace(*update.Description)
+		if description == "" {
+			return nil, fmt.Errorf("%w: description is required", ErrInvalidTransaction)
+		}
+		if description != tx.Description {
+			tx.Description = description
+			s.categorize(ctx, tx, update.MerchantName == nil, false)
+		}
+	}
+	if update.AmountMinor != nil && *update.AmountMinor != tx.AmountMinor {
+		if *update.AmountMinor == 0 {
+			return nil, fmt.Errorf("%w: amount is required", ErrInvalidTransaction)
+		}
+		splits, err := s.repo.ListSplits(ctx, userID, []uuid.UUID{id})
+		if err != nil {
+			return nil, err
+		}
+		if len(splits[id]) > 0 {
+			return nil, fmt.Errorf("%w: remove the split before changing the amount", ErrInvalidSplits)
+		}
+		tx.AmountMinor = *update.AmountMinor
+	}
+	if update.PostedAt != nil {
+		tx.PostedAt = *update.PostedAt
+	}
+	if update.AccountID != nil || update.CurrencyCode != nil {
+		if update.AccountID != nil {
+			tx.AccountID = update.AccountID
+		}
+		requested := tx.CurrencyCode
+		if update.CurrencyCode != nil {
+			requested = *update.CurrencyCode
+		}
+		tx.CurrencyCode, err = s.resolveCurrency(ctx, userID, tx.AccountID, requested)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case update.ClearCategory:
+		tx.CategoryID = nil
+	case update.CategoryID != nil:
+		tx.CategoryID = update.CategoryID
+	}
+	if update.MerchantName != nil {
+		if name := strings.TrimSpace(*update.MerchantName); name != "" {
+			tx.MerchantName = &name
+		} else {
+			tx.MerchantName = nil
+		}
+	}
+	if update.Notes != nil {
+		if notes := strings.TrimSpace(*update.Notes); notes != "" {
+			tx.Notes = &notes
+		} else {
+			tx.Notes = nil
+		}
+	}
+	if update.Intent != nil {
+		intent, err := normalizeIntent(*update.Intent)
+		if err != nil {
+			return nil, err
+		}
+		tx.Intent = intentOrNil(intent)
+	}
+
+	if err := s.repo.UpdateTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	if update.CategoryID != nil && (previousCategoryID == nil || *previousCategoryID != *update.CategoryID) {
+		s.recordCorrection(ctx, userID, categorization.Correction{
+			TransactionID:      &tx.ID,
+			Description:        tx.Description,
+			PreviousCategoryID: previousCategoryID,
+			CategoryID:         *update.CategoryID,
+		})
+	}
+	return tx, nil
+}
+
+// recordCorrection tells the learner about a category set by hand. Learning
+// is best effort and never fails the edit.
+func (s *Service) recordCorrection(ctx context.Context, userID uuid.UUID, correction categorization.Correction) {
+	if s.corrections == nil {
+		return
+	}
+	if err := s.corrections.RecordCorrection(ctx, userID, correction); err != nil {
+		s.logger.Warn("failed to record category correction", "transaction_id", correction.TransactionID, "error", err)
+	}
+}
+
+// DeleteTransaction deletes a user's transaction
+func (s *Service) DeleteTransaction(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.repo.DeleteTransaction(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTransactionNotFound
+	}
+	return nil
+}
+
+// resolveCurrency checks a requested currency against the account's. Without
+// a request the account's currency is used, or the user's base currency when
+// there is no account.
+func (s *Service) resolveCurrency(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, requested string) (string, error) {
+	requested = strings.ToUpper(strings.TrimSpace(requested))
+	if requested != "" && !isCurrencyCode(requested) {
+		return "", fmt.Errorf("%w: bad currency %q", ErrInvalidTransaction, requested)
+	}
+
+	if accountID != nil {
+		accountCurrency, err := s.repo.GetAccountCurrency(ctx, userID, *accountID)
+		if err != nil {
+			return "", err
+		}
+		if accountCurrency == "" {
+			return "", ErrAccountNotFound
+		}
+		if requested != "" && requested != accountCurrency {
+			return "", fmt.Errorf("%w: account uses %s, got %s", ErrCurrencyMismatch, accountCurrency, requested)
+		}
+		return accountCurrency, nil
+	}
+
+	if requested != "" {
+		return requested, nil
+	}
+	base, err := s.repo.GetBaseCurrency(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if base == "" {
+		base = "EUR"
+	}
+	return base, nil
+}
+
+// categorize sets the merchant name and category chosen by the user's rules
+// and the merchant database. Categorization failures leave the transaction as
+// entered.
+func (s *Service) categorize(ctx context.Context, tx *Transaction, setMerchant, setCategory bool) {
+	if s.categorizer == nil {
+		return
+	}
+	result, err := s.categorizer.Categorize(ctx, tx.UserID, categorization.Transaction{
+		Description: tx.Description,
+		AmountMinor: tx.AmountMinor,
+		AccountID:   tx.AccountID,
+	})
+	if err != nil || result == nil {
+		return
+	}
+	if setMerchant && result.CleanMerchantName != "" {
+		name := result.CleanMerchantName
+		tx.MerchantName = &name
+	}
+	if setCategory && result.CategoryID != nil {
+		tx.CategoryID = result.CategoryID
+	}
+}
+
+func isCurrencyCode(value string) bool {
+	if len(value) != 3 {
+		return false
+	}
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/categorization"
+)
+
+// fakeCategorizer assigns one category to every description
+type fakeCategorizer struct {
+	categoryID uuid.UUID
+}
+
+func (f *fakeCategorizer) Categorize(ctx context.Context, userID uuid.UUID, tx categorization.Transaction) (*categorization.CategorizationResult, error) {
+	return &categorization.CategorizationResult{CleanMerchantName: "Clean " + tx.Description, CategoryID: &f.categoryID}, nil
+}
+
+// fakeCorrectionRecorder keeps the corrections it is told about and fails
+type fakeCorrectionRecorder struct {
+	corrections []categorization.Correction
+}
+
+func (f *fakeCorrectionRecorder) RecordCorrection(ctx context.Context, userID uuid.UUID, correction categorization.Correction) error {
+	f.corrections = append(f.corrections, correction)
+	return errors.New("learning unavailable")
+}
+
+func TestParseQuickEntry(t *testing.T) {
+	tests := []struct {
+		text     string
+		amount   int64
+		currency string
+		desc     string
+	}{
+		{"4.20 coffee", -420, "", "coffee"},
+		{"coffee €4,20", -420, "EUR", "coffee"},
+		{"+250 USD refund from shop", 25000, "USD", "refund from shop"},
+		{"lunch 1.234,50 GBP", -123450, "GBP", "lunch"},
+		{"12 TEA 2 go", -1200, "", "TEA 2 go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			entry, err := ParseQuickEntry(tt.text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, entry.AmountMinor)
+			assert.Equal(t, tt.currency, entry.CurrencyCode)
+			assert.Equal(t, tt.desc, entry.Description)
+		})
+	}
+
+	_, err := ParseQuickEntry("coffee")
+	assert.True(t, errors.Is(err, ErrInvalidTransaction))
+	_, err = ParseQuickEntry("4.20")
+	assert.True(t, errors.Is(err, ErrInvalidTransaction))
+}
+
+func TestCreateTransaction(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	accountID := uuid.New()
+	groceries := uuid.New()
+
+	repo := newMockRepo()
+	repo.accounts[accountID] = "GBP"
+	repo.baseCurrency = "EUR"
+	svc := NewService(repo, testLogger).WithCategorizer(&fakeCategorizer{categoryID: groceries})
+
+	// Cash spending without an account uses the base currency and is categorized
+	tx, err := svc.CreateTransaction(ctx, userID, TransactionInput{Description: "market", AmountMinor: -800})
+	require.NoError(t, err)
+	assert.Equal(t, "manual", tx.Source)
+	assert.Equal(t, "EUR", tx.CurrencyCode)
+	assert.Equal(t, &groceries, tx.CategoryID)
+	assert.Equal(t, "Clean market", *tx.MerchantName)
+
+	// The account decides the currency
+	tx, err = svc.QuickCapture(ctx, userID, "9.99 books", &accountID, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "GBP", tx.CurrencyCode)
+	assert.Equal(t, int64(-999), tx.AmountMinor)
+
+	_, err = svc.CreateTransaction(ctx, userID, TransactionInput{AccountID: &accountID, Description: "x", AmountMinor: -1, CurrencyCode: "USD"})
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+	missing := uuid.New()
+	_, err = svc.CreateTransaction(ctx, userID, TransactionInput{AccountID: &missing, Description: "x", AmountMinor: -1})
+	assert.True(t, errors.Is(err, ErrAccountNotFound))
+	_, err = svc.CreateTransaction(ctx, userID, TransactionInput{Description: " ", AmountMinor: -1})
+	assert.True(t, errors.Is(err, ErrInvalidTransaction))
+}
+
+func TestUpdateTransaction(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo := newMockRepo()
+	svc := NewService(repo, testLogger)
+
+	manual, err := svc.CreateTransaction(ctx, userID, TransactionInput{Description: "taxi", AmountMinor: -1500, CurrencyCode: "EUR"})
+	require.NoError(t, err)
+
+	amount := int64(-1800)
+	notes := "airport"
+	updated, err := svc.UpdateTransaction(ctx, userID, manual.ID, TransactionUpdate{AmountMinor: &amount, Notes: &notes})
+	require.NoError(t, err)
+	assert.Equal(t, amount, updated.AmountMinor)
+	assert.Equal(t, "airport", *updated.Notes)
+
+	// Imported rows keep what their source reported
+	imported := &Transaction{ID: uuid.New(), UserID: userID, Description: "CARD 1234", AmountMinor: -500, CurrencyCode: "EUR", Source: "csv"}
+	repo.transactions[imported.ID] = imported
+	_, err = svc.UpdateTransaction(ctx, userID, imported.ID, TransactionUpdate{AmountMinor: &amount})
+	assert.True(t, errors.Is(err, ErrNotManual))
+	_, err = svc.UpdateTransaction(ctx, userID, imported.ID, TransactionUpdate{Notes: &notes})
+	require.NoError(t, err)
+
+	// Split amounts have to be removed first
+	repo.splits[manual.ID] = []Split{{AmountMinor: -900}, {AmountMinor: -900}}
+	other := int64(-2000)
+	_, err = svc.UpdateTransaction(ctx, userID, manual.ID, TransactionUpdate{AmountMinor: &other})
+	assert.True(t, errors.Is(err, ErrInvalidSplits))
+
+	require.NoError(t, svc.DeleteTransaction(ctx, userID, manual.ID))
+	assert.True(t, errors.Is(svc.DeleteTransaction(ctx, userID, manual.ID), ErrTransactionNotFound))
+}
+
+func TestUpdateTransaction_RecordsCorrections(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	groceries, restaurants := uuid.New(), uuid.New()
+
+	repo := newMockRepo()
+	learner := &fakeCorrectionRecorder{}
+	svc := NewService(repo, testLogger).WithCorrectionRecorder(learner)
+
+	imported := &Transaction{ID: uuid.New(), UserID: userID, Description: "PINGO DOCE LISBOA", AmountMinor: -500, CurrencyCode: "EUR", Source: "csv", CategoryID: &restaurants}
+	repo.transactions[imported.ID] = imported
+
+	// Learning failures never fail the edit
+	updated, err := svc.UpdateTransaction(ctx, userID, imported.ID, TransactionUpdate{CategoryID: &groceries})
+	require.NoError(t, err)
+	assert.Equal(t, groceries, *updated.CategoryID)
+	require.Len(t, learner.corrections, 1)
+	assert.Equal(t, imported.ID, *learner.corrections[0].TransactionID)
+	assert.Equal(t, "PINGO DOCE LISBOA", learner.corrections[0].Description)
+	assert.Equal(t, restaurants, *learner.corrections[0].PreviousCategoryID)
+	assert.Equal(t, groceries, learner.corrections[0].CategoryID)
+
+	// Setting the same category again, or editing anything else, teaches nothing
+	notes := "weekly shop"
+	_, err = svc.UpdateTransaction(ctx, userID, imported.ID, TransactionUpdate{CategoryID: &groceries, Notes: &notes})
+	require.NoError(t, err)
+	_, err = svc.UpdateTransaction(ctx, userID, imported.ID, TransactionUpdate{ClearCategory: true})
+	require.NoError(t, err)
+	assert.Len(t, learner.corrections, 1)
+}
@@ -0,0 +1,147 @@
+package transactions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/currency"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/normalizer"
+)
+
+// currencySymbols maps the symbols accepted in quick capture to currency codes
+var currencySymbols = map[string]string{
+	"€": "EUR",
+	"£": "GBP",
+	"$": "USD",
+	"¥": "JPY",
+}
+
+// QuickEntry is a transaction parsed from a one-line note such as "12,50 coffee"
+type QuickEntry struct {
+	AmountMinor  int64
+	CurrencyCode string // Empty when the note names no currency
+	Description  string
+}
+
+// ParseQuickEntry reads an amount, an optional currency and a description from
+// a short note: "4.20 coffee", "coffee €4,20", "+250 USD refund". Amounts are
+// spending unless prefixed with "+".
+func ParseQuickEntry(text string) (*QuickEntry, error) {
+	entry := &QuickEntry{}
+	var description []string
+	amountFound := false
+
+	for _, field := range strings.Fields(text) {
+		token := field
+		if code, rest, ok := cutCurrencySymbol(token); ok {
+			if entry.CurrencyCode == "" {
+				entry.CurrencyCode = code
+			}
+			token = rest
+			if token == "" {
+				continue
+			}
+		}
+		if entry.CurrencyCode == "" && isISOCurrency(token) {
+			entry.CurrencyCode = token
+			continue
+		}
+
+		if !amountFound && startsLikeAmount(token) {
+			amount, err := parseQuickAmount(token)
+			if err == nil && amount != 0 {
+				entry.AmountMinor = amount
+				amountFound = true
+				continue
+			}
+		}
+		description = append(description, field)
+	}
+
+	if !amountFound {
+		return nil, fmt.Errorf("%w: no amount in %q", ErrInvalidTransaction, text)
+	}
+	entry.Description = strings.Join(description, " ")
+	if entry.Description == "" {
+		return nil, fmt.Errorf("%w: no description in %q", ErrInvalidTransaction, text)
+	}
+	return entry, nil
+}
+
+// QuickCapture creates a manual transaction from a one-line note, posted now
+// unless at is set.
+func (s *Service) QuickCapture(ctx context.Context, userID uuid.UUID, text string, accountID *uuid.UUID, at time.Time) (*Transaction, error) {
+	entry, err := ParseQuickEntry(text)
+	if err != nil {
+		return nil, err
+	}
+	return s.CreateTransaction(ctx, userID, TransactionInput{
+		AccountID:    accountID,
+		PostedAt:     at,
+		Description:  entry.Description,
+		AmountMinor:  entry.AmountMinor,
+		CurrencyCode: entry.CurrencyCode,
+	})
+}
+
+// cutCurrencySymbol removes a leading or trailing currency symbol
+func cutCurrencySymbol(token string) (code, rest string, ok bool) {
+	sign := ""
+	if strings.HasPrefix(token, "+") || strings.HasPrefix(token, "-") {
+		sign, token = token[:1], token[1:]
+	}
+	for symbol, code := range currencySymbols {
+		if rest, found := strings.CutPrefix(token, symbol); found {
+			return code, sign + rest, true
+		}
+		if rest, found := strings.CutSuffix(token, symbol); found {
+			return code, sign + rest, true
+		}
+	}
+	return "", "", false
+}
+
+// isISOCurrency reports whether token is an upper-case ISO 4217 code, so a
+// word like "TEA" is not mistaken for one
+func isISOCurrency(token string) bool {
+	if !isCurrencyCode(token) {
+		return false
+	}
+	_, err := currency.ParseISO(token)
+	return err == nil
+}
+
+func startsLikeAmount(token string) bool {
+	token = strings.TrimLeft(token, "+-")
+	return token != "" && unicode.IsDigit(rune(token[0]))
+}
+
+// parseQuickAmount parses "12.50", "12,50" or "1.234,50"; a comma followed by
+// one or two digits at the end is a decimal separator.
+func parseQuickAmount(token string) (int64, error) {
+	income := strings.HasPrefix(token, "+")
+	token = strings.TrimLeft(token, "+-")
+	for _, r := range token {
+		if !unicode.IsDigit(r) && r != '.' && r != ',' {
+			return 0, normalizer.ErrInvalidAmount
+		}
+	}
+
+	european := false
+	if i := strings.LastIndex(token, ","); i >= 0 && len(token)-i-1 <= 2 && len(token)-i-1 > 0 {
+		european = true
+	}
+	amount, err := normalizer.ParseAmount(token, european)
+	if err != nil {
+		return 0, err
+	}
+	if !income {
+		amount = -amount
+	}
+	return amount, nil
+}
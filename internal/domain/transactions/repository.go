@@ -0,0 +1,704 @@
+// Package transactions manages transactions the user enters or edits by hand.
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/repository"
+)
+
+// Transaction is a single stored transaction as the user edits it
+type Transaction struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	AccountID    *uuid.UUID
+	CategoryID   *uuid.UUID
+	PostedAt     time.Time
+	Description  string
+	MerchantName *string
+	AmountMinor  int64
+	CurrencyCode string
+	Source       string // "manual", "csv" or "aggregator"
+	Notes        *string
+	Intent       *string // "necessary", "splurge", "regret" or "investment"
+	ReviewStatus *string // "pending", "approved" or "skipped"; nil when never queued for review
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// Split allocates part of a transaction's amount to a category
+type Split struct {
+	ID            uuid.UUID
+	TransactionID uuid.UUID
+	CategoryID    *uuid.UUID
+	CategoryName  *string // Joined from categories table
+	AmountMinor   int64
+	CurrencyCode  string // The parent transaction's currency
+	Note          *string
+}
+
+// Transfer pairs an outflow from one of the user's accounts with the matching
+// inflow on another
+type Transfer struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	OutflowID        uuid.UUID
+	InflowID         uuid.UUID
+	OutflowAccountID *uuid.UUID
+	InflowAccountID  *uuid.UUID
+	OutflowDate      time.Time
+	InflowDate       time.Time
+	AmountMinor      int64 // Amount moved, positive
+	CurrencyCode     string
+	Score            float64
+	Status           string // "pending", "confirmed" or "dismissed"
+	CreatedAt        time.Time
+	ResolvedAt       *time.Time
+}
+
+// BulkSelection picks the transactions of a bulk update: explicit IDs, the
+// transactions matching Filter, or the IDs among those matching Filter
+type BulkSelection struct {
+	IDs    []uuid.UUID
+	Filter repository.ListTransactionsFilter
+}
+
+// BulkChange lists the fields a bulk update sets; a nil value with its Set
+// flag clears the field
+type BulkChange struct {
+	SetCategory  bool
+	CategoryID   *uuid.UUID
+	SetMerchant  bool
+	MerchantName *string
+	SetNotes     bool
+	Notes        *string
+	SetIntent    bool
+	Intent       *string
+	AddTagIDs    []uuid.UUID // Tags of other users are ignored
+	RemoveTagIDs []uuid.UUID
+}
+
+// BulkUpdatedRow is a transaction changed by a bulk update
+type BulkUpdatedRow struct {
+	ID                 uuid.UUID
+	Description        string
+	PreviousCategoryID *uuid.UUID
+	IsSplit            bool // The category was left to the splits
+}
+
+// ReviewItem is an imported transaction held in the review queue
+type ReviewItem struct {
+	TransactionID      uuid.UUID
+	AccountID          *uuid.UUID
+	ImportJobID        *uuid.UUID
+	PostedAt           time.Time
+	Description        string
+	MerchantName       *string
+	AmountMinor        int64
+	CurrencyCode       string
+	CategoryID         *uuid.UUID
+	CategoryName       *string  // Joined from categories table
+	CategoryConfidence *float64 // How sure import's categorization was; nil when set any other way
+	Intent             *string
+	Status             string     // "pending", "approved" or "skipped"
+	Reasons            []string   // "uncategorized", "unknown_merchant", "possible_duplicate"
+	DuplicateOfID      *uuid.UUID // The other source's row of a pending duplicate link
+	ReviewedAt         *time.Time
+}
+
+// TransferCandidate is an outflow and an inflow of the same amount and currency
+// on different accounts with close posting dates
+type TransferCandidate struct {
+	OutflowID   uuid.UUID
+	OutflowDate time.Time
+	InflowID    uuid.UUID
+	InflowDate  time.Time
+}
+
+// TransactionRepository defines the interface for transaction edit data access
+type TransactionRepository interface {
+	// Single transactions
+	CreateTransaction(ctx context.Context, tx *Transaction) error
+	// GetTransaction returns a user's transaction, or nil when it does not exist.
+	GetTransaction(ctx context.Context, userID, id uuid.UUID) (*Transaction, error)
+	UpdateTransaction(ctx context.Context, tx *Transaction) error
+	DeleteTransaction(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	BulkUpdateTransactions(ctx context.Context, userID uuid.UUID, selection BulkSelection, change BulkChange) ([]BulkUpdatedRow, error)
+	// SetIntent sets (nil clears) the intent of the user's transactions and returns how many matched.
+	SetIntent(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, intent *string) (int, error)
+	// GetAccountCurrency returns the currency of a user's account, or "" when it does not exist.
+	GetAccountCurrency(ctx context.Context, userID, accountID uuid.UUID) (string, error)
+	GetBaseCurrency(ctx context.Context, userID uuid.UUID) (string, error)
+
+	// GetAmount returns the amount of a user's transaction, or nil when it does not exist.
+	GetAmount(ctx context.Context, userID, transactionID uuid.UUID) (*int64, error)
+	// ReplaceSplits swaps the transaction's splits for the given ones (none clears them).
+	ReplaceSplits(ctx context.Context, userID, transactionID uuid.UUID, splits []Split) ([]Split, error)
+	// ListSplits returns splits keyed by transaction ID, in their stored order.
+	ListSplits(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (map[uuid.UUID][]Split, error)
+
+	// Transfers between the user's accounts
+	FindTransferCandidates(ctx context.Context, userID uuid.UUID, importJobID *uuid.UUID, maxDays int) ([]TransferCandidate, error)
+	CreateTransfers(ctx context.Context, transfers []*Transfer) (int, error)
+	ListTransfers(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]*Transfer, int64, error)
+	ConfirmTransfer(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	UnpairTransfer(ctx context.Context, userID, id uuid.UUID) (bool, error)
+
+	// Review queue
+	ListReviewItems(ctx context.Context, userID uuid.UUID, status string, importJobID *uuid.UUID, belowConfidence *float64, limit, offset int) ([]*ReviewItem, int64, error)
+	// SetReviewStatus resolves queued transactions and returns how many matched.
+	SetReviewStatus(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, status string) (int, error)
+}
+
+// Ensure Repository implements TransactionRepository
+var _ TransactionRepository = (*Repository)(nil)
+
+// Repository handles database queries for transaction edits
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates a new transactions repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateTransaction inserts a manually entered transaction
+func (r *Repository) CreateTransaction(ctx context.Context, tx *Transaction) error {
+	tx.Source = "manual"
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO transactions (user_id, account_id, category_id, posted_at, description, merchant_name,
+		                          amount_minor, currency_code, source, notes, intent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'manual', $9, $10)
+		RETURNING id, created_at, updated_at
+	`, tx.UserID, tx.AccountID, tx.CategoryID, tx.PostedAt, tx.Description, tx.MerchantName,
+		tx.AmountMinor, tx.CurrencyCode, tx.Notes, tx.Intent,
+	).Scan(&tx.ID, &tx.CreatedAt, &tx.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return nil
+}
+
+// GetTransaction returns a user's transaction
+func (r *Repository) GetTransaction(ctx context.Context, userID, id uuid.UUID) (*Transaction, error) {
+	var tx Transaction
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, account_id, category_id, posted_at, description, merchant_name,
+		       amount_minor, currency_code, source::text, notes, intent, review_status, created_at, updated_at
+		FROM transactions
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, id, userID).Scan(
+		&tx.ID, &tx.UserID, &tx.AccountID, &tx.CategoryID, &tx.PostedAt, &tx.Description, &tx.MerchantName,
+		&tx.AmountMinor, &tx.CurrencyCode, &tx.Source, &tx.Notes, &tx.Intent, &tx.ReviewStatus, &tx.CreatedAt, &tx.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return &tx, nil
+}
+
+// UpdateTransaction stores the editable fields of a transaction
+func (r *Repository) UpdateTransaction(ctx context.Context, tx *Transaction) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE transactions
+		SET account_id = $3, category_id = $4, posted_at = $5, description = $6, merchant_name = $7,
+		    amount_minor = $8, currency_code = $9, notes = $10, intent = $11
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		RETURNING updated_at
+	`, tx.ID, tx.UserID, tx.AccountID, tx.CategoryID, tx.PostedAt, tx.Description, tx.MerchantName,
+		tx.AmountMinor, tx.CurrencyCode, tx.Notes, tx.Intent,
+	).Scan(&tx.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteTransaction deletes a user's transaction; splits, transfers and
+// duplicate links go with it. It reports false when nothing was deleted.
+func (r *Repository) DeleteTransaction(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM transactions WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete transaction: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetAccountCurrency returns the currency of a user's account
+func (r *Repository) GetAccountCurrency(ctx context.Context, userID, accountID uuid.UUID) (string, error) {
+	var code string
+	err := r.db.QueryRow(ctx, `
+		SELECT currency_code FROM accounts WHERE id = $1 AND user_id = $2
+	`, accountID, userID).Scan(&code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get account currency: %w", err)
+	}
+	return code, nil
+}
+
+// GetBaseCurrency returns the currency the user reports in
+func (r *Repository) GetBaseCurrency(ctx context.Context, userID uuid.UUID) (string, error) {
+	var code string
+	err := r.db.QueryRow(ctx, `SELECT base_currency FROM users WHERE id = $1`, userID).Scan(&code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get base currency: %w", err)
+	}
+	return code, nil
+}
+
+// GetAmount returns the amount of a user's transaction
+func (r *Repository) GetAmount(ctx context.Context, userID, transactionID uuid.UUID) (*int64, error) {
+	var amount int64
+	err := r.db.QueryRow(ctx, `
+		SELECT amount_minor FROM transactions WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, transactionID, userID).Scan(&amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return &amount, nil
+}
+
+// ReplaceSplits deletes the transaction's splits and inserts the new ones in one transaction
+func (r *Repository) ReplaceSplits(ctx context.Context, userID, transactionID uuid.UUID, splits []Split) ([]Split, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM transaction_splits WHERE transaction_id = $1 AND user_id = $2
+	`, transactionID, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete splits: %w", err)
+	}
+
+	stored := make([]Split, 0, len(splits))
+	for i, split := range splits {
+		split.TransactionID = transactionID
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO transaction_splits (user_id, transaction_id, category_id, amount_minor, note, position)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id,
+			          (SELECT name FROM categories WHERE id = $3),
+			          (SELECT currency_code FROM transactions WHERE id = $2)
+		`, userID, transactionID, split.CategoryID, split.AmountMinor, split.Note, i).Scan(&split.ID, &split.CategoryName, &split.CurrencyCode); err != nil {
+			return nil, fmt.Errorf("failed to insert split: %w", err)
+		}
+		stored = append(stored, split)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit splits: %w", err)
+	}
+	return stored, nil
+}
+
+// ListSplits returns the splits of the given transactions
+func (r *Repository) ListSplits(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (map[uuid.UUID][]Split, error) {
+	result := make(map[uuid.UUID][]Split)
+	if len(transactionIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT s.id, s.transaction_id, s.category_id, c.name, s.amount_minor, t.currency_code, s.note
+		FROM transaction_splits s
+		JOIN transactions t ON t.id = s.transaction_id
+		LEFT JOIN categories c ON c.id = s.category_id
+		WHERE s.user_id = $1 AND s.transaction_id = ANY($2)
+		ORDER BY s.transaction_id, s.position
+	`, userID, transactionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list splits: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s Split
+		if err := rows.Scan(&s.ID, &s.TransactionID, &s.CategoryID, &s.CategoryName, &s.AmountMinor, &s.CurrencyCode, &s.Note); err != nil {
+			return nil, fmt.Errorf("failed to scan split: %w", err)
+		}
+		result[s.TransactionID] = append(result[s.TransactionID], s)
+	}
+	return result, rows.Err()
+}
+
+// FindTransferCandidates pairs outflows with inflows of the opposite amount in
+// the same currency on another account, posted at most maxDays apart. With an
+// import job only pairs with a side from that job are returned. Transactions
+// already in an active transfer and pairs the user unpaired are skipped.
+func (r *Repository) FindTransferCandidates(ctx context.Context, userID uuid.UUID, importJobID *uuid.UUID, maxDays int) ([]TransferCandidate, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT o.id, o.posted_at, i.id, i.posted_at
+		FROM transactions o
+		JOIN transactions i
+		  ON i.user_id = o.user_id
+		 AND i.amount_minor = -o.amount_minor
+		 AND i.currency_code = o.currency_code
+		 AND i.account_id <> o.account_id
+		 AND i.posted_at BETWEEN o.posted_at - make_interval(days => $3) AND o.posted_at + make_interval(days => $3)
+		 AND i.deleted_at IS NULL
+		WHERE o.user_id = $1 AND o.deleted_at IS NULL
+		  AND o.amount_minor < 0
+		  AND ($2::uuid IS NULL OR o.import_job_id = $2 OR i.import_job_id = $2)
+		  AND NOT EXISTS (
+			SELECT 1 FROM transaction_transfers tr
+			WHERE (tr.status <> 'dismissed' AND (tr.outflow_transaction_id IN (o.id, i.id) OR tr.inflow_transaction_id IN (o.id, i.id)))
+			   OR (tr.outflow_transaction_id = o.id AND tr.inflow_transaction_id = i.id)
+		  )
+		ORDER BY o.posted_at, o.id
+	`, userID, importJobID, maxDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transfer candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []TransferCandidate
+	for rows.Next() {
+		var c TransferCandidate
+		if err := rows.Scan(&c.OutflowID, &c.OutflowDate, &c.InflowID, &c.InflowDate); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer candidate: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find transfer candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+// CreateTransfers stores pending transfers, skipping pairs that conflict with an
+// existing transfer. It returns how many were created.
+func (r *Repository) CreateTransfers(ctx context.Context, transfers []*Transfer) (int, error) {
+	if len(transfers) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uuid.UUID, len(transfers))
+	userIDs := make([]uuid.UUID, len(transfers))
+	outflowIDs := make([]uuid.UUID, len(transfers))
+	inflowIDs := make([]uuid.UUID, len(transfers))
+	scores := make([]float64, len(transfers))
+	for i, t := range transfers {
+		if t.ID == uuid.Nil {
+			t.ID = uuid.New()
+		}
+		ids[i], userIDs[i], outflowIDs[i], inflowIDs[i], scores[i] = t.ID, t.UserID, t.OutflowID, t.InflowID, t.Score
+	}
+
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO transaction_transfers (id, user_id, outflow_transaction_id, inflow_transaction_id, score)
+		SELECT * FROM unnest($1::uuid[], $2::uuid[], $3::uuid[], $4::uuid[], $5::numeric[])
+		ON CONFLICT DO NOTHING
+	`, ids, userIDs, outflowIDs, inflowIDs, scores)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create transfers: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// ListTransfers lists a user's transfers with the given status (all statuses
+// when empty), newest first, with the total count
+func (r *Repository) ListTransfers(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]*Transfer, int64, error) {
+	var totalCount int64
+	countQuery := `
+		SELECT COUNT(*)
+		FROM transaction_transfers tr
+		JOIN transactions o ON o.id = tr.outflow_transaction_id
+		JOIN transactions i ON i.id = tr.inflow_transaction_id
+		WHERE tr.user_id = $1 AND ($2 = '' OR tr.status = $2)
+		  AND o.deleted_at IS NULL AND i.deleted_at IS NULL
+	`
+	if err := r.db.QueryRow(ctx, countQuery, userID, status).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transfers: %w", err)
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT tr.id, tr.user_id, tr.outflow_transaction_id, tr.inflow_transaction_id,
+		       o.account_id, i.account_id, o.posted_at, i.posted_at,
+		       i.amount_minor, i.currency_code, tr.score::float8, tr.status, tr.created_at, tr.resolved_at
+		FROM transaction_transfers tr
+		JOIN transactions o ON o.id = tr.outflow_transaction_id
+		JOIN transactions i ON i.id = tr.inflow_transaction_id
+		WHERE tr.user_id = $1 AND ($2 = '' OR tr.status = $2)
+		  AND o.deleted_at IS NULL AND i.deleted_at IS NULL
+		ORDER BY tr.created_at DESC, tr.id
+		LIMIT $3 OFFSET $4
+	`, userID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var transfers []*Transfer
+	for rows.Next() {
+		var t Transfer
+		if err := rows.Scan(
+			&t.ID, &t.UserID, &t.OutflowID, &t.InflowID,
+			&t.OutflowAccountID, &t.InflowAccountID, &t.OutflowDate, &t.InflowDate,
+			&t.AmountMinor, &t.CurrencyCode, &t.Score, &t.Status, &t.CreatedAt, &t.ResolvedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan transfer: %w", err)
+		}
+		transfers = append(transfers, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list transfers: %w", err)
+	}
+
+	return transfers, totalCount, nil
+}
+
+// ConfirmTransfer marks a pending transfer as confirmed. It reports false when
+// no pending transfer matched.
+func (r *Repository) ConfirmTransfer(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE transaction_transfers SET status = 'confirmed', resolved_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'pending'
+	`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm transfer: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// UnpairTransfer marks a pending or confirmed transfer as dismissed, so both
+// transactions count as spending and income again. It reports false when no
+// active transfer matched.
+func (r *Repository) UnpairTransfer(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE transaction_transfers SET status = 'dismissed', resolved_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status IN ('pending', 'confirmed')
+	`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unpair transfer: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// BulkUpdateTransactions applies a change to the selected transactions in one
+// database transaction. Split transactions keep their category, which their
+// splits decide.
+func (r *Repository) BulkUpdateTransactions(ctx context.Context, userID uuid.UUID, selection BulkSelection, change BulkChange) ([]BulkUpdatedRow, error) {
+	whereSQL, args := repository.TransactionFilterSQL(userID, selection.Filter)
+	if len(selection.IDs) > 0 {
+		args = append(args, selection.IDs)
+		whereSQL += fmt.Sprintf(" AND t.id = ANY($%d)", len(args))
+	}
+
+	n := len(args)
+	args = append(args,
+		change.SetCategory, change.CategoryID,
+		change.SetMerchant, change.MerchantName,
+		change.SetNotes, change.Notes,
+		change.SetIntent, change.Intent,
+	)
+	query := fmt.Sprintf(`
+		WITH previous AS (
+		    SELECT t.id, t.category_id FROM transactions t
+		    %[9]s
+		    FOR UPDATE
+		)
+		UPDATE transactions t
+		SET category_id = CASE
+		        WHEN $%[1]d::boolean AND NOT EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+		        THEN $%[2]d::uuid
+		        ELSE t.category_id
+		    END,
+		    merchant_name = CASE WHEN $%[3]d::boolean THEN $%[4]d::text ELSE t.merchant_name END,
+		    notes = CASE WHEN $%[5]d::boolean THEN $%[6]d::text ELSE t.notes END,
+		    intent = CASE WHEN $%[7]d::boolean THEN $%[8]d::text ELSE t.intent END
+		FROM previous p
+		WHERE t.id = p.id
+		RETURNING t.id, t.description, p.category_id,
+		          EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+	`, n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, whereSQL)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bulk update transactions: %w", err)
+	}
+	var updated []BulkUpdatedRow
+	var ids []uuid.UUID
+	for rows.Next() {
+		var row BulkUpdatedRow
+		if err := rows.Scan(&row.ID, &row.Description, &row.PreviousCategoryID, &row.IsSplit); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan updated transaction: %w", err)
+		}
+		updated = append(updated, row)
+		ids = append(ids, row.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to bulk update transactions: %w", err)
+	}
+
+	if len(ids) > 0 && len(change.AddTagIDs) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO transaction_tags (transaction_id, tag_id)
+			SELECT t.id, tg.id
+			FROM unnest($1::uuid[]) AS t(id)
+			CROSS JOIN tags tg
+			WHERE tg.user_id = $2 AND tg.id = ANY($3)
+			ON CONFLICT DO NOTHING
+		`, ids, userID, change.AddTagIDs); err != nil {
+			return nil, fmt.Errorf("failed to add tags: %w", err)
+		}
+	}
+	if len(ids) > 0 && len(change.RemoveTagIDs) > 0 {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM transaction_tags WHERE transaction_id = ANY($1) AND tag_id = ANY($2)
+		`, ids, change.RemoveTagIDs); err != nil {
+			return nil, fmt.Errorf("failed to remove tags: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit bulk update: %w", err)
+	}
+	return updated, nil
+}
+
+// SetIntent sets (nil clears) the intent of the user's transactions
+func (r *Repository) SetIntent(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, intent *string) (int, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE transactions SET intent = $3
+		WHERE user_id = $1 AND id = ANY($2) AND deleted_at IS NULL
+	`, userID, transactionIDs, intent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set intent: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// ListReviewItems lists a user's queued transactions with the given review
+// status (all statuses when empty), optionally from one import job and only
+// those uncategorized or categorized with less than belowConfidence, newest
+// first, with the total count
+func (r *Repository) ListReviewItems(ctx context.Context, userID uuid.UUID, status string, importJobID *uuid.UUID, belowConfidence *float64, limit, offset int) ([]*ReviewItem, int64, error) {
+	var totalCount int64
+	countQuery := `
+		SELECT COUNT(*) FROM transactions
+		WHERE user_id = $1 AND review_status IS NOT NULL AND deleted_at IS NULL
+		  AND ($2 = '' OR review_status = $2)
+		  AND ($3::uuid IS NULL OR import_job_id = $3)
+		  AND ($4::real IS NULL OR category_id IS NULL OR category_confidence < $4)
+	`
+	if err := r.db.QueryRow(ctx, countQuery, userID, status, importJobID, belowConfidence).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count review items: %w", err)
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT t.id, t.account_id, t.import_job_id, t.posted_at, t.description, t.merchant_name,
+		       t.amount_minor, t.currency_code, t.category_id, c.name, t.category_confidence, t.intent,
+		       t.review_status, t.review_reasons,
+		       (SELECT l.duplicate_of_id FROM transaction_duplicate_links l
+		        WHERE l.transaction_id = t.id AND l.status = 'pending'
+		        ORDER BY l.score DESC LIMIT 1),
+		       t.reviewed_at
+		FROM transactions t
+		LEFT JOIN categories c ON c.id = t.category_id
+		WHERE t.user_id = $1 AND t.review_status IS NOT NULL AND t.deleted_at IS NULL
+		  AND ($2 = '' OR t.review_status = $2)
+		  AND ($3::uuid IS NULL OR t.import_job_id = $3)
+		  AND ($4::real IS NULL OR t.category_id IS NULL OR t.category_confidence < $4)
+		ORDER BY t.posted_at DESC, t.id DESC
+		LIMIT $5 OFFSET $6
+	`, userID, status, importJobID, belowConfidence, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list review items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*ReviewItem
+	for rows.Next() {
+		var item ReviewItem
+		if err := rows.Scan(
+			&item.TransactionID, &item.AccountID, &item.ImportJobID, &item.PostedAt, &item.Description, &item.MerchantName,
+			&item.AmountMinor, &item.CurrencyCode, &item.CategoryID, &item.CategoryName, &item.CategoryConfidence, &item.Intent,
+			&item.Status, &item.Reasons, &item.DuplicateOfID, &item.ReviewedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan review item: %w", err)
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list review items: %w", err)
+	}
+
+	return items, totalCount, nil
+}
+
+// SetReviewStatus sets the review status of the user's queued transactions.
+// Approving a possible duplicate keeps both rows, so its pending duplicate
+// links are dismissed in the same database transaction.
+func (r *Repository) SetReviewStatus(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, status string) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE transactions SET review_status = $3, reviewed_at = NOW()
+		WHERE user_id = $1 AND id = ANY($2) AND review_status IS NOT NULL AND deleted_at IS NULL
+	`, userID, transactionIDs, status)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set review status: %w", err)
+	}
+
+	if status == ReviewApproved && result.RowsAffected() > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE transaction_duplicate_links SET status = 'dismissed', resolved_at = NOW()
+			WHERE user_id = $1 AND transaction_id = ANY($2) AND status = 'pending'
+		`, userID, transactionIDs); err != nil {
+			return 0, fmt.Errorf("failed to dismiss duplicate links: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit review status: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
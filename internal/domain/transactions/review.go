@@ -0,0 +1,90 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Review statuses of imported transactions held in the review queue
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewSkipped  = "skipped" // Left out of insights
+)
+
+var (
+	// ErrInvalidReviewStatus is returned when listing the queue by an unknown status.
+	ErrInvalidReviewStatus = errors.New("invalid review status")
+	// ErrReviewItemNotFound is returned when a transaction was never queued for review.
+	ErrReviewItemNotFound = errors.New("review item not found")
+)
+
+// ListReviewQueue returns the user's transactions held for review with the
+// given status ("pending", "approved", "skipped" or empty for all), optionally
+// from one import job, newest first, and the total count. A belowConfidence
+// threshold keeps only rows left uncategorized or categorized with less
+// confidence, such as the statistical model's weaker guesses.
+func (s *Service) ListReviewQueue(ctx context.Context, userID uuid.UUID, status string, importJobID *uuid.UUID, belowConfidence *float64, limit, offset int) ([]*ReviewItem, int64, error) {
+	switch status {
+	case "", ReviewPending, ReviewApproved, ReviewSkipped:
+	default:
+		return nil, 0, fmt.Errorf("%w: %q", ErrInvalidReviewStatus, status)
+	}
+	if belowConfidence != nil && (*belowConfidence <= 0 || *belowConfidence > 1) {
+		return nil, 0, fmt.Errorf("%w: confidence threshold must be above 0 and at most 1", ErrInvalidTransaction)
+	}
+	return s.repo.ListReviewItems(ctx, userID, status, importJobID, belowConfidence, limit, offset)
+}
+
+// ApproveReview accepts queued transactions as they are. Approved possible
+// duplicates keep both rows. It returns how many were approved.
+func (s *Service) ApproveReview(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (int, error) {
+	return s.setReviewStatus(ctx, userID, transactionIDs, ReviewApproved)
+}
+
+// SkipReview leaves queued transactions out of insights without changing
+// them; approving them later brings them back. It returns how many were skipped.
+func (s *Service) SkipReview(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (int, error) {
+	return s.setReviewStatus(ctx, userID, transactionIDs, ReviewSkipped)
+}
+
+// FixReview corrects a queued transaction (typically its category, merchant
+// name or intent) and approves it.
+func (s *Service) FixReview(ctx context.Context, userID, id uuid.UUID, update TransactionUpdate) (*Transaction, error) {
+	current, err := s.repo.GetTransaction(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.ReviewStatus == nil {
+		return nil, ErrReviewItemNotFound
+	}
+
+	tx, err := s.UpdateTransaction(ctx, userID, id, update)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.setReviewStatus(ctx, userID, []uuid.UUID{id}, ReviewApproved); err != nil {
+		return nil, err
+	}
+	approved := ReviewApproved
+	tx.ReviewStatus = &approved
+	return tx, nil
+}
+
+func (s *Service) setReviewStatus(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, status string) (int, error) {
+	if len(transactionIDs) == 0 {
+		return 0, fmt.Errorf("%w: no transactions given", ErrInvalidTransaction)
+	}
+
+	updated, err := s.repo.SetReviewStatus(ctx, userID, transactionIDs, status)
+	if err != nil {
+		return 0, err
+	}
+	if updated == 0 {
+		return 0, ErrReviewItemNotFound
+	}
+	return updated, nil
+}
@@ -0,0 +1,67 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewQueue(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	repo := newMockRepo()
+	svc := NewService(repo, testLogger)
+
+	queued := func(description string) uuid.UUID {
+		id := uuid.New()
+		pending := ReviewPending
+		repo.transactions[id] = &Transaction{ID: id, UserID: userID, Description: description, AmountMinor: -1000,
+			CurrencyCode: "EUR", Source: "csv", ReviewStatus: &pending}
+		return id
+	}
+	coffee, taxi, pos := queued("COFFEE"), queued("TAXI"), queued("POS 4411")
+	reviewed := uuid.New()
+	repo.transactions[reviewed] = &Transaction{ID: reviewed, UserID: userID, Description: "RENT", Source: "csv"}
+
+	items, total, err := svc.ListReviewQueue(ctx, userID, ReviewPending, nil, nil, 50, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, items, 3)
+
+	approved, err := svc.ApproveReview(ctx, userID, []uuid.UUID{coffee, reviewed})
+	require.NoError(t, err)
+	assert.Equal(t, 1, approved, "rows never queued are not counted")
+	assert.Equal(t, ReviewApproved, *repo.transactions[coffee].ReviewStatus)
+
+	_, err = svc.SkipReview(ctx, userID, []uuid.UUID{taxi})
+	require.NoError(t, err)
+	assert.Equal(t, ReviewSkipped, *repo.transactions[taxi].ReviewStatus)
+
+	category := uuid.New()
+	intent := IntentNecessary
+	fixed, err := svc.FixReview(ctx, userID, pos, TransactionUpdate{CategoryID: &category, Intent: &intent})
+	require.NoError(t, err)
+	assert.Equal(t, category, *fixed.CategoryID)
+	assert.Equal(t, ReviewApproved, *fixed.ReviewStatus)
+	assert.Equal(t, ReviewApproved, *repo.transactions[pos].ReviewStatus)
+
+	_, total, err = svc.ListReviewQueue(ctx, userID, ReviewPending, nil, nil, 50, 0)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	_, err = svc.FixReview(ctx, userID, reviewed, TransactionUpdate{CategoryID: &category})
+	assert.True(t, errors.Is(err, ErrReviewItemNotFound), "got %v", err)
+	_, err = svc.ApproveReview(ctx, userID, []uuid.UUID{uuid.New()})
+	assert.True(t, errors.Is(err, ErrReviewItemNotFound), "got %v", err)
+	_, err = svc.SkipReview(ctx, userID, nil)
+	assert.True(t, errors.Is(err, ErrInvalidTransaction), "got %v", err)
+	_, _, err = svc.ListReviewQueue(ctx, userID, "flagged", nil, nil, 50, 0)
+	assert.True(t, errors.Is(err, ErrInvalidReviewStatus), "got %v", err)
+	threshold := 1.5
+	_, _, err = svc.ListReviewQueue(ctx, userID, ReviewPending, nil, &threshold, 50, 0)
+	assert.True(t, errors.Is(err, ErrInvalidTransaction), "got %v", err)
+}
@@ -0,0 +1,89 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTransactionNotFound is returned when the transaction does not exist for the user.
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrInvalidSplits is returned when splits do not add up to their transaction.
+	ErrInvalidSplits = errors.New("invalid splits")
+)
+
+// Service handles edits to stored transactions
+type Service struct {
+	repo        TransactionRepository
+	logger      *slog.Logger
+	categorizer Categorizer        // Optional: nil leaves manual entries uncategorized
+	rules       RuleCreator        // Optional: nil disables rules from bulk edits
+	corrections CorrectionRecorder // Optional: nil learns nothing from category edits
+}
+
+// NewService creates a new transactions service
+func NewService(repo TransactionRepository, logger *slog.Logger) *Service {
+	return &Service{repo: repo, logger: logger}
+}
+
+// WithCategorizer categorizes manually entered transactions
+func (s *Service) WithCategorizer(categorizer Categorizer) *Service {
+	s.categorizer = categorizer
+	return s
+}
+
+// WithCorrectionRecorder records categories set by hand so categorization can
+// learn from them
+func (s *Service) WithCorrectionRecorder(corrections CorrectionRecorder) *Service {
+	s.corrections = corrections
+	return s
+}
+
+// SetSplits replaces a transaction's splits. Splits must number at least two,
+// share the sign of the transaction and sum to its amount; passing none
+// removes the split so the transaction's own category applies again.
+func (s *Service) SetSplits(ctx context.Context, userID, transactionID uuid.UUID, splits []Split) ([]Split, error) {
+	amount, err := s.repo.GetAmount(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if amount == nil {
+		return nil, ErrTransactionNotFound
+	}
+
+	if err := validateSplits(*amount, splits); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ReplaceSplits(ctx, userID, transactionID, splits)
+}
+
+// ListSplits returns the splits of the given transactions keyed by transaction ID
+func (s *Service) ListSplits(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (map[uuid.UUID][]Split, error) {
+	return s.repo.ListSplits(ctx, userID, transactionIDs)
+}
+
+func validateSplits(amount int64, splits []Split) error {
+	if len(splits) == 0 {
+		return nil
+	}
+	if len(splits) == 1 {
+		return fmt.Errorf("%w: a split needs at least two parts", ErrInvalidSplits)
+	}
+
+	var sum int64
+	for i, split := range splits {
+		if split.AmountMinor == 0 || (split.AmountMinor < 0) != (amount < 0) {
+			return fmt.Errorf("%w: part %d must be non-zero with the same sign as the transaction", ErrInvalidSplits, i+1)
+		}
+		sum += split.AmountMinor
+	}
+	if sum != amount {
+		return fmt.Errorf("%w: parts sum to %d, transaction amount is %d", ErrInvalidSplits, sum, amount)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// MockTransactionRepository keeps transactions, splits and transfers in memory
+type MockTransactionRepository struct {
+	transactions map[uuid.UUID]*Transaction
+	amounts      map[uuid.UUID]int64
+	accounts     map[uuid.UUID]string
+	baseCurrency string
+	splits       map[uuid.UUID][]Split
+	candidates   []TransferCandidate
+	transfers    []*Transfer
+	bulkRows     []BulkUpdatedRow
+	bulkChange   *BulkChange
+}
+
+func newMockRepo() *MockTransactionRepository {
+	return &MockTransactionRepository{
+		transactions: make(map[uuid.UUID]*Transaction),
+		amounts:      make(map[uuid.UUID]int64),
+		accounts:     make(map[uuid.UUID]string),
+		splits:       make(map[uuid.UUID][]Split),
+	}
+}
+
+func (m *MockTransactionRepository) CreateTransaction(ctx context.Context, tx *Transaction) error {
+	tx.ID = uuid.New()
+	tx.Source = "manual"
+	stored := *tx
+	m.transactions[tx.ID] = &stored
+	return nil
+}
+
+func (m *MockTransactionRepository) GetTransaction(ctx context.Context, userID, id uuid.UUID) (*Transaction, error) {
+	tx, ok := m.transactions[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *tx
+	return &copied, nil
+}
+
+func (m *MockTransactionRepository) UpdateTransaction(ctx context.Context, tx *Transaction) error {
+	stored := *tx
+	m.transactions[tx.ID] = &stored
+	return nil
+}
+
+func (m *MockTransactionRepository) DeleteTransaction(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	_, ok := m.transactions[id]
+	delete(m.transactions, id)
+	return ok, nil
+}
+
+func (m *MockTransactionRepository) BulkUpdateTransactions(ctx context.Context, userID uuid.UUID, selection BulkSelection, change BulkChange) ([]BulkUpdatedRow, error) {
+	m.bulkChange = &change
+	return m.bulkRows, nil
+}
+
+func (m *MockTransactionRepository) SetIntent(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, intent *string) (int, error) {
+	updated := 0
+	for _, id := range transactionIDs {
+		if tx, ok := m.transactions[id]; ok {
+			tx.Intent = intent
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (m *MockTransactionRepository) GetAccountCurrency(ctx context.Context, userID, accountID uuid.UUID) (string, error) {
+	return m.accounts[accountID], nil
+}
+
+func (m *MockTransactionRepository) GetBaseCurrency(ctx context.Context, userID uuid.UUID) (string, error) {
+	return m.baseCurrency, nil
+}
+
+func (m *MockTransactionRepository) GetAmount(ctx context.Context, userID, transactionID uuid.UUID) (*int64, error) {
+	amount, ok := m.amounts[transactionID]
+	if !ok {
+		return nil, nil
+	}
+	return &amount, nil
+}
+
+func (m *MockTransactionRepository) ReplaceSplits(ctx context.Context, userID, transactionID uuid.UUID, splits []Split) ([]Split, error) {
+	stored := make([]Split, 0, len(splits))
+	for _, split := range splits {
+		split.ID = uuid.New()
+		split.TransactionID = transactionID
+		stored = append(stored, split)
+	}
+	if len(stored) == 0 {
+		delete(m.splits, transactionID)
+	} else {
+		m.splits[transactionID] = stored
+	}
+	return stored, nil
+}
+
+func (m *MockTransactionRepository) ListSplits(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID) (map[uuid.UUID][]Split, error) {
+	result := make(map[uuid.UUID][]Split)
+	for _, id := range transactionIDs {
+		if splits, ok := m.splits[id]; ok {
+			result[id] = splits
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTransactionRepository) FindTransferCandidates(ctx context.Context, userID uuid.UUID, importJobID *uuid.UUID, maxDays int) ([]TransferCandidate, error) {
+	return m.candidates, nil
+}
+
+func (m *MockTransactionRepository) CreateTransfers(ctx context.Context, transfers []*Transfer) (int, error) {
+	for _, t := range transfers {
+		t.ID = uuid.New()
+	}
+	m.transfers = append(m.transfers, transfers...)
+	return len(transfers), nil
+}
+
+func (m *MockTransactionRepository) ListTransfers(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]*Transfer, int64, error) {
+	var result []*Transfer
+	for _, t := range m.transfers {
+		if status == "" || t.Status == status {
+			result = append(result, t)
+		}
+	}
+	return result, int64(len(result)), nil
+}
+
+func (m *MockTransactionRepository) ConfirmTransfer(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	return m.setTransferStatus(id, "confirmed", "pending"), nil
+}
+
+func (m *MockTransactionRepository) UnpairTransfer(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	return m.setTransferStatus(id, "dismissed", "pending", "confirmed"), nil
+}
+
+func (m *MockTransactionRepository) ListReviewItems(ctx context.Context, userID uuid.UUID, status string, importJobID *uuid.UUID, belowConfidence *float64, limit, offset int) ([]*ReviewItem, int64, error) {
+	var result []*ReviewItem
+	for _, tx := range m.transactions {
+		if tx.ReviewStatus == nil || (status != "" && *tx.ReviewStatus != status) {
+			continue
+		}
+		result = append(result, &ReviewItem{TransactionID: tx.ID, Description: tx.Description, Status: *tx.ReviewStatus})
+	}
+	return result, int64(len(result)), nil
+}
+
+func (m *MockTransactionRepository) SetReviewStatus(ctx context.Context, userID uuid.UUID, transactionIDs []uuid.UUID, status string) (int, error) {
+	updated := 0
+	for _, id := range transactionIDs {
+		if tx, ok := m.transactions[id]; ok && tx.ReviewStatus != nil {
+			tx.ReviewStatus = &status
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (m *MockTransactionRepository) setTransferStatus(id uuid.UUID, status string, from ...string) bool {
+	for _, t := range m.transfers {
+		if t.ID != id {
+			continue
+		}
+		for _, f := range from {
+			if t.Status == f {
+				t.Status = status
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestSetSplits(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	txID := uuid.New()
+	groceries, household := uuid.New(), uuid.New()
+
+	repo := newMockRepo()
+	repo.amounts[txID] = -4250
+	svc := NewService(repo, testLogger)
+
+	stored, err := svc.SetSplits(ctx, userID, txID, []Split{
+		{CategoryID: &groceries, AmountMinor: -3000},
+		{CategoryID: &household, AmountMinor: -1250},
+	})
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+	assert.Equal(t, txID, stored[0].TransactionID)
+
+	listed, err := svc.ListSplits(ctx, userID, []uuid.UUID{txID, uuid.New()})
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+	assert.Len(t, listed[txID], 2)
+
+	// Clearing removes the split
+	_, err = svc.SetSplits(ctx, userID, txID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, repo.splits)
+}
+
+func TestSetSplits_Validation(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	txID := uuid.New()
+
+	repo := newMockRepo()
+	repo.amounts[txID] = -4250
+	svc := NewService(repo, testLogger)
+
+	tests := []struct {
+		name   string
+		splits []Split
+	}{
+		{"single part", []Split{{AmountMinor: -4250}}},
+		{"wrong sum", []Split{{AmountMinor: -3000}, {AmountMinor: -1000}}},
+		{"wrong sign", []Split{{AmountMinor: -5250}, {AmountMinor: 1000}}},
+		{"zero part", []Split{{AmountMinor: -4250}, {AmountMinor: 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.SetSplits(ctx, userID, txID, tt.splits)
+			assert.True(t, errors.Is(err, ErrInvalidSplits), "got %v", err)
+		})
+	}
+	assert.Empty(t, repo.splits)
+
+	_, err := svc.SetSplits(ctx, userID, uuid.New(), nil)
+	assert.True(t, errors.Is(err, ErrTransactionNotFound))
+}
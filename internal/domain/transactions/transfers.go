@@ -0,0 +1,123 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+
+	"github.com/FACorreiaa/smart-finance-tracker/internal/domain/import/dedup"
+)
+
+// ErrTransferNotFound is returned when a transfer does not exist, belongs to
+// another user or is not in a state the action applies to.
+var ErrTransferNotFound = errors.New("transfer not found")
+
+// transferMaxDays is the largest posting date gap between the two sides of a
+// transfer; banks may book an incoming transfer a few business days later.
+const transferMaxDays = 4
+
+// DetectTransfers pairs outflows and inflows of equal amounts across the user's
+// accounts and stores them as pending transfers. With an import job only pairs
+// involving that job's rows are considered. It returns how many were created.
+func (s *Service) DetectTransfers(ctx context.Context, userID uuid.UUID, importJobID *uuid.UUID) (int, error) {
+	candidates, err := s.repo.FindTransferCandidates(ctx, userID, importJobID, transferMaxDays)
+	if err != nil {
+		return 0, err
+	}
+
+	pairs := pairTransfers(candidates, transferMaxDays)
+	if len(pairs) == 0 {
+		return 0, nil
+	}
+
+	transfers := make([]*Transfer, 0, len(pairs))
+	for _, p := range pairs {
+		transfers = append(transfers, &Transfer{
+			UserID:    userID,
+			OutflowID: p.OutflowID,
+			InflowID:  p.InflowID,
+			Score:     p.Score,
+			Status:    "pending",
+		})
+	}
+	return s.repo.CreateTransfers(ctx, transfers)
+}
+
+// scoredTransfer is a candidate with its date closeness score
+type scoredTransfer struct {
+	TransferCandidate
+	Score float64
+}
+
+// pairTransfers keeps every transaction in one pair at most, preferring the
+// pairs with the closest posting dates.
+func pairTransfers(candidates []TransferCandidate, maxDays int) []scoredTransfer {
+	// Index both sides so dedup.Assign can match every transaction once.
+	outflows := make(map[uuid.UUID]int)
+	inflows := make(map[uuid.UUID]int)
+	byPair := make(map[[2]int]TransferCandidate)
+	var pairs []dedup.Pair
+	for _, c := range candidates {
+		days := math.Abs(c.OutflowDate.Sub(c.InflowDate).Hours()) / 24
+		if days > float64(maxDays) {
+			continue
+		}
+		l, ok := outflows[c.OutflowID]
+		if !ok {
+			l = len(outflows)
+			outflows[c.OutflowID] = l
+		}
+		r, ok := inflows[c.InflowID]
+		if !ok {
+			r = len(inflows)
+			inflows[c.InflowID] = r
+		}
+		byPair[[2]int{l, r}] = c
+		pairs = append(pairs, dedup.Pair{Left: l, Right: r, Score: 1 - days/float64(maxDays+1)})
+	}
+
+	assigned := dedup.Assign(pairs)
+	result := make([]scoredTransfer, 0, len(assigned))
+	for _, p := range assigned {
+		result = append(result, scoredTransfer{TransferCandidate: byPair[[2]int{p.Left, p.Right}], Score: p.Score})
+	}
+	return result
+}
+
+// ListTransfers returns the user's transfers with the given status ("pending",
+// "confirmed", "dismissed" or empty for all), newest first, and the total count.
+func (s *Service) ListTransfers(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]*Transfer, int64, error) {
+	transfers, total, err := s.repo.ListTransfers(ctx, userID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list transfers: %w", err)
+	}
+	return transfers, total, nil
+}
+
+// ConfirmTransfer accepts a pending transfer detected by the matcher.
+func (s *Service) ConfirmTransfer(ctx context.Context, userID, id uuid.UUID) error {
+	confirmed, err := s.repo.ConfirmTransfer(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return ErrTransferNotFound
+	}
+	return nil
+}
+
+// UnpairTransfer breaks up a pending or confirmed transfer; both transactions
+// count as spending and income again and the pair is not proposed again.
+func (s *Service) UnpairTransfer(ctx context.Context, userID, id uuid.UUID) error {
+	unpaired, err := s.repo.UnpairTransfer(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !unpaired {
+		return ErrTransferNotFound
+	}
+	return nil
+}
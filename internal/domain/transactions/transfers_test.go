@@ -0,0 +1,67 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPairTransfers(t *testing.T) {
+	day := time.Date(2025, 10, 6, 0, 0, 0, 0, time.UTC)
+	out1, out2 := uuid.New(), uuid.New()
+	in1, in2 := uuid.New(), uuid.New()
+
+	// Two identical 100.00 transfers two days apart: each outflow pairs with
+	// the inflow closest in date, not the first one found.
+	candidates := []TransferCandidate{
+		{OutflowID: out1, OutflowDate: day, InflowID: in2, InflowDate: day.AddDate(0, 0, 2)},
+		{OutflowID: out1, OutflowDate: day, InflowID: in1, InflowDate: day},
+		{OutflowID: out2, OutflowDate: day.AddDate(0, 0, 2), InflowID: in1, InflowDate: day},
+		{OutflowID: out2, OutflowDate: day.AddDate(0, 0, 2), InflowID: in2, InflowDate: day.AddDate(0, 0, 3)},
+		// Too far apart
+		{OutflowID: uuid.New(), OutflowDate: day, InflowID: uuid.New(), InflowDate: day.AddDate(0, 0, 10)},
+	}
+
+	pairs := pairTransfers(candidates, transferMaxDays)
+	require.Len(t, pairs, 2)
+	got := map[uuid.UUID]uuid.UUID{}
+	for _, p := range pairs {
+		got[p.OutflowID] = p.InflowID
+	}
+	assert.Equal(t, in1, got[out1])
+	assert.Equal(t, in2, got[out2])
+	assert.Equal(t, 1.0, pairs[0].Score)
+}
+
+func TestTransferLifecycle(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	day := time.Date(2025, 10, 6, 0, 0, 0, 0, time.UTC)
+
+	repo := newMockRepo()
+	repo.candidates = []TransferCandidate{
+		{OutflowID: uuid.New(), OutflowDate: day, InflowID: uuid.New(), InflowDate: day.AddDate(0, 0, 1)},
+	}
+	svc := NewService(repo, testLogger)
+
+	created, err := svc.DetectTransfers(ctx, userID, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, created)
+
+	pending, total, err := svc.ListTransfers(ctx, userID, "pending", 50, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	id := pending[0].ID
+
+	require.NoError(t, svc.ConfirmTransfer(ctx, userID, id))
+	assert.True(t, errors.Is(svc.ConfirmTransfer(ctx, userID, id), ErrTransferNotFound), "already confirmed")
+
+	require.NoError(t, svc.UnpairTransfer(ctx, userID, id))
+	assert.True(t, errors.Is(svc.UnpairTransfer(ctx, userID, id), ErrTransferNotFound), "already unpaired")
+	assert.Equal(t, "dismissed", repo.transfers[0].Status)
+}
@@ -12,12 +12,15 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Server        ServerConfig
-	Database      DatabaseConfig
-	Auth          AuthConfig
-	Observability ObservabilityConfig
-	Profiling     ProfilingConfig
-	Gemini        GeminiConfig
+	Server         ServerConfig
+	Database       DatabaseConfig
+	Auth           AuthConfig
+	Observability  ObservabilityConfig
+	Profiling      ProfilingConfig
+	Gemini         GeminiConfig
+	Storage        StorageConfig
+	FX             FXConfig
+	Categorization CategorizationConfig
 }
 
 type GeminiConfig struct {
@@ -25,6 +28,29 @@ type GeminiConfig struct {
 	Model  string
 }
 
+// StorageConfig selects where uploaded files are kept.
+type StorageConfig struct {
+	Backend   string // "local" or "s3"
+	LocalPath string
+
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3UsePathStyle    bool
+}
+
+// FXConfig points at ECB reference rate CSV files loaded at startup.
+type FXConfig struct {
+	RatesPath string // a file or a directory of .csv files
+}
+
+// CategorizationConfig tunes how categorization learns from the user.
+type CategorizationConfig struct {
+	AutoLearn bool // Create rules from repeated corrections instead of suggesting them
+}
+
 type ServerConfig struct {
 	Host               string
 	Port               int
@@ -91,6 +117,22 @@ func Load() (*Config, error) {
 			APIKey: getEnv("GEMINI_API_KEY", ""),
 			Model:  getEnv("GEMINI_MODEL", ""),
 		},
+		Storage: StorageConfig{
+			Backend:           getEnv("STORAGE_BACKEND", "local"),
+			LocalPath:         getEnv("STORAGE_LOCAL_PATH", "data/uploads"),
+			S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+			S3Region:          getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:          getEnv("S3_BUCKET", ""),
+			S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+			S3UsePathStyle:    getEnvAsBool("S3_USE_PATH_STYLE", true),
+		},
+		FX: FXConfig{
+			RatesPath: getEnv("FX_RATES_PATH", "data/fx"),
+		},
+		Categorization: CategorizationConfig{
+			AutoLearn: getEnvAsBool("CATEGORIZATION_AUTO_LEARN", false),
+		},
 	}
 
 	if cfg.Gemini.APIKey == "" {
@@ -101,6 +143,10 @@ func Load() (*Config, error) {
 		return nil, errors.New("GEMINI_MODEL is required")
 	}
 
+	if cfg.Storage.Backend != "local" && cfg.Storage.Backend != "s3" {
+		return nil, fmt.Errorf("unsupported STORAGE_BACKEND %q", cfg.Storage.Backend)
+	}
+
 	return cfg, nil
 }
 
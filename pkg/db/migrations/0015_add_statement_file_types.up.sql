@@ -0,0 +1,10 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- Structured bank statements: OFX/QFX and ISO 20022 CAMT.053
+ALTER TYPE user_file_type ADD VALUE IF NOT EXISTS 'ofx';
+
+ALTER TYPE user_file_type ADD VALUE IF NOT EXISTS 'camt';
+
+-- +goose Down
+-- Postgres cannot drop enum values; rows using them must be removed manually.
+SELECT 1;
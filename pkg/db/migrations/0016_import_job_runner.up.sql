@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Options needed to run a queued import (column mapping, sheet, header rows)
+ALTER TABLE import_jobs
+ADD COLUMN options JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Last sign of life from the worker running the job; stale jobs are requeued
+ALTER TABLE import_jobs ADD COLUMN heartbeat_at TIMESTAMPTZ;
+
+-- Workers claim the oldest pending job first
+CREATE INDEX idx_import_jobs_pending_requested_at ON import_jobs (requested_at)
+WHERE
+    status = 'pending';
+
+-- Recovery scans running jobs by their last heartbeat
+CREATE INDEX idx_import_jobs_running_heartbeat_at ON import_jobs (heartbeat_at)
+WHERE
+    status = 'running';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_import_jobs_running_heartbeat_at;
+
+DROP INDEX IF EXISTS idx_import_jobs_pending_requested_at;
+
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS heartbeat_at;
+
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS options;
+
+-- +goose StatementEnd
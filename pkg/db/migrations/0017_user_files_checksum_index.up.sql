@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Uploads are matched against earlier files with identical content
+CREATE INDEX idx_user_files_user_id_checksum_sha256 ON user_files (user_id, checksum_sha256)
+WHERE
+    checksum_sha256 IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_user_files_user_id_checksum_sha256;
+
+-- +goose StatementEnd
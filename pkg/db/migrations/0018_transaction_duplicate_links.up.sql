@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Probable duplicates between an imported row and a row from another source
+-- (aggregator, manual). Links are proposed by the matcher and resolved by the
+-- user: confirming deletes the imported copy, dismissing keeps both.
+CREATE TABLE transaction_duplicate_links (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    transaction_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    duplicate_of_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    score NUMERIC(4, 3) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ,
+    CONSTRAINT transaction_duplicate_links_status_chk CHECK (status IN ('pending', 'dismissed')),
+    CONSTRAINT transaction_duplicate_links_distinct_chk CHECK (transaction_id <> duplicate_of_id),
+    CONSTRAINT transaction_duplicate_links_pair_key UNIQUE (transaction_id, duplicate_of_id)
+);
+
+CREATE INDEX idx_transaction_duplicate_links_user_id_status_created_at ON transaction_duplicate_links (user_id, status, created_at DESC);
+
+CREATE INDEX idx_transaction_duplicate_links_duplicate_of_id ON transaction_duplicate_links (duplicate_of_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS transaction_duplicate_links;
+
+-- +goose StatementEnd
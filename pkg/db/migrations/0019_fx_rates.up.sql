@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Euro reference rates (units of currency_code per 1 EUR), loaded from ECB CSV
+-- files. Days without a publication (weekends, holidays) use the latest earlier rate.
+CREATE TABLE fx_rates (
+    currency_code CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,
+    source TEXT NOT NULL DEFAULT 'ecb',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (currency_code, rate_date),
+    CONSTRAINT fx_rates_currency_code_chk CHECK (currency_code ~ '^[A-Z]{3}$'),
+    CONSTRAINT fx_rates_rate_chk CHECK (rate > 0)
+);
+
+-- Currency that insights and balances are reported in
+ALTER TABLE users
+ADD COLUMN base_currency CHAR(3) NOT NULL DEFAULT 'EUR',
+ADD CONSTRAINT users_base_currency_chk CHECK (base_currency ~ '^[A-Z]{3}$');
+
+-- Latest rate for code on or before on_date; 1 for the euro, NULL when unknown
+CREATE OR REPLACE FUNCTION fx_rate_on(code CHAR(3), on_date DATE)
+    RETURNS NUMERIC AS $$
+    SELECT CASE
+        WHEN code = 'EUR' THEN 1::NUMERIC
+        ELSE (
+            SELECT rate FROM fx_rates
+            WHERE currency_code = code AND rate_date <= on_date
+            ORDER BY rate_date DESC
+            LIMIT 1
+        )
+    END;
+$$ LANGUAGE sql STABLE;
+
+-- Converts a minor-unit amount between currencies at the rates of on_date.
+-- Returns NULL when a rate is missing, so aggregates skip what cannot be converted.
+CREATE OR REPLACE FUNCTION fx_convert(amount BIGINT, from_code CHAR(3), to_code CHAR(3), on_date DATE)
+    RETURNS BIGINT AS $$
+    SELECT CASE
+        WHEN from_code = to_code THEN amount
+        ELSE ROUND(amount * fx_rate_on(to_code, on_date) / fx_rate_on(from_code, on_date))::BIGINT
+    END;
+$$ LANGUAGE sql STABLE;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP FUNCTION IF EXISTS fx_convert(BIGINT, CHAR(3), CHAR(3), DATE);
+DROP FUNCTION IF EXISTS fx_rate_on(CHAR(3), DATE);
+ALTER TABLE users DROP COLUMN IF EXISTS base_currency;
+DROP TABLE IF EXISTS fx_rates;
+
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Allocations of one transaction across several categories (a supermarket
+-- receipt that is groceries + household + pharmacy). When a transaction has
+-- splits their amounts sum to the parent amount and their categories replace
+-- the parent's category_id in reports.
+CREATE TABLE transaction_splits (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    transaction_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    category_id UUID REFERENCES categories (id) ON DELETE SET NULL,
+    amount_minor BIGINT NOT NULL,
+    note TEXT,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT transaction_splits_amount_chk CHECK (amount_minor <> 0)
+);
+
+CREATE INDEX idx_transaction_splits_transaction_id ON transaction_splits (transaction_id, position);
+
+CREATE INDEX idx_transaction_splits_user_id_category_id ON transaction_splits (user_id, category_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS transaction_splits;
+
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Money moved between two of the user's accounts: the outflow from one account
+-- and the matching inflow on another. Pending and confirmed transfers are left
+-- out of spending insights and balance totals; unpairing marks the pair
+-- dismissed so the matcher does not propose it again.
+CREATE TABLE transaction_transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    outflow_transaction_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    inflow_transaction_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    score NUMERIC(4, 3) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ,
+    CONSTRAINT transaction_transfers_status_chk CHECK (status IN ('pending', 'confirmed', 'dismissed')),
+    CONSTRAINT transaction_transfers_distinct_chk CHECK (outflow_transaction_id <> inflow_transaction_id),
+    CONSTRAINT transaction_transfers_pair_key UNIQUE (outflow_transaction_id, inflow_transaction_id)
+);
+
+-- A transaction belongs to at most one active transfer
+CREATE UNIQUE INDEX uniq_transaction_transfers_outflow_active ON transaction_transfers (outflow_transaction_id)
+WHERE status <> 'dismissed';
+
+CREATE UNIQUE INDEX uniq_transaction_transfers_inflow_active ON transaction_transfers (inflow_transaction_id)
+WHERE status <> 'dismissed';
+
+CREATE INDEX idx_transaction_transfers_user_id_status_created_at ON transaction_transfers (user_id, status, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS transaction_transfers;
+
+-- +goose StatementEnd
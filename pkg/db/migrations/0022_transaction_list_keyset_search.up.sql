@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Keyset pagination for ListTransactions orders by (posted_at, id)
+CREATE INDEX idx_transactions_user_id_posted_at_id ON transactions (user_id, posted_at DESC, id DESC);
+
+DROP INDEX IF EXISTS idx_transactions_user_id_posted_at;
+
+-- Full-text search over description, merchant and notes. The 'simple'
+-- configuration skips stemming so bank descriptions and merchant names match
+-- word prefixes as typed.
+ALTER TABLE transactions
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector(
+        'simple',
+        coalesce(description, '') || ' ' || coalesce(merchant_name, '') || ' ' || coalesce(notes, '')
+    )
+) STORED;
+
+CREATE INDEX idx_transactions_search_vector ON transactions USING GIN (search_vector);
+
+-- Merchant filter compares case-insensitively
+CREATE INDEX idx_transactions_user_id_lower_merchant_name ON transactions (user_id, lower(merchant_name))
+WHERE
+    merchant_name IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_transactions_user_id_lower_merchant_name;
+
+DROP INDEX IF EXISTS idx_transactions_search_vector;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS search_vector;
+
+CREATE INDEX idx_transactions_user_id_posted_at ON transactions (user_id, posted_at DESC);
+
+DROP INDEX IF EXISTS idx_transactions_user_id_posted_at_id;
+
+-- +goose StatementEnd
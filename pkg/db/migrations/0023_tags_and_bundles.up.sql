@@ -0,0 +1,78 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Free-form labels users attach to transactions ("gym", "therapy", "trip-lisbon")
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name CITEXT NOT NULL,
+    color TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT tags_user_id_name_key UNIQUE (user_id, name)
+);
+
+CREATE TRIGGER trigger_set_tags_updated_at
+BEFORE UPDATE ON tags
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE transaction_tags (
+    transaction_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (transaction_id, tag_id)
+);
+
+CREATE INDEX idx_transaction_tags_tag_id ON transaction_tags (tag_id);
+
+-- User-defined groups of tags, categories and merchants reported together
+-- ("Self-care" = gym + therapy + spa)
+CREATE TABLE tag_bundles (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name CITEXT NOT NULL,
+    description TEXT,
+    color TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT tag_bundles_user_id_name_key UNIQUE (user_id, name)
+);
+
+CREATE TRIGGER trigger_set_tag_bundles_updated_at
+BEFORE UPDATE ON tag_bundles
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Each member is exactly one of a tag, a category or a merchant name. A
+-- transaction belongs to a bundle when any member matches it.
+CREATE TABLE tag_bundle_members (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bundle_id UUID NOT NULL REFERENCES tag_bundles (id) ON DELETE CASCADE,
+    tag_id UUID REFERENCES tags (id) ON DELETE CASCADE,
+    category_id UUID REFERENCES categories (id) ON DELETE CASCADE,
+    merchant_name CITEXT,
+    CONSTRAINT tag_bundle_members_one_chk CHECK (num_nonnulls(tag_id, category_id, merchant_name) = 1)
+);
+
+CREATE UNIQUE INDEX uniq_tag_bundle_members_tag ON tag_bundle_members (bundle_id, tag_id)
+WHERE tag_id IS NOT NULL;
+
+CREATE UNIQUE INDEX uniq_tag_bundle_members_category ON tag_bundle_members (bundle_id, category_id)
+WHERE category_id IS NOT NULL;
+
+CREATE UNIQUE INDEX uniq_tag_bundle_members_merchant ON tag_bundle_members (bundle_id, merchant_name)
+WHERE merchant_name IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS tag_bundle_members;
+
+DROP TABLE IF EXISTS tag_bundles;
+
+DROP TABLE IF EXISTS transaction_tags;
+
+DROP TABLE IF EXISTS tags;
+
+-- +goose StatementEnd
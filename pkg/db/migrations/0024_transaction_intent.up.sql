@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Why the user spent the money, set while reviewing transactions. Insights
+-- break monthly spend down by intent and follow the "regret" share.
+ALTER TABLE transactions ADD COLUMN intent TEXT;
+
+ALTER TABLE transactions
+ADD CONSTRAINT transactions_intent_chk CHECK (intent IN ('necessary', 'splurge', 'regret', 'investment'));
+
+CREATE INDEX idx_transactions_user_id_intent_posted_at ON transactions (user_id, intent, posted_at DESC)
+WHERE
+    intent IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_transactions_user_id_intent_posted_at;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_intent_chk;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS intent;
+
+-- +goose StatementEnd
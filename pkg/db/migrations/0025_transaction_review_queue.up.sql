@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Imported rows the app is unsure about (no category, unknown merchant,
+-- possible duplicate) wait in a review queue. review_status stays NULL for
+-- rows that never needed review; skipped rows are left out of insights.
+ALTER TABLE transactions
+ADD COLUMN review_status TEXT,
+ADD COLUMN review_reasons TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN reviewed_at TIMESTAMPTZ;
+
+ALTER TABLE transactions
+ADD CONSTRAINT transactions_review_status_chk CHECK (review_status IN ('pending', 'approved', 'skipped'));
+
+CREATE INDEX idx_transactions_user_id_review_status_posted_at ON transactions (user_id, review_status, posted_at DESC, id DESC)
+WHERE
+    review_status IS NOT NULL;
+
+-- Users who only want reviewed transactions in their insights
+ALTER TABLE users
+ADD COLUMN insights_reviewed_only BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE users DROP COLUMN IF EXISTS insights_reviewed_only;
+
+DROP INDEX IF EXISTS idx_transactions_user_id_review_status_posted_at;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_review_status_chk;
+
+ALTER TABLE transactions
+DROP COLUMN IF EXISTS reviewed_at,
+DROP COLUMN IF EXISTS review_reasons,
+DROP COLUMN IF EXISTS review_status;
+
+-- +goose StatementEnd
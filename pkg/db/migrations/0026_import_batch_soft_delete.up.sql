@@ -0,0 +1,50 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- Deleting an import batch rolls the job back: its transactions are soft
+-- deleted and can be restored until they are purged after the retention window.
+ALTER TYPE import_status ADD VALUE IF NOT EXISTS 'rolled_back';
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+ALTER TABLE import_jobs
+ADD COLUMN IF NOT EXISTS rolled_back_at TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS status_before_rollback import_status,
+ADD COLUMN IF NOT EXISTS purged_at TIMESTAMPTZ;
+
+-- Soft-deleted rows must not block re-importing the same file
+DROP INDEX IF EXISTS uniq_transactions_user_id_source_external_id;
+
+CREATE UNIQUE INDEX uniq_transactions_user_id_source_external_id
+ON transactions (user_id, source, external_id)
+WHERE external_id IS NOT NULL AND deleted_at IS NULL;
+
+-- Restore and purge look up a batch's soft-deleted rows
+CREATE INDEX IF NOT EXISTS idx_transactions_import_job_id_deleted
+ON transactions (import_job_id)
+WHERE deleted_at IS NOT NULL;
+
+-- The purge sweep scans rolled back jobs past the retention window
+CREATE INDEX IF NOT EXISTS idx_import_jobs_rolled_back_at
+ON import_jobs (rolled_back_at)
+WHERE rolled_back_at IS NOT NULL AND purged_at IS NULL;
+
+-- +goose Down
+-- Postgres cannot drop enum values; rolled back jobs keep their status.
+DROP INDEX IF EXISTS idx_import_jobs_rolled_back_at;
+
+DROP INDEX IF EXISTS idx_transactions_import_job_id_deleted;
+
+DELETE FROM transactions WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS uniq_transactions_user_id_source_external_id;
+
+CREATE UNIQUE INDEX uniq_transactions_user_id_source_external_id
+ON transactions (user_id, source, external_id)
+WHERE external_id IS NOT NULL;
+
+ALTER TABLE import_jobs
+DROP COLUMN IF EXISTS purged_at,
+DROP COLUMN IF EXISTS status_before_rollback,
+DROP COLUMN IF EXISTS rolled_back_at;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS deleted_at;
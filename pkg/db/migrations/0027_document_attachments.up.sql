@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Receipts and invoices can be attached to the transaction that paid them,
+-- by the user or by the reconcile pass matching amount, currency and date.
+-- A 'manual' match_source without a transaction means the user detached the
+-- document, so the reconcile pass leaves it alone.
+ALTER TABLE documents
+ADD COLUMN transaction_id UUID REFERENCES transactions (id) ON DELETE SET NULL,
+ADD COLUMN match_source TEXT,
+ADD COLUMN match_score NUMERIC(4, 3),
+ADD COLUMN matched_at TIMESTAMPTZ;
+
+ALTER TABLE documents
+ADD CONSTRAINT documents_match_source_chk CHECK (match_source IN ('manual', 'auto'));
+
+CREATE INDEX idx_documents_transaction_id ON documents (transaction_id)
+WHERE
+    transaction_id IS NOT NULL;
+
+-- The reconcile pass scans documents still waiting for a transaction
+CREATE INDEX idx_documents_user_id_unattached ON documents (user_id, issued_at)
+WHERE
+    transaction_id IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_documents_user_id_unattached;
+
+DROP INDEX IF EXISTS idx_documents_transaction_id;
+
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_match_source_chk;
+
+ALTER TABLE documents
+DROP COLUMN IF EXISTS matched_at,
+DROP COLUMN IF EXISTS match_score,
+DROP COLUMN IF EXISTS match_source,
+DROP COLUMN IF EXISTS transaction_id;
+
+-- +goose StatementEnd
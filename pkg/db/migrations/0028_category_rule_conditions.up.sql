@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Rules can narrow their pattern by the transaction's amount, sign, account
+-- and institution. NULL leaves a condition out. A rule tied to an account goes
+-- with it rather than silently widening to every account.
+ALTER TABLE category_rules
+ADD COLUMN min_amount_minor BIGINT,
+ADD COLUMN max_amount_minor BIGINT,
+ADD COLUMN direction TEXT,
+ADD COLUMN account_id UUID REFERENCES accounts (id) ON DELETE CASCADE,
+ADD COLUMN institution_name TEXT;
+
+ALTER TABLE category_rules
+ADD CONSTRAINT category_rules_direction_chk CHECK (direction IN ('income', 'expense')),
+ADD CONSTRAINT category_rules_amount_range_chk CHECK (
+    min_amount_minor IS NULL
+    OR max_amount_minor IS NULL
+    OR min_amount_minor <= max_amount_minor
+);
+
+-- The same pattern may appear once per set of conditions
+DROP INDEX IF EXISTS idx_category_rules_unique_pattern;
+
+CREATE UNIQUE INDEX idx_category_rules_unique_rule ON category_rules (
+    user_id,
+    match_pattern,
+    COALESCE(min_amount_minor, -1),
+    COALESCE(max_amount_minor, -1),
+    COALESCE(direction, ''),
+    COALESCE(account_id, '00000000-0000-0000-0000-000000000000'::uuid),
+    lower(COALESCE(institution_name, ''))
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_category_rules_unique_rule;
+
+DELETE FROM category_rules r
+USING category_rules keep
+WHERE r.user_id = keep.user_id
+  AND r.match_pattern = keep.match_pattern
+  AND r.id <> keep.id
+  AND (r.created_at, r.id) > (keep.created_at, keep.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_category_rules_unique_pattern ON category_rules (user_id, match_pattern);
+
+ALTER TABLE category_rules
+DROP CONSTRAINT IF EXISTS category_rules_amount_range_chk,
+DROP CONSTRAINT IF EXISTS category_rules_direction_chk;
+
+ALTER TABLE category_rules
+DROP COLUMN IF EXISTS institution_name,
+DROP COLUMN IF EXISTS account_id,
+DROP COLUMN IF EXISTS direction,
+DROP COLUMN IF EXISTS max_amount_minor,
+DROP COLUMN IF EXISTS min_amount_minor;
+
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Disabled rules stay listed but no longer categorize anything
+ALTER TABLE category_rules
+ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT true;
+
+-- Each time a rule is applied to existing transactions the changed rows and
+-- their previous merchant name and category are kept, so a wrong rule can be
+-- rolled back. Runs outlive the rule they came from.
+CREATE TABLE IF NOT EXISTS category_rule_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    rule_id UUID REFERENCES category_rules (id) ON DELETE SET NULL,
+    match_pattern TEXT NOT NULL, -- The rule's pattern when it ran
+    transactions_changed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rolled_back_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_rule_runs_user_id ON category_rule_runs (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_category_rule_runs_rule_id ON category_rule_runs (rule_id)
+WHERE
+    rule_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS category_rule_run_changes (
+    run_id UUID NOT NULL REFERENCES category_rule_runs (id) ON DELETE CASCADE,
+    transaction_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    previous_merchant_name TEXT,
+    previous_category_id UUID REFERENCES categories (id) ON DELETE SET NULL,
+    merchant_name TEXT,
+    category_id UUID REFERENCES categories (id) ON DELETE SET NULL,
+    PRIMARY KEY (run_id, transaction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_rule_run_changes_transaction_id ON category_rule_run_changes (transaction_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS category_rule_run_changes;
+
+DROP TABLE IF EXISTS category_rule_runs;
+
+ALTER TABLE category_rules DROP COLUMN IF EXISTS enabled;
+
+-- +goose StatementEnd
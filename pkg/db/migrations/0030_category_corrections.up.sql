@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Categories users set by hand on single transactions. Repeated corrections
+-- of the same merchant turn into rule suggestions, and all of them act as a
+-- prior for descriptions no rule covers. merchant_key is the description
+-- normalized by categorization.MerchantKey. Corrections are resolved once a
+-- suggestion for their merchant is accepted or dismissed.
+CREATE TABLE IF NOT EXISTS category_corrections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    transaction_id UUID REFERENCES transactions (id) ON DELETE SET NULL,
+    merchant_key TEXT NOT NULL,
+    description TEXT NOT NULL,
+    previous_category_id UUID REFERENCES categories (id) ON DELETE SET NULL,
+    category_id UUID NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_corrections_user_key ON category_corrections (user_id, merchant_key, category_id);
+
+CREATE INDEX IF NOT EXISTS idx_category_corrections_unresolved ON category_corrections (user_id, merchant_key)
+WHERE
+    resolved_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS category_corrections;
+
+-- +goose StatementEnd
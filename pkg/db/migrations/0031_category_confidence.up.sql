@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- How sure categorization was of an imported row's category: 1 from a rule or
+-- known merchant, lower from past corrections and the statistical model. The
+-- review queue can filter on it. NULL when there is no category or it was
+-- set any other way.
+ALTER TABLE transactions
+ADD COLUMN category_confidence REAL;
+
+ALTER TABLE transactions
+ADD CONSTRAINT transactions_category_confidence_chk CHECK (category_confidence BETWEEN 0 AND 1);
+
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Any later change of category (an edit, a bulk update, a rule run or its
+-- rollback) makes the stored confidence stale, so it is cleared
+CREATE OR REPLACE FUNCTION clear_category_confidence()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.category_id IS DISTINCT FROM OLD.category_id
+       AND NEW.category_confidence IS NOT DISTINCT FROM OLD.category_confidence THEN
+        NEW.category_confidence = NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER trigger_transactions_clear_category_confidence
+    BEFORE UPDATE OF category_id ON transactions
+    FOR EACH ROW
+    EXECUTE FUNCTION clear_category_confidence();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS trigger_transactions_clear_category_confidence ON transactions;
+
+DROP FUNCTION IF EXISTS clear_category_confidence();
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_category_confidence_chk;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS category_confidence;
+
+-- +goose StatementEnd
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores objects as files below a root directory. URLs have the form
+// file:///<root>/<key>.
+type Local struct {
+	root string
+}
+
+var _ Storage = (*Local)(nil)
+
+// NewLocal creates a filesystem-backed store, creating root if needed.
+func NewLocal(root string) (*Local, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage root: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage root: %w", err)
+	}
+	return &Local{root: abs}, nil
+}
+
+// Put writes data atomically: a temporary file is renamed into place.
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(l.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create object: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", fmt.Errorf("failed to store object: %w", err)
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(target)}).String(), nil
+}
+
+// Get reads an object written by Put.
+func (l *Local) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	target, err := l.resolve(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes an object. Deleting a missing object is not an error.
+func (l *Local) Delete(ctx context.Context, rawURL string) error {
+	target, err := l.resolve(rawURL)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+// resolve maps a file:// URL back to a path, refusing paths outside the root.
+func (l *Local) resolve(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "file" {
+		return "", ErrInvalidURL
+	}
+	target := filepath.Clean(filepath.FromSlash(u.Path))
+	if !strings.HasPrefix(target, l.root+string(filepath.Separator)) {
+		return "", ErrInvalidURL
+	}
+	return target, nil
+}
@@ -0,0 +1,39 @@
+// Package storage keeps the original bytes of uploaded files on the local
+// filesystem or in an S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when an object does not exist.
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidKey is returned for empty keys or keys that escape the storage root.
+	ErrInvalidKey = errors.New("invalid object key")
+	// ErrInvalidURL is returned when a URL was not produced by the backend reading it.
+	ErrInvalidURL = errors.New("storage url does not belong to this backend")
+)
+
+// Storage stores opaque objects. Put returns a URL that is persisted with the
+// file record (user_files.storage_url) and later passed back to Get or Delete.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+	Get(ctx context.Context, url string) ([]byte, error)
+	Delete(ctx context.Context, url string) error
+}
+
+// cleanKey validates a slash-separated object key and returns it in canonical form.
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}